@@ -74,6 +74,21 @@ func Delete(key string) error {
 	return RedisClient.Del(Ctx, key).Err()
 }
 
+// RedirectKey 跳转热点缓存 key（按域名隔离）：redir:<domain_id>:<code>
+func RedirectKey(domainID int64, code string) string {
+	return fmt.Sprintf("redir:%d:%s", domainID, code)
+}
+
+// VisitorsKey 链接累计独立访客 HyperLogLog key：uv:<link_id>
+func VisitorsKey(linkID int64) string {
+	return fmt.Sprintf("uv:%d", linkID)
+}
+
+// VisitorsDayKey 链接按天独立访客 HyperLogLog key：uv:<link_id>:<YYYYMMDD>
+func VisitorsDayKey(linkID int64, day time.Time) string {
+	return fmt.Sprintf("uv:%d:%s", linkID, day.Format("20060102"))
+}
+
 // CloseRedis 关闭Redis连接
 func CloseRedis() error {
 	if RedisClient != nil {
@@ -1,27 +1,41 @@
 /**
  * Admin管理工具
  * 提供命令行工具用于管理admin用户
+ * 以及运维类操作（如重建点击预聚合表、全实例链接导入/导出、重建搜索索引）
  */
 package main
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	icfg "short-link/internal/config"
 	"short-link/internal/db"
+	"short-link/internal/importer"
+	"short-link/internal/jobs"
 	"short-link/internal/repo"
+	"short-link/internal/service"
+	"short-link/models"
 	"time"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func main() {
 	// 解析命令行参数
-	action := flag.String("action", "", "操作类型: reset-password (重置密码), show-info (显示信息)")
+	action := flag.String("action", "", "操作类型: reset-password (重置密码), show-info (显示信息), backfill-rollups (重建点击预聚合), export (导出链接，含密码哈希), import (导入链接，恢复密码/有效期/标签/文件夹), reindex (重建搜索索引), outbox-requeue (死信事件重新入队), partitions (维护访问日志分区)")
 	password := flag.String("password", "", "新密码（可选，不提供则随机生成）")
+	format := flag.String("format", "csv", "导入/导出格式: csv, json, ndjson")
+	file := flag.String("file", "-", "导入/导出文件路径（- 表示标准输入/输出）")
+	source := flag.String("source", "", "导入来源: bitly, yourls, shlink（为空表示本系统导出格式）")
+	username := flag.String("user", "", "第三方导入的归属用户名（默认 admin）")
+	domainID := flag.Int64("domain-id", 0, "导入到指定域名 ID（默认按记录中的域名/默认域名）")
+	dryRun := flag.Bool("dry-run", false, "演练导入：只校验并报告短码冲突，不写入数据库")
+	full := flag.Bool("full", false, "reindex 时全部链接重新索引（默认只修复缺失/过期/孤立文档）")
 	flag.Parse()
 	
 	// 加载配置
@@ -52,6 +66,25 @@ func main() {
 		resetAdminPassword(ctx, userRepo, *password)
 	case "show-info":
 		showAdminInfo(ctx, userRepo)
+	case "backfill-rollups":
+		backfillRollups(repo.NewStatsRepo(pool), cfg.StatsCountBots)
+	case "export":
+		exportLinks(newTransferService(cfg, pool, userRepo), *format, *file)
+	case "import":
+		importLinks(ctx, newTransferService(cfg, pool, userRepo), userRepo, importArgs{
+			format:   *format,
+			path:     *file,
+			source:   *source,
+			username: *username,
+			domainID: *domainID,
+			dryRun:   *dryRun,
+		})
+	case "reindex":
+		reindexLinks(cfg, pool, *full)
+	case "outbox-requeue":
+		requeueOutbox(ctx, repo.NewOutboxRepo(pool))
+	case "partitions":
+		maintainPartitions(cfg, pool)
 	case "":
 		showUsage()
 	default:
@@ -114,6 +147,167 @@ func showAdminInfo(ctx context.Context, userRepo *repo.UserRepo) {
 	fmt.Println("==========================================")
 }
 
+// backfillRollups 从 access_logs 重建小时/天点击预聚合表
+func backfillRollups(statsRepo *repo.StatsRepo, countBots bool) {
+	// 数据量大时耗时较长，不复用初始化用的 10s 超时
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	start := time.Now()
+	hourly, daily, err := statsRepo.RebuildRollups(ctx, countBots)
+	if err != nil {
+		log.Fatalf("重建预聚合表失败: %v", err)
+	}
+
+	fmt.Println("==========================================")
+	fmt.Println("✅ 点击预聚合表已重建")
+	fmt.Println("==========================================")
+	fmt.Printf("小时桶: %d 行\n", hourly)
+	fmt.Printf("天桶: %d 行\n", daily)
+	fmt.Printf("耗时: %s\n", time.Since(start).Round(time.Millisecond))
+	fmt.Println("==========================================")
+}
+
+// newTransferService 创建导入/导出服务（不启用统计与搜索 Worker）
+func newTransferService(cfg *icfg.Config, pool *db.Pool, userRepo *repo.UserRepo) *service.LinkTransferService {
+	linkRepo := repo.NewLinkRepo(pool)
+	domainRepo := repo.NewDomainRepo(pool)
+	linkService := service.NewLinkService(cfg.BaseURL, cfg.MinCodeLength, cfg.MaxCodeLength, linkRepo, domainRepo, repo.NewSettingsRepo(pool), userRepo, repo.NewAccessLogRepo(pool), nil, nil, nil, nil, nil, nil)
+	return service.NewLinkTransferService(linkService, linkRepo, domainRepo, userRepo, repo.NewFolderRepo(pool))
+}
+
+// exportLinks 导出全实例链接（含 username 与 password_hash 列，导出文件需妥善保管）
+func exportLinks(transferService *service.LinkTransferService, format string, path string) {
+	format, err := service.ParseLinkFormat(format)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			log.Fatalf("创建导出文件失败: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriterSize(out, 64<<10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	start := time.Now()
+	n, err := transferService.Export(ctx, w, format, 0)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		log.Fatalf("导出失败（已导出 %d 条）: %v", n, err)
+	}
+	// 统计信息输出到 stderr，避免污染导出到 stdout 的内容
+	fmt.Fprintf(os.Stderr, "✅ 已导出 %d 条链接（%s），耗时 %s\n", n, format, time.Since(start).Round(time.Millisecond))
+}
+
+// importArgs import 操作的命令行参数
+type importArgs struct {
+	format   string
+	path     string
+	source   string // 第三方来源（bitly/yourls/shlink），为空表示本系统导出格式
+	username string
+	domainID int64
+	dryRun   bool
+}
+
+// importLinks 导入链接（保留点击数、创建时间与访问密码哈希；生命周期、文件夹与标签随记录恢复）
+// - 本系统导出：按 username 列归属
+// - 第三方来源：全部归属 -user 指定的用户（默认 admin）
+func importLinks(ctx context.Context, transferService *service.LinkTransferService, userRepo *repo.UserRepo, args importArgs) {
+	opts := service.ImportOptions{
+		DomainID:         args.domainID,
+		PreserveStats:    true,
+		PreservePassword: true,
+		DryRun:           args.dryRun,
+	}
+	if args.source != "" || args.username != "" {
+		name := args.username
+		if name == "" {
+			name = "admin"
+		}
+		owner, err := userRepo.GetUserByUsername(ctx, name)
+		if err != nil {
+			log.Fatalf("用户 %s 不存在: %v", name, err)
+		}
+		opts.UserID = owner.ID
+	}
+
+	var in io.Reader = os.Stdin
+	if args.path != "-" {
+		f, err := os.Open(args.path)
+		if err != nil {
+			log.Fatalf("打开导入文件失败: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	in = bufio.NewReaderSize(in, 64<<10)
+
+	importCtx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	start := time.Now()
+	var report *models.LinkImportReport
+	if args.source != "" {
+		src, err := importer.Open(args.source, in)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		report, err = transferService.ImportRecords(importCtx, src, opts)
+		printImportReport(report)
+		if err != nil {
+			log.Fatalf("导入中断: %v", err)
+		}
+	} else {
+		format, err := service.ParseLinkFormat(args.format)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		report, err = transferService.Import(importCtx, in, format, opts)
+		printImportReport(report)
+		if err != nil {
+			log.Fatalf("导入中断: %v", err)
+		}
+	}
+	fmt.Printf("耗时: %s\n", time.Since(start).Round(time.Millisecond))
+}
+
+// printImportReport 输出导入结果（冲突/失败明细最多显示 20 条）
+func printImportReport(report *models.LinkImportReport) {
+	fmt.Println("==========================================")
+	if report.DryRun {
+		fmt.Println("📥 链接导入演练结果（未写入数据库）")
+	} else {
+		fmt.Println("📥 链接导入结果")
+	}
+	fmt.Println("==========================================")
+	fmt.Printf("总数: %d\n", report.Total)
+	if report.DryRun {
+		fmt.Printf("可导入: %d\n", report.Imported)
+	} else {
+		fmt.Printf("导入: %d\n", report.Imported)
+	}
+	fmt.Printf("冲突: %d\n", report.Conflicts)
+	fmt.Printf("失败: %d\n", report.Failed)
+	for i, e := range report.Errors {
+		if i == 20 {
+			fmt.Printf("  ...（共 %d 条）\n", report.Conflicts+report.Failed)
+			break
+		}
+		fmt.Printf("  第 %d 条 [%s/%s]: %s\n", e.Row, e.Domain, e.Code, e.Error)
+	}
+	fmt.Println("==========================================")
+}
+
 // generateRandomPassword 生成随机密码
 func generateRandomPassword(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*"
@@ -128,6 +322,93 @@ func generateRandomPassword(length int) string {
 	return string(bytes)
 }
 
+// reindexLinks 对账 Meilisearch 索引与 links 表（full 为 true 时全量重建）
+func reindexLinks(cfg *icfg.Config, pool *db.Pool, full bool) {
+	linkRepo := repo.NewLinkRepo(pool)
+	meiliWorker := jobs.NewMeiliWorker(cfg, linkRepo, repo.NewTagRepo(pool))
+	if err := meiliWorker.Healthy(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	reconciler := jobs.NewMeiliReconciler(meiliWorker, linkRepo, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	start := time.Now()
+	result, err := reconciler.Reconcile(ctx, full)
+	if err != nil {
+		if result != nil {
+			log.Printf("已遍历 %d 条链接，已修复 %d 个文档", result.Scanned, result.Repaired())
+		}
+		log.Fatalf("重建搜索索引失败: %v", err)
+	}
+
+	fmt.Println("==========================================")
+	fmt.Println("✅ 搜索索引对账完成")
+	fmt.Println("==========================================")
+	fmt.Printf("数据库链接: %d 条\n", result.Scanned)
+	fmt.Printf("索引文档（对账前）: %d 个\n", result.Indexed)
+	fmt.Printf("补写缺失: %d\n", result.Missing)
+	fmt.Printf("重写过期: %d\n", result.Stale)
+	fmt.Printf("删除孤立: %d\n", result.Orphaned)
+	fmt.Printf("刷新点击数: %d\n", result.Clicks)
+	if full {
+		fmt.Printf("全量重写: %d\n", result.Full)
+	}
+	fmt.Printf("耗时: %s\n", time.Since(start).Round(time.Millisecond))
+	fmt.Println("（索引写入由 Meilisearch 异步执行，可能需要片刻才能在搜索中生效）")
+	fmt.Println("==========================================")
+}
+
+// requeueOutbox 死信 outbox 事件重新入队（由运行中的服务重新投递）
+func requeueOutbox(ctx context.Context, outboxRepo *repo.OutboxRepo) {
+	n, err := outboxRepo.RequeueDead(ctx)
+	if err != nil {
+		log.Fatalf("重新入队失败: %v", err)
+	}
+	counts, err := outboxRepo.CountByStatus(ctx)
+	if err != nil {
+		log.Fatalf("统计 outbox 事件失败: %v", err)
+	}
+
+	fmt.Println("==========================================")
+	fmt.Println("✅ 死信事件已重新入队")
+	fmt.Println("==========================================")
+	fmt.Printf("重新入队: %d\n", n)
+	fmt.Printf("待投递: %d\n", counts[models.OutboxStatusPending])
+	fmt.Printf("已投递（保留期内）: %d\n", counts[models.OutboxStatusDone])
+	fmt.Printf("死信: %d\n", counts[models.OutboxStatusDead])
+	fmt.Println("==========================================")
+}
+
+// maintainPartitions 执行一次 access_logs 分区维护（建分区、搬迁旧表、按保留期删除/归档）
+func maintainPartitions(cfg *icfg.Config, pool *db.Pool) {
+	job := jobs.NewAccessLogPartitionJob(repo.NewAccessLogPartitionRepo(pool), cfg.AccessLogPartitionAhead, cfg.AccessLogRetentionMonths, cfg.AccessLogArchiveDir)
+
+	// 搬迁旧表数据量大时耗时较长
+	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Hour)
+	defer cancel()
+
+	start := time.Now()
+	result, err := job.RunOnce(ctx, time.Now())
+	if err != nil {
+		if result != nil {
+			log.Printf("已新建 %d 个分区，搬迁 %d 行，删除 %d 个分区", result.Created, result.Moved, result.Dropped)
+		}
+		log.Fatalf("分区维护失败: %v", err)
+	}
+
+	fmt.Println("==========================================")
+	fmt.Println("✅ 访问日志分区维护完成")
+	fmt.Println("==========================================")
+	fmt.Printf("新建分区: %d\n", result.Created)
+	fmt.Printf("旧表搬迁: %d 行\n", result.Moved)
+	fmt.Printf("归档分区: %d\n", result.Archived)
+	fmt.Printf("删除分区: %d\n", result.Dropped)
+	fmt.Printf("耗时: %s\n", time.Since(start).Round(time.Millisecond))
+	fmt.Println("==========================================")
+}
+
 // showUsage 显示使用说明
 func showUsage() {
 	fmt.Println("Admin管理工具")
@@ -135,14 +416,30 @@ func showUsage() {
 	fmt.Println("用法:")
 	fmt.Println("  nsl-admin -action=reset-password [-password=新密码]")
 	fmt.Println("  nsl-admin -action=show-info")
+	fmt.Println("  nsl-admin -action=backfill-rollups")
+	fmt.Println("  nsl-admin -action=export [-format=csv|json|ndjson] [-file=links.csv]")
+	fmt.Println("  nsl-admin -action=import [-format=csv|json|ndjson] -file=links.csv [-dry-run]")
+	fmt.Println("  nsl-admin -action=import -source=bitly|yourls|shlink -file=export.csv [-user=admin] [-domain-id=1] [-dry-run]")
+	fmt.Println("  nsl-admin -action=reindex [-full]")
+	fmt.Println("  nsl-admin -action=outbox-requeue")
+	fmt.Println("  nsl-admin -action=partitions")
 	fmt.Println("")
 	fmt.Println("操作说明:")
 	fmt.Println("  reset-password  重置admin用户密码（不提供-password参数则随机生成）")
 	fmt.Println("  show-info       显示admin用户信息")
+	fmt.Println("  backfill-rollups 从访问日志重建按小时/天的点击预聚合表")
+	fmt.Println("  export          导出全实例链接（含 username 列，-file 缺省输出到标准输出）")
+	fmt.Println("  import          导入链接（按 username 归属，空则归 admin；已存在的 domain+code 计为冲突）")
+	fmt.Println("                  -source 指定第三方导出（Bitly CSV / YOURLS SQL 或 JSON / Shlink JSON 或 CSV）")
+	fmt.Println("                  -dry-run 只报告短码冲突与无效记录，不写入")
+	fmt.Println("  reindex         对账搜索索引：补写缺失/过期文档、删除孤立文档（-full 全部重新索引）")
+	fmt.Println("  outbox-requeue  将投递失败转入死信的 outbox 事件（搜索索引等）重新入队")
+	fmt.Println("  partitions      维护访问日志月分区：提前建分区、搬迁升级前的旧表、按保留期删除（或先归档）过期分区")
 	fmt.Println("")
 	fmt.Println("示例:")
 	fmt.Println("  nsl-admin -action=reset-password")
 	fmt.Println("  nsl-admin -action=reset-password -password=MyNewPassword123")
 	fmt.Println("  nsl-admin -action=show-info")
+	fmt.Println("  nsl-admin -action=import -source=bitly -file=bitly_links.csv -dry-run")
 }
 
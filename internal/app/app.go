@@ -117,9 +117,31 @@ func Run() error {
 		if v2.StatsWorker != nil {
 			v2.StatsWorker.Start()
 		}
-		// 启动 Meilisearch Worker
-		if v2.LinkService != nil && v2.LinkService.GetMeiliWorker() != nil {
-			v2.LinkService.GetMeiliWorker().Start()
+		// 启动实时点击流分发
+		if v2.LiveHub != nil {
+			v2.LiveHub.Start()
+		}
+		// 启动 GeoIP 热加载（未配置时为空操作）
+		v2.GeoIP.Start()
+		// 启动 outbox 投递（搜索索引写入）
+		if v2.OutboxDispatcher != nil {
+			v2.OutboxDispatcher.Start()
+		}
+		// 启动 webhook 投递（含到期链接扫描）
+		if v2.WebhookWorker != nil {
+			v2.WebhookWorker.Start()
+		}
+		// 启动点击异常告警（ALERT_INTERVAL_MINUTES=0 时不启动）
+		if v2.ClickAlertJob != nil {
+			v2.ClickAlertJob.Start()
+		}
+		// 启动 access_logs 分区维护
+		if v2.PartitionJob != nil {
+			v2.PartitionJob.Start()
+		}
+		// 启动 Meilisearch 周期对账（MEILI_RECONCILE_INTERVAL_MINUTES=0 时不启动）
+		if v2.MeiliReconciler != nil {
+			v2.MeiliReconciler.Start()
 		}
 		httpv2.RegisterRoutes(router, v2)
 	}
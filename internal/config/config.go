@@ -40,9 +40,61 @@ type Config struct {
 	RedisPassword string
 	MeiliHost     string
 	MeiliKey      string
+	// Meilisearch 索引与 links 表的周期对账间隔（0 表示不启用）
+	MeiliReconcileInterval time.Duration
 
 	// Tracing（可选）
 	JaegerEndpoint string
+
+	// 统计：爬虫访问是否计入点击数（默认不计入，仅记录访问日志）
+	StatsCountBots bool
+	// 统计写入模式：memory（内存队列）/ durable（Redis Stream，未启用 Redis 时为本地 spool 文件）
+	StatsIngestMode string
+	// durable 模式未启用 Redis 时的 spool 目录
+	StatsSpoolDir string
+	// durable 模式 Redis Stream 的近似最大长度
+	StatsStreamMaxLen int64
+	// 统计 Worker 单批最大点击数 / 未满一批时的刷新间隔 / 并发写入 goroutine 数（memory 模式）
+	StatsBatchSize     int
+	StatsFlushInterval time.Duration
+	StatsConcurrency   int
+	// 访问日志隐私：IP 保存方式（full / truncate / hash / drop）/ hash 模式的密钥 / 是否遵循 DNT 与 Sec-GPC
+	StatsIPMode       string
+	StatsIPHashSecret string
+	StatsHonorDNT     bool
+
+	// access_logs 按月分区：提前创建的月数 / 保留的完整月数（0 表示永久保留）/ 删除前的归档目录（为空表示直接删除）
+	AccessLogPartitionAhead  int
+	AccessLogRetentionMonths int
+	AccessLogArchiveDir      string
+
+	// Webhook 投递：单次请求超时 / 最大尝试次数（超过后标记 failed）/ 并发投递数 / 投递记录保留天数
+	WebhookTimeout       time.Duration
+	WebhookMaxAttempts   int
+	WebhookConcurrency   int
+	WebhookRetentionDays int
+
+	// 点击异常告警：检查间隔（0 表示关闭）/ 基线小时数 / 全局默认阈值（用户与链接可覆盖）
+	AlertInterval        time.Duration
+	AlertBaselineHours   int
+	AlertSpikeFactor     float64
+	AlertSpikeMinClicks  int64
+	AlertDropMinBaseline float64
+	AlertBotRatio        float64
+
+	// SMTP（告警邮件；SMTP_HOST 为空时不发送邮件）
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// GeoIP（可选，本地 MaxMind mmdb 文件；为空则不解析归属地）
+	GeoIPCityDBPath string
+	GeoIPASNDBPath  string
+
+	// 批量创建链接单次请求的最大条数（POST /api/v2/links/batch）
+	LinkBatchMaxItems int
 }
 
 // Load 从环境变量加载配置（重写版）
@@ -70,7 +122,46 @@ func Load() (*Config, error) {
 		RedisPassword: getenv("REDIS_PASSWORD", ""),
 		MeiliHost:     getenv("MEILI_HOST", "http://localhost:7700"),
 		MeiliKey:      getenv("MEILI_KEY", ""),
+		MeiliReconcileInterval: time.Minute * time.Duration(getenvInt("MEILI_RECONCILE_INTERVAL_MINUTES", 60)),
 		JaegerEndpoint: getenv("JAEGER_ENDPOINT", ""),
+
+		StatsCountBots: getenvBool("STATS_COUNT_BOTS", false),
+		StatsIngestMode: getenv("STATS_INGEST_MODE", "memory"),
+		StatsSpoolDir:   getenv("STATS_SPOOL_DIR", "data/click-spool"),
+		StatsStreamMaxLen: int64(getenvInt("STATS_STREAM_MAXLEN", 1000000)),
+		StatsBatchSize:     getenvInt("STATS_BATCH_SIZE", 500),
+		StatsFlushInterval: time.Millisecond * time.Duration(getenvInt("STATS_FLUSH_INTERVAL_MS", 2000)),
+		StatsConcurrency:   getenvInt("STATS_WORKER_CONCURRENCY", 1),
+		StatsIPMode:        getenv("STATS_IP_MODE", "full"),
+		StatsIPHashSecret:  getenv("STATS_IP_HASH_SECRET", ""),
+		StatsHonorDNT:      getenvBool("STATS_HONOR_DNT", true),
+
+		AccessLogPartitionAhead:  getenvInt("ACCESS_LOG_PARTITION_AHEAD_MONTHS", 3),
+		AccessLogRetentionMonths: getenvInt("ACCESS_LOG_RETENTION_MONTHS", 0),
+		AccessLogArchiveDir:      getenv("ACCESS_LOG_ARCHIVE_DIR", ""),
+
+		WebhookTimeout:       time.Second * time.Duration(getenvInt("WEBHOOK_TIMEOUT_SECONDS", 10)),
+		WebhookMaxAttempts:   getenvInt("WEBHOOK_MAX_ATTEMPTS", 10),
+		WebhookConcurrency:   getenvInt("WEBHOOK_CONCURRENCY", 4),
+		WebhookRetentionDays: getenvInt("WEBHOOK_DELIVERY_RETENTION_DAYS", 30),
+
+		AlertInterval:        time.Minute * time.Duration(getenvInt("ALERT_INTERVAL_MINUTES", 5)),
+		AlertBaselineHours:   getenvInt("ALERT_BASELINE_HOURS", 168),
+		AlertSpikeFactor:     getenvFloat("ALERT_SPIKE_FACTOR", 5),
+		AlertSpikeMinClicks:  int64(getenvInt("ALERT_SPIKE_MIN_CLICKS", 100)),
+		AlertDropMinBaseline: getenvFloat("ALERT_DROP_MIN_BASELINE", 20),
+		AlertBotRatio:        getenvFloat("ALERT_BOT_RATIO", 0.5),
+
+		SMTPHost:     getenv("SMTP_HOST", ""),
+		SMTPPort:     getenvInt("SMTP_PORT", 587),
+		SMTPUsername: getenv("SMTP_USERNAME", ""),
+		SMTPPassword: getenv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getenv("SMTP_FROM", "noreply@localhost"),
+
+		GeoIPCityDBPath: getenv("GEOIP_CITY_DB", ""),
+		GeoIPASNDBPath:  getenv("GEOIP_ASN_DB", ""),
+
+		LinkBatchMaxItems: getenvInt("LINK_BATCH_MAX_ITEMS", 1000),
 	}
 
 	// 强制安全基线：生产/默认都要求 JWT_SECRET
@@ -80,6 +171,29 @@ func Load() (*Config, error) {
 	if cfg.MinCodeLength <= 0 || cfg.MaxCodeLength <= 0 || cfg.MinCodeLength > cfg.MaxCodeLength {
 		return nil, fmt.Errorf("MIN_CODE_LENGTH / MAX_CODE_LENGTH 配置无效")
 	}
+	if cfg.StatsIngestMode != "memory" && cfg.StatsIngestMode != "durable" {
+		return nil, fmt.Errorf("STATS_INGEST_MODE 配置无效（memory / durable）")
+	}
+	if cfg.StatsBatchSize <= 0 || cfg.StatsFlushInterval <= 0 || cfg.StatsConcurrency <= 0 {
+		return nil, fmt.Errorf("STATS_BATCH_SIZE / STATS_FLUSH_INTERVAL_MS / STATS_WORKER_CONCURRENCY 必须大于 0")
+	}
+	switch cfg.StatsIPMode {
+	case "full", "truncate", "hash", "drop":
+	default:
+		return nil, fmt.Errorf("STATS_IP_MODE 配置无效（full / truncate / hash / drop）")
+	}
+	if cfg.WebhookTimeout <= 0 || cfg.WebhookMaxAttempts <= 0 || cfg.WebhookConcurrency <= 0 || cfg.WebhookRetentionDays <= 0 {
+		return nil, fmt.Errorf("WEBHOOK_TIMEOUT_SECONDS / WEBHOOK_MAX_ATTEMPTS / WEBHOOK_CONCURRENCY / WEBHOOK_DELIVERY_RETENTION_DAYS 必须大于 0")
+	}
+	if cfg.AlertInterval < 0 || cfg.AlertBaselineHours <= 0 {
+		return nil, fmt.Errorf("ALERT_INTERVAL_MINUTES 不能为负数，ALERT_BASELINE_HOURS 必须大于 0")
+	}
+	if (cfg.AlertSpikeFactor != 0 && cfg.AlertSpikeFactor < 1) || cfg.AlertSpikeMinClicks < 0 || cfg.AlertDropMinBaseline < 0 || cfg.AlertBotRatio < 0 || cfg.AlertBotRatio > 1 {
+		return nil, fmt.Errorf("ALERT_SPIKE_FACTOR（>= 1 或 0）/ ALERT_SPIKE_MIN_CLICKS / ALERT_DROP_MIN_BASELINE / ALERT_BOT_RATIO（0~1）配置无效")
+	}
+	if cfg.SMTPHost != "" && (cfg.SMTPPort <= 0 || cfg.SMTPFrom == "") {
+		return nil, fmt.Errorf("启用 SMTP 时 SMTP_PORT 必须大于 0 且 SMTP_FROM 不能为空")
+	}
 	return cfg, nil
 }
 
@@ -102,4 +216,26 @@ func getenvInt(key string, def int) int {
 	return n
 }
 
+func getenvBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
 
+func getenvFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
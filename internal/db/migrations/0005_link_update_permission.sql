@@ -0,0 +1,13 @@
+-- 0005_link_update_permission.sql
+-- 新增权限点：更新短链接（PATCH /api/v2/links/:id）
+
+INSERT INTO permissions (name, description, resource_type) VALUES
+  ('link:update', '更新短链接', 'link')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission_id)
+SELECT r.role, p.id
+FROM permissions p
+CROSS JOIN (VALUES ('admin'), ('user')) AS r(role)
+WHERE p.name = 'link:update'
+ON CONFLICT (role, permission_id) DO NOTHING;
@@ -0,0 +1,8 @@
+-- 0006_link_expiration.sql
+-- 短链生命周期：按时间过期 / 按点击预算用尽
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS max_clicks BIGINT;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS expired_redirect_url TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_links_expires_at ON links(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,4 @@
+-- 0007_link_password.sql
+-- 密码保护短链：仅存储 bcrypt hash
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS password_hash VARCHAR(255);
@@ -0,0 +1,44 @@
+-- 0008_click_rollups.sql
+-- 点击预聚合表（按小时/按天），由 StatsWorker 与访问日志同事务写入
+-- domain_id/user_id 为冗余列，便于按域名/用户直接聚合，无需回表 links
+
+CREATE TABLE IF NOT EXISTS click_rollups_hourly (
+  bucket TIMESTAMP NOT NULL,
+  link_id BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  domain_id BIGINT NOT NULL DEFAULT 0,
+  user_id BIGINT NOT NULL DEFAULT 0,
+  click_count BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (link_id, bucket)
+);
+CREATE INDEX IF NOT EXISTS idx_click_rollups_hourly_bucket ON click_rollups_hourly(bucket);
+CREATE INDEX IF NOT EXISTS idx_click_rollups_hourly_user ON click_rollups_hourly(user_id, bucket);
+CREATE INDEX IF NOT EXISTS idx_click_rollups_hourly_domain ON click_rollups_hourly(domain_id, bucket);
+
+CREATE TABLE IF NOT EXISTS click_rollups_daily (
+  day DATE NOT NULL,
+  link_id BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  domain_id BIGINT NOT NULL DEFAULT 0,
+  user_id BIGINT NOT NULL DEFAULT 0,
+  click_count BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (link_id, day)
+);
+CREATE INDEX IF NOT EXISTS idx_click_rollups_daily_day ON click_rollups_daily(day);
+CREATE INDEX IF NOT EXISTS idx_click_rollups_daily_user ON click_rollups_daily(user_id, day);
+CREATE INDEX IF NOT EXISTS idx_click_rollups_daily_domain ON click_rollups_daily(domain_id, day);
+
+-- 首次上线时从已有访问日志回填（之后可用 nsl-admin -action=backfill-rollups 重建）
+INSERT INTO click_rollups_hourly (bucket, link_id, domain_id, user_id, click_count)
+SELECT date_trunc('hour', a.created_at), a.link_id, COALESCE(l.domain_id, 0), COALESCE(l.user_id, 0), COUNT(*)
+FROM access_logs a
+JOIN links l ON l.id = a.link_id
+WHERE a.created_at IS NOT NULL
+GROUP BY 1, 2, 3, 4
+ON CONFLICT (link_id, bucket) DO NOTHING;
+
+INSERT INTO click_rollups_daily (day, link_id, domain_id, user_id, click_count)
+SELECT a.created_at::date, a.link_id, COALESCE(l.domain_id, 0), COALESCE(l.user_id, 0), COUNT(*)
+FROM access_logs a
+JOIN links l ON l.id = a.link_id
+WHERE a.created_at IS NOT NULL
+GROUP BY 1, 2, 3, 4
+ON CONFLICT (link_id, day) DO NOTHING;
@@ -0,0 +1,11 @@
+-- 0009_access_log_user_agent.sql
+-- 访问日志入库时解析 UA：浏览器/版本、操作系统、设备类型、爬虫标记
+-- 历史记录不回填（解析在应用侧完成），这些列保持 NULL / false
+
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS browser VARCHAR(64);
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS browser_version VARCHAR(32);
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS os VARCHAR(64);
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS device VARCHAR(16);
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_access_logs_link_id_is_bot ON access_logs(link_id, is_bot);
@@ -0,0 +1,8 @@
+-- 0010_access_log_geoip.sql
+-- 访问日志 GeoIP 归属地（离线 mmdb 解析，未配置时保持 NULL）
+
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS country_code VARCHAR(2);
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS region VARCHAR(128);
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS city VARCHAR(128);
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS asn BIGINT;
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS as_org VARCHAR(255);
@@ -0,0 +1,20 @@
+-- 0011_link_rules.sql
+-- 定向跳转规则：按国家/设备/系统/语言/时间窗口匹配，命中则跳转到规则目标地址
+-- 同一链接的规则按 priority 升序逐条匹配，首条命中生效；均未命中时回退 original_url
+-- 条件列为空数组/NULL 表示不限制
+
+CREATE TABLE IF NOT EXISTS link_rules (
+  id BIGSERIAL PRIMARY KEY,
+  link_id BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  priority INT NOT NULL DEFAULT 0,
+  countries TEXT[] NOT NULL DEFAULT '{}',
+  devices TEXT[] NOT NULL DEFAULT '{}',
+  os TEXT[] NOT NULL DEFAULT '{}',
+  languages TEXT[] NOT NULL DEFAULT '{}',
+  starts_at TIMESTAMPTZ,
+  ends_at TIMESTAMPTZ,
+  target_url TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_link_rules_link_id ON link_rules(link_id, priority, id);
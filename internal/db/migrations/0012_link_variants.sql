@@ -0,0 +1,17 @@
+-- 0012_link_variants.sql
+-- A/B 分流：同一短链配置多个按权重分配的目标地址
+-- 访问日志记录命中的 variant_id，用于按变体统计点击（变体删除后历史日志保留原 ID）
+
+CREATE TABLE IF NOT EXISTS link_variants (
+  id BIGSERIAL PRIMARY KEY,
+  link_id BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  label VARCHAR(100) NOT NULL DEFAULT '',
+  target_url TEXT NOT NULL,
+  weight INT NOT NULL CHECK (weight > 0),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_link_variants_link_id ON link_variants(link_id, id);
+
+ALTER TABLE access_logs ADD COLUMN IF NOT EXISTS variant_id BIGINT;
+CREATE INDEX IF NOT EXISTS idx_access_logs_link_variant ON access_logs(link_id, variant_id) WHERE variant_id IS NOT NULL;
@@ -0,0 +1,30 @@
+-- 0013_link_tags_folders.sql
+-- 链接整理：用户级标签（多对多）与一级文件夹
+-- 删除文件夹时其中的链接变为未归档（folder_id 置空）；删除标签只解除关联
+
+CREATE TABLE IF NOT EXISTS folders (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(user_id, name)
+);
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS folder_id BIGINT REFERENCES folders(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_links_user_folder ON links(user_id, folder_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS tags (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS link_tags (
+  link_id BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (link_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_link_tags_tag_id ON link_tags(tag_id, link_id);
@@ -0,0 +1,19 @@
+-- 0014_outbox_events.sql
+-- 事务性 outbox：链接增删改与事件在同一事务内写入，由后台 dispatcher 投递（搜索索引等副作用）
+-- status: pending 待投递 / done 已投递 / dead 超过最大重试次数（死信，可由 nsl-admin 重新入队）
+-- available_at: 下次可被领取的时间（领取时顺延作为租约，失败时按退避时间顺延）
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+  id BIGSERIAL PRIMARY KEY,
+  event_type VARCHAR(50) NOT NULL,
+  aggregate_id BIGINT NOT NULL,
+  payload JSONB NOT NULL DEFAULT '{}',
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  last_error TEXT,
+  available_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  processed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(available_at, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_events_processed ON outbox_events(processed_at) WHERE status = 'done';
@@ -0,0 +1,14 @@
+-- 0015_links_search_tsv.sql
+-- 全文检索降级：Meilisearch 不可用时按 tsvector 搜索 title / original_url / code
+-- simple 配置不做词干化与停用词，适配多语言标题（中文按空白与标点切分，支持词首前缀匹配）
+-- original_url 先将非字母数字替换为空格，使域名、路径片段可单独命中
+-- 生成列会重写 links 表，数据量大时迁移耗时较长
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS search_tsv tsvector
+  GENERATED ALWAYS AS (
+    to_tsvector('simple',
+      coalesce(code, '') || ' ' ||
+      coalesce(title, '') || ' ' ||
+      regexp_replace(coalesce(original_url, ''), '[^[:alnum:]]+', ' ', 'g'))
+  ) STORED;
+CREATE INDEX IF NOT EXISTS idx_links_search_tsv ON links USING GIN (search_tsv);
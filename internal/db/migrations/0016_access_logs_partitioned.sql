@@ -0,0 +1,60 @@
+-- 0016_access_logs_partitioned.sql
+-- access_logs 改为按月分区（RANGE created_at），按月删除/归档过期明细，统计查询只扫描保留期内的分区
+-- 迁移不复制数据（避免大表长时间锁表与迁移超时）：
+--   旧表重命名为 access_logs_legacy，由后台分区维护任务分批搬迁到新表，搬完后删除
+--   搬迁期间旧明细逐批出现在 access_logs 中；时间维度统计读取预聚合表，不受影响
+-- 分区命名 access_logs_pYYYYMM，由后台任务提前创建；access_logs_default 兜底超出已建分区范围的记录
+
+ALTER TABLE access_logs RENAME TO access_logs_legacy;
+ALTER INDEX IF EXISTS access_logs_pkey RENAME TO access_logs_legacy_pkey;
+ALTER INDEX IF EXISTS idx_access_logs_link_id RENAME TO idx_access_logs_legacy_link_id;
+ALTER INDEX IF EXISTS idx_access_logs_created_at RENAME TO idx_access_logs_legacy_created_at;
+ALTER INDEX IF EXISTS idx_access_logs_link_id_is_bot RENAME TO idx_access_logs_legacy_link_id_is_bot;
+ALTER INDEX IF EXISTS idx_access_logs_link_variant RENAME TO idx_access_logs_legacy_link_variant;
+
+-- 沿用旧表的 ID 序列（搬迁的记录保留原 ID），扩展为 BIGINT
+ALTER SEQUENCE access_logs_id_seq AS BIGINT;
+
+CREATE TABLE access_logs (
+  id BIGINT NOT NULL DEFAULT nextval('access_logs_id_seq'),
+  link_id BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  ip VARCHAR(45),
+  user_agent TEXT,
+  referer TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  browser VARCHAR(64),
+  browser_version VARCHAR(32),
+  os VARCHAR(64),
+  device VARCHAR(16),
+  is_bot BOOLEAN NOT NULL DEFAULT false,
+  country_code VARCHAR(2),
+  region VARCHAR(128),
+  city VARCHAR(128),
+  asn BIGINT,
+  as_org VARCHAR(255),
+  variant_id BIGINT,
+  PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+ALTER SEQUENCE access_logs_id_seq OWNED BY access_logs.id;
+
+CREATE INDEX IF NOT EXISTS idx_access_logs_link_id ON access_logs(link_id);
+CREATE INDEX IF NOT EXISTS idx_access_logs_created_at ON access_logs(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_access_logs_link_id_is_bot ON access_logs(link_id, is_bot);
+CREATE INDEX IF NOT EXISTS idx_access_logs_link_variant ON access_logs(link_id, variant_id) WHERE variant_id IS NOT NULL;
+
+CREATE TABLE access_logs_default PARTITION OF access_logs DEFAULT;
+
+-- 当月及后两个月的分区（之后由后台任务按 ACCESS_LOG_PARTITION_AHEAD_MONTHS 提前创建）
+DO $$
+DECLARE
+  m DATE;
+BEGIN
+  FOR i IN 0..2 LOOP
+    m := (date_trunc('month', CURRENT_DATE) + make_interval(months => i))::date;
+    EXECUTE format(
+      'CREATE TABLE IF NOT EXISTS %I PARTITION OF access_logs FOR VALUES FROM (%L) TO (%L)',
+      'access_logs_p' || to_char(m, 'YYYYMM'), m, (m + interval '1 month')::date
+    );
+  END LOOP;
+END $$;
@@ -0,0 +1,10 @@
+-- 0017_unique_visitors.sql
+-- 独立访客数（HyperLogLog 估计）：links 为累计，click_rollups_daily 为按天
+-- unique_clicks 为估计值；visitors_hll 为未启用 Redis 时由 StatsWorker 维护的 sketch（启用 Redis 时 sketch 保存在 Redis，此列为空）
+-- 上线前的历史点击无法回填独立访客
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS unique_clicks BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS visitors_hll BYTEA;
+
+ALTER TABLE click_rollups_daily ADD COLUMN IF NOT EXISTS unique_clicks BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE click_rollups_daily ADD COLUMN IF NOT EXISTS visitors_hll BYTEA;
@@ -0,0 +1,59 @@
+-- 0018_webhooks.sql
+-- 出站 webhook：用户注册接收地址并订阅事件类型，投递记录保存在 webhook_deliveries
+-- 链接事件（link.created / updated / deleted / expired）与 outbox 事件在同一事务内写入投递记录
+-- 点击事件（link.clicked）由 StatsWorker 每批按接收地址合并为一条投递
+-- status: pending 待投递（含退避重试中）/ succeeded 已送达 / failed 超过最大尝试次数
+-- next_attempt_at: 下次可被领取的时间（领取时顺延作为租约，失败时按退避时间顺延）
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  secret VARCHAR(100) NOT NULL,
+  events TEXT[] NOT NULL DEFAULT '{}',
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_user_id ON webhook_endpoints(user_id) WHERE active;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  endpoint_id BIGINT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+  event_type VARCHAR(50) NOT NULL,
+  event_id VARCHAR(100) NOT NULL, -- 接收方去重用（手动重新投递沿用原事件 ID）
+  payload JSONB NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  response_status INT,
+  response_body TEXT,
+  last_error TEXT,
+  duration_ms INT,
+  redelivery_of BIGINT, -- 手动重新投递时指向原投递记录
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  delivered_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries(created_at);
+
+-- 链接失效时间（到期或点击预算用尽时写入，并产生 link.expired 事件；已失效的存量链接直接回填，不补发事件）
+ALTER TABLE links ADD COLUMN IF NOT EXISTS expired_at TIMESTAMP;
+UPDATE links SET expired_at = NOW()
+WHERE expired_at IS NULL
+  AND ((expires_at IS NOT NULL AND expires_at <= NOW()) OR (max_clicks IS NOT NULL AND click_count >= max_clicks));
+CREATE INDEX IF NOT EXISTS idx_links_pending_expiry ON links(expires_at) WHERE expired_at IS NULL AND expires_at IS NOT NULL;
+
+-- 新增权限点：管理 webhook
+INSERT INTO permissions (name, description, resource_type) VALUES
+  ('webhook:manage', '管理 webhook', 'webhook')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission_id)
+SELECT r.role, p.id
+FROM permissions p
+CROSS JOIN (VALUES ('admin'), ('user')) AS r(role)
+WHERE p.name = 'webhook:manage'
+ON CONFLICT (role, permission_id) DO NOTHING;
@@ -0,0 +1,39 @@
+-- 0019_click_alerts.sql
+-- 点击异常告警：ClickAlertJob 每小时把上一个完整小时的点击数与链接的滚动基线（此前 N 小时的平均每小时点击）比较
+-- alert_thresholds: 阈值设置；link_id 为空表示用户默认，否则为单个链接的覆盖；字段为 NULL 表示继承（链接 -> 用户 -> 全局配置）
+-- click_alerts: 告警记录（GET /api/v2/alerts），同一链接同一窗口同一类型只产生一条（多副本重复评估时去重）
+-- kind: spike 点击激增 / drop 常态繁忙的链接点击跌零 / bot_traffic 爬虫流量占比过高
+
+CREATE TABLE IF NOT EXISTS alert_thresholds (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  link_id BIGINT REFERENCES links(id) ON DELETE CASCADE,
+  enabled BOOLEAN,
+  spike_factor DOUBLE PRECISION,     -- 窗口点击 >= 基线 * spike_factor 时告警（0 表示关闭）
+  spike_min_clicks BIGINT,           -- 激增 / 爬虫告警的最小点击数（避免低流量链接误报）
+  drop_min_baseline DOUBLE PRECISION, -- 基线 >= 该值（次/小时）且窗口点击为 0 时告警（0 表示关闭）
+  bot_ratio DOUBLE PRECISION,        -- 窗口内爬虫占比 >= 该值时告警（0 表示关闭）
+  notify_email BOOLEAN,              -- 是否发送邮件（仅用户默认设置生效）
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_alert_thresholds_user ON alert_thresholds(user_id) WHERE link_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_alert_thresholds_link ON alert_thresholds(link_id) WHERE link_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS click_alerts (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  link_id BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  code VARCHAR(50) NOT NULL,
+  kind VARCHAR(20) NOT NULL,
+  window_start TIMESTAMP NOT NULL,
+  window_end TIMESTAMP NOT NULL,
+  observed BIGINT NOT NULL,           -- 窗口内点击数（bot_traffic 为爬虫点击数）
+  baseline DOUBLE PRECISION NOT NULL, -- 基线（平均每小时点击）
+  threshold DOUBLE PRECISION NOT NULL, -- 触发阈值（spike 为点击数，drop 为基线下限，bot_traffic 为爬虫占比）
+  message TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (link_id, kind, window_start)
+);
+CREATE INDEX IF NOT EXISTS idx_click_alerts_user ON click_alerts(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_click_alerts_created_at ON click_alerts(created_at);
@@ -0,0 +1,192 @@
+/**
+ * GeoIP 离线解析
+ * - 读取本地 MaxMind .mmdb（City 库 + 可选 ASN 库），不依赖任何网络服务
+ * - 未配置路径或文件不可用时降级为空结果，不影响统计写入
+ * - 后台轮询文件修改时间，文件更新后热加载
+ */
+package geoip
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"short-link/utils"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Location IP 归属地
+type Location struct {
+	CountryCode string
+	Region      string
+	City        string
+	ASN         uint
+	ASOrg       string
+}
+
+// dbFile 单个 mmdb 文件（支持按 mtime 热加载）
+type dbFile struct {
+	path    string
+	mu      sync.RWMutex
+	reader  *geoip2.Reader
+	modTime time.Time
+}
+
+// reload 文件修改时间变化时重新打开；打开失败时保留旧库
+func (f *dbFile) reload() {
+	st, err := os.Stat(f.path)
+	if err != nil {
+		f.mu.RLock()
+		loaded := f.reader != nil
+		f.mu.RUnlock()
+		if !loaded {
+			utils.LogWarn("GeoIP 数据库不可用: %s, error=%v", f.path, err)
+		}
+		return
+	}
+
+	f.mu.RLock()
+	unchanged := f.reader != nil && st.ModTime().Equal(f.modTime)
+	f.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	reader, err := geoip2.Open(f.path)
+	if err != nil {
+		utils.LogWarn("加载 GeoIP 数据库失败，继续使用旧版本: %s, error=%v", f.path, err)
+		return
+	}
+
+	f.mu.Lock()
+	old := f.reader
+	f.reader = reader
+	f.modTime = st.ModTime()
+	f.mu.Unlock()
+
+	if old != nil {
+		_ = old.Close() //nolint:errcheck
+	}
+	utils.LogInfo("GeoIP 数据库已加载: %s (%s)", f.path, reader.Metadata().DatabaseType)
+}
+
+func (f *dbFile) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.reader != nil {
+		_ = f.reader.Close() //nolint:errcheck
+		f.reader = nil
+	}
+}
+
+// Enricher GeoIP 解析器（nil 安全：未启用时 Lookup 返回空结果）
+type Enricher struct {
+	city     *dbFile
+	asn      *dbFile
+	interval time.Duration
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New 创建 GeoIP 解析器；两个路径均为空时返回 nil（功能关闭）
+// cityPath 对应 GeoLite2-City 等城市库，asnPath 对应 GeoLite2-ASN 库，可只配置其一
+func New(cityPath, asnPath string, interval time.Duration) *Enricher {
+	if cityPath == "" && asnPath == "" {
+		return nil
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	e := &Enricher{interval: interval, stopCh: make(chan struct{})}
+	if cityPath != "" {
+		e.city = &dbFile{path: cityPath}
+		e.city.reload()
+	}
+	if asnPath != "" {
+		e.asn = &dbFile{path: asnPath}
+		e.asn.reload()
+	}
+	return e
+}
+
+// Start 启动热加载轮询
+func (e *Enricher) Start() {
+	if e == nil {
+		return
+	}
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stopCh:
+				return
+			case <-ticker.C:
+				if e.city != nil {
+					e.city.reload()
+				}
+				if e.asn != nil {
+					e.asn.reload()
+				}
+			}
+		}
+	}()
+	utils.LogInfo("GeoIP 热加载已启动（检查间隔=%v）", e.interval)
+}
+
+// Stop 停止轮询并关闭数据库
+func (e *Enricher) Stop() {
+	if e == nil {
+		return
+	}
+	close(e.stopCh)
+	e.wg.Wait()
+	if e.city != nil {
+		e.city.close()
+	}
+	if e.asn != nil {
+		e.asn.close()
+	}
+}
+
+// Lookup 查询 IP 归属地；无法解析时返回空 Location
+func (e *Enricher) Lookup(ipStr string) Location {
+	var loc Location
+	if e == nil {
+		return loc
+	}
+	ip := net.ParseIP(ipStr)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() {
+		return loc
+	}
+
+	if e.city != nil {
+		e.city.mu.RLock()
+		if e.city.reader != nil {
+			if rec, err := e.city.reader.City(ip); err == nil {
+				loc.CountryCode = rec.Country.IsoCode
+				if len(rec.Subdivisions) > 0 {
+					loc.Region = rec.Subdivisions[0].Names["en"]
+				}
+				loc.City = rec.City.Names["en"]
+			}
+		}
+		e.city.mu.RUnlock()
+	}
+
+	if e.asn != nil {
+		e.asn.mu.RLock()
+		if e.asn.reader != nil {
+			if rec, err := e.asn.reader.ASN(ip); err == nil {
+				loc.ASN = rec.AutonomousSystemNumber
+				loc.ASOrg = rec.AutonomousSystemOrganization
+			}
+		}
+		e.asn.mu.RUnlock()
+	}
+	return loc
+}
@@ -0,0 +1,115 @@
+package geoip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"short-link/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogger()
+	os.Exit(m.Run())
+}
+
+// writeTestDB 写入一个空搜索树的最小 mmdb 文件（只包含元数据），并设置修改时间
+func writeTestDB(t *testing.T, path, dbType string, modTime time.Time) {
+	t.Helper()
+	buf := make([]byte, 16) // 搜索树为空，只有数据段分隔符
+	buf = append(buf, "\xAB\xCD\xEFMaxMind.com"...)
+	buf = append(buf, 0xE4) // map，4 个键
+	for _, kv := range []struct {
+		key string
+		val []byte
+	}{
+		{"node_count", []byte{0xC0}},      // uint32 0
+		{"record_size", []byte{0xA1, 24}}, // uint16 24
+		{"ip_version", []byte{0xA1, 4}},   // uint16 4
+		{"database_type", mmdbString(dbType)},
+	} {
+		buf = append(buf, mmdbString(kv.key)...)
+		buf = append(buf, kv.val...)
+	}
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("chtimes %s: %v", path, err)
+	}
+}
+
+func mmdbString(s string) []byte {
+	return append([]byte{0x40 | byte(len(s))}, s...)
+}
+
+func TestNilEnricher(t *testing.T) {
+	e := New("", "", time.Minute)
+	if e != nil {
+		t.Fatal("New without paths should return nil")
+	}
+	e.Start()
+	if loc := e.Lookup("8.8.8.8"); loc != (Location{}) {
+		t.Errorf("nil enricher lookup = %+v, want empty", loc)
+	}
+	e.Stop()
+}
+
+func TestMissingDatabase(t *testing.T) {
+	dir := t.TempDir()
+	e := New(filepath.Join(dir, "city.mmdb"), filepath.Join(dir, "asn.mmdb"), time.Hour)
+	if e == nil {
+		t.Fatal("configured enricher should not be nil")
+	}
+	e.Start()
+	defer e.Stop()
+	for _, ip := range []string{"8.8.8.8", "10.0.0.1", "not-an-ip"} {
+		if loc := e.Lookup(ip); loc != (Location{}) {
+			t.Errorf("lookup %s without database = %+v, want empty", ip, loc)
+		}
+	}
+}
+
+func TestReloadOnModTimeChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asn.mmdb")
+	base := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	writeTestDB(t, path, "GeoLite2-ASN", base)
+
+	f := &dbFile{path: path}
+	defer f.close()
+	f.reload()
+	first := f.reader
+	if first == nil {
+		t.Fatal("database should be loaded")
+	}
+
+	// mtime 未变化时不重新打开
+	f.reload()
+	if f.reader != first {
+		t.Fatal("unchanged file should not be reopened")
+	}
+
+	// mtime 变化后热加载
+	writeTestDB(t, path, "GeoLite2-City", base.Add(time.Minute))
+	f.reload()
+	if f.reader == first {
+		t.Fatal("modified file should be reloaded")
+	}
+	if got := f.reader.Metadata().DatabaseType; got != "GeoLite2-City" {
+		t.Fatalf("database type = %q, want GeoLite2-City", got)
+	}
+	second := f.reader
+
+	// 新文件损坏时保留旧库
+	if err := os.WriteFile(path, []byte("corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, base.Add(2*time.Minute), base.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	f.reload()
+	if f.reader != second {
+		t.Fatal("corrupt file should keep the previous database")
+	}
+}
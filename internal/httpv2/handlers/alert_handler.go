@@ -0,0 +1,188 @@
+/**
+ * v2 Alert Handler
+ * - GET    /api/v2/alerts 点击异常告警（分页，可按 kind / link_id 过滤）
+ * - GET    /api/v2/alerts/settings 告警设置（全局默认、用户默认、链接覆盖）
+ * - PUT    /api/v2/alerts/settings 设置用户默认阈值（整体替换，未提供的字段继承全局配置）
+ * - GET    /api/v2/links/:id/alert-settings 链接的阈值覆盖与生效值
+ * - PUT    /api/v2/links/:id/alert-settings 设置链接的阈值覆盖（未提供的字段继承用户默认设置）
+ * - DELETE /api/v2/links/:id/alert-settings 删除链接的阈值覆盖
+ * 仅本人 / 链接所有者可操作，非所有者统一返回 404
+ */
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/internal/service"
+	"short-link/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AlertHandler 告警处理器（v2）
+type AlertHandler struct {
+	alertService *service.AlertService
+	auditLogRepo *repo.AuditLogRepo
+}
+
+// NewAlertHandler 创建 AlertHandler
+func NewAlertHandler(alertService *service.AlertService, auditLogRepo *repo.AuditLogRepo) *AlertHandler {
+	return &AlertHandler{
+		alertService: alertService,
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+// respondAlertError 统一处理告警操作错误
+func respondAlertError(c *gin.Context, err error) {
+	if errors.Is(err, repo.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "链接或告警设置不存在"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// ListAlerts 获取告警列表
+func (h *AlertHandler) ListAlerts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	var linkID int64
+	if raw := c.Query("link_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的链接ID"})
+			return
+		}
+		linkID = id
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	alerts, total, err := h.alertService.ListAlerts(ctx, c.GetInt64("user_id"), c.Query("kind"), linkID, page, limit)
+	if err != nil {
+		respondAlertError(c, err)
+		return
+	}
+	if alerts == nil {
+		alerts = []models.ClickAlert{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"alerts":      alerts,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": int((total + int64(limit) - 1) / int64(limit)),
+	})
+}
+
+// GetSettings 获取告警设置
+func (h *AlertHandler) GetSettings(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	settings, err := h.alertService.GetSettings(ctx, c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取告警设置失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings 设置用户默认阈值
+func (h *AlertHandler) UpdateSettings(c *gin.Context) {
+	var req models.AlertThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := c.GetInt64("user_id")
+	t, err := h.alertService.UpdateUserSettings(ctx, userID, &req)
+	if err != nil {
+		respondAlertError(c, err)
+		return
+	}
+
+	auditResourceChange(ctx, c, h.auditLogRepo, "alert.settings.update", "user", userID, map[string]interface{}{"settings": req})
+	c.JSON(http.StatusOK, t)
+}
+
+// GetLinkSettings 获取链接的阈值覆盖
+func (h *AlertHandler) GetLinkSettings(c *gin.Context) {
+	linkID, ok := linkIDParam(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的链接ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	settings, err := h.alertService.GetLinkSettings(ctx, c.GetInt64("user_id"), linkID)
+	if err != nil {
+		respondAlertError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateLinkSettings 设置链接的阈值覆盖
+func (h *AlertHandler) UpdateLinkSettings(c *gin.Context) {
+	linkID, ok := linkIDParam(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的链接ID"})
+		return
+	}
+	var req models.AlertThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	t, err := h.alertService.UpdateLinkSettings(ctx, c.GetInt64("user_id"), linkID, &req)
+	if err != nil {
+		respondAlertError(c, err)
+		return
+	}
+
+	auditResourceChange(ctx, c, h.auditLogRepo, "link.alert_settings.update", "link", linkID, map[string]interface{}{"settings": req})
+	c.JSON(http.StatusOK, t)
+}
+
+// DeleteLinkSettings 删除链接的阈值覆盖
+func (h *AlertHandler) DeleteLinkSettings(c *gin.Context) {
+	linkID, ok := linkIDParam(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的链接ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.alertService.DeleteLinkSettings(ctx, c.GetInt64("user_id"), linkID); err != nil {
+		respondAlertError(c, err)
+		return
+	}
+
+	auditResourceChange(ctx, c, h.auditLogRepo, "link.alert_settings.delete", "link", linkID, map[string]interface{}{})
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
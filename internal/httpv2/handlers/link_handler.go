@@ -1,18 +1,22 @@
 /**
  * v2 Link Handler（重写版）
  * - POST /api/v2/links 创建短链
- * - GET  /api/v2/links 获取当前用户短链列表（分页）
+ * - POST /api/v2/links/batch 批量创建短链（逐项返回结果）
+ * - GET  /api/v2/links 获取当前用户短链列表（分页，可按 tag / folder 过滤）
+ * - PATCH /api/v2/links/:id 更新短链（目标地址/标题/code/标签/文件夹）
  */
 package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"short-link/cache"
 	appcfg "short-link/internal/config"
-	"short-link/internal/jobs"
 	"short-link/internal/metrics"
 	"short-link/internal/repo"
 	"short-link/internal/service"
@@ -28,24 +32,47 @@ type LinkHandler struct {
 	linkService *service.LinkService
 	linkRepo    *repo.LinkRepo
 	domainRepo  *repo.DomainRepo
+	tagRepo     *repo.TagRepo
 	searchService *service.SearchService
 	auditLogRepo *repo.AuditLogRepo
-	meiliWorker *jobs.MeiliWorker
 }
 
 // NewLinkHandler 创建 LinkHandler
-func NewLinkHandler(cfg *appcfg.Config, linkService *service.LinkService, linkRepo *repo.LinkRepo, domainRepo *repo.DomainRepo, searchService *service.SearchService, auditLogRepo *repo.AuditLogRepo, meiliWorker *jobs.MeiliWorker) *LinkHandler {
+func NewLinkHandler(cfg *appcfg.Config, linkService *service.LinkService, linkRepo *repo.LinkRepo, domainRepo *repo.DomainRepo, tagRepo *repo.TagRepo, searchService *service.SearchService, auditLogRepo *repo.AuditLogRepo) *LinkHandler {
 	return &LinkHandler{
 		cfg:           cfg,
 		linkService:   linkService,
 		linkRepo:      linkRepo,
 		domainRepo:    domainRepo,
+		tagRepo:       tagRepo,
 		searchService: searchService,
 		auditLogRepo:  auditLogRepo,
-		meiliWorker:   meiliWorker,
 	}
 }
 
+// toLinkResponse 构造链接响应
+func toLinkResponse(l *models.Link, shortURL string) models.LinkResponse {
+	resp := models.LinkResponse{
+		ID:          l.ID,
+		Code:        l.Code,
+		ShortURL:    shortURL,
+		OriginalURL: l.OriginalURL,
+		Title:       l.Title,
+		QRCode:      l.QRCode,
+		ClickCount:  l.ClickCount,
+		UniqueClicks: l.UniqueClicks,
+		MaxClicks:   l.MaxClicks,
+		PasswordProtected: l.IsProtected(),
+		Tags:        l.Tags,
+		FolderID:    l.FolderID,
+		CreatedAt:   l.CreatedAt.Format("2006-01-02T15:04:05"),
+	}
+	if l.ExpiresAt != nil {
+		resp.ExpiresAt = l.ExpiresAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
 // CreateLink 创建短链接
 func (h *LinkHandler) CreateLink(c *gin.Context) {
 	userID := c.GetInt64("user_id")
@@ -67,22 +94,69 @@ func (h *LinkHandler) CreateLink(c *gin.Context) {
 	// 记录 metrics
 	metrics.LinksCreatedTotal.Inc()
 
-	c.JSON(http.StatusOK, models.LinkResponse{
-		ID:          link.ID,
-		Code:        link.Code,
-		ShortURL:    shortURL,
-		OriginalURL: link.OriginalURL,
-		Title:       link.Title,
-		QRCode:      link.QRCode,
-		ClickCount:  link.ClickCount,
-		CreatedAt:   link.CreatedAt.Format("2006-01-02T15:04:05"),
-	})
+	c.JSON(http.StatusOK, toLinkResponse(link, shortURL))
+}
+
+// BatchCreateLinks 批量创建短链接（逐项返回结果，顺序与请求一致）
+func (h *LinkHandler) BatchCreateLinks(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	var req models.BatchCreateLinksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+	if max := h.cfg.LinkBatchMaxItems; max > 0 && len(req.Links) > max {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("单次最多创建 %d 条链接", max)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+	results, err := h.linkService.CreateLinksBatch(ctx, userID, req.Links)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "批量创建失败: " + err.Error()})
+		return
+	}
+
+	resp := models.BatchCreateLinksResponse{Results: make([]models.BatchLinkResult, len(results))}
+	for i, r := range results {
+		item := models.BatchLinkResult{Index: i}
+		if r.Err != nil {
+			item.Error = r.Err.Error()
+			resp.Failed++
+		} else {
+			lr := toLinkResponse(r.Link, r.ShortURL)
+			item.Success = true
+			item.Link = &lr
+			if r.Created {
+				resp.Created++
+			}
+		}
+		resp.Results[i] = item
+	}
+
+	// 记录 metrics
+	metrics.LinksCreatedTotal.Add(float64(resp.Created))
+
+	c.JSON(http.StatusOK, resp)
 }
 
 // GetLinks 获取当前用户的链接列表（分页）
+// 过滤：tag=标签名；folder=文件夹ID（0 表示未归档）
 func (h *LinkHandler) GetLinks(c *gin.Context) {
 	userID := c.GetInt64("user_id")
 
+	filter := repo.LinkFilter{Tag: strings.TrimSpace(c.Query("tag"))}
+	if raw := c.Query("folder"); raw != "" {
+		folderID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || folderID < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件夹ID"})
+			return
+		}
+		filter.FolderID = &folderID
+	}
+
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	if page < 1 {
@@ -95,12 +169,28 @@ func (h *LinkHandler) GetLinks(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 8*time.Second)
 	defer cancel()
 
-	links, total, err := h.linkRepo.GetUserLinks(ctx, userID, page, limit)
+	links, total, err := h.linkRepo.ListUserLinks(ctx, userID, filter, page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取链接列表失败: " + err.Error()})
 		return
 	}
 
+	// 标签：整页一次查询
+	if h.tagRepo != nil && len(links) > 0 {
+		ids := make([]int64, len(links))
+		for i := range links {
+			ids[i] = links[i].ID
+		}
+		tags, err := h.tagRepo.GetLinkTags(ctx, ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取链接标签失败: " + err.Error()})
+			return
+		}
+		for i := range links {
+			links[i].Tags = tags[links[i].ID]
+		}
+	}
+
 	// domain 缓存：避免 N 次重复查询
 	domainCache := map[int64]*models.Domain{}
 	buildDomain := func(domainID int64) *models.Domain {
@@ -124,16 +214,7 @@ func (h *LinkHandler) GetLinks(c *gin.Context) {
 	for _, l := range links {
 		d := buildDomain(l.DomainID)
 		shortURL := h.linkService.BuildShortURL(d, l.Code)
-		resp = append(resp, models.LinkResponse{
-			ID:          l.ID,
-			Code:        l.Code,
-			ShortURL:    shortURL,
-			OriginalURL: l.OriginalURL,
-			Title:       l.Title,
-			QRCode:      l.QRCode,
-			ClickCount:  l.ClickCount,
-			CreatedAt:   l.CreatedAt.Format("2006-01-02T15:04:05"),
-		})
+		resp = append(resp, toLinkResponse(&l, shortURL))
 	}
 
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
@@ -146,45 +227,180 @@ func (h *LinkHandler) GetLinks(c *gin.Context) {
 	})
 }
 
-// SearchLinks 搜索链接
+// SearchLinks 搜索链接（仅当前用户的链接；管理员可传 scope=all 全局搜索）
+// Meilisearch 不可用时自动降级到 PostgreSQL 全文检索，响应头 X-Search-Backend 标明实际后端
+// 过滤：domain_id、created_from / created_to（RFC3339 或 YYYY-MM-DD）、min_clicks / max_clicks、tag、folder
 func (h *LinkHandler) SearchLinks(c *gin.Context) {
-	if h.searchService == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "搜索服务未启用"})
+	opts, err := parseSearchOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	query := c.Query("q")
-	if query == "" {
+	if opts.Query == "" && !opts.HasFilter() {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词不能为空"})
 		return
 	}
-
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 200 {
-		limit = 20
+	if c.Query("scope") == "all" {
+		if c.GetString("role") != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "仅管理员可全局搜索"})
+			return
+		}
+		opts.UserID = 0
 	}
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	result, err := h.searchService.SearchLinks(ctx, query, page, limit)
+	result, backend, err := h.searchService.SearchLinks(ctx, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索失败: " + err.Error()})
 		return
 	}
+	c.Header("X-Search-Backend", backend)
 	c.JSON(http.StatusOK, result)
 }
 
-// DeleteLink 删除链接
+// parseSearchOptions 解析搜索参数（默认限定当前用户）
+func parseSearchOptions(c *gin.Context) (*service.LinkSearchOptions, error) {
+	opts := &service.LinkSearchOptions{
+		Query:  strings.TrimSpace(c.Query("q")),
+		UserID: c.GetInt64("user_id"),
+		Tag:    strings.TrimSpace(c.Query("tag")),
+	}
+
+	opts.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	opts.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.Limit < 1 || opts.Limit > 200 {
+		opts.Limit = 20
+	}
+
+	positive := func(name string) (int64, error) {
+		raw := c.Query(name)
+		if raw == "" {
+			return 0, nil
+		}
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v <= 0 {
+			return 0, fmt.Errorf("无效的参数 %s", name)
+		}
+		return v, nil
+	}
+	clicks := func(name string) (*int64, error) {
+		raw := c.Query(name)
+		if raw == "" {
+			return nil, nil
+		}
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("无效的参数 %s", name)
+		}
+		return &v, nil
+	}
+
+	var err error
+	if opts.DomainID, err = positive("domain_id"); err != nil {
+		return nil, err
+	}
+	if opts.FolderID, err = positive("folder"); err != nil {
+		return nil, err
+	}
+	if opts.MinClicks, err = clicks("min_clicks"); err != nil {
+		return nil, err
+	}
+	if opts.MaxClicks, err = clicks("max_clicks"); err != nil {
+		return nil, err
+	}
+	if opts.CreatedFrom, err = service.ParseSearchTime(c.Query("created_from"), false); err != nil {
+		return nil, err
+	}
+	if opts.CreatedTo, err = service.ParseSearchTime(c.Query("created_to"), true); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// UpdateLink 更新链接（目标地址/标题/code/标签/文件夹）
+func (h *LinkHandler) UpdateLink(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	username := c.GetString("username")
+	role := c.GetString("role")
+
+	linkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || linkID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的链接ID"})
+		return
+	}
+
+	var req models.UpdateLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+	if req.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的字段"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	before, after, shortURL, err := h.linkService.UpdateLink(ctx, userID, linkID, &req)
+	if err != nil {
+		if err == repo.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "链接不存在或无权限修改"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 记录审计日志（记录修改前后的值）
+	if h.auditLogRepo != nil {
+		auditLog := &models.AuditLog{
+			UserID:       &userID,
+			Username:     username,
+			Action:       "link.update",
+			ResourceType: "link",
+			ResourceID:   &linkID,
+			IP:           utils.GetRealIP(c.Request),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Details: map[string]interface{}{
+				"before": map[string]interface{}{
+					"code":         before.Code,
+					"original_url": before.OriginalURL,
+					"title":        before.Title,
+					"hash":         before.Hash,
+					"tags":         before.Tags,
+					"folder_id":    before.FolderID,
+				},
+				"after": map[string]interface{}{
+					"code":         after.Code,
+					"original_url": after.OriginalURL,
+					"title":        after.Title,
+					"hash":         after.Hash,
+					"tags":         after.Tags,
+					"folder_id":    after.FolderID,
+				},
+				"domain_id": after.DomainID,
+				"role":      role,
+			},
+			CreatedAt: time.Now(),
+		}
+		_ = h.auditLogRepo.CreateAuditLog(ctx, auditLog) // best-effort
+	}
+
+	c.JSON(http.StatusOK, toLinkResponse(after, shortURL))
+}
+
+// DeleteLink 删除链接（DELETE /api/v2/links/:code，按短代码删除；路由段通配符名为 :id）
 func (h *LinkHandler) DeleteLink(c *gin.Context) {
 	userID := c.GetInt64("user_id")
 	username := c.GetString("username")
 	role := c.GetString("role")
-	code := c.Param("code")
+	code := c.Param("id")
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
@@ -208,15 +424,15 @@ func (h *LinkHandler) DeleteLink(c *gin.Context) {
 		return
 	}
 
-	// 从 DB 删除（按 user + domain + code）
+	// 从 DB 删除（按 user + domain + code；索引删除由同事务写入的 outbox 事件异步完成）
 	if err := h.linkRepo.DeleteUserLink(ctx, userID, target.DomainID, code); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除链接失败: " + err.Error()})
 		return
 	}
 
-	// 异步提交 Meilisearch 删除任务（非阻塞）
-	if h.meiliWorker != nil {
-		h.meiliWorker.Submit("delete", nil, target.ID)
+	// 清理独立访客 HyperLogLog（按天的 key 自带过期时间）
+	if err := cache.Delete(cache.VisitorsKey(target.ID)); err != nil {
+		utils.LogWarn("清理独立访客计数失败: link_id=%d, error=%v", target.ID, err)
 	}
 
 	// 记录 metrics
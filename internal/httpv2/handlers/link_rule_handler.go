@@ -0,0 +1,196 @@
+/**
+ * v2 LinkRule Handler
+ * - GET    /api/v2/links/:id/rules 获取定向跳转规则
+ * - POST   /api/v2/links/:id/rules 新增规则
+ * - PUT    /api/v2/links/:id/rules/:rule_id 更新规则
+ * - DELETE /api/v2/links/:id/rules/:rule_id 删除规则
+ * 仅链接所有者可操作，非所有者统一返回 404
+ */
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/internal/service"
+	"short-link/models"
+	"short-link/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LinkRuleHandler 跳转规则处理器（v2）
+type LinkRuleHandler struct {
+	ruleService  *service.LinkRuleService
+	auditLogRepo *repo.AuditLogRepo
+}
+
+// NewLinkRuleHandler 创建 LinkRuleHandler
+func NewLinkRuleHandler(ruleService *service.LinkRuleService, auditLogRepo *repo.AuditLogRepo) *LinkRuleHandler {
+	return &LinkRuleHandler{
+		ruleService:  ruleService,
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+// linkIDParam 解析路径中的链接 ID（:id）
+func linkIDParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// parseRuleParams 解析链接 ID 与规则 ID
+func parseRuleParams(c *gin.Context, withRule bool) (int64, int64, bool) {
+	linkID, ok := linkIDParam(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的链接ID"})
+		return 0, 0, false
+	}
+	if !withRule {
+		return linkID, 0, true
+	}
+	ruleID, err := strconv.ParseInt(c.Param("rule_id"), 10, 64)
+	if err != nil || ruleID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的规则ID"})
+		return 0, 0, false
+	}
+	return linkID, ruleID, true
+}
+
+// respondRuleError 统一处理规则操作错误
+func respondRuleError(c *gin.Context, err error) {
+	if err == repo.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "链接或规则不存在"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// audit 记录规则变更审计日志（best-effort）
+func (h *LinkRuleHandler) audit(ctx context.Context, c *gin.Context, action string, linkID int64, details map[string]interface{}) {
+	auditLinkChange(ctx, c, h.auditLogRepo, action, linkID, details)
+}
+
+// auditLinkChange 记录链接子资源（规则/变体）变更审计日志（best-effort）
+func auditLinkChange(ctx context.Context, c *gin.Context, auditLogRepo *repo.AuditLogRepo, action string, linkID int64, details map[string]interface{}) {
+	auditResourceChange(ctx, c, auditLogRepo, action, "link", linkID, details)
+}
+
+// auditResourceChange 记录资源变更审计日志（best-effort）
+func auditResourceChange(ctx context.Context, c *gin.Context, auditLogRepo *repo.AuditLogRepo, action string, resourceType string, resourceID int64, details map[string]interface{}) {
+	if auditLogRepo == nil {
+		return
+	}
+	userID := c.GetInt64("user_id")
+	details["role"] = c.GetString("role")
+	auditLog := &models.AuditLog{
+		UserID:       &userID,
+		Username:     c.GetString("username"),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   &resourceID,
+		IP:           utils.GetRealIP(c.Request),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details:      details,
+		CreatedAt:    time.Now(),
+	}
+	_ = auditLogRepo.CreateAuditLog(ctx, auditLog) // best-effort
+}
+
+// ListRules 获取链接的跳转规则（按匹配顺序）
+func (h *LinkRuleHandler) ListRules(c *gin.Context) {
+	linkID, _, ok := parseRuleParams(c, false)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	rules, err := h.ruleService.ListRules(ctx, c.GetInt64("user_id"), linkID)
+	if err != nil {
+		respondRuleError(c, err)
+		return
+	}
+	if rules == nil {
+		rules = []models.LinkRule{}
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// CreateRule 新增跳转规则
+func (h *LinkRuleHandler) CreateRule(c *gin.Context) {
+	linkID, _, ok := parseRuleParams(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.LinkRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	rule, err := h.ruleService.CreateRule(ctx, c.GetInt64("user_id"), linkID, &req)
+	if err != nil {
+		respondRuleError(c, err)
+		return
+	}
+
+	h.audit(ctx, c, "link.rule.create", linkID, map[string]interface{}{"rule": rule})
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule 更新跳转规则（整体替换）
+func (h *LinkRuleHandler) UpdateRule(c *gin.Context) {
+	linkID, ruleID, ok := parseRuleParams(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.LinkRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	rule, err := h.ruleService.UpdateRule(ctx, c.GetInt64("user_id"), linkID, ruleID, &req)
+	if err != nil {
+		respondRuleError(c, err)
+		return
+	}
+
+	h.audit(ctx, c, "link.rule.update", linkID, map[string]interface{}{"rule": rule})
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule 删除跳转规则
+func (h *LinkRuleHandler) DeleteRule(c *gin.Context) {
+	linkID, ruleID, ok := parseRuleParams(c, true)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.ruleService.DeleteRule(ctx, c.GetInt64("user_id"), linkID, ruleID); err != nil {
+		respondRuleError(c, err)
+		return
+	}
+
+	h.audit(ctx, c, "link.rule.delete", linkID, map[string]interface{}{"rule_id": ruleID})
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
@@ -0,0 +1,226 @@
+/**
+ * v2 标签 / 文件夹 Handler
+ * - GET    /api/v2/tags 获取当前用户标签（含链接数）
+ * - PATCH  /api/v2/tags/:id 重命名标签
+ * - POST   /api/v2/tags/merge 合并标签（source_ids 并入 target）
+ * - DELETE /api/v2/tags/:id 删除标签（仅解除关联）
+ * - GET    /api/v2/folders 获取当前用户文件夹（含链接数）
+ * - POST   /api/v2/folders 创建文件夹
+ * - PATCH  /api/v2/folders/:id 重命名文件夹
+ * - DELETE /api/v2/folders/:id 删除文件夹（链接变为未归档）
+ * 链接的标签/文件夹通过创建、更新链接接口设置
+ */
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/internal/service"
+	"short-link/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LinkTagHandler 标签/文件夹处理器（v2）
+type LinkTagHandler struct {
+	tagService   *service.LinkTagService
+	auditLogRepo *repo.AuditLogRepo
+}
+
+// NewLinkTagHandler 创建 LinkTagHandler
+func NewLinkTagHandler(tagService *service.LinkTagService, auditLogRepo *repo.AuditLogRepo) *LinkTagHandler {
+	return &LinkTagHandler{
+		tagService:   tagService,
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+// idParam 解析路径中的 :id
+func idParam(c *gin.Context, msg string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondTagError 统一处理标签/文件夹操作错误
+func respondTagError(c *gin.Context, err error, notFound string) {
+	if err == repo.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// ListTags 获取当前用户的标签
+func (h *LinkTagHandler) ListTags(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	tags, err := h.tagService.ListTags(ctx, c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败: " + err.Error()})
+		return
+	}
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// RenameTag 重命名标签
+func (h *LinkTagHandler) RenameTag(c *gin.Context) {
+	tagID, ok := idParam(c, "无效的标签ID")
+	if !ok {
+		return
+	}
+
+	var req models.RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	tag, err := h.tagService.RenameTag(ctx, c.GetInt64("user_id"), tagID, req.Name)
+	if err != nil {
+		respondTagError(c, err, "标签不存在")
+		return
+	}
+
+	auditResourceChange(ctx, c, h.auditLogRepo, "tag.rename", "tag", tagID, map[string]interface{}{"name": tag.Name})
+	c.JSON(http.StatusOK, tag)
+}
+
+// MergeTags 合并标签
+func (h *LinkTagHandler) MergeTags(c *gin.Context) {
+	var req models.MergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	tag, err := h.tagService.MergeTags(ctx, c.GetInt64("user_id"), &req)
+	if err != nil {
+		respondTagError(c, err, "标签不存在")
+		return
+	}
+
+	auditResourceChange(ctx, c, h.auditLogRepo, "tag.merge", "tag", tag.ID, map[string]interface{}{
+		"source_ids": req.SourceIDs,
+		"target":     tag.Name,
+	})
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag 删除标签
+func (h *LinkTagHandler) DeleteTag(c *gin.Context) {
+	tagID, ok := idParam(c, "无效的标签ID")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.tagService.DeleteTag(ctx, c.GetInt64("user_id"), tagID); err != nil {
+		respondTagError(c, err, "标签不存在")
+		return
+	}
+
+	auditResourceChange(ctx, c, h.auditLogRepo, "tag.delete", "tag", tagID, map[string]interface{}{})
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// ListFolders 获取当前用户的文件夹
+func (h *LinkTagHandler) ListFolders(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	folders, err := h.tagService.ListFolders(ctx, c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文件夹失败: " + err.Error()})
+		return
+	}
+	if folders == nil {
+		folders = []models.Folder{}
+	}
+	c.JSON(http.StatusOK, gin.H{"folders": folders})
+}
+
+// CreateFolder 创建文件夹
+func (h *LinkTagHandler) CreateFolder(c *gin.Context) {
+	var req models.FolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	folder, err := h.tagService.CreateFolder(ctx, c.GetInt64("user_id"), req.Name)
+	if err != nil {
+		respondTagError(c, err, "文件夹不存在")
+		return
+	}
+
+	auditResourceChange(ctx, c, h.auditLogRepo, "folder.create", "folder", folder.ID, map[string]interface{}{"name": folder.Name})
+	c.JSON(http.StatusCreated, folder)
+}
+
+// RenameFolder 重命名文件夹
+func (h *LinkTagHandler) RenameFolder(c *gin.Context) {
+	folderID, ok := idParam(c, "无效的文件夹ID")
+	if !ok {
+		return
+	}
+
+	var req models.FolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	folder, err := h.tagService.RenameFolder(ctx, c.GetInt64("user_id"), folderID, req.Name)
+	if err != nil {
+		respondTagError(c, err, "文件夹不存在")
+		return
+	}
+
+	auditResourceChange(ctx, c, h.auditLogRepo, "folder.rename", "folder", folderID, map[string]interface{}{"name": folder.Name})
+	c.JSON(http.StatusOK, folder)
+}
+
+// DeleteFolder 删除文件夹
+func (h *LinkTagHandler) DeleteFolder(c *gin.Context) {
+	folderID, ok := idParam(c, "无效的文件夹ID")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.tagService.DeleteFolder(ctx, c.GetInt64("user_id"), folderID); err != nil {
+		respondTagError(c, err, "文件夹不存在")
+		return
+	}
+
+	auditResourceChange(ctx, c, h.auditLogRepo, "folder.delete", "folder", folderID, map[string]interface{}{})
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
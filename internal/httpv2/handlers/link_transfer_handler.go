@@ -0,0 +1,172 @@
+/**
+ * v2 LinkTransfer Handler
+ * - GET  /api/v2/links/export?format=csv|json|ndjson 流式导出当前用户的全部链接
+ * - POST /api/v2/links/import?format=csv|json|ndjson 导入链接（请求体或 multipart 字段 file），返回冲突/失败明细
+ */
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/internal/service"
+	"short-link/models"
+	"short-link/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBodyBytes 导入内容大小上限
+const maxImportBodyBytes = 64 << 20
+
+// LinkTransferHandler 链接导入/导出处理器（v2）
+type LinkTransferHandler struct {
+	transferService *service.LinkTransferService
+	auditLogRepo    *repo.AuditLogRepo
+}
+
+// NewLinkTransferHandler 创建 LinkTransferHandler
+func NewLinkTransferHandler(transferService *service.LinkTransferService, auditLogRepo *repo.AuditLogRepo) *LinkTransferHandler {
+	return &LinkTransferHandler{
+		transferService: transferService,
+		auditLogRepo:    auditLogRepo,
+	}
+}
+
+// exportContentTypes 导出格式对应的 Content-Type
+var exportContentTypes = map[string]string{
+	service.LinkFormatCSV:    "text/csv; charset=utf-8",
+	service.LinkFormatJSON:   "application/json; charset=utf-8",
+	service.LinkFormatNDJSON: "application/x-ndjson; charset=utf-8",
+}
+
+// ExportLinks 流式导出当前用户的链接
+func (h *LinkTransferHandler) ExportLinks(c *gin.Context) {
+	format, err := service.ParseLinkFormat(c.DefaultQuery("format", service.LinkFormatCSV))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.GetInt64("user_id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
+	defer cancel()
+
+	filename := fmt.Sprintf("links-%s.%s", time.Now().Format("20060102"), format)
+	c.Header("Content-Type", exportContentTypes[format])
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	w := bufio.NewWriterSize(c.Writer, 32<<10)
+	n, err := h.transferService.Export(ctx, w, format, userID)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		// 响应头已写出，只能中断并记录日志
+		utils.LogError("导出链接失败: user_id=%d, exported=%d, error=%v", userID, n, err)
+		return
+	}
+	c.Writer.Flush()
+}
+
+// ImportLinks 导入链接（格式取 format 参数，缺省时按文件扩展名 / Content-Type 推断）
+func (h *LinkTransferHandler) ImportLinks(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodyBytes)
+
+	var (
+		body     io.Reader = c.Request.Body
+		filename string
+	)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "缺少上传文件（字段 file）"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "读取上传文件失败: " + err.Error()})
+			return
+		}
+		defer f.Close()
+		body = f
+		filename = fh.Filename
+	}
+
+	format, err := service.ParseLinkFormat(detectImportFormat(c.Query("format"), filename, c.ContentType()))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
+	defer cancel()
+
+	report, err := h.transferService.Import(ctx, body, format, service.ImportOptions{
+		UserID:       c.GetInt64("user_id"),
+		EnforceQuota: true,
+	})
+	h.audit(ctx, c, format, report)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "导入失败: " + err.Error(), "report": report})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// detectImportFormat 推断导入格式：显式参数 > 文件扩展名 > Content-Type
+func detectImportFormat(explicit, filename, contentType string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), "."); ext != "" {
+		if ext == "jsonl" {
+			return service.LinkFormatNDJSON
+		}
+		return ext
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		return service.LinkFormatJSON
+	case "application/x-ndjson", "application/jsonl":
+		return service.LinkFormatNDJSON
+	default:
+		return service.LinkFormatCSV
+	}
+}
+
+// audit 记录导入审计日志（best-effort）
+func (h *LinkTransferHandler) audit(ctx context.Context, c *gin.Context, format string, report *models.LinkImportReport) {
+	if h.auditLogRepo == nil || report == nil {
+		return
+	}
+	userID := c.GetInt64("user_id")
+	auditLog := &models.AuditLog{
+		UserID:       &userID,
+		Username:     c.GetString("username"),
+		Action:       "link.import",
+		ResourceType: "link",
+		IP:           utils.GetRealIP(c.Request),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"format":    format,
+			"total":     report.Total,
+			"imported":  report.Imported,
+			"conflicts": report.Conflicts,
+			"failed":    report.Failed,
+		},
+		CreatedAt: time.Now(),
+	}
+	_ = h.auditLogRepo.CreateAuditLog(ctx, auditLog) // best-effort
+}
@@ -0,0 +1,155 @@
+/**
+ * v2 LinkVariant Handler
+ * - GET    /api/v2/links/:id/variants 获取 A/B 分流变体
+ * - POST   /api/v2/links/:id/variants 新增变体
+ * - PUT    /api/v2/links/:id/variants/:variant_id 更新变体（标签/目标地址/权重）
+ * - DELETE /api/v2/links/:id/variants/:variant_id 删除变体
+ * 仅链接所有者可操作，非所有者统一返回 404
+ */
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/internal/service"
+	"short-link/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LinkVariantHandler 分流变体处理器（v2）
+type LinkVariantHandler struct {
+	variantService *service.LinkVariantService
+	auditLogRepo   *repo.AuditLogRepo
+}
+
+// NewLinkVariantHandler 创建 LinkVariantHandler
+func NewLinkVariantHandler(variantService *service.LinkVariantService, auditLogRepo *repo.AuditLogRepo) *LinkVariantHandler {
+	return &LinkVariantHandler{
+		variantService: variantService,
+		auditLogRepo:   auditLogRepo,
+	}
+}
+
+// parseVariantParams 解析链接 ID 与变体 ID
+func parseVariantParams(c *gin.Context, withVariant bool) (int64, int64, bool) {
+	linkID, ok := linkIDParam(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的链接ID"})
+		return 0, 0, false
+	}
+	if !withVariant {
+		return linkID, 0, true
+	}
+	variantID, err := strconv.ParseInt(c.Param("variant_id"), 10, 64)
+	if err != nil || variantID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的变体ID"})
+		return 0, 0, false
+	}
+	return linkID, variantID, true
+}
+
+// respondVariantError 统一处理变体操作错误
+func respondVariantError(c *gin.Context, err error) {
+	if err == repo.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "链接或变体不存在"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// ListVariants 获取链接的分流变体
+func (h *LinkVariantHandler) ListVariants(c *gin.Context) {
+	linkID, _, ok := parseVariantParams(c, false)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	variants, err := h.variantService.ListVariants(ctx, c.GetInt64("user_id"), linkID)
+	if err != nil {
+		respondVariantError(c, err)
+		return
+	}
+	if variants == nil {
+		variants = []models.LinkVariant{}
+	}
+	c.JSON(http.StatusOK, gin.H{"variants": variants})
+}
+
+// CreateVariant 新增分流变体
+func (h *LinkVariantHandler) CreateVariant(c *gin.Context) {
+	linkID, _, ok := parseVariantParams(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.LinkVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	variant, err := h.variantService.CreateVariant(ctx, c.GetInt64("user_id"), linkID, &req)
+	if err != nil {
+		respondVariantError(c, err)
+		return
+	}
+
+	auditLinkChange(ctx, c, h.auditLogRepo, "link.variant.create", linkID, map[string]interface{}{"variant": variant})
+	c.JSON(http.StatusCreated, variant)
+}
+
+// UpdateVariant 更新分流变体（整体替换）
+func (h *LinkVariantHandler) UpdateVariant(c *gin.Context) {
+	linkID, variantID, ok := parseVariantParams(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.LinkVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	variant, err := h.variantService.UpdateVariant(ctx, c.GetInt64("user_id"), linkID, variantID, &req)
+	if err != nil {
+		respondVariantError(c, err)
+		return
+	}
+
+	auditLinkChange(ctx, c, h.auditLogRepo, "link.variant.update", linkID, map[string]interface{}{"variant": variant})
+	c.JSON(http.StatusOK, variant)
+}
+
+// DeleteVariant 删除分流变体
+func (h *LinkVariantHandler) DeleteVariant(c *gin.Context) {
+	linkID, variantID, ok := parseVariantParams(c, true)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.variantService.DeleteVariant(ctx, c.GetInt64("user_id"), linkID, variantID); err != nil {
+		respondVariantError(c, err)
+		return
+	}
+
+	auditLinkChange(ctx, c, h.auditLogRepo, "link.variant.delete", linkID, map[string]interface{}{"variant_id": variantID})
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
@@ -0,0 +1,113 @@
+/**
+ * v2 Live Handler（实时点击流，Server-Sent Events）
+ * - GET /api/v2/links/:id/live - 单链接实时点击（仅链接所有者或管理员）
+ * - GET /api/v2/stats/live - 名下全部链接的实时点击（管理员为全局）
+ * 事件名 click，data 为 models.ClickEvent；每 15 秒发送注释行保活
+ * 浏览器可直接用 EventSource 订阅（同源时携带 access_token Cookie）
+ */
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"short-link/internal/live"
+	"short-link/internal/repo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// liveHeartbeatInterval SSE 保活间隔（避免代理因空闲断开连接）
+const liveHeartbeatInterval = 15 * time.Second
+
+// LiveHandler 实时点击流处理器（v2）
+type LiveHandler struct {
+	hub      *live.Hub
+	linkRepo *repo.LinkRepo
+}
+
+// NewLiveHandler 创建 LiveHandler
+func NewLiveHandler(hub *live.Hub, linkRepo *repo.LinkRepo) *LiveHandler {
+	return &LiveHandler{
+		hub:      hub,
+		linkRepo: linkRepo,
+	}
+}
+
+// LinkLive 订阅单个链接的实时点击
+func (h *LiveHandler) LinkLive(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	role := c.GetString("role")
+
+	linkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || linkID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的链接ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	link, err := h.linkRepo.GetLinkByID(ctx, linkID)
+	cancel()
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "链接不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取链接失败: " + err.Error()})
+		return
+	}
+	// 非所有者统一返回 404，避免泄露链接是否存在
+	if link.UserID != userID && role != "admin" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "链接不存在"})
+		return
+	}
+
+	h.stream(c, live.Filter{LinkID: link.ID})
+}
+
+// StatsLive 订阅名下全部链接的实时点击（管理员为全局）
+func (h *LiveHandler) StatsLive(c *gin.Context) {
+	filter := live.Filter{}
+	if c.GetString("role") != "admin" {
+		filter.UserID = c.GetInt64("user_id")
+	}
+	h.stream(c, filter)
+}
+
+// stream 推送事件直到客户端断开或服务关闭
+func (h *LiveHandler) stream(c *gin.Context, filter live.Filter) {
+	sub := h.hub.Subscribe(filter)
+	defer h.hub.Unsubscribe(sub)
+
+	// 长连接不受服务端写超时限制
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 Nginx 缓冲
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case ev := <-sub.C:
+			c.SSEvent("click", ev)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		case <-h.hub.Done():
+			return false
+		}
+	})
+}
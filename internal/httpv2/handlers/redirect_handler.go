@@ -1,13 +1,19 @@
 /**
  * v2 Redirect Handler（重写版）
  * - GET /:code
+ * - POST /:code 受密码保护链接的解锁（按 域名 + code + IP 限制密码错误次数）
+ * - 已过期/点击预算用尽：410 Gone（若配置 expired_redirect_url 则跳转兜底地址）
+ * - 定向规则（国家/设备/系统/语言/时间窗口）由 LinkService 在跳转时匹配
+ * - A/B 变体：分配结果写入 Cookie（nsl_ab_<code>），同一访客后续访问保持同一变体
  * 使用 pgxpool 解析 code（按 Host 匹配 domain），并写入点击/访问日志
  */
 package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"short-link/internal/metrics"
@@ -16,46 +22,140 @@ import (
 	"short-link/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// 解锁限流：同一 域名 + code + IP 在窗口内最多允许的密码错误次数
+const (
+	unlockAttemptWindow = 15 * time.Minute
+	unlockAttemptLimit  = 5
+)
+
+// A/B 变体粘性 Cookie
+const (
+	variantCookiePrefix = "nsl_ab_"
+	variantCookieMaxAge = 30 * 24 * 3600
 )
 
 // RedirectHandler v2 重定向处理器
 type RedirectHandler struct {
-	linkService *service.LinkService
+	linkService   *service.LinkService
+	unlockLimiter *utils.SlidingWindowLimiter
 }
 
 // NewRedirectHandler 创建 RedirectHandler
-func NewRedirectHandler(linkService *service.LinkService) *RedirectHandler {
-	return &RedirectHandler{linkService: linkService}
+// redisClient 为 nil 时解锁限流使用进程内计数（仅对本副本生效）
+func NewRedirectHandler(linkService *service.LinkService, redisClient *redis.Client) *RedirectHandler {
+	return &RedirectHandler{
+		linkService:   linkService,
+		unlockLimiter: utils.NewSlidingWindowLimiter(redisClient, unlockAttemptWindow, unlockAttemptLimit),
+	}
 }
 
-// Redirect 执行 302 跳转
+// Redirect 执行 302 跳转（受密码保护的链接渲染解锁页）
 func (h *RedirectHandler) Redirect(c *gin.Context) {
 	code := c.Param("code")
 
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	url, err := h.linkService.RedirectLink(
-		ctx,
-		c.Request.Host,
-		code,
-		utils.GetRealIP(c.Request),
-		c.GetHeader("User-Agent"),
-		c.GetHeader("Referer"),
-	)
+	res, err := h.linkService.RedirectLink(ctx, c.Request.Host, code, visitorFromRequest(c, code, utils.GetRealIP(c.Request)))
 	if err != nil {
-		if err == repo.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "链接不存在"})
+		if err == service.ErrPasswordRequired {
+			h.renderUnlock(c, http.StatusOK, code, "")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "重定向失败: " + err.Error()})
+		h.respondError(c, err)
 		return
 	}
 
 	// 记录 metrics
 	metrics.LinksRedirectedTotal.Inc()
 
-	c.Redirect(http.StatusFound, url)
+	h.redirect(c, code, res)
+}
+
+// Unlock 校验访问密码（POST /:code，表单字段 password），成功后 302 跳转
+func (h *RedirectHandler) Unlock(c *gin.Context) {
+	code := c.Param("code")
+	ip := utils.GetRealIP(c.Request)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	// 密码错误次数限制在 service 内原子检查并计数（只有密码错误才计数，正确的密码不消耗次数）
+	res, err := h.linkService.UnlockLink(ctx, c.Request.Host, code, c.PostForm("password"), visitorFromRequest(c, code, ip), h.unlockLimiter)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidLinkPassword:
+			h.renderUnlock(c, http.StatusUnauthorized, code, "密码错误")
+			return
+		case service.ErrTooManyUnlockAttempts:
+			metrics.RateLimitRejectedTotal.Inc()
+			h.renderUnlock(c, http.StatusTooManyRequests, code, "尝试次数过多，请稍后再试")
+			return
+		}
+		h.respondError(c, err)
+		return
+	}
+
+	metrics.LinksRedirectedTotal.Inc()
+	h.redirect(c, code, res)
+}
+
+// redirect 执行 302 跳转；命中 A/B 变体时写入粘性 Cookie
+func (h *RedirectHandler) redirect(c *gin.Context, code string, res *service.RedirectResult) {
+	if res.VariantID > 0 {
+		c.Header("Cache-Control", "no-store")
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(variantCookiePrefix+code, strconv.FormatInt(res.VariantID, 10), variantCookieMaxAge, "/", "", c.Request.TLS != nil, true)
+	}
+	c.Redirect(http.StatusFound, res.URL)
+}
+
+// visitorFromRequest 提取访客信息（统计 + 定向规则匹配 + 已分配的 A/B 变体）
+func visitorFromRequest(c *gin.Context, code string, ip string) *service.Visitor {
+	v := &service.Visitor{
+		IP:             ip,
+		UserAgent:      c.GetHeader("User-Agent"),
+		Referer:        c.GetHeader("Referer"),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		// Do Not Track / Global Privacy Control
+		OptOut: c.GetHeader("DNT") == "1" || c.GetHeader("Sec-GPC") == "1",
+	}
+	if raw, err := c.Cookie(variantCookiePrefix + code); err == nil {
+		if id, err := strconv.ParseInt(raw, 10, 64); err == nil && id > 0 {
+			v.VariantID = id
+		}
+	}
+	return v
 }
 
+// renderUnlock 渲染解锁页（web/templates/unlock.html）
+func (h *RedirectHandler) renderUnlock(c *gin.Context, status int, code string, errMsg string) {
+	c.Header("Cache-Control", "no-store")
+	c.HTML(status, "unlock.html", gin.H{
+		"title": "受保护的链接",
+		"code":  code,
+		"error": errMsg,
+	})
+}
 
+// respondError 统一处理跳转失败（404 / 410 / 500）
+func (h *RedirectHandler) respondError(c *gin.Context, err error) {
+	if err == repo.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "链接不存在"})
+		return
+	}
+	var goneErr *service.LinkGoneError
+	if errors.As(err, &goneErr) {
+		// 过期/点击预算用尽：有兜底地址则跳转，否则 410
+		if goneErr.FallbackURL != "" {
+			c.Redirect(http.StatusFound, goneErr.FallbackURL)
+			return
+		}
+		c.JSON(http.StatusGone, gin.H{"error": "链接已失效", "reason": goneErr.Reason})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "重定向失败: " + err.Error()})
+}
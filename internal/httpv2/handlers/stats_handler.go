@@ -2,11 +2,16 @@
  * v2 Stats Handler
  * - GET /api/v2/stats - 基础统计
  * - GET /api/v2/stats/aggregated - 聚合统计（日/周/月、来源、UA 等）
+ * - GET /api/v2/links/:id/stats - 单链接统计（仅链接所有者或管理员）
+ * 时间维度读取 click_rollups_* 预聚合表，来源/UA/IP 维度仍查询 access_logs
+ * 全局统计仅对管理员开放，普通用户只能看到自己名下链接的数据
+ * IP 以哈希保存或不保存时（STATS_IP_MODE=hash/drop）不返回 IP 维度
  */
 package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -23,23 +28,33 @@ type StatsHandler struct {
 	linkService *service.LinkService
 	statsRepo   *repo.StatsRepo
 	linkRepo    *repo.LinkRepo
+	hideIPs     bool // IP 维度已无意义（哈希按天轮换或不保存），不查询 Top IPs
 }
 
 // NewStatsHandler 创建 StatsHandler
-func NewStatsHandler(linkService *service.LinkService, statsRepo *repo.StatsRepo, linkRepo *repo.LinkRepo) *StatsHandler {
+func NewStatsHandler(linkService *service.LinkService, statsRepo *repo.StatsRepo, linkRepo *repo.LinkRepo, hideIPs bool) *StatsHandler {
 	return &StatsHandler{
 		linkService: linkService,
 		statsRepo:   statsRepo,
 		linkRepo:    linkRepo,
+		hideIPs:     hideIPs,
 	}
 }
 
+// statsScope 根据调用者角色确定统计范围：管理员看全局，普通用户只看自己的链接
+func statsScope(c *gin.Context) repo.StatsScope {
+	if c.GetString("role") == "admin" {
+		return repo.StatsScope{}
+	}
+	return repo.StatsScope{UserID: c.GetInt64("user_id")}
+}
+
 // GetStats 获取基础统计信息
 func (h *StatsHandler) GetStats(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	stats, err := h.linkService.GetStats(ctx)
+	stats, err := h.linkService.GetStats(ctx, statsScope(c).UserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取统计信息失败: " + err.Error(),
@@ -55,9 +70,10 @@ func (h *StatsHandler) GetAggregatedStats(c *gin.Context) {
 	defer cancel()
 
 	stats := &models.AggregatedStats{}
+	scope := statsScope(c)
 
 	// 基础统计
-	linkStats, err := h.linkService.GetStats(ctx)
+	linkStats, err := h.linkService.GetStats(ctx, scope.UserID)
 	if err == nil {
 		stats.TotalLinks = linkStats.TotalLinks
 		stats.TotalClicks = linkStats.TotalClicks
@@ -65,41 +81,164 @@ func (h *StatsHandler) GetAggregatedStats(c *gin.Context) {
 		stats.TopLinks = linkStats.TopLinks
 	}
 
+	// 小时统计（最近24小时）
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if hourlyStats, err := h.statsRepo.GetHourlyStats(ctx, scope, hours); err == nil {
+		stats.HourlyStats = hourlyStats
+	}
+
 	// 日统计（最近30天）
 	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
-	if dailyStats, err := h.statsRepo.GetDailyStats(ctx, days); err == nil {
+	if dailyStats, err := h.statsRepo.GetDailyStats(ctx, scope, days); err == nil {
 		stats.DailyStats = dailyStats
 	}
 
 	// 周统计（最近12周）
 	weeks, _ := strconv.Atoi(c.DefaultQuery("weeks", "12"))
-	if weeklyStats, err := h.statsRepo.GetWeeklyStats(ctx, weeks); err == nil {
+	if weeklyStats, err := h.statsRepo.GetWeeklyStats(ctx, scope, weeks); err == nil {
 		stats.WeeklyStats = weeklyStats
 	}
 
 	// 月统计（最近12个月）
 	months, _ := strconv.Atoi(c.DefaultQuery("months", "12"))
-	if monthlyStats, err := h.statsRepo.GetMonthlyStats(ctx, months); err == nil {
+	if monthlyStats, err := h.statsRepo.GetMonthlyStats(ctx, scope, months); err == nil {
 		stats.MonthlyStats = monthlyStats
 	}
 
 	// Top 来源
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	if topReferers, err := h.statsRepo.GetTopReferers(ctx, limit); err == nil {
+	if topReferers, err := h.statsRepo.GetTopReferers(ctx, scope, limit); err == nil {
 		stats.TopReferers = topReferers
 	}
 
 	// Top UA
-	if topUAs, err := h.statsRepo.GetTopUserAgents(ctx, limit); err == nil {
+	if topUAs, err := h.statsRepo.GetTopUserAgents(ctx, scope, limit); err == nil {
 		stats.TopUserAgents = topUAs
 	}
 
 	// Top IPs
-	if topIPs, err := h.statsRepo.GetTopIPs(ctx, limit); err == nil {
-		stats.TopIPs = topIPs
+	if !h.hideIPs {
+		if topIPs, err := h.statsRepo.GetTopIPs(ctx, scope, limit); err == nil {
+			stats.TopIPs = topIPs
+		}
+	}
+
+	// 浏览器/系统/设备（UA 解析维度，不含爬虫）
+	if browsers, err := h.statsRepo.GetTopBrowsers(ctx, scope, limit); err == nil {
+		stats.TopBrowsers = browsers
+	}
+	if versions, err := h.statsRepo.GetTopBrowserVersions(ctx, scope, limit); err == nil {
+		stats.TopBrowserVersions = versions
+	}
+	if oses, err := h.statsRepo.GetTopOS(ctx, scope, limit); err == nil {
+		stats.TopOS = oses
+	}
+	if devices, err := h.statsRepo.GetDeviceStats(ctx, scope); err == nil {
+		stats.Devices = devices
+	}
+	if bots, err := h.statsRepo.GetBotStats(ctx, scope); err == nil {
+		stats.Bots = bots
+	}
+	if countries, err := h.statsRepo.GetTopCountries(ctx, scope, limit); err == nil {
+		stats.TopCountries = countries
+	}
+	if cities, err := h.statsRepo.GetTopCities(ctx, scope, limit); err == nil {
+		stats.TopCities = cities
 	}
 
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetLinkStats 获取单个链接的统计信息（日/周/月、来源、UA、IP）
+func (h *StatsHandler) GetLinkStats(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	role := c.GetString("role")
+
+	linkID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || linkID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的链接ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	link, err := h.linkRepo.GetLinkByID(ctx, linkID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "链接不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取链接失败: " + err.Error()})
+		return
+	}
+	// 非所有者统一返回 404，避免泄露链接是否存在
+	if link.UserID != userID && role != "admin" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "链接不存在"})
+		return
+	}
 
+	scope := repo.StatsScope{LinkID: link.ID}
+	stats := &models.LinkAnalytics{
+		LinkID:       link.ID,
+		Code:         link.Code,
+		ClickCount:   link.ClickCount,
+		UniqueClicks: link.UniqueClicks,
+	}
+
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if hourlyStats, err := h.statsRepo.GetHourlyStats(ctx, scope, hours); err == nil {
+		stats.HourlyStats = hourlyStats
+	}
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if dailyStats, err := h.statsRepo.GetDailyStats(ctx, scope, days); err == nil {
+		stats.DailyStats = dailyStats
+	}
+	weeks, _ := strconv.Atoi(c.DefaultQuery("weeks", "12"))
+	if weeklyStats, err := h.statsRepo.GetWeeklyStats(ctx, scope, weeks); err == nil {
+		stats.WeeklyStats = weeklyStats
+	}
+	months, _ := strconv.Atoi(c.DefaultQuery("months", "12"))
+	if monthlyStats, err := h.statsRepo.GetMonthlyStats(ctx, scope, months); err == nil {
+		stats.MonthlyStats = monthlyStats
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if topReferers, err := h.statsRepo.GetTopReferers(ctx, scope, limit); err == nil {
+		stats.TopReferers = topReferers
+	}
+	if topUAs, err := h.statsRepo.GetTopUserAgents(ctx, scope, limit); err == nil {
+		stats.TopUserAgents = topUAs
+	}
+	if !h.hideIPs {
+		if topIPs, err := h.statsRepo.GetTopIPs(ctx, scope, limit); err == nil {
+			stats.TopIPs = topIPs
+		}
+	}
+	if browsers, err := h.statsRepo.GetTopBrowsers(ctx, scope, limit); err == nil {
+		stats.TopBrowsers = browsers
+	}
+	if versions, err := h.statsRepo.GetTopBrowserVersions(ctx, scope, limit); err == nil {
+		stats.TopBrowserVersions = versions
+	}
+	if oses, err := h.statsRepo.GetTopOS(ctx, scope, limit); err == nil {
+		stats.TopOS = oses
+	}
+	if devices, err := h.statsRepo.GetDeviceStats(ctx, scope); err == nil {
+		stats.Devices = devices
+	}
+	if bots, err := h.statsRepo.GetBotStats(ctx, scope); err == nil {
+		stats.Bots = bots
+	}
+	if countries, err := h.statsRepo.GetTopCountries(ctx, scope, limit); err == nil {
+		stats.TopCountries = countries
+	}
+	if cities, err := h.statsRepo.GetTopCities(ctx, scope, limit); err == nil {
+		stats.TopCities = cities
+	}
+	if variants, err := h.statsRepo.GetVariantStats(ctx, link.ID); err == nil {
+		stats.Variants = variants
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,268 @@
+/**
+ * v2 Webhook Handler
+ * - GET    /api/v2/webhooks 获取接收地址列表
+ * - POST   /api/v2/webhooks 注册接收地址（响应含签名密钥，仅此一次）
+ * - GET    /api/v2/webhooks/:id 获取接收地址
+ * - PATCH  /api/v2/webhooks/:id 更新接收地址（url/events/description/active）
+ * - DELETE /api/v2/webhooks/:id 删除接收地址
+ * - POST   /api/v2/webhooks/:id/secret 轮换签名密钥（响应含新密钥）
+ * - GET    /api/v2/webhooks/:id/deliveries 投递记录（分页，可按 status 过滤）
+ * - GET    /api/v2/webhooks/:id/deliveries/:delivery_id 投递记录详情（含请求体与响应体）
+ * - POST   /api/v2/webhooks/:id/deliveries/:delivery_id/redeliver 手动重新投递
+ * 仅所有者可操作，非所有者统一返回 404
+ */
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/internal/service"
+	"short-link/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler webhook 处理器（v2）
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+	auditLogRepo   *repo.AuditLogRepo
+}
+
+// NewWebhookHandler 创建 WebhookHandler
+func NewWebhookHandler(webhookService *service.WebhookService, auditLogRepo *repo.AuditLogRepo) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		auditLogRepo:   auditLogRepo,
+	}
+}
+
+// parseWebhookParams 解析接收地址 ID 与投递记录 ID
+func parseWebhookParams(c *gin.Context, withDelivery bool) (int64, int64, bool) {
+	endpointID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || endpointID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 webhook ID"})
+		return 0, 0, false
+	}
+	if !withDelivery {
+		return endpointID, 0, true
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil || deliveryID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的投递记录ID"})
+		return 0, 0, false
+	}
+	return endpointID, deliveryID, true
+}
+
+// respondWebhookError 统一处理 webhook 操作错误
+func respondWebhookError(c *gin.Context, err error) {
+	if errors.Is(err, repo.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook 或投递记录不存在"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// audit 记录 webhook 变更审计日志（best-effort）
+func (h *WebhookHandler) audit(ctx context.Context, c *gin.Context, action string, endpointID int64, details map[string]interface{}) {
+	auditResourceChange(ctx, c, h.auditLogRepo, action, "webhook", endpointID, details)
+}
+
+// ListWebhooks 获取接收地址列表
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	endpoints, err := h.webhookService.ListEndpoints(ctx, c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取 webhook 失败: " + err.Error()})
+		return
+	}
+	if endpoints == nil {
+		endpoints = []models.WebhookEndpoint{}
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": endpoints, "event_types": models.WebhookEventTypes})
+}
+
+// CreateWebhook 注册接收地址
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.WebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	endpoint, err := h.webhookService.CreateEndpoint(ctx, c.GetInt64("user_id"), &req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	h.audit(ctx, c, "webhook.create", endpoint.ID, map[string]interface{}{"url": endpoint.URL, "events": endpoint.Events})
+	c.JSON(http.StatusCreated, endpoint)
+}
+
+// GetWebhook 获取接收地址
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	endpointID, _, ok := parseWebhookParams(c, false)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	endpoint, err := h.webhookService.GetEndpoint(ctx, c.GetInt64("user_id"), endpointID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// UpdateWebhook 更新接收地址
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	endpointID, _, ok := parseWebhookParams(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookEndpointUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	endpoint, err := h.webhookService.UpdateEndpoint(ctx, c.GetInt64("user_id"), endpointID, &req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	h.audit(ctx, c, "webhook.update", endpoint.ID, map[string]interface{}{"url": endpoint.URL, "events": endpoint.Events, "active": endpoint.Active})
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// DeleteWebhook 删除接收地址
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	endpointID, _, ok := parseWebhookParams(c, false)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.webhookService.DeleteEndpoint(ctx, c.GetInt64("user_id"), endpointID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	h.audit(ctx, c, "webhook.delete", endpointID, map[string]interface{}{})
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// RotateWebhookSecret 轮换签名密钥
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	endpointID, _, ok := parseWebhookParams(c, false)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	endpoint, err := h.webhookService.RotateSecret(ctx, c.GetInt64("user_id"), endpointID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	h.audit(ctx, c, "webhook.rotate_secret", endpoint.ID, map[string]interface{}{})
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// ListDeliveries 获取投递记录（新到旧）
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	endpointID, _, ok := parseWebhookParams(c, false)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	deliveries, total, err := h.webhookService.ListDeliveries(ctx, c.GetInt64("user_id"), endpointID, c.Query("status"), page, limit)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries":  deliveries,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": int((total + int64(limit) - 1) / int64(limit)),
+	})
+}
+
+// GetDelivery 获取投递记录详情
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	endpointID, deliveryID, ok := parseWebhookParams(c, true)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	delivery, err := h.webhookService.GetDelivery(ctx, c.GetInt64("user_id"), endpointID, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// Redeliver 手动重新投递（新建一条投递记录，沿用原事件 ID）
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	endpointID, deliveryID, ok := parseWebhookParams(c, true)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	delivery, err := h.webhookService.Redeliver(ctx, c.GetInt64("user_id"), endpointID, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	h.audit(ctx, c, "webhook.redeliver", endpointID, map[string]interface{}{"delivery_id": deliveryID, "redelivery_id": delivery.ID})
+	c.JSON(http.StatusAccepted, delivery)
+}
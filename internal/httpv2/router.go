@@ -9,14 +9,19 @@ import (
 	"fmt"
 	"time"
 
+	"short-link/cache"
 	"short-link/internal/config"
 	"short-link/internal/db"
+	"short-link/internal/geoip"
 	"short-link/internal/httpv2/handlers"
 	v2mw "short-link/internal/httpv2/middleware"
 	"short-link/internal/jobs"
+	"short-link/internal/live"
+	"short-link/internal/notify"
 	"short-link/internal/repo"
 	"short-link/internal/service"
 	"short-link/middleware"
+	"short-link/models"
 	"short-link/utils"
 
 	"github.com/gin-gonic/gin"
@@ -31,7 +36,14 @@ type Module struct {
 	SettingsRepo *repo.SettingsRepo
 	LinkRepo    *repo.LinkRepo
 	AccessLogRepo *repo.AccessLogRepo
+	GeoIP       *geoip.Enricher
 	StatsWorker *jobs.StatsWorker
+	LiveHub     *live.Hub // 实时点击流（启用 Redis 时跨副本广播）
+	WebhookWorker *jobs.WebhookWorker
+	ClickAlertJob *jobs.ClickAlertJob // ALERT_INTERVAL_MINUTES=0 时不启动
+	OutboxDispatcher *jobs.OutboxDispatcher // Meilisearch 不可用期间事件退避重试
+	MeiliReconciler *jobs.MeiliReconciler
+	PartitionJob *jobs.AccessLogPartitionJob
 	UserService *service.UserService
 	PermissionService *service.PermissionService
 	LinkService *service.LinkService
@@ -40,6 +52,13 @@ type Module struct {
 	LinkHandler *handlers.LinkHandler
 	RedirectHandler *handlers.RedirectHandler
 	StatsHandler *handlers.StatsHandler
+	LiveHandler *handlers.LiveHandler
+	WebhookHandler *handlers.WebhookHandler
+	AlertHandler *handlers.AlertHandler
+	LinkRuleHandler *handlers.LinkRuleHandler
+	LinkVariantHandler *handlers.LinkVariantHandler
+	LinkTransferHandler *handlers.LinkTransferHandler
+	LinkTagHandler *handlers.LinkTagHandler
 }
 
 // New 创建 v2 模块
@@ -69,31 +88,85 @@ func New() (*Module, error) {
 	auditLogRepo := repo.NewAuditLogRepo(pool)
 	permissionRepo := repo.NewPermissionRepo(pool)
 	statsRepo := repo.NewStatsRepo(pool)
+	linkRuleRepo := repo.NewLinkRuleRepo(pool)
+	linkVariantRepo := repo.NewLinkVariantRepo(pool)
+	tagRepo := repo.NewTagRepo(pool)
+	folderRepo := repo.NewFolderRepo(pool)
+	outboxRepo := repo.NewOutboxRepo(pool)
+	partitionRepo := repo.NewAccessLogPartitionRepo(pool)
+	webhookRepo := repo.NewWebhookRepo(pool)
+	alertRepo := repo.NewAlertRepo(pool)
 
-	// 初始化异步统计 Worker（批量大小50，等待间隔2秒）
-	statsWorker := jobs.NewStatsWorker(linkRepo, accessLogRepo, 50, 2*time.Second)
+	// GeoIP（可选，未配置 mmdb 路径时为 nil；每分钟检查文件是否更新）
+	geo := geoip.New(cfg.GeoIPCityDBPath, cfg.GeoIPASNDBPath, time.Minute)
 
-	// 初始化 Meilisearch Worker（最大重试3次，重试间隔5秒）
-	var meiliWorker *jobs.MeiliWorker
-	meiliWorker, err = jobs.NewMeiliWorker(cfg, 3, 5*time.Second)
-	if err != nil {
-		utils.LogWarn("Meilisearch Worker 初始化失败，索引写入将不可用: %v", err)
-		meiliWorker = nil
+	// durable 模式：点击事件先写入持久化队列（优先 Redis Stream，未启用 Redis 时为本地 spool 文件）
+	var clickQueue jobs.ClickQueue
+	if cfg.StatsIngestMode == jobs.StatsIngestDurable {
+		if cache.RedisClient != nil {
+			clickQueue, err = jobs.NewRedisClickQueue(ctx, cache.RedisClient, cfg.StatsStreamMaxLen)
+		} else {
+			clickQueue, err = jobs.NewSpoolClickQueue(cfg.StatsSpoolDir)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("初始化点击事件队列失败: %w", err)
+		}
+	}
+
+	// 初始化异步统计 Worker（批量大小、刷新间隔、并发数见 STATS_* 配置）
+	// 访问日志隐私策略（IP 截断/哈希/丢弃、DNT/GPC）在写入前应用
+	privacy := jobs.NewPrivacyPolicy(cfg.StatsIPMode, cfg.StatsIPHashSecret, cfg.StatsHonorDNT)
+	// 实时点击流（SSE）：启用 Redis 时经 pub/sub 跨副本广播，否则只在进程内分发
+	liveHub := live.NewHub(cache.RedisClient, geo, cfg.StatsHonorDNT)
+	// Webhook 投递（链接事件随链接变更写入投递记录，点击事件由 StatsWorker 每批写入后按接收地址合并）
+	webhookWorker := jobs.NewWebhookWorker(webhookRepo, linkRepo, cfg.WebhookTimeout, cfg.WebhookMaxAttempts, cfg.WebhookConcurrency, 24*time.Hour*time.Duration(cfg.WebhookRetentionDays), cfg.StatsCountBots)
+	statsWorker := jobs.NewStatsWorker(accessLogRepo, cfg.StatsBatchSize, cfg.StatsFlushInterval, cfg.StatsConcurrency, cfg.StatsCountBots, geo, clickQueue, privacy, liveHub, webhookWorker)
+
+	// 点击异常告警（告警写入 click_alerts 并经 webhook link.alert 投递；配置 SMTP 时按用户设置发送邮件）
+	alertDefaults := models.AlertRule{
+		Enabled:         true,
+		SpikeFactor:     cfg.AlertSpikeFactor,
+		SpikeMinClicks:  cfg.AlertSpikeMinClicks,
+		DropMinBaseline: cfg.AlertDropMinBaseline,
+		BotRatio:        cfg.AlertBotRatio,
 	}
+	mailer := notify.NewMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	clickAlertJob := jobs.NewClickAlertJob(alertRepo, userRepo, mailer, alertDefaults, cfg.AlertInterval, cfg.AlertBaselineHours)
+
+	// access_logs 按月分区维护（提前建分区、搬迁旧表、按保留期删除/归档）
+	partitionJob := jobs.NewAccessLogPartitionJob(partitionRepo, cfg.AccessLogPartitionAhead, cfg.AccessLogRetentionMonths, cfg.AccessLogArchiveDir)
+
+	// 初始化 Meilisearch Worker 与 outbox 投递（批量大小100，轮询间隔1秒，最多投递10次后转入死信）
+	// 启动时 Meilisearch 不可用不影响创建：投递失败后退避重试，恢复后自动继续；死信由周期对账补齐
+	meiliWorker := jobs.NewMeiliWorker(cfg, linkRepo, tagRepo)
+	if err := meiliWorker.Healthy(); err != nil {
+		utils.LogWarn("Meilisearch 暂不可用，索引事件将保留在 outbox 中退避重试直至恢复: %v", err)
+	}
+	outboxDispatcher := jobs.NewOutboxDispatcher(outboxRepo, 100, time.Second, 10, meiliWorker)
+	meiliReconciler := jobs.NewMeiliReconciler(meiliWorker, linkRepo, cfg.MeiliReconcileInterval)
 
 	userService := service.NewUserService(userRepo)
 	permissionService := service.NewPermissionService(permissionRepo)
-	linkService := service.NewLinkService(cfg.BaseURL, cfg.MinCodeLength, cfg.MaxCodeLength, linkRepo, domainRepo, settingsRepo, userRepo, accessLogRepo, statsWorker, meiliWorker)
-	searchService, err := service.NewSearchService(cfg)
-	if err != nil {
-		utils.LogWarn("Meilisearch(v2) 初始化失败，搜索功能将不可用: %v", err)
-		searchService = nil
-	}
+	linkService := service.NewLinkService(cfg.BaseURL, cfg.MinCodeLength, cfg.MaxCodeLength, linkRepo, domainRepo, settingsRepo, userRepo, accessLogRepo, statsWorker, linkRuleRepo, linkVariantRepo, tagRepo, folderRepo, geo)
+	linkRuleService := service.NewLinkRuleService(linkRepo, linkRuleRepo)
+	linkVariantService := service.NewLinkVariantService(linkRepo, linkVariantRepo)
+	linkTransferService := service.NewLinkTransferService(linkService, linkRepo, domainRepo, userRepo, folderRepo)
+	linkTagService := service.NewLinkTagService(tagRepo, folderRepo)
+	searchService := service.NewSearchService(cfg, linkService, linkRepo, tagRepo, domainRepo)
+	webhookService := service.NewWebhookService(webhookRepo)
+	alertService := service.NewAlertService(alertRepo, linkRepo, alertDefaults, mailer != nil)
 
 	authHandler := handlers.NewAuthHandler(cfg, userService, auditLogRepo)
-	linkHandler := handlers.NewLinkHandler(cfg, linkService, linkRepo, domainRepo, searchService, auditLogRepo, meiliWorker)
-	redirectHandler := handlers.NewRedirectHandler(linkService)
-	statsHandler := handlers.NewStatsHandler(linkService, statsRepo, linkRepo)
+	linkHandler := handlers.NewLinkHandler(cfg, linkService, linkRepo, domainRepo, tagRepo, searchService, auditLogRepo)
+	redirectHandler := handlers.NewRedirectHandler(linkService, cache.RedisClient)
+	statsHandler := handlers.NewStatsHandler(linkService, statsRepo, linkRepo, privacy.HidesIPs())
+	liveHandler := handlers.NewLiveHandler(liveHub, linkRepo)
+	webhookHandler := handlers.NewWebhookHandler(webhookService, auditLogRepo)
+	alertHandler := handlers.NewAlertHandler(alertService, auditLogRepo)
+	linkRuleHandler := handlers.NewLinkRuleHandler(linkRuleService, auditLogRepo)
+	linkVariantHandler := handlers.NewLinkVariantHandler(linkVariantService, auditLogRepo)
+	linkTransferHandler := handlers.NewLinkTransferHandler(linkTransferService, auditLogRepo)
+	linkTagHandler := handlers.NewLinkTagHandler(linkTagService, auditLogRepo)
 
 	return &Module{
 		Cfg:         cfg,
@@ -103,7 +176,14 @@ func New() (*Module, error) {
 		SettingsRepo: settingsRepo,
 		LinkRepo:    linkRepo,
 		AccessLogRepo: accessLogRepo,
+		GeoIP:       geo,
 		StatsWorker: statsWorker,
+		LiveHub:     liveHub,
+		WebhookWorker: webhookWorker,
+		ClickAlertJob: clickAlertJob,
+		OutboxDispatcher: outboxDispatcher,
+		MeiliReconciler: meiliReconciler,
+		PartitionJob: partitionJob,
 		UserService: userService,
 		PermissionService: permissionService,
 		LinkService: linkService,
@@ -112,6 +192,13 @@ func New() (*Module, error) {
 		LinkHandler: linkHandler,
 		RedirectHandler: redirectHandler,
 		StatsHandler: statsHandler,
+		LiveHandler: liveHandler,
+		WebhookHandler: webhookHandler,
+		AlertHandler: alertHandler,
+		LinkRuleHandler: linkRuleHandler,
+		LinkVariantHandler: linkVariantHandler,
+		LinkTransferHandler: linkTransferHandler,
+		LinkTagHandler: linkTagHandler,
 	}, nil
 }
 
@@ -121,8 +208,28 @@ func (m *Module) Close() {
 		if m.StatsWorker != nil {
 			m.StatsWorker.Stop()
 		}
-		if m.LinkService != nil && m.LinkService.GetMeiliWorker() != nil {
-			m.LinkService.GetMeiliWorker().Stop()
+		if m.LiveHub != nil {
+			m.LiveHub.Stop()
+		}
+		if m.WebhookWorker != nil {
+			m.WebhookWorker.Stop()
+		}
+		if m.ClickAlertJob != nil {
+			m.ClickAlertJob.Stop()
+		}
+		// StatsWorker 停止后再关闭 GeoIP（flush 期间仍会查询）
+		m.GeoIP.Stop()
+		if m.MeiliReconciler != nil {
+			m.MeiliReconciler.Stop()
+		}
+		if m.PartitionJob != nil {
+			m.PartitionJob.Stop()
+		}
+		if m.OutboxDispatcher != nil {
+			m.OutboxDispatcher.Stop()
+		}
+		if m.SearchService != nil {
+			m.SearchService.Stop()
 		}
 		if m.Pool != nil {
 			m.Pool.Close()
@@ -136,6 +243,7 @@ func RegisterRoutes(router *gin.Engine, m *Module) {
 
 	// 重写版 redirect（替换 legacy 的任意域名查询，修复多域名 code 冲突风险）
 	router.GET("/:code", m.RedirectHandler.Redirect)
+	router.POST("/:code", m.RedirectHandler.Unlock)
 
 	api := router.Group("/api/v2")
 	{
@@ -155,13 +263,64 @@ func RegisterRoutes(router *gin.Engine, m *Module) {
 
 			// 链接管理（v2 优先迁移核心能力：创建/列表）
 			protected.POST("/links", v2mw.RequirePermission(m.PermissionService, "link:create"), m.LinkHandler.CreateLink)
+			protected.POST("/links/batch", v2mw.RequirePermission(m.PermissionService, "link:create"), m.LinkHandler.BatchCreateLinks)
 			protected.GET("/links", v2mw.RequirePermission(m.PermissionService, "link:list"), m.LinkHandler.GetLinks)
 			protected.GET("/links/search", v2mw.RequirePermission(m.PermissionService, "link:view"), m.LinkHandler.SearchLinks)
-			protected.DELETE("/links/:code", v2mw.RequirePermission(m.PermissionService, "link:delete"), m.LinkHandler.DeleteLink)
+			protected.GET("/links/export", v2mw.RequirePermission(m.PermissionService, "link:list"), m.LinkTransferHandler.ExportLinks)
+			protected.POST("/links/import", v2mw.RequirePermission(m.PermissionService, "link:create"), m.LinkTransferHandler.ImportLinks)
+			// /links/ 下的单链接路由统一使用 :id 通配符（gin 要求同一路径段通配符名一致）
+			protected.PATCH("/links/:id", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkHandler.UpdateLink)
+			protected.DELETE("/links/:id", v2mw.RequirePermission(m.PermissionService, "link:delete"), m.LinkHandler.DeleteLink)
+
+			// 定向跳转规则
+			protected.GET("/links/:id/rules", v2mw.RequirePermission(m.PermissionService, "link:view"), m.LinkRuleHandler.ListRules)
+			protected.POST("/links/:id/rules", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkRuleHandler.CreateRule)
+			protected.PUT("/links/:id/rules/:rule_id", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkRuleHandler.UpdateRule)
+			protected.DELETE("/links/:id/rules/:rule_id", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkRuleHandler.DeleteRule)
+
+			// A/B 分流变体
+			protected.GET("/links/:id/variants", v2mw.RequirePermission(m.PermissionService, "link:view"), m.LinkVariantHandler.ListVariants)
+			protected.POST("/links/:id/variants", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkVariantHandler.CreateVariant)
+			protected.PUT("/links/:id/variants/:variant_id", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkVariantHandler.UpdateVariant)
+			protected.DELETE("/links/:id/variants/:variant_id", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkVariantHandler.DeleteVariant)
+
+			// 标签 / 文件夹
+			protected.GET("/tags", v2mw.RequirePermission(m.PermissionService, "link:list"), m.LinkTagHandler.ListTags)
+			protected.POST("/tags/merge", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkTagHandler.MergeTags)
+			protected.PATCH("/tags/:id", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkTagHandler.RenameTag)
+			protected.DELETE("/tags/:id", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkTagHandler.DeleteTag)
+			protected.GET("/folders", v2mw.RequirePermission(m.PermissionService, "link:list"), m.LinkTagHandler.ListFolders)
+			protected.POST("/folders", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkTagHandler.CreateFolder)
+			protected.PATCH("/folders/:id", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkTagHandler.RenameFolder)
+			protected.DELETE("/folders/:id", v2mw.RequirePermission(m.PermissionService, "link:update"), m.LinkTagHandler.DeleteFolder)
 
 			// 统计
 			protected.GET("/stats", v2mw.RequirePermission(m.PermissionService, "stats:view"), m.StatsHandler.GetStats)
 			protected.GET("/stats/aggregated", v2mw.RequirePermission(m.PermissionService, "stats:view"), m.StatsHandler.GetAggregatedStats)
+			protected.GET("/links/:id/stats", v2mw.RequirePermission(m.PermissionService, "stats:view"), m.StatsHandler.GetLinkStats)
+
+			// 实时点击流（SSE）
+			protected.GET("/stats/live", v2mw.RequirePermission(m.PermissionService, "stats:view"), m.LiveHandler.StatsLive)
+			protected.GET("/links/:id/live", v2mw.RequirePermission(m.PermissionService, "stats:view"), m.LiveHandler.LinkLive)
+
+			// 点击异常告警与阈值设置（查看需 stats:view，修改阈值需 link:update）
+			protected.GET("/alerts", v2mw.RequirePermission(m.PermissionService, "stats:view"), m.AlertHandler.ListAlerts)
+			protected.GET("/alerts/settings", v2mw.RequirePermission(m.PermissionService, "stats:view"), m.AlertHandler.GetSettings)
+			protected.PUT("/alerts/settings", v2mw.RequirePermission(m.PermissionService, "link:update"), m.AlertHandler.UpdateSettings)
+			protected.GET("/links/:id/alert-settings", v2mw.RequirePermission(m.PermissionService, "stats:view"), m.AlertHandler.GetLinkSettings)
+			protected.PUT("/links/:id/alert-settings", v2mw.RequirePermission(m.PermissionService, "link:update"), m.AlertHandler.UpdateLinkSettings)
+			protected.DELETE("/links/:id/alert-settings", v2mw.RequirePermission(m.PermissionService, "link:update"), m.AlertHandler.DeleteLinkSettings)
+
+			// Webhook（接收地址与投递记录）
+			protected.GET("/webhooks", v2mw.RequirePermission(m.PermissionService, "webhook:manage"), m.WebhookHandler.ListWebhooks)
+			protected.POST("/webhooks", v2mw.RequirePermission(m.PermissionService, "webhook:manage"), m.WebhookHandler.CreateWebhook)
+			protected.GET("/webhooks/:id", v2mw.RequirePermission(m.PermissionService, "webhook:manage"), m.WebhookHandler.GetWebhook)
+			protected.PATCH("/webhooks/:id", v2mw.RequirePermission(m.PermissionService, "webhook:manage"), m.WebhookHandler.UpdateWebhook)
+			protected.DELETE("/webhooks/:id", v2mw.RequirePermission(m.PermissionService, "webhook:manage"), m.WebhookHandler.DeleteWebhook)
+			protected.POST("/webhooks/:id/secret", v2mw.RequirePermission(m.PermissionService, "webhook:manage"), m.WebhookHandler.RotateWebhookSecret)
+			protected.GET("/webhooks/:id/deliveries", v2mw.RequirePermission(m.PermissionService, "webhook:manage"), m.WebhookHandler.ListDeliveries)
+			protected.GET("/webhooks/:id/deliveries/:delivery_id", v2mw.RequirePermission(m.PermissionService, "webhook:manage"), m.WebhookHandler.GetDelivery)
+			protected.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", v2mw.RequirePermission(m.PermissionService, "webhook:manage"), m.WebhookHandler.Redeliver)
 		}
 	}
 }
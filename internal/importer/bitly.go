@@ -0,0 +1,26 @@
+/**
+ * Bitly CSV 导出解析
+ * - 短码取自 Bitlink 列（如 bit.ly/3abcXYZ），自定义后缀（Custom Bitlinks）作为额外短码导入
+ * - 点击数取自 Clicks / Total Clicks / Engagements 列
+ */
+package importer
+
+import (
+	"io"
+
+	"short-link/internal/service"
+)
+
+var bitlyAliases = map[string][]string{
+	fieldCode:    {"bitlink", "link", "short link", "short url", "shortened link", "id"},
+	fieldURL:     {"long url", "destination url", "destination", "original url", "url"},
+	fieldTitle:   {"title"},
+	fieldClicks:  {"clicks", "total clicks", "engagements", "total engagements"},
+	fieldCreated: {"created", "created at", "date created", "creation date"},
+	fieldAlias:   {"custom bitlinks", "custom bitlink", "custom back half", "custom back halves"},
+}
+
+// parseBitly 解析 Bitly 链接导出 CSV
+func parseBitly(r io.Reader) (service.LinkRecordReader, error) {
+	return newCSVReader(r, bitlyAliases)
+}
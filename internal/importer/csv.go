@@ -0,0 +1,119 @@
+/**
+ * 通用 CSV 记录读取（按表头别名定位列）
+ */
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"short-link/internal/service"
+	"short-link/models"
+)
+
+// csv 字段
+const (
+	fieldCode    = "code"
+	fieldURL     = "url"
+	fieldTitle   = "title"
+	fieldClicks  = "clicks"
+	fieldCreated = "created"
+	fieldAlias   = "alias" // 额外短码（如 Bitly 自定义后缀），逐个生成点击数为 0 的记录
+)
+
+// normalizeHeader 表头规范化：小写、去 BOM，下划线/连字符视为空格
+func normalizeHeader(h string) string {
+	h = strings.TrimPrefix(h, "\ufeff")
+	h = strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(strings.TrimSpace(h)))
+	return strings.Join(strings.Fields(h), " ")
+}
+
+// csvReader 按别名映射列的 CSV 记录来源
+type csvReader struct {
+	r       *csv.Reader
+	col     map[string]int
+	pending []*models.LinkRecord // 同一行展开的额外记录
+}
+
+// newCSVReader 读取表头并按 aliases（字段 -> 候选表头，已规范化）定位列
+func newCSVReader(r io.Reader, aliases map[string][]string) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("CSV 内容为空")
+		}
+		return nil, fmt.Errorf("读取 CSV 表头失败: %w", err)
+	}
+	index := map[string]int{}
+	for i, h := range header {
+		if _, ok := index[normalizeHeader(h)]; !ok {
+			index[normalizeHeader(h)] = i
+		}
+	}
+	col := map[string]int{}
+	for field, names := range aliases {
+		for _, name := range names {
+			if i, ok := index[name]; ok {
+				col[field] = i
+				break
+			}
+		}
+	}
+	if _, ok := col[fieldCode]; !ok {
+		return nil, fmt.Errorf("CSV 缺少短链接/短码列（表头: %s）", strings.Join(header, ", "))
+	}
+	if _, ok := col[fieldURL]; !ok {
+		return nil, fmt.Errorf("CSV 缺少目标地址列（表头: %s）", strings.Join(header, ", "))
+	}
+	return &csvReader{r: cr, col: col}, nil
+}
+
+func (c *csvReader) field(row []string, name string) string {
+	if i, ok := c.col[name]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+func (c *csvReader) Decode() (*models.LinkRecord, error) {
+	if len(c.pending) > 0 {
+		rec := c.pending[0]
+		c.pending = c.pending[1:]
+		return rec, nil
+	}
+	row, err := c.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	rec := &models.LinkRecord{
+		Code:        codeFromShortURL(c.field(row, fieldCode)),
+		OriginalURL: c.field(row, fieldURL),
+		Title:       c.field(row, fieldTitle),
+		CreatedAt:   parseTime(c.field(row, fieldCreated)),
+	}
+	clicks, err := parseCount(c.field(row, fieldClicks))
+	if err != nil {
+		return rec, &service.RecordError{Err: err}
+	}
+	rec.ClickCount = clicks
+
+	// 额外短码：同一目标地址的别名，点击数已计入主记录
+	for _, alias := range strings.FieldsFunc(c.field(row, fieldAlias), func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '|'
+	}) {
+		if code := codeFromShortURL(alias); code != "" && code != rec.Code {
+			c.pending = append(c.pending, &models.LinkRecord{
+				Code:        code,
+				OriginalURL: rec.OriginalURL,
+				Title:       rec.Title,
+				CreatedAt:   rec.CreatedAt,
+			})
+		}
+	}
+	return rec, nil
+}
@@ -0,0 +1,178 @@
+/**
+ * 第三方短链服务导入（internal/importer）
+ * - 可插拔解析器：按来源名注册（bitly / yourls / shlink），输出统一的 models.LinkRecord
+ * - 解析器只负责格式转换，写入、冲突检测与演练由 service.LinkTransferService 完成
+ * - 保留原短码与历史点击总数（click_count）
+ */
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"short-link/internal/service"
+	"short-link/models"
+)
+
+// Parser 解析第三方导出内容，返回逐条读取的记录来源
+type Parser func(r io.Reader) (service.LinkRecordReader, error)
+
+var parsers = map[string]Parser{}
+
+// Register 注册解析器（来源名不区分大小写）
+func Register(source string, p Parser) {
+	parsers[strings.ToLower(source)] = p
+}
+
+// Sources 已注册的来源名（排序后）
+func Sources() []string {
+	out := make([]string, 0, len(parsers))
+	for name := range parsers {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Open 按来源名解析导入内容
+func Open(source string, r io.Reader) (service.LinkRecordReader, error) {
+	p, ok := parsers[strings.ToLower(strings.TrimSpace(source))]
+	if !ok {
+		return nil, fmt.Errorf("不支持的导入来源: %q（可选 %s）", source, strings.Join(Sources(), "/"))
+	}
+	return p(r)
+}
+
+func init() {
+	Register("bitly", parseBitly)
+	Register("yourls", parseYOURLS)
+	Register("shlink", parseShlink)
+}
+
+// sliceReader 基于内存切片的记录来源（JSON 等需整体解析的格式）
+type sliceReader struct {
+	records []*models.LinkRecord
+	errs    []error
+	pos     int
+}
+
+func (s *sliceReader) add(rec *models.LinkRecord, err error) {
+	s.records = append(s.records, rec)
+	s.errs = append(s.errs, err)
+}
+
+func (s *sliceReader) Decode() (*models.LinkRecord, error) {
+	if s.pos >= len(s.records) {
+		return nil, io.EOF
+	}
+	i := s.pos
+	s.pos++
+	if s.errs[i] != nil {
+		return s.records[i], &service.RecordError{Err: s.errs[i]}
+	}
+	return s.records[i], nil
+}
+
+// codeFromShortURL 从短链接（如 https://bit.ly/abc123 或 bit.ly/abc123）提取短码，不含 / 时视为短码本身
+func codeFromShortURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || !strings.Contains(raw, "/") {
+		return raw
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	path := strings.Trim(u.Path, "/")
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		path = path[i+1:]
+	}
+	return path
+}
+
+// timeLayouts 第三方导出中常见的时间格式
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"1/2/2006 15:04",
+	"01/02/2006",
+	"1/2/2006",
+}
+
+// parseTime 解析时间（支持常见格式与 Unix 秒），无法解析时返回零值
+func parseTime(raw string) time.Time {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t
+		}
+	}
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil && n > 0 {
+		return time.Unix(n, 0).UTC()
+	}
+	return time.Time{}
+}
+
+// parseCount 解析点击数（允许千分位逗号，空值为 0）
+func parseCount(raw string) (int64, error) {
+	raw = strings.ReplaceAll(strings.TrimSpace(raw), ",", "")
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("点击数不合法: %q", raw)
+	}
+	return n, nil
+}
+
+// peekFormat 跳过 UTF-8 BOM 并返回首个非空白字符，用于格式探测（不消费内容）
+func peekFormat(br *bufio.Reader) byte {
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		_, _ = br.Discard(3)
+	}
+	head, _ := br.Peek(512)
+	for _, b := range head {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		default:
+			return b
+		}
+	}
+	return 0
+}
+
+// jsonString 从 JSON 对象中按候选键读取字符串（数字转为字符串）
+func jsonString(obj map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		switch v := obj[k].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"short-link/internal/service"
+	"short-link/models"
+)
+
+func readAll(t *testing.T, src service.LinkRecordReader) ([]*models.LinkRecord, int) {
+	t.Helper()
+	var out []*models.LinkRecord
+	bad := 0
+	for {
+		rec, err := src.Decode()
+		if err == io.EOF {
+			return out, bad
+		}
+		var recErr *service.RecordError
+		if errors.As(err, &recErr) {
+			bad++
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		out = append(out, rec)
+	}
+}
+
+func TestBitlyCSV(t *testing.T) {
+	in := "\ufeffBitlink,Long URL,Title,Created,Clicks,Custom Bitlinks\n" +
+		"bit.ly/3abcXYZ,https://example.com/a,Home,2023-05-01 10:00:00,\"1,204\",bit.ly/promo\n" +
+		"https://bit.ly/4def,https://example.com/b,,,7,\n"
+	src, err := Open("Bitly", strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	recs, bad := readAll(t, src)
+	if bad != 0 || len(recs) != 3 {
+		t.Fatalf("got %d records, %d bad", len(recs), bad)
+	}
+	if recs[0].Code != "3abcXYZ" || recs[0].ClickCount != 1204 || recs[0].CreatedAt.Year() != 2023 {
+		t.Errorf("unexpected first record: %+v", recs[0])
+	}
+	if recs[1].Code != "promo" || recs[1].OriginalURL != "https://example.com/a" || recs[1].ClickCount != 0 {
+		t.Errorf("unexpected alias record: %+v", recs[1])
+	}
+	if recs[2].Code != "4def" || recs[2].ClickCount != 7 {
+		t.Errorf("unexpected third record: %+v", recs[2])
+	}
+}
+
+func TestShlinkJSONAndCSV(t *testing.T) {
+	js := `{"shortUrls":{"data":[
+		{"shortCode":"abc","longUrl":"https://example.com","title":"T","dateCreated":"2022-01-02T03:04:05+00:00","visitsSummary":{"total":12}},
+		{"shortUrl":"https://s.test/def","longUrl":"https://example.org","visitsCount":3}
+	]}}`
+	src, err := Open("shlink", strings.NewReader(js))
+	if err != nil {
+		t.Fatalf("Open json: %v", err)
+	}
+	recs, _ := readAll(t, src)
+	if len(recs) != 2 || recs[0].Code != "abc" || recs[0].ClickCount != 12 || recs[1].Code != "def" || recs[1].ClickCount != 3 {
+		t.Fatalf("unexpected json records: %+v", recs)
+	}
+
+	csvIn := "shortCode,shortUrl,longUrl,title,createdAt,visits\nxyz,https://s.test/xyz,https://example.net,,2022-01-02,5\n"
+	src, err = Open("shlink", strings.NewReader(csvIn))
+	if err != nil {
+		t.Fatalf("Open csv: %v", err)
+	}
+	recs, _ = readAll(t, src)
+	if len(recs) != 1 || recs[0].Code != "xyz" || recs[0].OriginalURL != "https://example.net" || recs[0].ClickCount != 5 {
+		t.Fatalf("unexpected csv records: %+v", recs)
+	}
+}
+
+func TestYOURLSSQL(t *testing.T) {
+	in := "-- MySQL dump\n" +
+		"/*!40101 SET NAMES utf8 */;\n" +
+		"CREATE TABLE `yourls_url` (`keyword` varchar(100));\n" +
+		"INSERT INTO `yourls_options` VALUES (1,'version','1.9');\n" +
+		"INSERT INTO `yourls_url` VALUES ('ozh','http://ozh.org/','Ozh; \\'s blog','2009-09-05 20:56:53','127.0.0.1',42)," +
+		"('g','https://google.com/',NULL,'2010-01-01 00:00:00','::1',0),('bad','http://x',NULL,'2010-01-01','::1','n/a');\n" +
+		"INSERT INTO `yourls_url` (`keyword`,`url`,`clicks`) VALUES ('it''s','http://y',1);\n"
+	src, err := Open("yourls", strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	recs, bad := readAll(t, src)
+	if bad != 1 || len(recs) != 3 {
+		t.Fatalf("got %d records, %d bad", len(recs), bad)
+	}
+	if recs[0].Code != "ozh" || recs[0].Title != "Ozh; 's blog" || recs[0].ClickCount != 42 || recs[0].CreatedAt.Year() != 2009 {
+		t.Errorf("unexpected first record: %+v", recs[0])
+	}
+	if recs[1].Code != "g" || recs[1].Title != "" {
+		t.Errorf("unexpected second record: %+v", recs[1])
+	}
+	if recs[2].Code != "it's" || recs[2].OriginalURL != "http://y" || recs[2].ClickCount != 1 {
+		t.Errorf("unexpected column-list record: %+v", recs[2])
+	}
+}
+
+func TestYOURLSJSON(t *testing.T) {
+	in := `{"links":{
+		"link_2":{"shorturl":"https://sho.rt/b","url":"https://b.example","clicks":"2"},
+		"link_10":{"shorturl":"https://sho.rt/c","url":"https://c.example","clicks":"0"},
+		"link_1":{"shorturl":"https://sho.rt/a","url":"https://a.example","title":"A","timestamp":"2020-02-02 02:02:02","clicks":"9"}
+	}}`
+	src, err := Open("yourls", strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	recs, _ := readAll(t, src)
+	if len(recs) != 3 || recs[0].Code != "a" || recs[1].Code != "b" || recs[2].Code != "c" || recs[0].ClickCount != 9 {
+		t.Fatalf("unexpected records: %+v", recs)
+	}
+}
+
+func TestOpenUnknownSource(t *testing.T) {
+	if _, err := Open("tinyurl", strings.NewReader("")); err == nil {
+		t.Fatal("expected error for unknown source")
+	}
+}
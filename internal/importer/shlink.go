@@ -0,0 +1,83 @@
+/**
+ * Shlink 导出解析
+ * - JSON：REST API 列表响应（{"shortUrls": {"data": [...]}}）或其中的 data 数组
+ * - CSV：shlink-web-client 导出（shortCode / shortUrl / longUrl / title / createdAt / visits）
+ * - 点击数优先取 visitsSummary.total，兼容旧版 visitsCount
+ */
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"short-link/internal/service"
+	"short-link/models"
+)
+
+var shlinkAliases = map[string][]string{
+	fieldCode:    {"short code", "shortcode", "short url", "shorturl"},
+	fieldURL:     {"long url", "longurl"},
+	fieldTitle:   {"title"},
+	fieldClicks:  {"visits", "visits count", "visitscount", "visits total"},
+	fieldCreated: {"created at", "createdat", "date created", "datecreated"},
+}
+
+// parseShlink 按首字符区分 JSON / CSV
+func parseShlink(r io.Reader) (service.LinkRecordReader, error) {
+	br := bufio.NewReader(r)
+	switch peekFormat(br) {
+	case '{', '[':
+		return parseShlinkJSON(br)
+	default:
+		return newCSVReader(br, shlinkAliases)
+	}
+}
+
+func parseShlinkJSON(r io.Reader) (service.LinkRecordReader, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("解析 Shlink JSON 失败: %w", err)
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		var wrapped struct {
+			ShortURLs struct {
+				Data []map[string]interface{} `json:"data"`
+			} `json:"shortUrls"`
+			Data []map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, fmt.Errorf("解析 Shlink JSON 失败: %w", err)
+		}
+		items = wrapped.ShortURLs.Data
+		if items == nil {
+			items = wrapped.Data
+		}
+	}
+
+	out := &sliceReader{}
+	for _, item := range items {
+		rec := &models.LinkRecord{
+			Code:        jsonString(item, "shortCode"),
+			OriginalURL: jsonString(item, "longUrl"),
+			Title:       jsonString(item, "title"),
+			CreatedAt:   parseTime(jsonString(item, "dateCreated", "createdAt")),
+		}
+		if rec.Code == "" {
+			rec.Code = codeFromShortURL(jsonString(item, "shortUrl"))
+		}
+		clicks := jsonString(item, "visitsCount")
+		if summary, ok := item["visitsSummary"].(map[string]interface{}); ok {
+			if total := jsonString(summary, "total"); total != "" {
+				clicks = total
+			}
+		}
+		n, err := parseCount(clicks)
+		rec.ClickCount = n
+		out.add(rec, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,373 @@
+/**
+ * YOURLS 导出解析
+ * - SQL：mysqldump 的 INSERT INTO `yourls_url` ... VALUES (...), (...);（流式解析，表前缀任意）
+ * - JSON：API stats 响应（{"links": {"link_1": {...}}}）或对象数组
+ * 字段：keyword（短码）、url、title、timestamp、clicks
+ */
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"short-link/internal/service"
+	"short-link/models"
+)
+
+// yourlsDefaultColumns yourls_url 表的默认列顺序（INSERT 未写列名时使用）
+var yourlsDefaultColumns = []string{"keyword", "url", "title", "timestamp", "ip", "clicks"}
+
+// parseYOURLS 按首字符区分 JSON / SQL
+func parseYOURLS(r io.Reader) (service.LinkRecordReader, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	switch peekFormat(br) {
+	case '{', '[':
+		return parseYOURLSJSON(br)
+	default:
+		return &yourlsSQLReader{lex: &sqlLexer{r: br}}, nil
+	}
+}
+
+func parseYOURLSJSON(r io.Reader) (service.LinkRecordReader, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("解析 YOURLS JSON 失败: %w", err)
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		var wrapped struct {
+			Links map[string]map[string]interface{} `json:"links"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, fmt.Errorf("解析 YOURLS JSON 失败: %w", err)
+		}
+		// link_1, link_2, ... 按序号排序，保证导入顺序稳定
+		keys := make([]string, 0, len(wrapped.Links))
+		for k := range wrapped.Links {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			ni, _ := strconv.Atoi(strings.TrimPrefix(keys[i], "link_"))
+			nj, _ := strconv.Atoi(strings.TrimPrefix(keys[j], "link_"))
+			if ni != nj {
+				return ni < nj
+			}
+			return keys[i] < keys[j]
+		})
+		for _, k := range keys {
+			items = append(items, wrapped.Links[k])
+		}
+	}
+
+	out := &sliceReader{}
+	for _, item := range items {
+		rec := &models.LinkRecord{
+			Code:        jsonString(item, "keyword"),
+			OriginalURL: jsonString(item, "url"),
+			Title:       jsonString(item, "title"),
+			CreatedAt:   parseTime(jsonString(item, "timestamp")),
+		}
+		if rec.Code == "" {
+			rec.Code = codeFromShortURL(jsonString(item, "shorturl"))
+		}
+		n, err := parseCount(jsonString(item, "clicks"))
+		rec.ClickCount = n
+		out.add(rec, err)
+	}
+	return out, nil
+}
+
+// yourlsSQLReader 逐行（元组）读取 yourls_url 的 INSERT 语句
+type yourlsSQLReader struct {
+	lex     *sqlLexer
+	columns []string // 当前 INSERT 的列顺序；nil 表示不在 VALUES 中
+}
+
+func (y *yourlsSQLReader) Decode() (*models.LinkRecord, error) {
+	for {
+		if y.columns == nil {
+			if err := y.nextInsert(); err != nil {
+				return nil, err
+			}
+		}
+		tok, err := y.lex.next()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		switch {
+		case tok.kind == tokPunct && tok.text == ",":
+			continue
+		case tok.kind == tokPunct && tok.text == ";":
+			y.columns = nil
+			continue
+		case tok.kind == tokPunct && tok.text == "(":
+			values, err := y.lex.tuple()
+			if err != nil {
+				return nil, err
+			}
+			return yourlsRecord(y.columns, values)
+		default:
+			return nil, fmt.Errorf("SQL 解析失败：VALUES 中出现意外的 %q", tok.text)
+		}
+	}
+}
+
+// nextInsert 跳到下一条 INSERT INTO <prefix>url 语句的 VALUES 之后，并解析列名
+func (y *yourlsSQLReader) nextInsert() error {
+	for {
+		tok, err := y.lex.next()
+		if err != nil {
+			return err // io.EOF：没有更多语句
+		}
+		if tok.kind != tokWord || !strings.EqualFold(tok.text, "INSERT") {
+			continue
+		}
+		// INSERT [IGNORE] INTO table
+		var table string
+		for {
+			t, err := y.lex.next()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			if t.kind == tokWord && (strings.EqualFold(t.text, "IGNORE") || strings.EqualFold(t.text, "INTO")) {
+				continue
+			}
+			table = t.text
+			break
+		}
+		if i := strings.LastIndex(table, "."); i >= 0 {
+			table = table[i+1:]
+		}
+		if !strings.EqualFold(table, "url") && !strings.HasSuffix(strings.ToLower(table), "_url") {
+			continue
+		}
+
+		columns := yourlsDefaultColumns
+		t, err := y.lex.next()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if t.kind == tokPunct && t.text == "(" {
+			names, err := y.lex.tuple()
+			if err != nil {
+				return err
+			}
+			columns = make([]string, len(names))
+			for i, n := range names {
+				columns[i] = strings.ToLower(n.text)
+			}
+			if t, err = y.lex.next(); err != nil {
+				return unexpectedEOF(err)
+			}
+		}
+		if t.kind != tokWord || !strings.EqualFold(t.text, "VALUES") {
+			return fmt.Errorf("SQL 解析失败：INSERT INTO %s 后缺少 VALUES", table)
+		}
+		y.columns = columns
+		return nil
+	}
+}
+
+// yourlsRecord 按列名把元组转换为导入记录
+func yourlsRecord(columns []string, values []sqlToken) (*models.LinkRecord, error) {
+	if len(values) != len(columns) {
+		return nil, &service.RecordError{Err: fmt.Errorf("列数不匹配：期望 %d，实际 %d", len(columns), len(values))}
+	}
+	rec := &models.LinkRecord{}
+	var clicks string
+	for i, col := range columns {
+		v := values[i].text
+		switch col {
+		case "keyword":
+			rec.Code = v
+		case "url":
+			rec.OriginalURL = v
+		case "title":
+			rec.Title = v
+		case "timestamp":
+			rec.CreatedAt = parseTime(v)
+		case "clicks":
+			clicks = v
+		}
+	}
+	n, err := parseCount(clicks)
+	if err != nil {
+		return rec, &service.RecordError{Err: err}
+	}
+	rec.ClickCount = n
+	return rec, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return fmt.Errorf("SQL 解析失败：语句不完整: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// SQL 词法单元类型
+const (
+	tokWord   = iota // 关键字 / 未加引号的标识符 / 数字 / NULL
+	tokString        // '...' 或 "..." 字符串（已反转义）
+	tokIdent         // `...` 标识符
+	tokPunct         // ( ) , ;
+)
+
+type sqlToken struct {
+	kind int
+	text string
+}
+
+// sqlLexer MySQL dump 的最小词法分析器（跳过注释，支持双单引号与反斜杠转义）
+type sqlLexer struct {
+	r *bufio.Reader
+}
+
+func (l *sqlLexer) next() (sqlToken, error) {
+	for {
+		c, _, err := l.r.ReadRune()
+		if err != nil {
+			return sqlToken{}, err
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == '#':
+			l.skipLine()
+		case c == '-':
+			if p, _ := l.r.Peek(1); len(p) == 1 && p[0] == '-' {
+				l.skipLine()
+				continue
+			}
+			return l.word(c)
+		case c == '/':
+			if p, _ := l.r.Peek(1); len(p) == 1 && p[0] == '*' {
+				if err := l.skipBlockComment(); err != nil {
+					return sqlToken{}, err
+				}
+				continue
+			}
+			return sqlToken{kind: tokPunct, text: "/"}, nil
+		case c == '\'' || c == '"':
+			s, err := l.quoted(c)
+			return sqlToken{kind: tokString, text: s}, err
+		case c == '`':
+			s, err := l.quoted(c)
+			return sqlToken{kind: tokIdent, text: s}, err
+		case c == '(' || c == ')' || c == ',' || c == ';':
+			return sqlToken{kind: tokPunct, text: string(c)}, nil
+		default:
+			return l.word(c)
+		}
+	}
+}
+
+// tuple 读取 "(" 之后直到匹配 ")" 的逗号分隔值
+func (l *sqlLexer) tuple() ([]sqlToken, error) {
+	var values []sqlToken
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if t.kind == tokPunct {
+			switch t.text {
+			case ")":
+				return values, nil
+			case ",":
+				continue
+			default:
+				return nil, fmt.Errorf("SQL 解析失败：元组中出现意外的 %q", t.text)
+			}
+		}
+		if t.kind == tokWord && strings.EqualFold(t.text, "NULL") {
+			t.text = ""
+		}
+		values = append(values, t)
+	}
+}
+
+func (l *sqlLexer) word(first rune) (sqlToken, error) {
+	var sb strings.Builder
+	sb.WriteRune(first)
+	for {
+		c, _, err := l.r.ReadRune()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return sqlToken{}, err
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '(' || c == ')' || c == ',' || c == ';' ||
+			c == '\'' || c == '"' || c == '`' {
+			_ = l.r.UnreadRune()
+			break
+		}
+		sb.WriteRune(c)
+	}
+	return sqlToken{kind: tokWord, text: sb.String()}, nil
+}
+
+func (l *sqlLexer) quoted(quote rune) (string, error) {
+	var sb strings.Builder
+	for {
+		c, _, err := l.r.ReadRune()
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+		switch {
+		case c == '\\' && quote != '`':
+			e, _, err := l.r.ReadRune()
+			if err != nil {
+				return "", unexpectedEOF(err)
+			}
+			switch e {
+			case 'n':
+				sb.WriteRune('\n')
+			case 'r':
+				sb.WriteRune('\r')
+			case 't':
+				sb.WriteRune('\t')
+			case '0':
+				sb.WriteRune(0)
+			default:
+				sb.WriteRune(e)
+			}
+		case c == quote:
+			// 连续两个引号表示转义的引号
+			if p, _ := l.r.Peek(1); len(p) == 1 && rune(p[0]) == quote {
+				_, _, _ = l.r.ReadRune()
+				sb.WriteRune(quote)
+				continue
+			}
+			return sb.String(), nil
+		default:
+			sb.WriteRune(c)
+		}
+	}
+}
+
+func (l *sqlLexer) skipLine() {
+	_, _ = l.r.ReadString('\n')
+}
+
+func (l *sqlLexer) skipBlockComment() error {
+	_, _, _ = l.r.ReadRune() // *
+	prev := rune(0)
+	for {
+		c, _, err := l.r.ReadRune()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if prev == '*' && c == '/' {
+			return nil
+		}
+		prev = c
+	}
+}
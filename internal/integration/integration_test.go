@@ -7,6 +7,8 @@ package integration
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,7 +18,9 @@ import (
 	"short-link/internal/repo"
 	"short-link/internal/service"
 	"short-link/models"
+	"short-link/utils"
 
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/modules/redis"
@@ -134,7 +138,7 @@ func TestLinkService_CreateLink(t *testing.T) {
 	}
 
 	// 创建 statsWorker（简化版，仅用于测试）
-	statsWorker := jobs.NewStatsWorker(linkRepo, accessLogRepo, 10, 1*time.Second)
+	statsWorker := jobs.NewStatsWorker(accessLogRepo, 10, 1*time.Second, 1, false, nil, nil, nil, nil, nil)
 
 	// 创建 service
 	linkService := service.NewLinkService(
@@ -147,7 +151,11 @@ func TestLinkService_CreateLink(t *testing.T) {
 		userRepo,
 		accessLogRepo,
 		statsWorker,
-		nil, // meiliWorker
+		nil, // ruleRepo
+		nil, // variantRepo
+		nil, // tagRepo
+		nil, // folderRepo
+		nil, // geo
 	)
 	defer statsWorker.Stop()
 
@@ -228,5 +236,35 @@ func TestRedisIntegration(t *testing.T) {
 	// 这里可以测试 Redis 缓存功能
 	// 由于需要初始化 cache 包，这里仅做示例
 	t.Logf("Redis 端点: %s", endpoint)
+
+	// 并发占用名额：检查与计数在同一 Lua 脚本内完成，并发请求不能越过上限
+	client := goredis.NewClient(&goredis.Options{Addr: endpoint})
+	defer client.Close()
+	limiter := utils.NewSlidingWindowLimiter(client, time.Minute, 5)
+	var wg sync.WaitGroup
+	var allowed int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := limiter.Reserve("link_unlock:1:abc:1.2.3.4"); ok {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 5 {
+		t.Fatalf("并发占用允许 %d 次，期望 5 次", allowed)
+	}
+
+	// 归还的名额可再次占用
+	ok, release := limiter.Reserve("link_unlock:1:xyz:1.2.3.4")
+	if !ok {
+		t.Fatal("新 key 应允许")
+	}
+	release()
+	if n := client.ZCard(ctx, "link_unlock:1:xyz:1.2.3.4").Val(); n != 0 {
+		t.Fatalf("归还后剩余 %d 条记录，期望 0", n)
+	}
 }
 
@@ -0,0 +1,254 @@
+/**
+ * access_logs 分区维护任务
+ * - 提前创建当月及之后 ahead 个月的分区
+ * - 分批把 0016 迁移前的旧表 access_logs_legacy 搬迁到分区表，搬空后删除旧表
+ * - 保留策略：早于保留期的月分区 DETACH 后 DROP；配置了归档目录时先导出为 gzip 压缩的 NDJSON
+ * - 服务启动时执行一次，之后每小时执行；也可由 nsl-admin -action=partitions 手动触发
+ */
+package jobs
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"short-link/internal/metrics"
+	"short-link/internal/repo"
+	"short-link/utils"
+)
+
+const (
+	// partitionInterval 分区维护间隔
+	partitionInterval = time.Hour
+	// partitionLegacyBatch 旧表每批搬迁的行数
+	partitionLegacyBatch = 5000
+)
+
+// PartitionResult 一次分区维护的结果
+type PartitionResult struct {
+	Created  int   // 新建的月分区数
+	Moved    int64 // 从旧表搬迁的行数
+	Archived int   // 导出到归档目录的月分区数
+	Dropped  int   // 删除的月分区数
+}
+
+// AccessLogPartitionJob access_logs 分区维护任务
+type AccessLogPartitionJob struct {
+	partitionRepo   *repo.AccessLogPartitionRepo
+	ahead           int    // 提前创建的月数
+	retentionMonths int    // 保留的完整月数（<= 0 表示不删除）
+	archiveDir      string // 删除前的归档目录（为空表示直接删除）
+	mu              sync.Mutex
+	wg              sync.WaitGroup
+	ctx             context.Context
+	cancel          context.CancelFunc
+}
+
+// NewAccessLogPartitionJob 创建分区维护任务
+func NewAccessLogPartitionJob(partitionRepo *repo.AccessLogPartitionRepo, ahead int, retentionMonths int, archiveDir string) *AccessLogPartitionJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AccessLogPartitionJob{
+		partitionRepo:   partitionRepo,
+		ahead:           ahead,
+		retentionMonths: retentionMonths,
+		archiveDir:      archiveDir,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// Start 启动周期维护（立即执行一次）
+func (j *AccessLogPartitionJob) Start() {
+	j.wg.Add(1)
+	go j.run()
+	utils.LogInfo("access_logs 分区维护任务已启动（提前创建=%d 个月，保留=%d 个月，归档目录=%q）", j.ahead, j.retentionMonths, j.archiveDir)
+}
+
+// run 周期维护主循环
+func (j *AccessLogPartitionJob) run() {
+	defer j.wg.Done()
+
+	ticker := time.NewTicker(partitionInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := j.RunOnce(j.ctx, time.Now()); err != nil && j.ctx.Err() == nil {
+			utils.LogError("access_logs 分区维护失败: %v", err)
+		}
+		select {
+		case <-j.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 执行一次分区维护（同一时间只执行一次）
+func (j *AccessLogPartitionJob) RunOnce(ctx context.Context, now time.Time) (*PartitionResult, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	result := &PartitionResult{}
+	for _, m := range partitionMonthsAhead(now, j.ahead) {
+		created, err := j.partitionRepo.EnsureMonth(ctx, m)
+		if err != nil {
+			return result, err
+		}
+		if created {
+			result.Created++
+			metrics.AccessLogPartitionOpsTotal.WithLabelValues("created").Inc()
+			utils.LogInfo("已创建 access_logs 分区: %s", repo.AccessLogPartitionName(m))
+		}
+	}
+
+	if err := j.migrateLegacy(ctx, result); err != nil {
+		return result, err
+	}
+	if err := j.applyRetention(ctx, now, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// migrateLegacy 分批搬迁旧表（先创建旧记录所在月份的分区，搬空后删除旧表）
+func (j *AccessLogPartitionJob) migrateLegacy(ctx context.Context, result *PartitionResult) error {
+	exists, from, to, err := j.partitionRepo.LegacyRange(ctx)
+	if err != nil || !exists {
+		return err
+	}
+	if from != nil && to != nil {
+		first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+		for m := first; !m.After(*to); m = m.AddDate(0, 1, 0) {
+			created, err := j.partitionRepo.EnsureMonth(ctx, m)
+			if err != nil {
+				return err
+			}
+			if created {
+				result.Created++
+				metrics.AccessLogPartitionOpsTotal.WithLabelValues("created").Inc()
+			}
+		}
+
+		utils.LogInfo("开始搬迁 access_logs_legacy（%s ~ %s）", from.Format("2006-01-02"), to.Format("2006-01-02"))
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			n, err := j.partitionRepo.MoveLegacyBatch(ctx, partitionLegacyBatch)
+			if err != nil {
+				return err
+			}
+			result.Moved += n
+			metrics.AccessLogPartitionOpsTotal.WithLabelValues("legacy_moved").Add(float64(n))
+			if n < partitionLegacyBatch {
+				break
+			}
+		}
+	}
+
+	if err := j.partitionRepo.DropLegacy(ctx); err != nil {
+		return err
+	}
+	utils.LogInfo("access_logs_legacy 已搬迁完成并删除（共 %d 行）", result.Moved)
+	return nil
+}
+
+// applyRetention 删除早于保留期的月分区（配置了归档目录时先导出）
+func (j *AccessLogPartitionJob) applyRetention(ctx context.Context, now time.Time, result *PartitionResult) error {
+	cutoff := retentionCutoff(now, j.retentionMonths)
+	if cutoff.IsZero() {
+		return nil
+	}
+	months, err := j.partitionRepo.ListMonths(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range months {
+		if !m.Before(cutoff) {
+			continue
+		}
+		name := repo.AccessLogPartitionName(m)
+		if j.archiveDir != "" {
+			path, rows, err := j.archive(ctx, m)
+			if err != nil {
+				return fmt.Errorf("归档分区 %s 失败: %w", name, err)
+			}
+			result.Archived++
+			metrics.AccessLogPartitionOpsTotal.WithLabelValues("archived").Inc()
+			utils.LogInfo("已归档 access_logs 分区: %s -> %s（%d 行）", name, path, rows)
+		}
+		if err := j.partitionRepo.DropMonth(ctx, m); err != nil {
+			return fmt.Errorf("删除分区 %s 失败: %w", name, err)
+		}
+		result.Dropped++
+		metrics.AccessLogPartitionOpsTotal.WithLabelValues("dropped").Inc()
+		utils.LogInfo("已删除过期的 access_logs 分区: %s", name)
+	}
+	return nil
+}
+
+// archive 将月分区导出为 <archiveDir>/<分区名>.ndjson.gz（先写临时文件，完成后 rename）
+func (j *AccessLogPartitionJob) archive(ctx context.Context, month time.Time) (string, int64, error) {
+	if err := os.MkdirAll(j.archiveDir, 0o755); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(j.archiveDir, repo.AccessLogPartitionName(month)+".ndjson.gz")
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp) // rename 成功后为空操作
+
+	zw := gzip.NewWriter(f)
+	rows, err := j.partitionRepo.ExportMonth(ctx, month, zw)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", 0, err
+	}
+	return path, rows, nil
+}
+
+// partitionMonthsAhead 需要存在的分区月份：当月及之后 ahead 个月
+func partitionMonthsAhead(now time.Time, ahead int) []time.Time {
+	if ahead < 0 {
+		ahead = 0
+	}
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	months := make([]time.Time, 0, ahead+1)
+	for i := 0; i <= ahead; i++ {
+		months = append(months, first.AddDate(0, i, 0))
+	}
+	return months
+}
+
+// retentionCutoff 保留期起点：当月往前 months 个完整月的 1 日，早于此月份的分区可删除
+// months <= 0 表示永久保留，返回零值
+func retentionCutoff(now time.Time, months int) time.Time {
+	if months <= 0 {
+		return time.Time{}
+	}
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -months, 0)
+}
+
+// Stop 停止周期维护（等待进行中的维护结束）
+func (j *AccessLogPartitionJob) Stop() {
+	j.cancel()
+	j.wg.Wait()
+	utils.LogInfo("access_logs 分区维护任务已停止")
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPartitionMonthsAhead(t *testing.T) {
+	now := time.Date(2026, 11, 30, 23, 0, 0, 0, time.Local)
+	got := partitionMonthsAhead(now, 2)
+	want := []string{"2026-11", "2026-12", "2027-01"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d months, want %d", len(got), len(want))
+	}
+	for i, m := range got {
+		if m.Format("2006-01") != want[i] || m.Day() != 1 {
+			t.Errorf("month %d: got %v, want %s-01", i, m, want[i])
+		}
+	}
+}
+
+func TestRetentionCutoff(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	if c := retentionCutoff(now, 0); !c.IsZero() {
+		t.Errorf("retention 0: got %v, want zero", c)
+	}
+	c := retentionCutoff(now, 3)
+	if want := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC); !c.Equal(want) {
+		t.Errorf("retention 3: got %v, want %v", c, want)
+	}
+	// 2025-11 分区早于保留期，2025-12 分区保留
+	if !time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC).Before(c) {
+		t.Error("2025-11 should be dropped")
+	}
+	if time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC).Before(c) {
+		t.Error("2025-12 should be kept")
+	}
+}
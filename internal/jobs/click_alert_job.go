@@ -0,0 +1,335 @@
+/**
+ * 点击异常告警任务
+ * - 每个完整小时结束（留出 alertSettleDelay 等待统计批量写入）后评估一次：窗口为上一个整点小时（本地时区，与 click_rollups_hourly.bucket 对齐）
+ * - 任务暂停或落后时从上次评估的窗口起逐小时补评估，最多补 alertMaxCatchUpWindows 个窗口，更早的窗口跳过并记录日志
+ * - 基线为此前 baselineHours 小时（链接创建较晚时按实际存在的小时数）的平均每小时点击（click_rollups_hourly）
+ * - spike：窗口点击 >= max(spike_min_clicks, spike_factor * 基线)
+ * - drop：基线 >= drop_min_baseline 且窗口点击为 0（基线不足 24 小时或整个窗口全站无点击时不判定，后者多为统计写入中断）
+ * - bot_traffic：窗口内爬虫访问 >= spike_min_clicks 且占比 >= bot_ratio（access_logs.is_bot）
+ * - 阈值按 链接覆盖 -> 用户默认 -> 全局配置 逐字段继承
+ * - 告警写入 click_alerts（GET /api/v2/alerts），同一事务内为订阅 link.alert 的 webhook 写入投递；
+ *   开启 notify_email 的用户按次汇总发送一封邮件（未配置 SMTP 时跳过）
+ * - 多副本同时评估时由 (link_id, kind, window_start) 唯一约束去重，只有实际写入的告警会发送邮件
+ */
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"short-link/internal/metrics"
+	"short-link/internal/notify"
+	"short-link/internal/repo"
+	"short-link/models"
+	"short-link/utils"
+)
+
+const (
+	// alertSettleDelay 整点后等待统计批量写入的时间
+	alertSettleDelay = 5 * time.Minute
+	// alertMinDropBaselineHours 判定 drop 所需的最短基线小时数
+	alertMinDropBaselineHours = 24
+	// alertRetention 告警记录保留时间
+	alertRetention = 90 * 24 * time.Hour
+	// alertMaxCatchUpWindows 单次最多补评估的小时窗口数
+	alertMaxCatchUpWindows = 24
+)
+
+// ClickAlertJob 点击异常告警任务
+type ClickAlertJob struct {
+	alertRepo     *repo.AlertRepo
+	userRepo      *repo.UserRepo
+	mailer        *notify.Mailer   // 未配置 SMTP 时为 nil
+	defaults      models.AlertRule // 全局默认阈值
+	interval      time.Duration    // 检查间隔（<= 0 表示不启动）
+	baselineHours int
+	lastWindow    time.Time // 最近一次评估完成的窗口起点
+	mu            sync.Mutex
+	wg            sync.WaitGroup
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+// NewClickAlertJob 创建点击异常告警任务
+func NewClickAlertJob(alertRepo *repo.AlertRepo, userRepo *repo.UserRepo, mailer *notify.Mailer, defaults models.AlertRule, interval time.Duration, baselineHours int) *ClickAlertJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ClickAlertJob{
+		alertRepo:     alertRepo,
+		userRepo:      userRepo,
+		mailer:        mailer,
+		defaults:      defaults,
+		interval:      interval,
+		baselineHours: baselineHours,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Start 启动周期检查（interval <= 0 时不启动）
+func (j *ClickAlertJob) Start() {
+	if j.interval <= 0 {
+		return
+	}
+	j.wg.Add(1)
+	go j.run()
+	utils.LogInfo("点击异常告警任务已启动（间隔=%v，基线=%d 小时，邮件=%v）", j.interval, j.baselineHours, j.mailer != nil)
+}
+
+// Stop 停止任务
+func (j *ClickAlertJob) Stop() {
+	j.cancel()
+	j.wg.Wait()
+	utils.LogInfo("点击异常告警任务已停止")
+}
+
+// run 周期检查主循环（每个窗口只评估一次）
+func (j *ClickAlertJob) run() {
+	defer j.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := j.RunOnce(j.ctx, time.Now()); err != nil && j.ctx.Err() == nil {
+			utils.LogError("点击异常检测失败: %v", err)
+		}
+		select {
+		case <-j.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 评估上次评估之后到 now 为止的全部完整小时（首次运行只评估最近一个），返回新写入的告警
+func (j *ClickAlertJob) RunOnce(ctx context.Context, now time.Time) ([]models.ClickAlert, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	windows, skipped := alertWindows(now, j.lastWindow, alertMaxCatchUpWindows)
+	if skipped > 0 {
+		utils.LogWarn("点击异常检测落后 %d 个小时窗口，跳过 %s 至 %s，从 %s 开始补评估",
+			skipped, j.lastWindow.Add(time.Hour).Format("2006-01-02 15:04"),
+			windows[0].Add(-time.Hour).Format("2006-01-02 15:04"), windows[0].Format("2006-01-02 15:04"))
+	}
+
+	var all []models.ClickAlert
+	for _, windowStart := range windows {
+		inserted, err := j.evaluateWindow(ctx, windowStart)
+		if err != nil {
+			return all, err
+		}
+		j.lastWindow = windowStart
+		all = append(all, inserted...)
+	}
+	if len(windows) == 0 {
+		return nil, nil
+	}
+
+	if n, err := j.alertRepo.PurgeAlerts(ctx, alertRetention); err != nil {
+		utils.LogWarn("清理点击告警失败: %v", err)
+	} else if n > 0 {
+		utils.LogInfo("已清理 %d 条点击告警", n)
+	}
+	return all, nil
+}
+
+// alertWindows 待评估的窗口起点（按时间升序）与因超过 maxWindows 而跳过的窗口数
+// 窗口为本地时区的整点小时（半小时时区同样对齐整点）；lastWindow 为零值时只返回最近一个窗口
+func alertWindows(now, lastWindow time.Time, maxWindows int) ([]time.Time, int) {
+	t := now.Add(-alertSettleDelay)
+	latest := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(-time.Hour)
+	if lastWindow.IsZero() {
+		return []time.Time{latest}, 0
+	}
+	first := lastWindow.Add(time.Hour)
+	if first.After(latest) {
+		return nil, 0
+	}
+	skipped := 0
+	if n := int(latest.Sub(first)/time.Hour) + 1; n > maxWindows {
+		skipped = n - maxWindows
+		first = latest.Add(-time.Duration(maxWindows-1) * time.Hour)
+	}
+	var windows []time.Time
+	for w := first; !w.After(latest); w = w.Add(time.Hour) {
+		windows = append(windows, w)
+	}
+	return windows, skipped
+}
+
+// evaluateWindow 评估单个小时窗口，写入告警并发送邮件，返回新写入的告警
+func (j *ClickAlertJob) evaluateWindow(ctx context.Context, windowStart time.Time) ([]models.ClickAlert, error) {
+	windowEnd := windowStart.Add(time.Hour)
+	baselineStart := windowStart.Add(-time.Duration(j.baselineHours) * time.Hour)
+
+	stats, err := j.alertRepo.WindowStats(ctx, windowStart, windowEnd, baselineStart)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := j.loadRules(ctx, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, s := range stats {
+		total += s.Clicks
+	}
+	var alerts []models.ClickAlert
+	for _, s := range stats {
+		alerts = append(alerts, evaluateLinkWindow(s, rules[s.LinkID], windowStart, windowEnd, baselineStart, total > 0)...)
+	}
+
+	inserted, err := j.alertRepo.InsertAlerts(ctx, alerts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range inserted {
+		metrics.ClickAlertsTotal.WithLabelValues(a.Kind).Inc()
+	}
+	if len(inserted) > 0 {
+		utils.LogInfo("点击异常检测：窗口 %s 产生 %d 条告警", windowStart.Format("2006-01-02 15:04"), len(inserted))
+	}
+	j.sendEmails(ctx, inserted, rules)
+	return inserted, nil
+}
+
+// loadRules 按链接合并生效阈值
+func (j *ClickAlertJob) loadRules(ctx context.Context, stats []repo.LinkWindowStat) (map[int64]models.AlertRule, error) {
+	rules := make(map[int64]models.AlertRule, len(stats))
+	if len(stats) == 0 {
+		return rules, nil
+	}
+
+	seen := make(map[int64]bool)
+	var userIDs []int64
+	for _, s := range stats {
+		if !seen[s.UserID] {
+			seen[s.UserID] = true
+			userIDs = append(userIDs, s.UserID)
+		}
+	}
+	thresholds, err := j.alertRepo.ListThresholds(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	userDefaults := make(map[int64]*models.AlertThreshold)
+	linkOverrides := make(map[int64]*models.AlertThreshold)
+	for i := range thresholds {
+		t := &thresholds[i]
+		if t.LinkID == nil {
+			userDefaults[t.UserID] = t
+		} else {
+			linkOverrides[*t.LinkID] = t
+		}
+	}
+	for _, s := range stats {
+		rules[s.LinkID] = j.defaults.Apply(userDefaults[s.UserID], linkOverrides[s.LinkID])
+	}
+	return rules, nil
+}
+
+// sendEmails 按用户汇总发送告警邮件（best-effort，失败只记录日志）
+func (j *ClickAlertJob) sendEmails(ctx context.Context, alerts []models.ClickAlert, rules map[int64]models.AlertRule) {
+	if j.mailer == nil {
+		return
+	}
+	byUser := make(map[int64][]models.ClickAlert)
+	var userIDs []int64
+	for _, a := range alerts {
+		if !rules[a.LinkID].NotifyEmail {
+			continue
+		}
+		if _, ok := byUser[a.UserID]; !ok {
+			userIDs = append(userIDs, a.UserID)
+		}
+		byUser[a.UserID] = append(byUser[a.UserID], a)
+	}
+
+	for _, userID := range userIDs {
+		u, err := j.userRepo.GetUserByID(ctx, userID)
+		if err != nil || u.Email == "" {
+			metrics.AlertEmailsTotal.WithLabelValues("skipped").Inc()
+			continue
+		}
+		subject, body := alertEmail(byUser[userID])
+		if err := j.mailer.Send([]string{u.Email}, subject, body); err != nil {
+			metrics.AlertEmailsTotal.WithLabelValues("failed").Inc()
+			utils.LogWarn("发送告警邮件失败: user_id=%d, error=%v", userID, err)
+			continue
+		}
+		metrics.AlertEmailsTotal.WithLabelValues("sent").Inc()
+	}
+}
+
+// alertEmail 生成告警邮件主题与正文
+func alertEmail(alerts []models.ClickAlert) (string, string) {
+	subject := fmt.Sprintf("[短链接] %d 条点击异常告警", len(alerts))
+	var b strings.Builder
+	for _, a := range alerts {
+		b.WriteString("- " + a.Message + "\n")
+	}
+	b.WriteString("\n完整记录见 GET /api/v2/alerts；阈值可在 PUT /api/v2/alerts/settings 调整。\n")
+	return subject, b.String()
+}
+
+// evaluateLinkWindow 评估单个链接的窗口统计，返回触发的告警（未写入，ID 为 0）
+func evaluateLinkWindow(s repo.LinkWindowStat, rule models.AlertRule, windowStart, windowEnd, baselineStart time.Time, checkDrops bool) []models.ClickAlert {
+	if !rule.Enabled {
+		return nil
+	}
+
+	// 基线小时数：链接创建晚于基线起点时按实际存在的小时数计算
+	from := baselineStart
+	if s.CreatedAt.After(from) {
+		from = s.CreatedAt
+	}
+	hours := math.Ceil(windowStart.Sub(from).Hours())
+	if hours < 1 {
+		hours = 1
+	}
+	baseline := float64(s.BaselineClicks) / hours
+	at := windowStart.Format("2006-01-02 15:04")
+
+	newAlert := func(kind string, observed int64, threshold float64, message string) models.ClickAlert {
+		return models.ClickAlert{
+			UserID:      s.UserID,
+			LinkID:      s.LinkID,
+			Code:        s.Code,
+			Kind:        kind,
+			WindowStart: windowStart,
+			WindowEnd:   windowEnd,
+			Observed:    observed,
+			Baseline:    baseline,
+			Threshold:   threshold,
+			Message:     message,
+		}
+	}
+
+	var alerts []models.ClickAlert
+	if rule.SpikeFactor > 0 {
+		threshold := math.Max(float64(rule.SpikeMinClicks), rule.SpikeFactor*math.Max(baseline, 1))
+		if s.Clicks > 0 && float64(s.Clicks) >= threshold {
+			alerts = append(alerts, newAlert(models.AlertKindSpike, s.Clicks, threshold,
+				fmt.Sprintf("链接 %s 在 %s 起的一小时内点击 %d 次，基线 %.1f 次/小时（阈值 %.0f）", s.Code, at, s.Clicks, baseline, threshold)))
+		}
+	}
+	if checkDrops && rule.DropMinBaseline > 0 && hours >= alertMinDropBaselineHours && s.Clicks == 0 && baseline >= rule.DropMinBaseline {
+		alerts = append(alerts, newAlert(models.AlertKindDrop, 0, rule.DropMinBaseline,
+			fmt.Sprintf("链接 %s 在 %s 起的一小时内没有点击，基线 %.1f 次/小时", s.Code, at, baseline)))
+	}
+	if rule.BotRatio > 0 && s.TotalLogs > 0 && s.BotClicks >= rule.SpikeMinClicks && s.BotClicks > 0 {
+		ratio := float64(s.BotClicks) / float64(s.TotalLogs)
+		if ratio >= rule.BotRatio {
+			alerts = append(alerts, newAlert(models.AlertKindBotTraffic, s.BotClicks, rule.BotRatio,
+				fmt.Sprintf("链接 %s 在 %s 起的一小时内爬虫访问 %d 次，占全部访问的 %.0f%%", s.Code, at, s.BotClicks, ratio*100)))
+		}
+	}
+	return alerts
+}
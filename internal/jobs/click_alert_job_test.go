@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/models"
+)
+
+var testAlertDefaults = models.AlertRule{
+	Enabled:         true,
+	SpikeFactor:     5,
+	SpikeMinClicks:  100,
+	DropMinBaseline: 20,
+	BotRatio:        0.5,
+}
+
+func TestAlertRuleApply(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	n := func(v int64) *int64 { return &v }
+	b := func(v bool) *bool { return &v }
+
+	user := &models.AlertThreshold{SpikeFactor: f(3), SpikeMinClicks: n(50), NotifyEmail: b(true)}
+	link := &models.AlertThreshold{SpikeMinClicks: n(10), BotRatio: f(0), NotifyEmail: b(false)}
+
+	got := testAlertDefaults.Apply(user, link)
+	want := models.AlertRule{Enabled: true, SpikeFactor: 3, SpikeMinClicks: 10, DropMinBaseline: 20, BotRatio: 0, NotifyEmail: true}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got := testAlertDefaults.Apply(nil, nil); got != testAlertDefaults {
+		t.Errorf("no overrides: got %+v", got)
+	}
+	if got := testAlertDefaults.Apply(&models.AlertThreshold{Enabled: b(false)}, &models.AlertThreshold{Enabled: b(true)}); !got.Enabled {
+		t.Error("link override should re-enable alerts")
+	}
+}
+
+func TestEvaluateLinkWindow(t *testing.T) {
+	windowStart := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	windowEnd := windowStart.Add(time.Hour)
+	baselineStart := windowStart.Add(-168 * time.Hour)
+	old := baselineStart.Add(-time.Hour)
+
+	kinds := func(alerts []models.ClickAlert) string {
+		var out []string
+		for _, a := range alerts {
+			out = append(out, a.Kind)
+		}
+		return strings.Join(out, ",")
+	}
+
+	cases := []struct {
+		name       string
+		stat       repo.LinkWindowStat
+		rule       models.AlertRule
+		checkDrops bool
+		want       string
+	}{
+		{"quiet", repo.LinkWindowStat{CreatedAt: old, Clicks: 12, BaselineClicks: 168 * 10}, testAlertDefaults, true, ""},
+		{"spike over baseline", repo.LinkWindowStat{CreatedAt: old, Clicks: 500, BaselineClicks: 168 * 10}, testAlertDefaults, true, "spike"},
+		{"spike below min clicks", repo.LinkWindowStat{CreatedAt: old, Clicks: 60, BaselineClicks: 168}, testAlertDefaults, true, ""},
+		{"new link counts only its own hours", repo.LinkWindowStat{CreatedAt: windowStart.Add(-2 * time.Hour), Clicks: 150, BaselineClicks: 100}, testAlertDefaults, true, ""},
+		{"drop to zero", repo.LinkWindowStat{CreatedAt: old, Clicks: 0, BaselineClicks: 168 * 30}, testAlertDefaults, true, "drop"},
+		{"drop skipped when site has no clicks", repo.LinkWindowStat{CreatedAt: old, Clicks: 0, BaselineClicks: 168 * 30}, testAlertDefaults, false, ""},
+		{"drop needs a day of baseline", repo.LinkWindowStat{CreatedAt: windowStart.Add(-3 * time.Hour), Clicks: 0, BaselineClicks: 300}, testAlertDefaults, true, ""},
+		{"bot traffic", repo.LinkWindowStat{CreatedAt: old, Clicks: 10, BaselineClicks: 168 * 10, BotClicks: 400, TotalLogs: 410}, testAlertDefaults, true, "bot_traffic"},
+		{"bot ratio disabled", repo.LinkWindowStat{CreatedAt: old, Clicks: 10, BaselineClicks: 168 * 10, BotClicks: 400, TotalLogs: 410},
+			models.AlertRule{Enabled: true, SpikeFactor: 5, SpikeMinClicks: 100}, true, ""},
+		{"disabled", repo.LinkWindowStat{CreatedAt: old, Clicks: 5000, BaselineClicks: 168}, models.AlertRule{SpikeFactor: 5}, true, ""},
+	}
+	for _, tc := range cases {
+		got := evaluateLinkWindow(tc.stat, tc.rule, windowStart, windowEnd, baselineStart, tc.checkDrops)
+		if k := kinds(got); k != tc.want {
+			t.Errorf("%s: got [%s], want [%s]", tc.name, k, tc.want)
+		}
+	}
+
+	alerts := evaluateLinkWindow(repo.LinkWindowStat{LinkID: 7, UserID: 3, Code: "abc123", CreatedAt: old, Clicks: 500, BaselineClicks: 168 * 10},
+		testAlertDefaults, windowStart, windowEnd, baselineStart, true)
+	a := alerts[0]
+	if a.LinkID != 7 || a.UserID != 3 || a.Observed != 500 || a.Baseline != 10 || a.Threshold != 100 || !a.WindowStart.Equal(windowStart) {
+		t.Errorf("unexpected alert: %+v", a)
+	}
+	if !strings.Contains(a.Message, "abc123") {
+		t.Errorf("message should name the link: %q", a.Message)
+	}
+}
+
+func TestAlertWindows(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800) // UTC+5:30
+	at := func(h, m int) time.Time { return time.Date(2026, 10, 17, h, m, 0, 0, ist) }
+	format := func(ws []time.Time) string {
+		parts := make([]string, len(ws))
+		for i, w := range ws {
+			parts[i] = w.Format("15:04")
+		}
+		return strings.Join(parts, ",")
+	}
+
+	cases := []struct {
+		name        string
+		now         time.Time
+		last        time.Time
+		max         int
+		want        string
+		wantSkipped int
+	}{
+		{"first run evaluates latest local hour", at(10, 40), time.Time{}, 24, "09:00", 0},
+		{"inside settle delay", at(10, 3), time.Time{}, 24, "08:00", 0},
+		{"already evaluated", at(10, 40), at(9, 0), 24, "", 0},
+		{"next hour", at(11, 10), at(9, 0), 24, "10:00", 0},
+		{"catch up missed hours", at(13, 10), at(9, 0), 24, "10:00,11:00,12:00", 0},
+		{"cap skips oldest hours", at(15, 10), at(9, 0), 3, "12:00,13:00,14:00", 2},
+	}
+	for _, c := range cases {
+		got, skipped := alertWindows(c.now, c.last, c.max)
+		if format(got) != c.want || skipped != c.wantSkipped {
+			t.Errorf("%s: got [%s] skipped %d, want [%s] skipped %d", c.name, format(got), skipped, c.want, c.wantSkipped)
+		}
+		for _, w := range got {
+			if w.Minute() != 0 || w.Location() != ist {
+				t.Errorf("%s: window %v is not a local whole hour", c.name, w)
+			}
+		}
+	}
+}
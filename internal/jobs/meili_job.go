@@ -1,173 +1,192 @@
 /**
- * Meilisearch 写入任务队列与 Worker（异步写入 + 失败重试）
+ * Meilisearch 索引写入（outbox 消费方）
  * 实现 redo.md 2.6：Meilisearch 写入失败补偿/重试/后台任务
+ * - 链接变更与 outbox 事件在同一事务内写入，由 OutboxDispatcher 领取后批量交给 MeiliWorker
+ * - 按事件中的链接 ID 读取最新状态：存在则写入文档（含标签），不存在则删除文档；重复投递幂等
+ * - 重试、退避与死信由 OutboxDispatcher 负责，进程重启或多副本部署不会丢失索引更新
+ * - 启动时不要求 Meilisearch 可用：不可用期间投递失败并退避重试，恢复后自动继续
  */
 package jobs
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"sync"
-	"time"
+	"sort"
+	"strconv"
 
 	"short-link/internal/config"
+	"short-link/internal/metrics"
+	"short-link/internal/repo"
 	"short-link/models"
-	"short-link/utils"
 
 	"github.com/meilisearch/meilisearch-go"
 )
 
-// MeiliTask Meilisearch 写入任务
-type MeiliTask struct {
-	Action string      `json:"action"` // "index", "delete"
-	Link   *models.Link `json:"link,omitempty"`
-	LinkID int64       `json:"link_id,omitempty"`
-}
-
-// MeiliWorker Meilisearch 写入 Worker
+// MeiliWorker Meilisearch 索引写入
 type MeiliWorker struct {
-	taskChan    chan *MeiliTask
-	client      *meilisearch.Client
-	index       *meilisearch.Index
-	maxRetries  int
-	retryDelay  time.Duration
-	wg          sync.WaitGroup
-	ctx         context.Context
-	cancel      context.CancelFunc
+	client   *meilisearch.Client
+	index    *meilisearch.Index
+	linkRepo *repo.LinkRepo
+	tagRepo  *repo.TagRepo // 可为 nil（不写入标签）
 }
 
-// NewMeiliWorker 创建 Meilisearch Worker
-func NewMeiliWorker(cfg *config.Config, maxRetries int, retryDelay time.Duration) (*MeiliWorker, error) {
+// NewMeiliWorker 创建 Meilisearch Worker（不检查连接，Meilisearch 晚于服务启动时同样可用）
+func NewMeiliWorker(cfg *config.Config, linkRepo *repo.LinkRepo, tagRepo *repo.TagRepo) *MeiliWorker {
 	client := meilisearch.NewClient(meilisearch.ClientConfig{
 		Host:   cfg.MeiliHost,
 		APIKey: cfg.MeiliKey,
 	})
-
-	// 测试连接
-	if _, err := client.Health(); err != nil {
-		return nil, fmt.Errorf("Meilisearch连接失败: %w", err)
-	}
-
-	index := client.Index("links")
-	ctx, cancel := context.WithCancel(context.Background())
-
 	return &MeiliWorker{
-		taskChan:   make(chan *MeiliTask, 1000), // 缓冲1000个任务
-		client:     client,
-		index:      index,
-		maxRetries: maxRetries,
-		retryDelay: retryDelay,
-		ctx:        ctx,
-		cancel:     cancel,
-	}, nil
-}
-
-// Submit 提交 Meilisearch 写入任务（非阻塞）
-func (w *MeiliWorker) Submit(action string, link *models.Link, linkID int64) {
-	task := &MeiliTask{
-		Action: action,
-		Link:   link,
-		LinkID: linkID,
-	}
-
-	select {
-	case w.taskChan <- task:
-		// 成功提交
-	default:
-		// 队列满，静默丢弃（避免阻塞主流程）
-		utils.LogWarn("Meilisearch任务队列已满，丢弃任务: action=%s, link_id=%d", action, linkID)
+		client:   client,
+		index:    client.Index("links"),
+		linkRepo: linkRepo,
+		tagRepo:  tagRepo,
 	}
 }
 
-// Start 启动 Worker（后台 goroutine）
-func (w *MeiliWorker) Start() {
-	w.wg.Add(1)
-	go w.run()
-	utils.LogInfo("Meilisearch Worker 已启动（最大重试次数=%d，重试间隔=%v）", w.maxRetries, w.retryDelay)
+// Healthy 检查 Meilisearch 是否可用
+func (w *MeiliWorker) Healthy() error {
+	if _, err := w.client.Health(); err != nil {
+		return fmt.Errorf("Meilisearch连接失败: %w", err)
+	}
+	return nil
 }
 
-// run Worker 主循环
-func (w *MeiliWorker) run() {
-	defer w.wg.Done()
-
-	for {
-		select {
-		case <-w.ctx.Done():
-			return
-
-		case task := <-w.taskChan:
-			w.processTask(task)
+// HandleOutboxEvents 处理一批 outbox 事件（实现 OutboxHandler）
+// Meilisearch 不可用时直接返回错误（不读库），由 OutboxDispatcher 退避重试
+// 同一链接的多个事件合并为一次读取；单次 AddDocuments 写入存在的链接，单次 DeleteDocuments 删除已不存在的链接
+func (w *MeiliWorker) HandleOutboxEvents(ctx context.Context, events []models.OutboxEvent) error {
+	if err := w.Healthy(); err != nil {
+		return err
+	}
+	seen := make(map[int64]bool, len(events))
+	ids := make([]int64, 0, len(events))
+	for _, e := range events {
+		if !seen[e.AggregateID] {
+			seen[e.AggregateID] = true
+			ids = append(ids, e.AggregateID)
 		}
 	}
-}
+	if len(ids) == 0 {
+		return nil
+	}
 
-// processTask 处理单个任务（带重试）
-func (w *MeiliWorker) processTask(task *MeiliTask) {
-	var err error
-	for attempt := 0; attempt < w.maxRetries; attempt++ {
-		if attempt > 0 {
-			// 重试前等待
-			select {
-			case <-w.ctx.Done():
-				return
-			case <-time.After(w.retryDelay * time.Duration(attempt)):
-			}
-		}
+	rows, err := w.linkRepo.GetLinksByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	links := make([]*models.Link, len(rows))
+	for i := range rows {
+		links[i] = &rows[i]
+		delete(seen, rows[i].ID)
+	}
+	if err := w.indexLinks(ctx, links); err != nil {
+		return err
+	}
 
-		switch task.Action {
-		case "index":
-			err = w.indexLink(task.Link)
-		case "delete":
-			err = w.deleteLink(task.LinkID)
-		default:
-			utils.LogError("未知的 Meilisearch 任务类型: %s", task.Action)
-			return
+	// 剩余 ID 对应的链接已删除
+	if len(seen) > 0 {
+		deleted := make([]string, 0, len(seen))
+		for id := range seen {
+			deleted = append(deleted, strconv.FormatInt(id, 10))
 		}
-
-		if err == nil {
-			// 成功
-			return
+		if _, err := w.index.DeleteDocuments(deleted); err != nil {
+			metrics.MeilisearchWritesTotal.WithLabelValues("failure").Inc()
+			return fmt.Errorf("删除索引文档失败: %w", err)
 		}
-
-		utils.LogWarn("Meilisearch写入失败（尝试 %d/%d）: action=%s, link_id=%d, error=%v",
-			attempt+1, w.maxRetries, task.Action, task.LinkID, err)
+		metrics.MeilisearchWritesTotal.WithLabelValues("success").Inc()
 	}
-
-	// 所有重试都失败，记录错误（后续可扩展为死信队列）
-	utils.LogError("Meilisearch写入最终失败: action=%s, link_id=%d, error=%v", task.Action, task.LinkID, err)
+	return nil
 }
 
-// indexLink 索引链接到 Meilisearch
-func (w *MeiliWorker) indexLink(link *models.Link) error {
-	if link == nil {
-		return fmt.Errorf("link 不能为空")
+// indexLinks 加载标签后批量写入索引（单次 AddDocuments）
+func (w *MeiliWorker) indexLinks(ctx context.Context, links []*models.Link) error {
+	if len(links) == 0 {
+		return nil
 	}
-
-	doc := map[string]interface{}{
-		"id":           link.ID,
-		"code":         link.Code,
-		"original_url": link.OriginalURL,
-		"title":        link.Title,
-		"user_id":      link.UserID,
-		"domain_id":    link.DomainID,
-		"created_at":   link.CreatedAt.Unix(),
+	if err := w.loadLinkTags(ctx, links); err != nil {
+		return err
 	}
-
-	_, err := w.index.AddDocuments([]map[string]interface{}{doc}, "id")
-	return err
+	return w.writeLinkDocuments(links)
 }
 
-// deleteLink 从 Meilisearch 删除链接
-func (w *MeiliWorker) deleteLink(linkID int64) error {
-	_, err := w.index.DeleteDocument(fmt.Sprintf("%d", linkID))
-	return err
+// loadLinkTags 批量加载链接标签（tagRepo 为 nil 时不加载）
+func (w *MeiliWorker) loadLinkTags(ctx context.Context, links []*models.Link) error {
+	if w.tagRepo == nil || len(links) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(links))
+	for i, l := range links {
+		ids[i] = l.ID
+	}
+	tags, err := w.tagRepo.GetLinkTags(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		l.Tags = tags[l.ID]
+	}
+	return nil
 }
 
-// Stop 停止 Worker
-func (w *MeiliWorker) Stop() {
-	w.cancel()
-	w.wg.Wait()
-	utils.LogInfo("Meilisearch Worker 已停止")
+// writeLinkDocuments 批量写入索引文档（标签需已加载）
+func (w *MeiliWorker) writeLinkDocuments(links []*models.Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+	docs := make([]map[string]interface{}, len(links))
+	for i, l := range links {
+		docs[i] = linkDocument(l)
+	}
+	if _, err := w.index.AddDocuments(docs, "id"); err != nil {
+		metrics.MeilisearchWritesTotal.WithLabelValues("failure").Inc()
+		return fmt.Errorf("写入索引文档失败: %w", err)
+	}
+	metrics.MeilisearchWritesTotal.WithLabelValues("success").Inc()
+	return nil
 }
 
+// linkDocument 构建链接的索引文档（tags 需预先加载，否则会覆盖为空）
+// 可过滤/排序字段的配置见 service.SearchService（启动时设置）
+func linkDocument(link *models.Link) map[string]interface{} {
+	tags := link.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return map[string]interface{}{
+		"id":            link.ID,
+		"code":          link.Code,
+		"original_url":  link.OriginalURL,
+		"title":         link.Title,
+		"user_id":       link.UserID,
+		"domain_id":     link.DomainID,
+		"folder_id":     link.FolderID,
+		"tags":          tags,
+		"click_count":   link.ClickCount, // 索引写入时的快照，非实时
+		"unique_clicks": link.UniqueClicks,
+		"created_at":    link.CreatedAt.Unix(),
+		"updated_at":    link.UpdatedAt.Unix(),
+		"content_hash":  linkContentHash(link), // 对账时与数据库计算值比较
+	}
+}
 
+// linkContentHash 索引内容字段的摘要（不含点击数与 updated_at：点击批次会刷新二者，不属于内容漂移）
+func linkContentHash(link *models.Link) string {
+	tags := append([]string(nil), link.Tags...)
+	sort.Strings(tags)
+	b, _ := json.Marshal(struct {
+		Code        string   `json:"code"`
+		OriginalURL string   `json:"original_url"`
+		Title       string   `json:"title"`
+		UserID      int64    `json:"user_id"`
+		DomainID    int64    `json:"domain_id"`
+		FolderID    *int64   `json:"folder_id"`
+		Tags        []string `json:"tags"`
+		CreatedAt   int64    `json:"created_at"`
+	}{link.Code, link.OriginalURL, link.Title, link.UserID, link.DomainID, link.FolderID, tags, link.CreatedAt.Unix()})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:16])
+}
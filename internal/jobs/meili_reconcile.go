@@ -0,0 +1,294 @@
+/**
+ * Meilisearch 对账任务（索引与 Postgres 漂移修复）
+ * - outbox 事件进入死信、Meilisearch 数据丢失或手工改库时，索引会偏离 links 表
+ * - 对账：分页读取索引文档（id + content_hash + 点击数），再按 ID 键集分页遍历 links 表
+ *   缺失或内容摘要不一致（含标签、文件夹）的文档重新索引，索引中多出的文档（孤立）删除
+ * - 点击批次会刷新 links.updated_at 与点击数，仅点击数变化的文档单独计为 clicks 刷新，不计入漂移
+ * - full 模式：全部链接重新索引
+ * - 可作为周期后台任务运行，也可由 nsl-admin -action=reindex 手动触发
+ */
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"short-link/internal/metrics"
+	"short-link/internal/repo"
+	"short-link/models"
+	"short-link/utils"
+
+	"github.com/meilisearch/meilisearch-go"
+)
+
+const (
+	// reconcilePageSize 对账时每页读取的索引文档 / 链接数
+	reconcilePageSize = 1000
+	// reconcileRunTimeout 周期对账单次执行的超时
+	reconcileRunTimeout = 30 * time.Minute
+)
+
+// 对账修复原因（Prometheus reason 标签）
+const (
+	reconcileMissing = "missing"
+	reconcileStale   = "stale"
+	reconcileOrphan  = "orphan"
+	reconcileFull    = "full"
+	reconcileClicks  = "clicks" // 仅点击数快照刷新，不属于漂移
+)
+
+// indexedDoc 索引中已有文档的对账字段
+type indexedDoc struct {
+	hash         string
+	clicks       int64
+	uniqueClicks int64
+}
+
+// ReconcileResult 对账结果
+type ReconcileResult struct {
+	Scanned  int64 // 遍历的链接数
+	Indexed  int64 // 索引中的文档数（对账开始时）
+	Missing  int64 // 索引缺失、已补写的文档数
+	Stale    int64 // 内容摘要不一致、已重写的文档数
+	Orphaned int64 // 链接已不存在、已删除的文档数
+	Full     int64 // full 模式下重写的文档数
+	Clicks   int64 // 仅点击数变化、已刷新的文档数（不计入 Repaired）
+}
+
+// Repaired 修复的漂移文档总数（不含点击数刷新）
+func (r *ReconcileResult) Repaired() int64 {
+	return r.Missing + r.Stale + r.Orphaned + r.Full
+}
+
+// MeiliReconciler Meilisearch 对账任务
+type MeiliReconciler struct {
+	worker   *MeiliWorker
+	linkRepo *repo.LinkRepo
+	interval time.Duration // <= 0 表示不启用周期对账
+	mu       sync.Mutex    // 同一时间只允许一次对账
+	wg       sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewMeiliReconciler 创建对账任务（复用 MeiliWorker 的索引连接与文档构建）
+func NewMeiliReconciler(worker *MeiliWorker, linkRepo *repo.LinkRepo, interval time.Duration) *MeiliReconciler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &MeiliReconciler{
+		worker:   worker,
+		linkRepo: linkRepo,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start 启动周期对账（interval <= 0 时不启动）
+func (r *MeiliReconciler) Start() {
+	if r.interval <= 0 {
+		return
+	}
+	r.wg.Add(1)
+	go r.run()
+	utils.LogInfo("Meilisearch 对账任务已启动（间隔=%v）", r.interval)
+}
+
+// run 周期对账主循环（启动后先等待一个周期，避免与启动期写入竞争）
+func (r *MeiliReconciler) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(r.ctx, reconcileRunTimeout)
+			result, err := r.Reconcile(ctx, false)
+			cancel()
+			if err != nil {
+				utils.LogError("Meilisearch 对账失败: %v", err)
+				continue
+			}
+			if result.Repaired() > 0 {
+				utils.LogWarn("Meilisearch 对账修复: missing=%d, stale=%d, orphan=%d",
+					result.Missing, result.Stale, result.Orphaned)
+			}
+			if result.Clicks > 0 {
+				utils.LogInfo("Meilisearch 对账刷新点击数: %d 个文档", result.Clicks)
+			}
+		}
+	}
+}
+
+// Stop 停止周期对账（等待进行中的对账结束）
+func (r *MeiliReconciler) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// Reconcile 执行一次对账；full 为 true 时全部链接重新索引
+func (r *MeiliReconciler) Reconcile(ctx context.Context, full bool) (result *ReconcileResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "failure"
+		}
+		metrics.MeilisearchReconcileRunsTotal.WithLabelValues(status).Inc()
+	}()
+
+	indexed, err := r.loadIndexedDocs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result = &ReconcileResult{Indexed: int64(len(indexed))}
+
+	var afterID int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		links, err := r.linkRepo.ListLinksAfter(ctx, afterID, reconcilePageSize)
+		if err != nil {
+			return result, err
+		}
+		if len(links) == 0 {
+			break
+		}
+		afterID = links[len(links)-1].ID
+		result.Scanned += int64(len(links))
+
+		page := make([]*models.Link, len(links))
+		for i := range links {
+			page[i] = &links[i]
+		}
+		// 内容摘要包含标签，比较前先加载
+		if err := r.worker.loadLinkTags(ctx, page); err != nil {
+			return result, err
+		}
+
+		var repair []*models.Link
+		var reasons []string
+		for _, l := range page {
+			doc, ok := indexed[l.ID]
+			delete(indexed, l.ID)
+			if reason := reconcileReason(doc, ok, l, full); reason != "" {
+				repair = append(repair, l)
+				reasons = append(reasons, reason)
+			}
+		}
+		if len(repair) == 0 {
+			continue
+		}
+		// 直接写入索引，不经过 outbox（对账本身即补偿，无需再排队）
+		if err := r.worker.writeLinkDocuments(repair); err != nil {
+			return result, err
+		}
+		for _, reason := range reasons {
+			switch reason {
+			case reconcileMissing:
+				result.Missing++
+			case reconcileStale:
+				result.Stale++
+			case reconcileFull:
+				result.Full++
+			case reconcileClicks:
+				result.Clicks++
+			}
+			metrics.MeilisearchReconcileRepairedTotal.WithLabelValues(reason).Inc()
+		}
+	}
+
+	// 剩余的索引文档在 links 表中已不存在
+	if len(indexed) > 0 {
+		ids := make([]string, 0, len(indexed))
+		for id := range indexed {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		for start := 0; start < len(ids); start += reconcilePageSize {
+			end := start + reconcilePageSize
+			if end > len(ids) {
+				end = len(ids)
+			}
+			if _, err := r.worker.index.DeleteDocuments(ids[start:end]); err != nil {
+				return result, fmt.Errorf("删除孤立索引文档失败: %w", err)
+			}
+			result.Orphaned += int64(end - start)
+			metrics.MeilisearchReconcileRepairedTotal.WithLabelValues(reconcileOrphan).Add(float64(end - start))
+		}
+	}
+	return result, nil
+}
+
+// reconcileReason 判断链接是否需要重新索引，返回原因（空串表示索引已是最新）
+// 链接需已加载标签；内容摘要不一致为漂移，仅点击数不同为点击数刷新
+func reconcileReason(doc indexedDoc, indexed bool, link *models.Link, full bool) string {
+	switch {
+	case !indexed:
+		return reconcileMissing
+	case doc.hash != linkContentHash(link):
+		return reconcileStale
+	case full:
+		return reconcileFull
+	case doc.clicks != link.ClickCount || doc.uniqueClicks != link.UniqueClicks:
+		return reconcileClicks
+	default:
+		return ""
+	}
+}
+
+// loadIndexedDocs 读取索引中全部文档的对账字段（旧文档无 content_hash 时为空串，按过期处理）
+func (r *MeiliReconciler) loadIndexedDocs(ctx context.Context) (map[int64]indexedDoc, error) {
+	docs := make(map[int64]indexedDoc)
+	var offset int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var page meilisearch.DocumentsResult
+		err := r.worker.index.GetDocuments(&meilisearch.DocumentsQuery{
+			Offset: offset,
+			Limit:  reconcilePageSize,
+			Fields: []string{"id", "content_hash", "click_count", "unique_clicks"},
+		}, &page)
+		if err != nil {
+			return nil, fmt.Errorf("读取索引文档失败: %w", err)
+		}
+		for _, doc := range page.Results {
+			id, ok := documentID(doc["id"])
+			if !ok {
+				continue
+			}
+			hash, _ := doc["content_hash"].(string)
+			clicks, _ := doc["click_count"].(float64)
+			uniqueClicks, _ := doc["unique_clicks"].(float64)
+			docs[id] = indexedDoc{hash: hash, clicks: int64(clicks), uniqueClicks: int64(uniqueClicks)}
+		}
+		offset += int64(len(page.Results))
+		if len(page.Results) < reconcilePageSize || offset >= page.Total {
+			break
+		}
+	}
+	return docs, nil
+}
+
+// documentID 解析索引文档主键（数字或数字字符串）
+func documentID(v interface{}) (int64, bool) {
+	switch id := v.(type) {
+	case float64:
+		return int64(id), true
+	case string:
+		n, err := strconv.ParseInt(id, 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
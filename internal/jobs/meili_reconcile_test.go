@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"short-link/models"
+)
+
+func TestReconcileReason(t *testing.T) {
+	folder := int64(3)
+	link := &models.Link{ID: 1, Code: "abc", OriginalURL: "https://example.com", Tags: []string{"b", "a"}, ClickCount: 10, UniqueClicks: 4}
+	current := indexedDoc{hash: linkContentHash(link), clicks: 10, uniqueClicks: 4}
+	retagged := *link
+	retagged.Tags = []string{"a"}
+	moved := *link
+	moved.FolderID = &folder
+	clicked := *link
+	clicked.ClickCount, clicked.UniqueClicks = 25, 9
+	clicked.UpdatedAt = link.UpdatedAt.Add(time.Hour) // 点击批次会刷新 updated_at
+
+	cases := []struct {
+		name    string
+		doc     indexedDoc
+		indexed bool
+		link    *models.Link
+		full    bool
+		want    string
+	}{
+		{"missing", indexedDoc{}, false, link, false, reconcileMissing},
+		{"up to date", current, true, link, false, ""},
+		{"legacy doc without content_hash", indexedDoc{clicks: 10, uniqueClicks: 4}, true, link, false, reconcileStale},
+		{"tags changed", current, true, &retagged, false, reconcileStale},
+		{"folder changed", current, true, &moved, false, reconcileStale},
+		{"clicks only", current, true, &clicked, false, reconcileClicks},
+		{"full rebuild", current, true, link, true, reconcileFull},
+		{"full covers clicks", current, true, &clicked, true, reconcileFull},
+		{"missing wins over full", indexedDoc{}, false, link, true, reconcileMissing},
+	}
+	for _, c := range cases {
+		if got := reconcileReason(c.doc, c.indexed, c.link, c.full); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestLinkContentHash(t *testing.T) {
+	base := &models.Link{ID: 1, Code: "abc", OriginalURL: "https://example.com", Tags: []string{"x", "y"}}
+	reordered := *base
+	reordered.Tags = []string{"y", "x"}
+	if linkContentHash(base) != linkContentHash(&reordered) {
+		t.Fatal("tag order must not change the hash")
+	}
+	clicked := *base
+	clicked.ClickCount = 99
+	clicked.UpdatedAt = time.Now()
+	if linkContentHash(base) != linkContentHash(&clicked) {
+		t.Fatal("click count and updated_at must not change the hash")
+	}
+	retitled := *base
+	retitled.Title = "new"
+	if linkContentHash(base) == linkContentHash(&retitled) {
+		t.Fatal("title change must change the hash")
+	}
+	if base.Tags[0] != "x" {
+		t.Fatal("hashing must not reorder the link's tags")
+	}
+}
+
+func TestDocumentID(t *testing.T) {
+	if id, ok := documentID(float64(42)); !ok || id != 42 {
+		t.Fatalf("float id: got %d, %v", id, ok)
+	}
+	if id, ok := documentID("17"); !ok || id != 17 {
+		t.Fatalf("string id: got %d, %v", id, ok)
+	}
+	if _, ok := documentID("abc"); ok {
+		t.Fatalf("expected invalid id")
+	}
+	if _, ok := documentID(nil); ok {
+		t.Fatalf("expected missing id")
+	}
+}
@@ -0,0 +1,189 @@
+/**
+ * Outbox 事件投递 Worker
+ * - 轮询 outbox_events，FOR UPDATE SKIP LOCKED 领取到期事件（多副本并行互不重复），批量交给各 OutboxHandler
+ * - 全部 handler 成功则标记 done；失败按指数退避重试，超过最大次数标记 dead（nsl-admin -action=outbox-requeue 重新入队）
+ * - 领取时顺延 available_at 作为租约：进程在投递中途退出时，租约到期后事件会被重新领取
+ * - 定期清理已投递超过保留期的事件
+ */
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"short-link/internal/metrics"
+	"short-link/internal/repo"
+	"short-link/models"
+	"short-link/utils"
+)
+
+const (
+	// outboxLease 领取事件后的租约时长（超过后未确认的事件可被再次领取）
+	outboxLease = 5 * time.Minute
+	// outboxRetryBase / outboxRetryMax 失败重试的指数退避基数与上限
+	outboxRetryBase = 2 * time.Second
+	outboxRetryMax  = 10 * time.Minute
+	// outboxRetention 已投递事件的保留时长
+	outboxRetention = 7 * 24 * time.Hour
+	// outboxPurgeInterval 清理已投递事件的间隔
+	outboxPurgeInterval = time.Hour
+)
+
+// OutboxHandler outbox 事件消费方
+// 须幂等：同一事件可能因其他 handler 失败重试或租约到期被重复投递
+type OutboxHandler interface {
+	HandleOutboxEvents(ctx context.Context, events []models.OutboxEvent) error
+}
+
+// OutboxDispatcher outbox 事件投递 Worker
+type OutboxDispatcher struct {
+	outboxRepo   *repo.OutboxRepo
+	handlers     []OutboxHandler
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int
+	wg           sync.WaitGroup
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+// NewOutboxDispatcher 创建 outbox 投递 Worker
+func NewOutboxDispatcher(outboxRepo *repo.OutboxRepo, batchSize int, pollInterval time.Duration, maxAttempts int, handlers ...OutboxHandler) *OutboxDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OutboxDispatcher{
+		outboxRepo:   outboxRepo,
+		handlers:     handlers,
+		batchSize:    batchSize,
+		pollInterval: pollInterval,
+		maxAttempts:  maxAttempts,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Start 启动 Worker（后台 goroutine）
+func (d *OutboxDispatcher) Start() {
+	d.wg.Add(1)
+	go d.run()
+	utils.LogInfo("Outbox 投递 Worker 已启动（批量大小=%d，轮询间隔=%v，最大重试次数=%d）", d.batchSize, d.pollInterval, d.maxAttempts)
+}
+
+// run Worker 主循环：领取满一批时立即继续，否则等待下一个轮询周期
+func (d *OutboxDispatcher) run() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	lastPurge := time.Now()
+
+	for {
+		if d.dispatchOnce() >= d.batchSize {
+			if d.ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		if time.Since(lastPurge) >= outboxPurgeInterval {
+			lastPurge = time.Now()
+			if n, err := d.outboxRepo.PurgeDone(d.ctx, outboxRetention); err != nil {
+				utils.LogWarn("清理 outbox 事件失败: %v", err)
+			} else if n > 0 {
+				utils.LogInfo("已清理 %d 个已投递的 outbox 事件", n)
+			}
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchOnce 领取并投递一批事件，返回成功投递的事件数
+func (d *OutboxDispatcher) dispatchOnce() int {
+	events, err := d.outboxRepo.ClaimEvents(d.ctx, d.batchSize, outboxLease)
+	if err != nil {
+		if d.ctx.Err() == nil {
+			utils.LogError("领取 outbox 事件失败: %v", err)
+		}
+		return 0
+	}
+	if len(events) == 0 {
+		return 0
+	}
+
+	var handleErr error
+	for _, h := range d.handlers {
+		if handleErr = h.HandleOutboxEvents(d.ctx, events); handleErr != nil {
+			break
+		}
+	}
+
+	if handleErr == nil {
+		ids := make([]int64, len(events))
+		for i, e := range events {
+			ids[i] = e.ID
+		}
+		if err := d.outboxRepo.MarkDone(d.ctx, ids); err != nil {
+			// 未标记的事件租约到期后会重复投递（handler 幂等）
+			utils.LogError("标记 outbox 事件完成失败: count=%d, error=%v", len(ids), err)
+			return 0
+		}
+		metrics.OutboxEventsTotal.WithLabelValues("delivered").Add(float64(len(events)))
+		return len(events)
+	}
+
+	if d.ctx.Err() != nil {
+		// 停止中：不计入失败，租约到期后由本实例或其他副本重新领取
+		return 0
+	}
+	for _, e := range events {
+		if e.Attempts >= d.maxAttempts {
+			if err := d.outboxRepo.MarkDead(d.ctx, e.ID, handleErr.Error()); err != nil {
+				utils.LogError("标记 outbox 死信失败: event_id=%d, error=%v", e.ID, err)
+				continue
+			}
+			metrics.OutboxEventsTotal.WithLabelValues("dead").Inc()
+			utils.LogError("outbox 事件投递最终失败，已转入死信: event_id=%d, type=%s, link_id=%d, attempts=%d, error=%v",
+				e.ID, e.EventType, e.AggregateID, e.Attempts, handleErr)
+			continue
+		}
+		if err := d.outboxRepo.MarkRetry(d.ctx, e.ID, outboxBackoff(e.Attempts), handleErr.Error()); err != nil {
+			utils.LogError("标记 outbox 重试失败: event_id=%d, error=%v", e.ID, err)
+			continue
+		}
+		metrics.OutboxEventsTotal.WithLabelValues("retry").Inc()
+	}
+	utils.LogWarn("outbox 事件投递失败，将退避重试: count=%d, error=%v", len(events), handleErr)
+	return 0
+}
+
+// outboxBackoff 第 attempts 次失败后的重试等待（指数退避，封顶 outboxRetryMax）
+func outboxBackoff(attempts int) time.Duration {
+	return expBackoff(attempts, outboxRetryBase, outboxRetryMax)
+}
+
+// expBackoff 第 attempts 次失败后的重试等待：base * 2^(attempts-1)，封顶 max
+func expBackoff(attempts int, base, max time.Duration) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
+
+// Stop 停止 Worker（等待进行中的批次结束）
+func (d *OutboxDispatcher) Stop() {
+	d.cancel()
+	d.wg.Wait()
+	utils.LogInfo("Outbox 投递 Worker 已停止")
+}
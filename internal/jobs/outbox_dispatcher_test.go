@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 2 * time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{5, 32 * time.Second},
+		{9, 512 * time.Second},
+		{10, outboxRetryMax},
+		{100, outboxRetryMax},
+	}
+	for _, c := range cases {
+		if got := outboxBackoff(c.attempts); got != c.want {
+			t.Errorf("attempts=%d: got %v, want %v", c.attempts, got, c.want)
+		}
+	}
+}
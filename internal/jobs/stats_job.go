@@ -1,6 +1,13 @@
 /**
  * 统计任务队列与 Worker（异步写入访问日志/点击数）
  * 实现 redo.md 5.3：跳转路径必须极快，统计写入异步化
+ * - memory 模式：内存队列，队列满时丢弃，进程崩溃时丢失未写入的批次
+ * - durable 模式：跳转路径追加到 ClickQueue（Redis Stream / spool 文件），写入 Postgres 成功后确认，重启后重放
+ * - 每批在一个事务内写入：CopyFrom 访问日志 + unnest 批量累加点击数 + 预聚合
+ * - 写入前应用 PrivacyPolicy（IP 截断/哈希/丢弃、DNT/GPC），见 stats_privacy.go
+ * - Submit 同时把点击投递到实时点击流（live.Hub，非阻塞）
+ * - 独立访客：匿名化前计算访客指纹，总是在写入事务内合并 Postgres 中的 HLL sketch，Redis 可用时另行 PFADD/PFCOUNT，见 stats_visitors.go
+ * - 每批写入成功后交给 ClickSink（webhook link.clicked 按批投递，见 webhook_worker.go）
  */
 package jobs
 
@@ -9,6 +16,10 @@ import (
 	"sync"
 	"time"
 
+	"short-link/cache"
+	"short-link/internal/geoip"
+	"short-link/internal/live"
+	"short-link/internal/metrics"
 	"short-link/internal/repo"
 	"short-link/models"
 	"short-link/utils"
@@ -16,63 +27,139 @@ import (
 
 // StatsTask 统计任务
 type StatsTask struct {
-	LinkID    int64
-	IP        string
-	UserAgent string
-	Referer   string
-	CreatedAt time.Time
+	LinkID    int64     `json:"link_id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"ua,omitempty"`
+	Referer   string    `json:"ref,omitempty"`
+	CreatedAt time.Time `json:"ts"`
+	VariantID int64     `json:"variant_id,omitempty"` // 命中的 A/B 变体（0 表示无）
+	OptOut    bool      `json:"opt_out,omitempty"`    // 访客发送了 DNT: 1 或 Sec-GPC: 1
+
+	// GeoIP 归属地（flushBatch 写入前由 geoip.Enricher 填充，不写入持久化队列）
+	CountryCode string `json:"-"`
+	Region      string `json:"-"`
+	City        string `json:"-"`
+	ASN         uint   `json:"-"`
+	ASOrg       string `json:"-"`
+}
+
+// ClickSink 点击写入 Postgres 后的下游消费方（传入已应用隐私策略的访问日志）
+// 在写入 goroutine 中同步调用，失败自行记录日志，不影响批次确认
+type ClickSink interface {
+	HandleClicks(ctx context.Context, logs []*models.AccessLog)
 }
 
+// statsLagInterval 更新持久化队列积压指标的间隔
+const statsLagInterval = 5 * time.Second
+
 // StatsWorker 统计写入 Worker
 type StatsWorker struct {
 	taskChan    chan *StatsTask
 	batchSize   int
 	batchWait   time.Duration
-	linkRepo    *repo.LinkRepo
+	concurrency int // memory 模式的并发写入 goroutine 数（durable 模式按顺序确认，单 goroutine 消费）
 	accessLogRepo *repo.AccessLogRepo
+	countBots   bool // 爬虫访问是否计入点击数
+	geo         *geoip.Enricher // 可为 nil（未配置 GeoIP）
+	queue       ClickQueue      // 持久化队列（nil 表示 memory 模式）
+	privacy     *PrivacyPolicy  // 可为 nil（保存完整记录）
+	live        *live.Hub       // 可为 nil（不推送实时点击流）
+	clicks      ClickSink       // 可为 nil（不投递点击 webhook）
 	wg          sync.WaitGroup
 	ctx         context.Context
 	cancel      context.CancelFunc
 }
 
-// NewStatsWorker 创建统计 Worker
-func NewStatsWorker(linkRepo *repo.LinkRepo, accessLogRepo *repo.AccessLogRepo, batchSize int, batchWait time.Duration) *StatsWorker {
+// NewStatsWorker 创建统计 Worker（queue 为 nil 时使用内存队列）
+func NewStatsWorker(accessLogRepo *repo.AccessLogRepo, batchSize int, batchWait time.Duration, concurrency int, countBots bool, geo *geoip.Enricher, queue ClickQueue, privacy *PrivacyPolicy, hub *live.Hub, clicks ClickSink) *StatsWorker {
 	ctx, cancel := context.WithCancel(context.Background())
+	if concurrency < 1 {
+		concurrency = 1
+	}
 	return &StatsWorker{
 		taskChan:     make(chan *StatsTask, 1000), // 缓冲1000个任务
 		batchSize:    batchSize,
 		batchWait:    batchWait,
-		linkRepo:     linkRepo,
+		concurrency:  concurrency,
 		accessLogRepo: accessLogRepo,
+		countBots:    countBots,
+		geo:          geo,
+		queue:        queue,
+		privacy:      privacy,
+		live:         hub,
+		clicks:       clicks,
 		ctx:          ctx,
 		cancel:       cancel,
 	}
 }
 
-// Submit 提交统计任务（非阻塞）
-func (w *StatsWorker) Submit(linkID int64, ip, userAgent, referer string) {
+// Submit 提交统计任务（非阻塞）；optOut 为访客的 DNT / Sec-GPC 信号
+// userID / code 为链接所有者与短码，仅用于实时点击流
+func (w *StatsWorker) Submit(linkID int64, userID int64, code string, ip, userAgent, referer string, variantID int64, optOut bool) {
 	task := &StatsTask{
 		LinkID:    linkID,
 		IP:        ip,
 		UserAgent: userAgent,
 		Referer:   referer,
 		CreatedAt: time.Now(),
+		VariantID: variantID,
+		OptOut:    optOut,
+	}
+
+	if w.live != nil {
+		w.live.Publish(&live.Click{
+			LinkID:    linkID,
+			UserID:    userID,
+			Code:      code,
+			IP:        ip,
+			UserAgent: userAgent,
+			Referer:   referer,
+			VariantID: variantID,
+			OptOut:    optOut,
+			CreatedAt: task.CreatedAt,
+		})
+	}
+
+	if w.queue != nil {
+		err := w.queue.Append(w.ctx, task)
+		if err == nil {
+			return
+		}
+		// 持久化队列不可用时降级到内存队列
+		metrics.StatsIngestFallbackTotal.Inc()
+		utils.LogWarn("追加点击事件到 %s 失败，降级到内存队列: link_id=%d, error=%v", w.queue.Name(), linkID, err)
 	}
 
 	select {
 	case w.taskChan <- task:
 		// 成功提交
 	default:
-		// 队列满，静默丢弃（避免阻塞跳转路径）
+		// 队列满，丢弃（避免阻塞跳转路径）
+		recordClicksDropped("queue_full", 1)
 		utils.LogWarn("统计任务队列已满，丢弃任务: link_id=%d", linkID)
 	}
 }
 
+// recordClicksDropped 记录丢弃的点击事件数
+func recordClicksDropped(reason string, n int) {
+	metrics.StatsClicksDroppedTotal.WithLabelValues(reason).Add(float64(n))
+}
+
 // Start 启动 Worker（后台 goroutine）
 func (w *StatsWorker) Start() {
-	w.wg.Add(1)
-	go w.run()
-	utils.LogInfo("统计 Worker 已启动（批量大小=%d，等待间隔=%v）", w.batchSize, w.batchWait)
+	if w.queue != nil {
+		// 内存队列仅作为持久化队列追加失败时的降级路径
+		w.wg.Add(2)
+		go w.run()
+		go w.consume()
+		utils.LogInfo("统计 Worker 已启动（持久化队列=%s，批量大小=%d，等待间隔=%v）", w.queue.Name(), w.batchSize, w.batchWait)
+		return
+	}
+	for i := 0; i < w.concurrency; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+	utils.LogInfo("统计 Worker 已启动（批量大小=%d，等待间隔=%v，并发=%d）", w.batchSize, w.batchWait, w.concurrency)
 }
 
 // run Worker 主循环（批量处理）
@@ -88,72 +175,181 @@ func (w *StatsWorker) run() {
 		case <-w.ctx.Done():
 			// 关闭时处理剩余任务
 			if len(batch) > 0 {
-				w.flushBatch(batch)
+				w.flushBatch(batch) //nolint:errcheck // 内存模式失败即丢弃（已记录日志）
 			}
 			return
 
 		case task := <-w.taskChan:
 			batch = append(batch, task)
 			if len(batch) >= w.batchSize {
-				w.flushBatch(batch)
+				w.flushBatch(batch) //nolint:errcheck
 				batch = batch[:0] // 重置切片但保留容量
 			}
 
 		case <-ticker.C:
 			// 定时刷新（即使未满 batchSize）
 			if len(batch) > 0 {
-				w.flushBatch(batch)
+				w.flushBatch(batch) //nolint:errcheck
 				batch = batch[:0]
 			}
 		}
 	}
 }
 
-// flushBatch 批量写入统计
-func (w *StatsWorker) flushBatch(batch []*StatsTask) {
+// consume 持久化队列消费循环：读取一批 → 写入 Postgres → 确认
+// 写入失败时不确认，等待 batchWait 后重新读取同一批（重放）
+func (w *StatsWorker) consume() {
+	defer w.wg.Done()
+
+	var lastLag time.Time
+	for w.ctx.Err() == nil {
+		if time.Since(lastLag) >= statsLagInterval {
+			lastLag = time.Now()
+			if lag, err := w.queue.Lag(w.ctx); err == nil {
+				metrics.StatsIngestLag.Set(float64(lag))
+			}
+		}
+
+		entries, err := w.queue.Read(w.ctx, w.batchSize, w.batchWait)
+		if err != nil {
+			if w.ctx.Err() == nil {
+				utils.LogError("读取点击事件失败: queue=%s, error=%v", w.queue.Name(), err)
+				w.sleep(w.batchWait)
+			}
+			continue
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		ids := make([]string, len(entries))
+		batch := make([]*StatsTask, 0, len(entries))
+		for i, e := range entries {
+			ids[i] = e.ID
+			if e.Task != nil {
+				batch = append(batch, e.Task)
+			}
+		}
+		if err := w.flushBatch(batch); err != nil {
+			w.sleep(w.batchWait)
+			continue
+		}
+
+		// 确认使用独立 context：停止时已写入的批次仍需确认，避免重启后重复写入
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := w.queue.Ack(ctx, ids); err != nil {
+			utils.LogError("确认点击事件失败（重启后将重复写入）: count=%d, error=%v", len(ids), err)
+		} else if n := len(entries) - len(batch); n > 0 {
+			recordClicksDropped("decode", n)
+		}
+		cancel()
+	}
+}
+
+// sleep 等待 d 或 Worker 停止
+func (w *StatsWorker) sleep(d time.Duration) {
+	select {
+	case <-w.ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// flushBatch 批量写入统计（单个事务，失败时整批回滚并返回错误，持久化模式据此重放整批）
+func (w *StatsWorker) flushBatch(batch []*StatsTask) error {
 	if len(batch) == 0 {
-		return
+		return nil
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// 按 link_id 分组，合并点击数
-	clickCounts := make(map[int64]int)
 	accessLogs := make([]*models.AccessLog, 0, len(batch))
 
 	for _, task := range batch {
-		clickCounts[task.LinkID]++
-		accessLogs = append(accessLogs, &models.AccessLog{
-			LinkID:    task.LinkID,
-			IP:        task.IP,
-			UserAgent: task.UserAgent,
-			Referer:   task.Referer,
-			CreatedAt: task.CreatedAt,
-		})
-	}
+		w.enrichLocation(task)
 
-	// 批量写入点击数（使用事务或批量 UPDATE）
-	for linkID, count := range clickCounts {
-		if err := w.linkRepo.IncrementClickCount(ctx, linkID, count); err != nil {
-			utils.LogError("批量写入点击数失败: link_id=%d, count=%d, error=%v", linkID, count, err)
+		// UA 解析放在 Worker 中，不占用跳转路径
+		ua := utils.ParseUserAgent(task.UserAgent)
+		accessLog := &models.AccessLog{
+			LinkID:         task.LinkID,
+			IP:             task.IP,
+			UserAgent:      task.UserAgent,
+			Referer:        task.Referer,
+			CreatedAt:      task.CreatedAt,
+			Browser:        ua.Browser,
+			BrowserVersion: ua.BrowserVersion,
+			OS:             ua.OS,
+			Device:         ua.Device,
+			IsBot:          ua.IsBot,
+			CountryCode:    task.CountryCode,
+			Region:         task.Region,
+			City:           task.City,
+			ASN:            int64(task.ASN),
+			ASOrg:          task.ASOrg,
+			VariantID:      task.VariantID,
 		}
+		accessLog.VisitorHash = w.privacy.fingerprint(task.IP, task.UserAgent, task.OptOut)
+		w.privacy.apply(accessLog, task.OptOut)
+		accessLogs = append(accessLogs, accessLog)
 	}
 
-	// 批量写入访问日志
-	for _, log := range accessLogs {
-		if err := w.accessLogRepo.CreateAccessLog(ctx, log); err != nil {
-			utils.LogError("批量写入访问日志失败: link_id=%d, error=%v", log.LinkID, err)
-		}
+	start := time.Now()
+	visitors := w.countVisitors(ctx, accessLogs)
+	states, err := w.accessLogRepo.WriteClickBatch(ctx, accessLogs, w.countBots, visitors)
+	metrics.StatsFlushBatchSize.Observe(float64(len(accessLogs)))
+	if err != nil {
+		metrics.StatsFlushDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
+		utils.LogError("批量写入统计失败: count=%d, error=%v", len(accessLogs), err)
+		return err
 	}
+	metrics.StatsFlushDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
 
-	utils.LogInfo("批量写入统计完成: 点击数=%d, 访问日志=%d", len(clickCounts), len(accessLogs))
+	for i := range states {
+		w.enforceClickBudget(&states[i])
+	}
+	if w.clicks != nil {
+		w.clicks.HandleClicks(ctx, accessLogs)
+	}
+
+	utils.LogInfo("批量写入统计完成: 点击数=%d, 访问日志=%d", len(states), len(accessLogs))
+	return nil
+}
+
+// enrichLocation 用本地 GeoIP 库补充归属地（未配置时为空）
+func (w *StatsWorker) enrichLocation(task *StatsTask) {
+	if w.geo == nil {
+		return
+	}
+	loc := w.geo.Lookup(task.IP)
+	task.CountryCode = loc.CountryCode
+	task.Region = loc.Region
+	task.City = loc.City
+	task.ASN = loc.ASN
+	task.ASOrg = loc.ASOrg
+}
+
+// enforceClickBudget 点击预算用尽时清理跳转缓存，使后续跳转回源 DB 并返回 410
+// 说明：计数为批量异步写入，预算在一个批次窗口内可能有少量超出
+func (w *StatsWorker) enforceClickBudget(st *repo.ClickBudgetState) {
+	if st == nil || !st.Exhausted() {
+		return
+	}
+	if err := cache.Delete(cache.RedirectKey(st.DomainID, st.Code)); err != nil {
+		utils.LogWarn("清理跳转缓存失败: link_id=%d, error=%v", st.LinkID, err)
+		return
+	}
+	utils.LogInfo("链接点击预算已用尽: link_id=%d, click_count=%d, max_clicks=%d", st.LinkID, st.ClickCount, *st.MaxClicks)
 }
 
-// Stop 停止 Worker
+// Stop 停止 Worker（持久化模式下随后关闭队列）
 func (w *StatsWorker) Stop() {
 	w.cancel()
 	w.wg.Wait()
+	if w.queue != nil {
+		if err := w.queue.Close(); err != nil {
+			utils.LogWarn("关闭点击事件队列失败: %v", err)
+		}
+	}
 	utils.LogInfo("统计 Worker 已停止")
 }
 
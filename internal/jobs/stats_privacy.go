@@ -0,0 +1,84 @@
+/**
+ * 访问日志隐私策略（StatsWorker 写入 Postgres 前应用）
+ * - IP：完整 / 截断（/24、/48）/ 按天轮换的加盐哈希 / 不保存（STATS_IP_MODE）
+ * - 访客发送 DNT: 1 或 Sec-GPC: 1 时（STATS_HONOR_DNT）：不保存 IP、原始 UA 与城市级归属地，
+ *   仍计入点击数并保留国家、浏览器/系统/设备等粗粒度维度
+ * - GeoIP 查询与 UA 解析在应用策略之前完成
+ */
+package jobs
+
+import (
+	"crypto/rand"
+
+	"short-link/models"
+	"short-link/utils"
+)
+
+// PrivacyPolicy 访问日志隐私策略
+type PrivacyPolicy struct {
+	ipMode         string
+	hashSecret     []byte
+	fingerprintKey []byte // 独立访客指纹密钥（STATS_IP_HASH_SECRET，可为空）
+	honorDNT       bool
+}
+
+// NewPrivacyPolicy 创建隐私策略
+// hash 模式未配置密钥时随机生成（进程重启后同一访客当天的哈希会变化）
+func NewPrivacyPolicy(ipMode string, hashSecret string, honorDNT bool) *PrivacyPolicy {
+	if !utils.IsValidIPMode(ipMode) {
+		ipMode = utils.IPModeFull
+	}
+	p := &PrivacyPolicy{ipMode: ipMode, hashSecret: []byte(hashSecret), fingerprintKey: []byte(hashSecret), honorDNT: honorDNT}
+	if ipMode == utils.IPModeHash && len(p.hashSecret) == 0 {
+		p.hashSecret = make([]byte, 32)
+		if _, err := rand.Read(p.hashSecret); err != nil {
+			// 拿不到随机数时不冒险使用弱密钥
+			utils.LogWarn("生成 IP 哈希密钥失败，改为不保存 IP: %v", err)
+			p.ipMode = utils.IPModeDrop
+		} else {
+			utils.LogWarn("STATS_IP_HASH_SECRET 未设置，使用进程内随机密钥（重启或多副本之间哈希不一致）")
+		}
+	}
+	return p
+}
+
+// HidesIPs IP 维度统计是否已无意义（哈希按天轮换或不保存 IP）
+func (p *PrivacyPolicy) HidesIPs() bool {
+	return p != nil && (p.ipMode == utils.IPModeHash || p.ipMode == utils.IPModeDrop)
+}
+
+// fingerprint 独立访客指纹（在 apply 之前用原始 IP/UA 计算，不落库）；访客拒绝跟踪时返回 0，不计入独立访客
+func (p *PrivacyPolicy) fingerprint(ip, userAgent string, optOut bool) uint64 {
+	if p == nil {
+		return utils.VisitorFingerprint(nil, ip, userAgent)
+	}
+	if optOut && p.honorDNT {
+		return 0
+	}
+	return utils.VisitorFingerprint(p.fingerprintKey, ip, userAgent)
+}
+
+// apply 对一条待写入的访问日志应用策略；optOut 为访客的 DNT / Sec-GPC 信号
+func (p *PrivacyPolicy) apply(log *models.AccessLog, optOut bool) {
+	if p == nil {
+		return
+	}
+	if optOut && p.honorDNT {
+		log.IP = ""
+		log.UserAgent = ""
+		log.Region = ""
+		log.City = ""
+		log.ASN = 0
+		log.ASOrg = ""
+		return
+	}
+
+	switch p.ipMode {
+	case utils.IPModeTruncate:
+		log.IP = utils.TruncateIP(log.IP)
+	case utils.IPModeHash:
+		log.IP = utils.HashIP(p.hashSecret, log.CreatedAt, log.IP)
+	case utils.IPModeDrop:
+		log.IP = ""
+	}
+}
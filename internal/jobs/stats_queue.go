@@ -0,0 +1,59 @@
+/**
+ * 点击事件持久化队列（STATS_INGEST_MODE=durable）
+ * - 跳转路径将点击事件追加到持久化队列（Redis Stream；未启用 Redis 时为本地追加写 spool 文件）
+ * - StatsWorker 批量读取写入 Postgres，写入成功后确认；未确认的事件在重启后重放
+ */
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 统计写入模式
+const (
+	StatsIngestMemory  = "memory"  // 内存队列（默认；队列满或进程崩溃时丢失）
+	StatsIngestDurable = "durable" // 持久化队列
+)
+
+// ClickEntry 从持久化队列读取的点击事件
+type ClickEntry struct {
+	ID   string     // 确认用的队列内 ID
+	Task *StatsTask // 为 nil 表示事件内容无法解码（确认后丢弃）
+}
+
+// ClickQueue 点击事件持久化队列
+// 单消费者语义：Read 总是先返回已读取但尚未确认的事件（写入失败后重试、重启后重放）
+type ClickQueue interface {
+	// Name 队列类型（日志用）
+	Name() string
+	// Append 追加点击事件（跳转路径调用，须快速返回）
+	Append(ctx context.Context, task *StatsTask) error
+	// Read 读取最多 max 条事件；没有事件时最多等待 wait，超时返回空切片
+	Read(ctx context.Context, max int, wait time.Duration) ([]ClickEntry, error)
+	// Ack 确认事件已写入 Postgres
+	Ack(ctx context.Context, ids []string) error
+	// Lag 尚未确认的事件数
+	Lag(ctx context.Context) (int64, error)
+	// Close 关闭队列
+	Close() error
+}
+
+// encodeClick 编码点击事件（只在队列中保存跳转路径采集的字段，归属地在写入时补充）
+func encodeClick(task *StatsTask) ([]byte, error) {
+	return json.Marshal(task)
+}
+
+// decodeClick 解码点击事件
+func decodeClick(data []byte) (*StatsTask, error) {
+	var task StatsTask
+	if err := json.Unmarshal(data, &task); err != nil {
+		return nil, fmt.Errorf("解码点击事件失败: %w", err)
+	}
+	if task.LinkID <= 0 {
+		return nil, fmt.Errorf("点击事件缺少 link_id")
+	}
+	return &task, nil
+}
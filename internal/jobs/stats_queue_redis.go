@@ -0,0 +1,211 @@
+/**
+ * 点击事件持久化队列：Redis Stream 实现
+ * - XADD 追加（MAXLEN ~ 近似裁剪，防止消费长期停滞时无限增长）
+ * - 消费者组 XREADGROUP 读取，写入成功后 XACK + XDEL；消费者名为主机名，重启后先重放自己未确认的事件
+ * - 定期 XAUTOCLAIM 接管其他消费者（已下线副本）长时间未确认的事件
+ */
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"short-link/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// clickStreamKey / clickStreamGroup 点击事件 Stream 与消费者组
+	clickStreamKey   = "stats:clicks"
+	clickStreamGroup = "stats-worker"
+	// clickStreamField 事件内容字段
+	clickStreamField = "task"
+	// clickAppendTimeout 跳转路径追加事件的超时（超时后降级到内存队列）
+	clickAppendTimeout = 200 * time.Millisecond
+	// clickClaimIdle / clickClaimInterval 接管其他消费者未确认事件的空闲阈值与检查间隔
+	clickClaimIdle     = 5 * time.Minute
+	clickClaimInterval = time.Minute
+)
+
+// redisClickQueue Redis Stream 点击队列
+type redisClickQueue struct {
+	client   *redis.Client
+	consumer string
+	maxLen   int64
+
+	mu        sync.Mutex
+	replay    bool                // 需要从头读取本消费者未确认的事件（启动时 / 有未确认事件时）
+	unacked   map[string]struct{} // 已读取未确认的事件 ID
+	lastClaim time.Time
+}
+
+// NewRedisClickQueue 创建 Redis Stream 点击队列（消费者组不存在时创建）
+func NewRedisClickQueue(ctx context.Context, client *redis.Client, maxLen int64) (ClickQueue, error) {
+	err := client.XGroupCreateMkStream(ctx, clickStreamKey, clickStreamGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, fmt.Errorf("创建点击事件消费者组失败: %w", err)
+	}
+
+	consumer, err := os.Hostname()
+	if err != nil || consumer == "" {
+		consumer = "stats-worker"
+	}
+	return &redisClickQueue{
+		client:   client,
+		consumer: consumer,
+		maxLen:   maxLen,
+		replay:   true,
+		unacked:  map[string]struct{}{},
+	}, nil
+}
+
+// Name 队列类型
+func (q *redisClickQueue) Name() string {
+	return "redis-stream"
+}
+
+// Append 追加点击事件
+func (q *redisClickQueue) Append(ctx context.Context, task *StatsTask) error {
+	data, err := encodeClick(task)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, clickAppendTimeout)
+	defer cancel()
+	return q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: clickStreamKey,
+		MaxLen: q.maxLen,
+		Approx: true,
+		Values: []interface{}{clickStreamField, data},
+	}).Err()
+}
+
+// Read 读取事件：先重放本消费者未确认的事件，再接管其他消费者的超时事件，最后阻塞读取新事件
+func (q *redisClickQueue) Read(ctx context.Context, max int, wait time.Duration) ([]ClickEntry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.replay || len(q.unacked) > 0 {
+		msgs, err := q.readGroup(ctx, "0", max, -1)
+		if err != nil {
+			return nil, err
+		}
+		if len(msgs) > 0 {
+			return q.entries(ctx, msgs), nil
+		}
+		q.replay = false
+		q.unacked = map[string]struct{}{}
+	}
+
+	if time.Since(q.lastClaim) >= clickClaimInterval {
+		q.lastClaim = time.Now()
+		msgs, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   clickStreamKey,
+			Group:    clickStreamGroup,
+			Consumer: q.consumer,
+			MinIdle:  clickClaimIdle,
+			Start:    "0-0",
+			Count:    int64(max),
+		}).Result()
+		if err != nil {
+			utils.LogWarn("接管超时点击事件失败: %v", err)
+		} else if len(msgs) > 0 {
+			utils.LogInfo("已接管 %d 个其他消费者未确认的点击事件", len(msgs))
+			return q.entries(ctx, msgs), nil
+		}
+	}
+
+	msgs, err := q.readGroup(ctx, ">", max, wait)
+	if err != nil {
+		return nil, err
+	}
+	return q.entries(ctx, msgs), nil
+}
+
+// readGroup XREADGROUP 读取（block < 0 表示不阻塞）
+func (q *redisClickQueue) readGroup(ctx context.Context, id string, max int, block time.Duration) ([]redis.XMessage, error) {
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    clickStreamGroup,
+		Consumer: q.consumer,
+		Streams:  []string{clickStreamKey, id},
+		Count:    int64(max),
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取点击事件失败: %w", err)
+	}
+	var msgs []redis.XMessage
+	for _, s := range streams {
+		msgs = append(msgs, s.Messages...)
+	}
+	return msgs, nil
+}
+
+// entries 解码消息；无法解码的消息（内容损坏或已被裁剪）直接确认丢弃
+func (q *redisClickQueue) entries(ctx context.Context, msgs []redis.XMessage) []ClickEntry {
+	entries := make([]ClickEntry, 0, len(msgs))
+	var bad []string
+	for _, m := range msgs {
+		raw, _ := m.Values[clickStreamField].(string)
+		task, err := decodeClick([]byte(raw))
+		if err != nil {
+			bad = append(bad, m.ID)
+			continue
+		}
+		q.unacked[m.ID] = struct{}{}
+		entries = append(entries, ClickEntry{ID: m.ID, Task: task})
+	}
+	if len(bad) > 0 {
+		recordClicksDropped("decode", len(bad))
+		if err := q.ack(ctx, bad); err != nil {
+			utils.LogWarn("丢弃无效点击事件失败: %v", err)
+		}
+	}
+	return entries
+}
+
+// Ack 确认并删除事件
+func (q *redisClickQueue) Ack(ctx context.Context, ids []string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.ack(ctx, ids); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		delete(q.unacked, id)
+	}
+	return nil
+}
+
+// ack XACK + XDEL（同一 pipeline）
+func (q *redisClickQueue) ack(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	pipe := q.client.TxPipeline()
+	pipe.XAck(ctx, clickStreamKey, clickStreamGroup, ids...)
+	pipe.XDel(ctx, clickStreamKey, ids...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("确认点击事件失败: %w", err)
+	}
+	return nil
+}
+
+// Lag 尚未确认的事件数（确认后即删除，Stream 长度即积压量）
+func (q *redisClickQueue) Lag(ctx context.Context) (int64, error) {
+	return q.client.XLen(ctx, clickStreamKey).Result()
+}
+
+// Close Redis 连接由 cache 包统一管理，这里无需关闭
+func (q *redisClickQueue) Close() error {
+	return nil
+}
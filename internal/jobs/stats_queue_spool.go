@@ -0,0 +1,453 @@
+/**
+ * 点击事件持久化队列：本地 spool 文件实现（未启用 Redis 时使用）
+ * - 事件以 JSON Lines 追加写入分段文件 <seq>.spool，超过 spoolSegmentSize 切换到新分段；每秒 fsync 一次
+ * - 读取位置（分段序号 + 字节偏移）只在 Ack 时推进并以 rename 原子写入 cursor 文件，未确认的事件重启后重放
+ * - 已完全确认的旧分段在 Ack 时删除；无法解码的行以 Task 为 nil 返回，确认后跳过
+ * - 仅支持单进程读写（每个副本使用自己的目录）
+ */
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"short-link/utils"
+)
+
+const (
+	// spoolSegmentSize 单个分段文件的最大字节数
+	spoolSegmentSize = 16 << 20
+	// spoolSyncInterval fsync 间隔
+	spoolSyncInterval = time.Second
+	// spoolCursorFile 读取位置文件名
+	spoolCursorFile = "cursor"
+)
+
+// spoolPos spool 读取位置
+type spoolPos struct {
+	seq    int64
+	offset int64
+}
+
+// String 事件 ID：事件结束位置 "<seq>:<offset>"
+func (p spoolPos) String() string {
+	return fmt.Sprintf("%d:%d", p.seq, p.offset)
+}
+
+// parseSpoolPos 解析事件 ID
+func parseSpoolPos(s string) (spoolPos, error) {
+	seq, off, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return spoolPos{}, fmt.Errorf("无效的 spool 位置: %q", s)
+	}
+	p := spoolPos{}
+	var err error
+	if p.seq, err = strconv.ParseInt(seq, 10, 64); err != nil {
+		return spoolPos{}, fmt.Errorf("无效的 spool 位置: %q", s)
+	}
+	if p.offset, err = strconv.ParseInt(off, 10, 64); err != nil {
+		return spoolPos{}, fmt.Errorf("无效的 spool 位置: %q", s)
+	}
+	return p, nil
+}
+
+// after 是否位于 o 之后
+func (p spoolPos) after(o spoolPos) bool {
+	return p.seq > o.seq || (p.seq == o.seq && p.offset > o.offset)
+}
+
+// spoolClickQueue spool 文件点击队列
+type spoolClickQueue struct {
+	dir    string
+	notify chan struct{} // Append 后唤醒等待中的 Read
+
+	mu     sync.Mutex
+	file   *os.File // 当前写入分段
+	seq    int64    // 当前写入分段序号
+	size   int64    // 当前写入分段大小
+	dirty  bool     // 有未 fsync 的写入
+	cursor spoolPos // 已确认位置
+	lag    int64    // 未确认的事件数
+	closed bool
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewSpoolClickQueue 打开（或创建）spool 目录
+func NewSpoolClickQueue(dir string) (ClickQueue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建 spool 目录失败: %w", err)
+	}
+	q := &spoolClickQueue{dir: dir, notify: make(chan struct{}, 1)}
+
+	segs, err := q.segments()
+	if err != nil {
+		return nil, err
+	}
+	if len(segs) == 0 {
+		segs = []int64{1}
+	}
+	q.seq = segs[len(segs)-1]
+
+	// 进程崩溃时最后一行可能只写了一半：截断到最后一个完整行
+	if q.size, err = truncatePartialLine(q.segmentPath(q.seq)); err != nil {
+		return nil, err
+	}
+	if q.file, err = os.OpenFile(q.segmentPath(q.seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return nil, fmt.Errorf("打开 spool 分段失败: %w", err)
+	}
+
+	if q.cursor, err = q.loadCursor(); err != nil {
+		q.file.Close()
+		return nil, err
+	}
+	if q.cursor.seq < segs[0] {
+		q.cursor = spoolPos{seq: segs[0]}
+	}
+	if q.lag, err = q.countPending(); err != nil {
+		q.file.Close()
+		return nil, err
+	}
+	if q.lag > 0 {
+		utils.LogInfo("spool 中有 %d 个未写入的点击事件，将重放", q.lag)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	q.wg.Add(1)
+	go q.syncLoop(ctx)
+	return q, nil
+}
+
+// Name 队列类型
+func (q *spoolClickQueue) Name() string {
+	return "spool"
+}
+
+// segmentPath 分段文件路径
+func (q *spoolClickQueue) segmentPath(seq int64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d.spool", seq))
+}
+
+// segments 按序号升序列出现有分段
+func (q *spoolClickQueue) segments() ([]int64, error) {
+	names, err := filepath.Glob(filepath.Join(q.dir, "*.spool"))
+	if err != nil {
+		return nil, err
+	}
+	segs := make([]int64, 0, len(names))
+	for _, name := range names {
+		seq, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(name), ".spool"), 10, 64)
+		if err == nil {
+			segs = append(segs, seq)
+		}
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i] < segs[j] })
+	return segs, nil
+}
+
+// Append 追加点击事件（超过分段大小时切换分段）
+func (q *spoolClickQueue) Append(ctx context.Context, task *StatsTask) error {
+	data, err := encodeClick(task)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return fmt.Errorf("spool 已关闭")
+	}
+	if q.size > 0 && q.size+int64(len(data)) > spoolSegmentSize {
+		if err := q.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := q.file.Write(data)
+	q.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("写入 spool 失败: %w", err)
+	}
+	q.dirty = true
+	q.lag++
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// rotate 切换到新分段（调用方持有 q.mu）
+func (q *spoolClickQueue) rotate() error {
+	if err := q.file.Sync(); err != nil {
+		return fmt.Errorf("同步 spool 分段失败: %w", err)
+	}
+	f, err := os.OpenFile(q.segmentPath(q.seq+1), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("创建 spool 分段失败: %w", err)
+	}
+	q.file.Close()
+	q.file = f
+	q.seq++
+	q.size = 0
+	q.dirty = false
+	return nil
+}
+
+// Read 从已确认位置开始读取最多 max 条完整事件
+func (q *spoolClickQueue) Read(ctx context.Context, max int, wait time.Duration) ([]ClickEntry, error) {
+	entries, err := q.read(max)
+	if err != nil || len(entries) > 0 || wait <= 0 {
+		return entries, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+	case <-q.notify:
+	}
+	return q.read(max)
+}
+
+// read 读取一次（不等待）
+func (q *spoolClickQueue) read(max int) ([]ClickEntry, error) {
+	q.mu.Lock()
+	pos, active := q.cursor, q.seq
+	q.mu.Unlock()
+
+	var entries []ClickEntry
+	for len(entries) < max && pos.seq <= active {
+		remaining := max - len(entries)
+		n, err := q.readSegment(pos, remaining, func(end spoolPos, line []byte) {
+			task, err := decodeClick(line)
+			if err != nil {
+				// 损坏的行：以 Task 为 nil 返回，由调用方确认跳过
+				utils.LogWarn("spool 中有无效的点击事件: position=%s, error=%v", end, err)
+			}
+			entries = append(entries, ClickEntry{ID: end.String(), Task: task})
+		})
+		if err != nil {
+			return nil, err
+		}
+		if n >= remaining {
+			break
+		}
+		// 当前分段已读完，继续读下一个分段
+		pos = spoolPos{seq: pos.seq + 1}
+	}
+	return entries, nil
+}
+
+// readSegment 从 pos 开始读取分段中的完整行，返回读取的行数
+func (q *spoolClickQueue) readSegment(pos spoolPos, max int, fn func(end spoolPos, line []byte)) (int, error) {
+	f, err := os.Open(q.segmentPath(pos.seq))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("打开 spool 分段失败: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(pos.offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("定位 spool 分段失败: %w", err)
+	}
+
+	r := bufio.NewReader(f)
+	offset := pos.offset
+	n := 0
+	for n < max {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// 末尾不完整的行可能正在写入，留待下次读取
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("读取 spool 分段失败: %w", err)
+		}
+		offset += int64(len(line))
+		n++
+		fn(spoolPos{seq: pos.seq, offset: offset}, bytes.TrimSpace(line))
+	}
+	return n, nil
+}
+
+// Ack 推进已确认位置到 ids 中最靠后的位置，并删除已完全确认的旧分段
+// 说明：读取按顺序进行，确认一批即表示该批之前的事件均已处理
+func (q *spoolClickQueue) Ack(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	next := q.cursor
+	for _, id := range ids {
+		p, err := parseSpoolPos(id)
+		if err != nil {
+			return err
+		}
+		if p.after(next) {
+			next = p
+		}
+	}
+	if !next.after(q.cursor) {
+		return nil
+	}
+	if err := q.saveCursor(next); err != nil {
+		return err
+	}
+	for seq := q.cursor.seq; seq < next.seq; seq++ {
+		if err := os.Remove(q.segmentPath(seq)); err != nil && !os.IsNotExist(err) {
+			utils.LogWarn("删除已确认的 spool 分段失败: seq=%d, error=%v", seq, err)
+		}
+	}
+	q.cursor = next
+	if q.lag -= int64(len(ids)); q.lag < 0 {
+		q.lag = 0
+	}
+	return nil
+}
+
+// Lag 尚未确认的事件数
+func (q *spoolClickQueue) Lag(ctx context.Context) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.lag, nil
+}
+
+// loadCursor 读取已确认位置（文件不存在时从头开始）
+func (q *spoolClickQueue) loadCursor() (spoolPos, error) {
+	data, err := os.ReadFile(filepath.Join(q.dir, spoolCursorFile))
+	if os.IsNotExist(err) {
+		return spoolPos{}, nil
+	}
+	if err != nil {
+		return spoolPos{}, fmt.Errorf("读取 spool 位置失败: %w", err)
+	}
+	return parseSpoolPos(string(data))
+}
+
+// saveCursor 原子写入已确认位置（先写临时文件再 rename）
+func (q *spoolClickQueue) saveCursor(p spoolPos) error {
+	path := filepath.Join(q.dir, spoolCursorFile)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("写入 spool 位置失败: %w", err)
+	}
+	if _, err := f.WriteString(p.String()); err != nil {
+		f.Close()
+		return fmt.Errorf("写入 spool 位置失败: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("写入 spool 位置失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("写入 spool 位置失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("写入 spool 位置失败: %w", err)
+	}
+	return nil
+}
+
+// countPending 统计已确认位置之后的完整事件数
+func (q *spoolClickQueue) countPending() (int64, error) {
+	var total int64
+	for seq := q.cursor.seq; seq <= q.seq; seq++ {
+		pos := spoolPos{seq: seq}
+		if seq == q.cursor.seq {
+			pos = q.cursor
+		}
+		n, err := q.readSegment(pos, int(^uint(0)>>1), func(spoolPos, []byte) {})
+		if err != nil {
+			return 0, err
+		}
+		total += int64(n)
+	}
+	return total, nil
+}
+
+// syncLoop 定期 fsync 当前分段
+func (q *spoolClickQueue) syncLoop(ctx context.Context) {
+	defer q.wg.Done()
+	ticker := time.NewTicker(spoolSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.sync()
+		}
+	}
+}
+
+// sync fsync 当前分段（没有新写入时跳过）
+func (q *spoolClickQueue) sync() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.dirty || q.closed {
+		return
+	}
+	if err := q.file.Sync(); err != nil {
+		utils.LogWarn("同步 spool 分段失败: %v", err)
+		return
+	}
+	q.dirty = false
+}
+
+// Close 停止 fsync 并关闭当前分段
+func (q *spoolClickQueue) Close() error {
+	q.cancel()
+	q.wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	if err := q.file.Sync(); err != nil {
+		q.file.Close()
+		return fmt.Errorf("同步 spool 分段失败: %w", err)
+	}
+	return q.file.Close()
+}
+
+// truncatePartialLine 将文件截断到最后一个换行符，返回截断后的大小（文件不存在时返回 0）
+func truncatePartialLine(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("读取 spool 分段失败: %w", err)
+	}
+	size := int64(bytes.LastIndexByte(data, '\n') + 1)
+	if size == int64(len(data)) {
+		return size, nil
+	}
+	utils.LogWarn("spool 分段末尾有不完整的事件，已截断: path=%s, bytes=%d", path, int64(len(data))-size)
+	if err := os.Truncate(path, size); err != nil {
+		return 0, fmt.Errorf("截断 spool 分段失败: %w", err)
+	}
+	return size, nil
+}
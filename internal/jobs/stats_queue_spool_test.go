@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"short-link/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogger()
+	os.Exit(m.Run())
+}
+
+func openSpool(t *testing.T, dir string) *spoolClickQueue {
+	t.Helper()
+	q, err := NewSpoolClickQueue(dir)
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	return q.(*spoolClickQueue)
+}
+
+func TestSpoolReplayUnacked(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	q := openSpool(t, dir)
+	for i := int64(1); i <= 3; i++ {
+		if err := q.Append(ctx, &StatsTask{LinkID: i, IP: "1.2.3.4", CreatedAt: time.Unix(i, 0)}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	entries, err := q.Read(ctx, 2, 0)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("read: got %d entries, err=%v", len(entries), err)
+	}
+	// 未确认时重复读取返回同一批
+	again, _ := q.Read(ctx, 2, 0)
+	if len(again) != 2 || again[0].ID != entries[0].ID {
+		t.Fatalf("unacked entries not replayed: %+v", again)
+	}
+	if err := q.Ack(ctx, []string{entries[0].ID, entries[1].ID}); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// 重启后只重放未确认的事件
+	q = openSpool(t, dir)
+	defer q.Close()
+	if lag, _ := q.Lag(ctx); lag != 1 {
+		t.Fatalf("lag after reopen: got %d, want 1", lag)
+	}
+	entries, err = q.Read(ctx, 10, 0)
+	if err != nil || len(entries) != 1 || entries[0].Task.LinkID != 3 {
+		t.Fatalf("replay: got %+v, err=%v", entries, err)
+	}
+	if !entries[0].Task.CreatedAt.Equal(time.Unix(3, 0)) {
+		t.Errorf("created_at: got %v", entries[0].Task.CreatedAt)
+	}
+}
+
+func TestSpoolTruncatesPartialLine(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	q := openSpool(t, dir)
+	if err := q.Append(ctx, &StatsTask{LinkID: 1}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	q.Close()
+
+	// 模拟崩溃时写了一半的事件
+	f, err := os.OpenFile(q.segmentPath(1), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"link_id":2,"ip"`)
+	f.Close()
+
+	q = openSpool(t, dir)
+	defer q.Close()
+	if err := q.Append(ctx, &StatsTask{LinkID: 3}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	entries, err := q.Read(ctx, 10, 0)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("read: got %d entries, err=%v", len(entries), err)
+	}
+	if entries[0].Task.LinkID != 1 || entries[1].Task.LinkID != 3 {
+		t.Errorf("got link ids %d, %d", entries[0].Task.LinkID, entries[1].Task.LinkID)
+	}
+}
+
+func TestSpoolPos(t *testing.T) {
+	p, err := parseSpoolPos(spoolPos{seq: 3, offset: 120}.String())
+	if err != nil || p.seq != 3 || p.offset != 120 {
+		t.Fatalf("round trip: got %+v, err=%v", p, err)
+	}
+	if !(spoolPos{seq: 2, offset: 0}).after(spoolPos{seq: 1, offset: 500}) {
+		t.Error("next segment should be after")
+	}
+	if _, err := parseSpoolPos("bad"); err == nil {
+		t.Error("expected error for invalid position")
+	}
+}
@@ -0,0 +1,79 @@
+/**
+ * 独立访客计数（HyperLogLog）
+ * - 持久数据：WriteClickBatch 总是在写入事务内把本批指纹合并进 Postgres 中的 visitors_hll sketch
+ * - Redis 可用时：每批对 uv:<link_id>（累计）与 uv:<link_id>:<YYYYMMDD>（按天）执行 PFADD + PFCOUNT，
+ *   估计值与 sketch 估计取较大值写入 unique_clicks；Redis 数据丢失不影响 sketch
+ * - 爬虫访问与拒绝跟踪（DNT/GPC）的访客不计入
+ */
+package jobs
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"short-link/cache"
+	"short-link/internal/repo"
+	"short-link/models"
+	"short-link/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// visitorDayKeyTTL 按天 HLL key 的保留时间（覆盖跨天与 durable 模式延迟重放的点击）
+const visitorDayKeyTTL = 72 * time.Hour
+
+// countVisitors 用 Redis HyperLogLog 计算本批涉及链接的独立访客估计；返回 nil 表示只使用 Postgres sketch
+func (w *StatsWorker) countVisitors(ctx context.Context, logs []*models.AccessLog) *repo.VisitorCounts {
+	client := cache.RedisClient
+	if client == nil {
+		return nil
+	}
+
+	linkFPs := make(map[int64][]interface{})
+	dayFPs := make(map[repo.VisitorDay][]interface{})
+	for _, log := range logs {
+		if log.VisitorHash == 0 || (log.IsBot && !w.countBots) {
+			continue
+		}
+		fp := strconv.FormatUint(log.VisitorHash, 16)
+		day := repo.VisitorDay{LinkID: log.LinkID, Day: repo.VisitorDayOf(log.CreatedAt)}
+		linkFPs[log.LinkID] = append(linkFPs[log.LinkID], fp)
+		dayFPs[day] = append(dayFPs[day], fp)
+	}
+	if len(linkFPs) == 0 {
+		return nil
+	}
+
+	// 同一 pipeline 内命令按顺序执行，PFCOUNT 能看到本批 PFADD 的结果
+	pipe := client.Pipeline()
+	linkCmds := make(map[int64]*redis.IntCmd, len(linkFPs))
+	for id, fps := range linkFPs {
+		key := cache.VisitorsKey(id)
+		pipe.PFAdd(ctx, key, fps...)
+		linkCmds[id] = pipe.PFCount(ctx, key)
+	}
+	dayCmds := make(map[repo.VisitorDay]*redis.IntCmd, len(dayFPs))
+	for day, fps := range dayFPs {
+		key := cache.VisitorsDayKey(day.LinkID, day.Day)
+		pipe.PFAdd(ctx, key, fps...)
+		pipe.Expire(ctx, key, visitorDayKeyTTL)
+		dayCmds[day] = pipe.PFCount(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		utils.LogWarn("Redis 独立访客计数失败，只使用 Postgres sketch: error=%v", err)
+		return nil
+	}
+
+	counts := &repo.VisitorCounts{
+		Links: make(map[int64]int64, len(linkCmds)),
+		Days:  make(map[repo.VisitorDay]int64, len(dayCmds)),
+	}
+	for id, cmd := range linkCmds {
+		counts.Links[id] = cmd.Val()
+	}
+	for day, cmd := range dayCmds {
+		counts.Days[day] = cmd.Val()
+	}
+	return counts
+}
@@ -0,0 +1,338 @@
+/**
+ * Webhook 投递 Worker
+ * - 轮询 webhook_deliveries，FOR UPDATE SKIP LOCKED 领取到期投递（多副本并行互不重复），并发 POST 到接收地址
+ * - 请求签名：X-Webhook-Signature = sha256=hex(HMAC-SHA256(secret, "<X-Webhook-Timestamp>.<body>"))，见 utils.SignWebhook
+ * - 2xx 视为送达；其他响应码、超时或连接失败按指数退避重试，超过最大尝试次数标记 failed（可通过 API 手动重新投递）
+ * - 不跟随重定向；建立连接时按解析后的 IP 再次校验，禁止投递到内网地址（ALLOW_PRIVATE_URLS=true 除外）
+ * - 点击事件：StatsWorker 每批写入成功后调用 HandleClicks，按接收地址合并为一条 link.clicked 投递（不含 IP 与原始 UA）
+ * - 定期把到期链接标记为失效并写入 link.expired 事件（点击预算用尽在 StatsWorker 写入事务内标记）
+ * - 定期清理超过保留期的投递记录
+ */
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"short-link/internal/live"
+	"short-link/internal/metrics"
+	"short-link/internal/repo"
+	"short-link/models"
+	"short-link/utils"
+)
+
+const (
+	// webhookBatchSize 单次领取的投递数
+	webhookBatchSize = 50
+	// webhookPollInterval 没有到期投递时的轮询间隔
+	webhookPollInterval = time.Second
+	// webhookRetryBase / webhookRetryMax 失败重试的指数退避基数与上限
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = 6 * time.Hour
+	// webhookResponseLimit 保存的响应体最大字节数
+	webhookResponseLimit = 4 << 10
+	// webhookSubscriberTTL 缓存「是否有 link.clicked 订阅」的时长（新订阅最多延迟该时长生效）
+	webhookSubscriberTTL = 30 * time.Second
+	// webhookExpiryInterval / webhookExpiryBatch 扫描到期链接的间隔与单次标记数
+	webhookExpiryInterval = 30 * time.Second
+	webhookExpiryBatch    = 500
+	// webhookPurgeInterval 清理投递记录的间隔
+	webhookPurgeInterval = time.Hour
+	// webhookUserAgent 投递请求的 User-Agent
+	webhookUserAgent = "short-link-webhook/1.0"
+)
+
+// WebhookWorker webhook 投递 Worker
+type WebhookWorker struct {
+	webhookRepo *repo.WebhookRepo
+	linkRepo    *repo.LinkRepo
+	client      *http.Client
+	maxAttempts int
+	concurrency int
+	retention   time.Duration
+	countBots   bool         // 爬虫点击是否投递 link.clicked（与点击数口径一致）
+	clickSubs   atomic.Bool  // 是否存在 link.clicked 订阅（缓存）
+	clickSubsAt atomic.Int64 // clickSubs 的检查时间（UnixNano）
+	wg          sync.WaitGroup
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+// NewWebhookWorker 创建 webhook 投递 Worker（timeout 为单次请求超时，concurrency 为并发投递数）
+func NewWebhookWorker(webhookRepo *repo.WebhookRepo, linkRepo *repo.LinkRepo, timeout time.Duration, maxAttempts int, concurrency int, retention time.Duration, countBots bool) *WebhookWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         utils.DialExternal(&net.Dialer{Timeout: 5 * time.Second}),
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConnsPerHost: concurrency,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// 重定向视为失败（接收地址应直接返回 2xx）
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &WebhookWorker{
+		webhookRepo: webhookRepo,
+		linkRepo:    linkRepo,
+		client:      client,
+		maxAttempts: maxAttempts,
+		concurrency: concurrency,
+		retention:   retention,
+		countBots:   countBots,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start 启动 Worker（后台 goroutine）
+func (w *WebhookWorker) Start() {
+	w.wg.Add(1)
+	go w.run()
+	utils.LogInfo("Webhook 投递 Worker 已启动（并发=%d，超时=%v，最大尝试次数=%d）", w.concurrency, w.client.Timeout, w.maxAttempts)
+}
+
+// run Worker 主循环：领取满一批时立即继续，否则等待下一个轮询周期
+func (w *WebhookWorker) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	var lastExpiry time.Time
+	lastPurge := time.Now()
+
+	for {
+		if time.Since(lastExpiry) >= webhookExpiryInterval {
+			lastExpiry = time.Now()
+			w.markExpired()
+		}
+
+		if time.Since(lastPurge) >= webhookPurgeInterval {
+			lastPurge = time.Now()
+			if n, err := w.webhookRepo.PurgeDeliveries(w.ctx, w.retention); err != nil {
+				utils.LogWarn("清理 webhook 投递记录失败: %v", err)
+			} else if n > 0 {
+				utils.LogInfo("已清理 %d 条 webhook 投递记录", n)
+			}
+		}
+
+		if w.deliverOnce() >= webhookBatchSize && w.ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// markExpired 标记到期链接失效（写入 link.expired 事件）
+func (w *WebhookWorker) markExpired() {
+	for w.ctx.Err() == nil {
+		n, err := w.linkRepo.MarkExpiredLinks(w.ctx, webhookExpiryBatch)
+		if err != nil {
+			if w.ctx.Err() == nil {
+				utils.LogError("标记到期链接失败: %v", err)
+			}
+			return
+		}
+		if n > 0 {
+			utils.LogInfo("已标记 %d 个到期链接失效", n)
+		}
+		if n < webhookExpiryBatch {
+			return
+		}
+	}
+}
+
+// deliverOnce 领取并投递一批，返回领取的投递数
+func (w *WebhookWorker) deliverOnce() int {
+	// 租约覆盖请求超时，避免投递中的记录被其他副本重复领取
+	deliveries, err := w.webhookRepo.ClaimDeliveries(w.ctx, webhookBatchSize, w.client.Timeout+time.Minute)
+	if err != nil {
+		if w.ctx.Err() == nil {
+			utils.LogError("领取 webhook 投递失败: %v", err)
+		}
+		return 0
+	}
+
+	sem := make(chan struct{}, w.concurrency)
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		d := &deliveries[i]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			w.deliver(d)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries)
+}
+
+// deliver 投递一次并记录结果
+func (w *WebhookWorker) deliver(d *repo.PendingDelivery) {
+	attempt := sendWebhook(w.ctx, w.client, d, time.Now())
+	if w.ctx.Err() != nil && attempt.ResponseStatus == 0 {
+		// 停止中被取消：不记录结果，租约到期后由本实例或其他副本重新投递
+		return
+	}
+
+	status, result := models.WebhookDeliverySucceeded, "succeeded"
+	var retryAfter time.Duration
+	switch {
+	case webhookSucceeded(attempt):
+	case d.Attempts >= w.maxAttempts:
+		status, result = models.WebhookDeliveryFailed, "failed"
+	default:
+		status, result = models.WebhookDeliveryPending, "retry"
+		retryAfter = expBackoff(d.Attempts, webhookRetryBase, webhookRetryMax)
+	}
+
+	// 记录结果使用独立 context：停止时已完成的投递仍需落库，避免重复投递
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.webhookRepo.RecordAttempt(ctx, d.ID, status, retryAfter, attempt); err != nil {
+		utils.LogError("记录 webhook 投递结果失败: delivery_id=%d, error=%v", d.ID, err)
+	}
+	metrics.WebhookDeliveriesTotal.WithLabelValues(result).Inc()
+	metrics.WebhookDeliveryDuration.Observe(attempt.Duration.Seconds())
+	if status == models.WebhookDeliveryFailed {
+		utils.LogWarn("webhook 投递最终失败: delivery_id=%d, endpoint_id=%d, event=%s, attempts=%d, error=%s",
+			d.ID, d.EndpointID, d.EventType, d.Attempts, attempt.Error)
+	}
+}
+
+// webhookSucceeded 接收方返回 2xx 视为送达
+func webhookSucceeded(a *repo.WebhookAttempt) bool {
+	return a.ResponseStatus >= 200 && a.ResponseStatus < 300
+}
+
+// sendWebhook 签名并 POST 投递记录的 payload，返回本次尝试的结果
+func sendWebhook(ctx context.Context, client *http.Client, d *repo.PendingDelivery, now time.Time) *repo.WebhookAttempt {
+	start := time.Now()
+	a := &repo.WebhookAttempt{}
+	defer func() { a.Duration = time.Since(start) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	ts := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set("X-Webhook-Id", d.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("X-Webhook-Signature", utils.SignWebhook(d.Secret, ts, d.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // 读完剩余响应以复用连接
+	a.ResponseStatus = resp.StatusCode
+	// 响应体存入 TEXT 列：去掉非法 UTF-8 与 NUL
+	a.ResponseBody = strings.ReplaceAll(strings.ToValidUTF8(string(body), ""), "\x00", "")
+	if !webhookSucceeded(a) {
+		a.Error = fmt.Sprintf("接收方返回 HTTP %d", resp.StatusCode)
+	}
+	return a
+}
+
+// HandleClicks 一批点击写入成功后，按接收地址合并为 link.clicked 投递（实现 ClickSink）
+// logs 已应用访问日志隐私策略；事件只包含来源域名、UA 解析结果与归属地
+func (w *WebhookWorker) HandleClicks(ctx context.Context, logs []*models.AccessLog) {
+	if !w.hasClickSubscribers(ctx) {
+		return
+	}
+
+	linkIDs := make([]int64, 0, len(logs))
+	clicks := make([]string, 0, len(logs))
+	for _, log := range logs {
+		if log.IsBot && !w.countBots {
+			continue
+		}
+		b, err := json.Marshal(&models.ClickEvent{
+			LinkID:         log.LinkID,
+			Timestamp:      log.CreatedAt,
+			RefererHost:    live.RefererHost(log.Referer),
+			VariantID:      log.VariantID,
+			Browser:        log.Browser,
+			BrowserVersion: log.BrowserVersion,
+			OS:             log.OS,
+			Device:         log.Device,
+			IsBot:          log.IsBot,
+			CountryCode:    log.CountryCode,
+			Region:         log.Region,
+			City:           log.City,
+		})
+		if err != nil {
+			continue
+		}
+		linkIDs = append(linkIDs, log.LinkID)
+		clicks = append(clicks, string(b))
+	}
+	if len(clicks) == 0 {
+		return
+	}
+
+	batch := make([]byte, 8)
+	if _, err := rand.Read(batch); err != nil {
+		utils.LogWarn("生成点击 webhook 批次 ID 失败: %v", err)
+		return
+	}
+	if _, err := w.webhookRepo.EnqueueClicks(ctx, hex.EncodeToString(batch), linkIDs, clicks); err != nil {
+		// 点击已写入 Postgres，webhook 为 best-effort：不影响批次确认
+		utils.LogWarn("写入点击 webhook 失败，本批点击不再投递: count=%d, error=%v", len(clicks), err)
+	}
+}
+
+// hasClickSubscribers 是否存在 link.clicked 订阅（缓存 webhookSubscriberTTL；查询失败时按存在处理）
+func (w *WebhookWorker) hasClickSubscribers(ctx context.Context) bool {
+	if time.Since(time.Unix(0, w.clickSubsAt.Load())) < webhookSubscriberTTL {
+		return w.clickSubs.Load()
+	}
+	ok, err := w.webhookRepo.HasSubscribers(ctx, models.WebhookLinkClicked)
+	if err != nil {
+		utils.LogWarn("查询 link.clicked 订阅失败: %v", err)
+		ok = true
+	}
+	w.clickSubs.Store(ok)
+	w.clickSubsAt.Store(time.Now().UnixNano())
+	return ok
+}
+
+// Stop 停止 Worker（等待进行中的投递结束）
+func (w *WebhookWorker) Stop() {
+	w.cancel()
+	w.wg.Wait()
+	utils.LogInfo("Webhook 投递 Worker 已停止")
+}
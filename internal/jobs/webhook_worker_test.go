@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/utils"
+)
+
+func TestWebhookBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, webhookRetryMax},
+	}
+	for _, c := range cases {
+		if got := expBackoff(c.attempts, webhookRetryBase, webhookRetryMax); got != c.want {
+			t.Errorf("attempts=%d: got %v, want %v", c.attempts, got, c.want)
+		}
+	}
+}
+
+func TestSendWebhookSigned(t *testing.T) {
+	t.Setenv("ALLOW_PRIVATE_URLS", "true")
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w := NewWebhookWorker(nil, nil, 2*time.Second, 3, 1, time.Hour, false)
+	d := &repo.PendingDelivery{
+		ID:        42,
+		EventType: "link.created",
+		EventID:   "evt_7",
+		Payload:   []byte(`{"id":"evt_7","type":"link.created","data":{"id":1}}`),
+		URL:       srv.URL,
+		Secret:    "whsec_test",
+	}
+	now := time.Now()
+	a := sendWebhook(w.ctx, w.client, d, now)
+	if !webhookSucceeded(a) || a.Error != "" {
+		t.Fatalf("unexpected attempt result: %+v", a)
+	}
+
+	r := <-got
+	if string(r.body) != string(d.Payload) {
+		t.Errorf("body = %s, want %s", r.body, d.Payload)
+	}
+	if r.header.Get("X-Webhook-Id") != "evt_7" || r.header.Get("X-Webhook-Event") != "link.created" || r.header.Get("X-Webhook-Delivery") != "42" {
+		t.Errorf("unexpected headers: %v", r.header)
+	}
+	if !utils.VerifyWebhookSignature("whsec_test", r.header.Get("X-Webhook-Timestamp"), r.body, r.header.Get("X-Webhook-Signature"), 5*time.Minute, now) {
+		t.Error("signature verification failed")
+	}
+}
+
+func TestSendWebhookFailure(t *testing.T) {
+	t.Setenv("ALLOW_PRIVATE_URLS", "true")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, strings.Repeat("x", webhookResponseLimit*2))
+	}))
+	defer srv.Close()
+
+	w := NewWebhookWorker(nil, nil, 2*time.Second, 3, 1, time.Hour, false)
+	d := &repo.PendingDelivery{ID: 1, EventType: "link.clicked", EventID: "clk_1", Payload: []byte(`{}`), URL: srv.URL, Secret: "s"}
+
+	a := sendWebhook(w.ctx, w.client, d, time.Now())
+	if webhookSucceeded(a) || a.ResponseStatus != http.StatusInternalServerError || a.Error == "" {
+		t.Fatalf("unexpected attempt result: status=%d error=%q", a.ResponseStatus, a.Error)
+	}
+	if len(a.ResponseBody) != webhookResponseLimit {
+		t.Errorf("response body not truncated: %d bytes", len(a.ResponseBody))
+	}
+
+	// 不跟随重定向
+	d.URL = srv.URL + "/redirect"
+	if a := sendWebhook(w.ctx, w.client, d, time.Now()); a.ResponseStatus != http.StatusFound || webhookSucceeded(a) {
+		t.Errorf("redirect should be reported as failure, got status %d", a.ResponseStatus)
+	}
+}
+
+func TestSendWebhookRejectsPrivateAddress(t *testing.T) {
+	t.Setenv("ALLOW_PRIVATE_URLS", "false")
+
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer srv.Close()
+
+	w := NewWebhookWorker(nil, nil, 2*time.Second, 3, 1, time.Hour, false)
+	d := &repo.PendingDelivery{ID: 1, EventType: "link.created", EventID: "evt_1", Payload: []byte(`{}`), URL: srv.URL, Secret: "s"}
+	a := sendWebhook(w.ctx, w.client, d, time.Now())
+	if a.ResponseStatus != 0 || a.Error == "" || hit {
+		t.Fatalf("loopback delivery should be refused: %+v", a)
+	}
+}
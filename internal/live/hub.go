@@ -0,0 +1,292 @@
+/**
+ * 实时点击流 Hub（SSE 订阅的扇出）
+ * - StatsWorker.Submit 调用 Publish：非阻塞投递到缓冲队列，队列满时丢弃，不影响跳转路径
+ * - 后台 goroutine 补充 UA 解析与 GeoIP 归属地后分发；启用 Redis 时经 pub/sub（stats:live）广播到所有副本，否则只在进程内分发
+ * - 启用 Redis 时有订阅者的副本定期刷新 stats:live:active，所有副本都没有订阅者时不广播
+ * - 订阅者按链接所有者（或单个链接）过滤；订阅者缓冲区满时丢弃该订阅者的事件（慢订阅者不阻塞其他订阅者）
+ */
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"short-link/internal/geoip"
+	"short-link/internal/metrics"
+	"short-link/models"
+	"short-link/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisChannel 跨副本广播的 Redis pub/sub 频道
+	redisChannel = "stats:live"
+	// redisActiveKey 存在时表示至少一个副本有订阅者
+	redisActiveKey = "stats:live:active"
+	// activeInterval 刷新/检查 redisActiveKey 的间隔（过期时间为其 3 倍）
+	activeInterval = 5 * time.Second
+	// publishBuffer 待处理事件缓冲
+	publishBuffer = 4096
+	// subscriberBuffer 单个订阅者的事件缓冲
+	subscriberBuffer = 64
+)
+
+// Click 跳转路径提交的原始点击（IP 仅用于 GeoIP 查询，不随事件推送）
+type Click struct {
+	LinkID    int64
+	UserID    int64
+	Code      string
+	IP        string
+	UserAgent string
+	Referer   string
+	VariantID int64
+	OptOut    bool // 访客发送了 DNT: 1 或 Sec-GPC: 1
+	CreatedAt time.Time
+}
+
+// Filter 订阅范围：UserID 为 0 表示全部链接（管理员）；LinkID 非 0 时只订阅该链接
+type Filter struct {
+	UserID int64
+	LinkID int64
+}
+
+func (f Filter) match(ev *models.ClickEvent) bool {
+	if f.LinkID != 0 && ev.LinkID != f.LinkID {
+		return false
+	}
+	return f.UserID == 0 || ev.UserID == f.UserID
+}
+
+// Subscriber 订阅者
+type Subscriber struct {
+	C      <-chan *models.ClickEvent
+	ch     chan *models.ClickEvent
+	filter Filter
+}
+
+// Hub 实时点击流扇出
+type Hub struct {
+	client   *redis.Client   // 可为 nil（只在进程内分发）
+	geo      *geoip.Enricher // 可为 nil（未配置 GeoIP）
+	honorDNT bool
+	in       chan *Click
+	mu       sync.RWMutex
+	subs     map[*Subscriber]struct{}
+	active   atomic.Bool // Redis 模式下是否有副本存在订阅者
+	wg       sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewHub 创建 Hub（client 为 nil 时只在进程内分发）
+func NewHub(client *redis.Client, geo *geoip.Enricher, honorDNT bool) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Hub{
+		client:   client,
+		geo:      geo,
+		honorDNT: honorDNT,
+		in:       make(chan *Click, publishBuffer),
+		subs:     make(map[*Subscriber]struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start 启动后台分发
+func (h *Hub) Start() {
+	h.wg.Add(1)
+	go h.run()
+	if h.client != nil {
+		h.wg.Add(1)
+		go h.receive()
+	}
+	utils.LogInfo("实时点击流已启动: redis=%t", h.client != nil)
+}
+
+// Stop 停止后台分发
+func (h *Hub) Stop() {
+	h.cancel()
+	h.wg.Wait()
+}
+
+// Done Hub 停止时关闭（SSE 连接据此结束）
+func (h *Hub) Done() <-chan struct{} {
+	return h.ctx.Done()
+}
+
+// Publish 提交一次点击（非阻塞）
+func (h *Hub) Publish(c *Click) {
+	// 没有订阅者时不做任何处理
+	if h.client != nil {
+		if !h.active.Load() {
+			return
+		}
+	} else if h.subscriberCount() == 0 {
+		return
+	}
+	select {
+	case h.in <- c:
+	default:
+		metrics.LiveEventsDroppedTotal.WithLabelValues("hub_full").Inc()
+	}
+}
+
+// Subscribe 新增订阅者，调用方结束时必须 Unsubscribe
+func (h *Hub) Subscribe(f Filter) *Subscriber {
+	ch := make(chan *models.ClickEvent, subscriberBuffer)
+	s := &Subscriber{C: ch, ch: ch, filter: f}
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	metrics.LiveSubscribers.Inc()
+	// 本副本立即开始广播，其他副本在下一次检查时生效
+	h.active.Store(true)
+	return s
+}
+
+// Unsubscribe 移除订阅者
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		metrics.LiveSubscribers.Dec()
+	}
+	h.mu.Unlock()
+}
+
+func (h *Hub) subscriberCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// run 补充 UA/GeoIP 后广播（Redis）或直接分发（进程内）
+func (h *Hub) run() {
+	defer h.wg.Done()
+	ticker := time.NewTicker(activeInterval)
+	defer ticker.Stop()
+	h.refreshActive()
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-ticker.C:
+			h.refreshActive()
+		case c := <-h.in:
+			ev := h.enrich(c)
+			if h.client == nil {
+				h.dispatch(ev)
+				continue
+			}
+			b, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(h.ctx, 200*time.Millisecond)
+			if err := h.client.Publish(ctx, redisChannel, b).Err(); err != nil {
+				// Redis 不可用时退化为只推送给本副本的订阅者
+				h.dispatch(ev)
+			}
+			cancel()
+		}
+	}
+}
+
+// refreshActive 有订阅者时续期 redisActiveKey，并据此更新是否需要广播（Redis 出错时按有订阅者处理）
+func (h *Hub) refreshActive() {
+	if h.client == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(h.ctx, time.Second)
+	defer cancel()
+	if h.subscriberCount() > 0 {
+		if err := h.client.Set(ctx, redisActiveKey, 1, 3*activeInterval).Err(); err != nil {
+			h.active.Store(true)
+			return
+		}
+	}
+	n, err := h.client.Exists(ctx, redisActiveKey).Result()
+	h.active.Store(err != nil || n > 0)
+}
+
+// receive 订阅 Redis 频道，把所有副本的事件分发给本副本的订阅者（断线由 go-redis 自动重连）
+func (h *Hub) receive() {
+	defer h.wg.Done()
+	pubsub := h.client.Subscribe(h.ctx, redisChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var ev models.ClickEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+				continue
+			}
+			h.dispatch(&ev)
+		}
+	}
+}
+
+// dispatch 非阻塞地推送给匹配的订阅者
+func (h *Hub) dispatch(ev *models.ClickEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subs {
+		if !s.filter.match(ev) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			metrics.LiveEventsDroppedTotal.WithLabelValues("slow_subscriber").Inc()
+		}
+	}
+}
+
+// enrich 构造推送事件：来源只保留域名，补充 UA 解析与归属地
+func (h *Hub) enrich(c *Click) *models.ClickEvent {
+	ua := utils.ParseUserAgent(c.UserAgent)
+	ev := &models.ClickEvent{
+		LinkID:         c.LinkID,
+		UserID:         c.UserID,
+		Code:           c.Code,
+		Timestamp:      c.CreatedAt,
+		RefererHost:    RefererHost(c.Referer),
+		VariantID:      c.VariantID,
+		Browser:        ua.Browser,
+		BrowserVersion: ua.BrowserVersion,
+		OS:             ua.OS,
+		Device:         ua.Device,
+		IsBot:          ua.IsBot,
+	}
+	loc := h.geo.Lookup(c.IP)
+	ev.CountryCode = loc.CountryCode
+	if !(c.OptOut && h.honorDNT) {
+		ev.Region = loc.Region
+		ev.City = loc.City
+	}
+	return ev
+}
+
+// RefererHost 来源地址的域名（无法解析时为空）
+func RefererHost(referer string) string {
+	if referer == "" {
+		return ""
+	}
+	u, err := url.Parse(referer)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
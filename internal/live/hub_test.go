@@ -0,0 +1,80 @@
+package live
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"short-link/models"
+	"short-link/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogger()
+	os.Exit(m.Run())
+}
+
+func TestFilterMatch(t *testing.T) {
+	ev := &models.ClickEvent{LinkID: 7, UserID: 3}
+	cases := []struct {
+		f    Filter
+		want bool
+	}{
+		{Filter{}, true},
+		{Filter{UserID: 3}, true},
+		{Filter{UserID: 4}, false},
+		{Filter{UserID: 3, LinkID: 7}, true},
+		{Filter{UserID: 3, LinkID: 8}, false},
+		{Filter{LinkID: 7}, true},
+	}
+	for _, c := range cases {
+		if got := c.f.match(ev); got != c.want {
+			t.Errorf("%+v: got %v, want %v", c.f, got, c.want)
+		}
+	}
+}
+
+func TestRefererHost(t *testing.T) {
+	cases := map[string]string{
+		"https://www.example.com/path?q=1": "www.example.com",
+		"http://example.com:8080/":         "example.com",
+		"":                                 "",
+		"not a url":                        "",
+	}
+	for in, want := range cases {
+		if got := RefererHost(in); got != want {
+			t.Errorf("RefererHost(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHubInProcessFanOut(t *testing.T) {
+	h := NewHub(nil, nil, true)
+	h.Start()
+	defer h.Stop()
+
+	owner := h.Subscribe(Filter{UserID: 1})
+	defer h.Unsubscribe(owner)
+	other := h.Subscribe(Filter{UserID: 2})
+	defer h.Unsubscribe(other)
+	slow := h.Subscribe(Filter{}) // 从不读取
+	defer h.Unsubscribe(slow)
+
+	// 超过订阅者缓冲的事件数：慢订阅者丢弃事件，不阻塞其他订阅者
+	n := subscriberBuffer * 2
+	for i := 0; i < n; i++ {
+		h.Publish(&Click{LinkID: 9, UserID: 1, Code: "abc", Referer: "https://t.co/x", CreatedAt: time.Now()})
+		ev := <-owner.C
+		if ev.Code != "abc" || ev.RefererHost != "t.co" {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	}
+	select {
+	case ev := <-other.C:
+		t.Fatalf("subscriber of another user received %+v", ev)
+	default:
+	}
+	if len(slow.C) != subscriberBuffer {
+		t.Errorf("slow subscriber buffered %d events, want %d", len(slow.C), subscriberBuffer)
+	}
+}
@@ -87,6 +87,148 @@ var (
 		[]string{"status"}, // "success" or "failure"
 	)
 
+	// 搜索请求数（按实际使用的后端：meilisearch / postgres 降级）
+	SearchRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "search_requests_total",
+			Help: "搜索请求总数",
+		},
+		[]string{"backend"},
+	)
+
+	// Meilisearch 对账修复的文档数（按原因：missing 缺失 / stale 内容漂移 / orphan 孤立 / full 全量重建 / clicks 仅刷新点击数）
+	MeilisearchReconcileRepairedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "meilisearch_reconcile_repaired_total",
+			Help: "Meilisearch 对账修复的文档总数",
+		},
+		[]string{"reason"},
+	)
+
+	// Meilisearch 对账执行次数
+	MeilisearchReconcileRunsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "meilisearch_reconcile_runs_total",
+			Help: "Meilisearch 对账执行总数",
+		},
+		[]string{"status"}, // "success" or "failure"
+	)
+
+	// Outbox 事件投递结果（delivered 已投递 / retry 失败待重试 / dead 转入死信）
+	OutboxEventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_events_total",
+			Help: "Outbox 事件投递总数",
+		},
+		[]string{"status"},
+	)
+
+	// 丢弃的点击事件数（queue_full 内存队列已满 / decode 持久化队列中的事件无法解码）
+	StatsClicksDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stats_clicks_dropped_total",
+			Help: "丢弃的点击事件总数",
+		},
+		[]string{"reason"},
+	)
+
+	// 持久化点击队列追加失败、降级到内存队列的次数
+	StatsIngestFallbackTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "stats_ingest_fallback_total",
+			Help: "点击事件追加持久化队列失败并降级到内存队列的总数",
+		},
+	)
+
+	// 持久化点击队列中尚未写入 Postgres 的事件数
+	StatsIngestLag = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "stats_ingest_lag",
+			Help: "持久化点击队列积压的事件数",
+		},
+	)
+
+	// 统计 Worker 单批写入耗时（按结果：success / failure）
+	StatsFlushDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "stats_flush_duration_seconds",
+			Help:    "统计 Worker 单批写入延迟（秒）",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		},
+		[]string{"status"},
+	)
+
+	// 统计 Worker 单批写入的点击数
+	StatsFlushBatchSize = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "stats_flush_batch_size",
+			Help:    "统计 Worker 单批写入的点击数",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+		},
+	)
+
+	// access_logs 分区维护操作数（created 新建 / archived 归档 / dropped 删除 / legacy_moved 旧表搬迁行数）
+	AccessLogPartitionOpsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "access_log_partition_ops_total",
+			Help: "access_logs 分区维护操作总数",
+		},
+		[]string{"op"},
+	)
+
+	// 实时点击流（SSE）当前订阅数
+	LiveSubscribers = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "live_subscribers",
+			Help: "实时点击流当前订阅数",
+		},
+	)
+
+	// 实时点击流丢弃的事件数（reason: hub_full / slow_subscriber）
+	LiveEventsDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "live_events_dropped_total",
+			Help: "实时点击流丢弃的事件总数",
+		},
+		[]string{"reason"},
+	)
+
+	// Webhook 投递结果（succeeded 已送达 / retry 失败待重试 / failed 超过最大尝试次数）
+	WebhookDeliveriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_deliveries_total",
+			Help: "Webhook 投递尝试总数",
+		},
+		[]string{"result"},
+	)
+
+	// Webhook 单次投递耗时（含失败）
+	WebhookDeliveryDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "webhook_delivery_duration_seconds",
+			Help:    "Webhook 单次投递延迟（秒）",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+	)
+
+	// 点击异常告警数（spike / drop / bot_traffic）
+	ClickAlertsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "click_alerts_total",
+			Help: "点击异常告警总数",
+		},
+		[]string{"kind"},
+	)
+
+	// 告警邮件发送结果（sent / failed / skipped 无邮箱）
+	AlertEmailsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alert_emails_total",
+			Help: "告警邮件发送总数",
+		},
+		[]string{"result"},
+	)
+
 	// 限流拒绝的请求数
 	RateLimitRejectedTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
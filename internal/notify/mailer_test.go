@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// smtpMessage 测试 SMTP 服务器收到的邮件
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// startTestSMTP 启动只接收一封邮件的最小 SMTP 服务器（不支持 STARTTLS / AUTH）
+func startTestSMTP(t *testing.T) (string, int, <-chan smtpMessage) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan smtpMessage, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+
+		var msg smtpMessage
+		reply("220 test ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			switch upper := strings.ToUpper(cmd); {
+			case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+				reply("250 test")
+			case strings.HasPrefix(upper, "MAIL FROM:"):
+				msg.from = strings.Trim(cmd[len("MAIL FROM:"):], "<> ")
+				reply("250 OK")
+			case strings.HasPrefix(upper, "RCPT TO:"):
+				msg.to = append(msg.to, strings.Trim(cmd[len("RCPT TO:"):], "<> "))
+				reply("250 OK")
+			case upper == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				msg.data = data.String()
+				received <- msg
+				reply("250 OK")
+			case upper == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 unsupported")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+func TestMailerSend(t *testing.T) {
+	host, port, received := startTestSMTP(t)
+	m := NewMailer(host, port, "", "", "alerts@example.com")
+
+	body := "链接 abc123 最近一小时点击 500 次"
+	if err := m.Send([]string{"owner@example.com"}, "点击告警", body); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	msg := <-received
+	if msg.from != "alerts@example.com" || len(msg.to) != 1 || msg.to[0] != "owner@example.com" {
+		t.Fatalf("unexpected envelope: from=%q to=%v", msg.from, msg.to)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(msg.data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "点击告警" {
+		t.Errorf("subject = %q (%v)", subject, err)
+	}
+	raw, _ := io.ReadAll(parsed.Body)
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(raw), "\r\n", ""))
+	if err != nil || string(decoded) != body {
+		t.Errorf("body = %q (%v)", decoded, err)
+	}
+}
+
+func TestNewMailerDisabled(t *testing.T) {
+	if m := NewMailer("", 587, "", "", "alerts@example.com"); m != nil {
+		t.Error("empty host should disable the mailer")
+	}
+}
+
+func TestMailerSendUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	m := NewMailer("127.0.0.1", port, "", "", "alerts@example.com")
+	if err := m.Send([]string{"owner@example.com"}, "subject", "body"); err == nil {
+		t.Error("expected error for unreachable server")
+	}
+}
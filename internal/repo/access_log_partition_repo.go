@@ -0,0 +1,233 @@
+/**
+ * access_logs 分区 Repo
+ * - 按月 RANGE 分区：access_logs_pYYYYMM 覆盖 [当月 1 日, 次月 1 日)，access_logs_default 兜底
+ * - 创建分区时先把 default 分区中落在该月的记录搬入，再 ATTACH（否则 ATTACH 校验失败）
+ * - 过期分区 DETACH 后 DROP；可先导出为 NDJSON（由调用方压缩写入归档文件）
+ * - access_logs_legacy 为 0016 迁移前的未分区旧表，分批搬迁到分区表后删除
+ */
+package repo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"short-link/internal/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// accessLogPartitionPrefix 月分区表名前缀（后接 YYYYMM）
+	accessLogPartitionPrefix = "access_logs_p"
+	// accessLogDefaultPartition 兜底分区
+	accessLogDefaultPartition = "access_logs_default"
+	// accessLogLegacyTable 迁移前的未分区旧表
+	accessLogLegacyTable = "access_logs_legacy"
+	// accessLogPartitionLock 创建分区的事务级 advisory lock 名称（多副本同时维护时串行化）
+	accessLogPartitionLock = "access_logs_partition"
+)
+
+// AccessLogPartitionRepo access_logs 分区维护仓储
+type AccessLogPartitionRepo struct {
+	pool *db.Pool
+}
+
+// NewAccessLogPartitionRepo 创建 AccessLogPartitionRepo
+func NewAccessLogPartitionRepo(pool *db.Pool) *AccessLogPartitionRepo {
+	return &AccessLogPartitionRepo{pool: pool}
+}
+
+// AccessLogPartitionName 月份对应的分区表名
+func AccessLogPartitionName(month time.Time) string {
+	return accessLogPartitionPrefix + month.Format("200601")
+}
+
+// monthStart 当月 1 日 0 点（UTC 日期口径，与 TIMESTAMP 列一致）
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// EnsureMonth 确保 month 所在月份的分区存在，返回是否新建
+// 检查前先取事务级 advisory lock：多个副本同时启动时，后到者等待先到者提交后看到分区已存在
+func (r *AccessLogPartitionRepo) EnsureMonth(ctx context.Context, month time.Time) (bool, error) {
+	from := monthStart(month)
+	to := from.AddDate(0, 1, 0)
+	name := AccessLogPartitionName(from)
+	ident := pgx.Identifier{name}.Sanitize()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin ensure partition tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, accessLogPartitionLock); err != nil {
+		return false, fmt.Errorf("lock partition maintenance failed: %w", err)
+	}
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return false, fmt.Errorf("check partition failed: %w", err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `CREATE TABLE `+ident+` (LIKE access_logs INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`); err != nil {
+		return false, fmt.Errorf("create partition %s failed: %w", name, err)
+	}
+	if _, err := tx.Exec(ctx, `
+		WITH moved AS (
+			DELETE FROM `+accessLogDefaultPartition+` WHERE created_at >= $1 AND created_at < $2 RETURNING *
+		)
+		INSERT INTO `+ident+` SELECT * FROM moved
+	`, from, to); err != nil {
+		return false, fmt.Errorf("move default partition rows into %s failed: %w", name, err)
+	}
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE access_logs ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`,
+		ident, from.Format("2006-01-02"), to.Format("2006-01-02"))); err != nil {
+		return false, fmt.Errorf("attach partition %s failed: %w", name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit ensure partition tx failed: %w", err)
+	}
+	return true, nil
+}
+
+// ListMonths 列出已挂载的月分区（升序，不含 default 分区）
+func (r *AccessLogPartitionRepo) ListMonths(ctx context.Context) ([]time.Time, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'access_logs'::regclass
+		ORDER BY c.relname
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list partitions failed: %w", err)
+	}
+	defer rows.Close()
+
+	var months []time.Time
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan partition failed: %w", err)
+		}
+		if !strings.HasPrefix(name, accessLogPartitionPrefix) {
+			continue
+		}
+		m, err := time.Parse("200601", strings.TrimPrefix(name, accessLogPartitionPrefix))
+		if err != nil {
+			continue // 非本任务创建的分区
+		}
+		months = append(months, m)
+	}
+	return months, rows.Err()
+}
+
+// ExportMonth 以 NDJSON（每行一条 row_to_json）写出月分区全部记录，返回行数
+func (r *AccessLogPartitionRepo) ExportMonth(ctx context.Context, month time.Time, w io.Writer) (int64, error) {
+	ident := pgx.Identifier{AccessLogPartitionName(month)}.Sanitize()
+	rows, err := r.pool.Query(ctx, `SELECT row_to_json(t)::text FROM `+ident+` t ORDER BY t.id`)
+	if err != nil {
+		return 0, fmt.Errorf("export partition failed: %w", err)
+	}
+	defer rows.Close()
+
+	var n int64
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return n, fmt.Errorf("scan partition row failed: %w", err)
+		}
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// DropMonth 卸载并删除月分区
+func (r *AccessLogPartitionRepo) DropMonth(ctx context.Context, month time.Time) error {
+	ident := pgx.Identifier{AccessLogPartitionName(month)}.Sanitize()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin drop partition tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if _, err := tx.Exec(ctx, `ALTER TABLE access_logs DETACH PARTITION `+ident); err != nil {
+		return fmt.Errorf("detach partition failed: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DROP TABLE `+ident); err != nil {
+		return fmt.Errorf("drop partition failed: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// LegacyRange 旧表是否存在，及其中记录的最早/最晚时间（旧表为空时为 nil）
+func (r *AccessLogPartitionRepo) LegacyRange(ctx context.Context) (bool, *time.Time, *time.Time, error) {
+	var exists bool
+	if err := r.pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, accessLogLegacyTable).Scan(&exists); err != nil {
+		return false, nil, nil, fmt.Errorf("check legacy access logs failed: %w", err)
+	}
+	if !exists {
+		return false, nil, nil, nil
+	}
+	var from, to *time.Time
+	if err := r.pool.QueryRow(ctx, `SELECT MIN(created_at), MAX(created_at) FROM `+accessLogLegacyTable).Scan(&from, &to); err != nil {
+		return true, nil, nil, fmt.Errorf("get legacy access logs range failed: %w", err)
+	}
+	return true, from, to, nil
+}
+
+// MoveLegacyBatch 从旧表按 ID 顺序搬迁最多 limit 条记录到分区表（保留原 ID），返回搬迁行数
+// created_at 为空的记录以 1970-01-01 写入 default 分区
+func (r *AccessLogPartitionRepo) MoveLegacyBatch(ctx context.Context, limit int) (int64, error) {
+	ct, err := r.pool.Exec(ctx, `
+		WITH moved AS (
+			DELETE FROM `+accessLogLegacyTable+`
+			WHERE id IN (SELECT id FROM `+accessLogLegacyTable+` ORDER BY id LIMIT $1)
+			RETURNING *
+		)
+		INSERT INTO access_logs (
+			id, link_id, ip, user_agent, referer, created_at, browser, browser_version, os, device, is_bot,
+			country_code, region, city, asn, as_org, variant_id
+		)
+		SELECT id, link_id, ip, user_agent, referer, COALESCE(created_at, TIMESTAMP '1970-01-01'),
+			browser, browser_version, os, device, is_bot, country_code, region, city, asn, as_org, variant_id
+		FROM moved
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("move legacy access logs failed: %w", err)
+	}
+	return ct.RowsAffected(), nil
+}
+
+// DropLegacy 删除已搬空的旧表（仍有记录时不删除）
+func (r *AccessLogPartitionRepo) DropLegacy(ctx context.Context) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin drop legacy tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var remaining bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+accessLogLegacyTable+`)`).Scan(&remaining); err != nil {
+		return fmt.Errorf("check legacy access logs failed: %w", err)
+	}
+	if remaining {
+		return fmt.Errorf("access_logs_legacy 仍有记录，未删除")
+	}
+	if _, err := tx.Exec(ctx, `DROP TABLE `+accessLogLegacyTable); err != nil {
+		return fmt.Errorf("drop legacy access logs failed: %w", err)
+	}
+	return tx.Commit(ctx)
+}
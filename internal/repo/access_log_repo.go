@@ -1,6 +1,10 @@
 /**
  * AccessLog Repo（重写版）
  * - 负责 access_logs 表写入（pgxpool）
+ * - 批量写入（WriteClickBatch）在同一事务内 COPY 访问日志、累加 links.click_count 与 click_rollups_hourly/daily 预聚合表
+ * - 爬虫访问（is_bot）默认只记录日志，不计入预聚合
+ * - 独立访客（unique_clicks）：每批访客指纹总是在同一事务内合并进 Postgres 中的 HLL sketch（持久数据）；
+ *   Redis HyperLogLog 的估计值只用于及时更新 unique_clicks，不替代 sketch
  */
 package repo
 
@@ -9,6 +13,10 @@ import (
 	"fmt"
 	"short-link/internal/db"
 	"short-link/models"
+	"short-link/utils"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // AccessLogRepo 访问日志仓储
@@ -23,11 +31,331 @@ func NewAccessLogRepo(pool *db.Pool) *AccessLogRepo {
 
 // CreateAccessLog 写入访问日志
 func (r *AccessLogRepo) CreateAccessLog(ctx context.Context, log *models.AccessLog) error {
-	query := `INSERT INTO access_logs (link_id, ip, user_agent, referer, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	if err := r.pool.QueryRow(ctx, query, log.LinkID, log.IP, log.UserAgent, log.Referer, log.CreatedAt).Scan(&log.ID); err != nil {
+	query := `
+		INSERT INTO access_logs (
+			link_id, ip, user_agent, referer, created_at, browser, browser_version, os, device, is_bot,
+			country_code, region, city, asn, as_org, variant_id
+		)
+		VALUES (
+			$1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10,
+			NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, 0), NULLIF($15, ''),
+			NULLIF($16, 0)
+		)
+		RETURNING id
+	`
+	if err := r.pool.QueryRow(ctx, query,
+		log.LinkID, log.IP, log.UserAgent, log.Referer, log.CreatedAt,
+		log.Browser, log.BrowserVersion, log.OS, log.Device, log.IsBot,
+		log.CountryCode, log.Region, log.City, log.ASN, log.ASOrg, log.VariantID,
+	).Scan(&log.ID); err != nil {
 		return fmt.Errorf("create access log failed: %w", err)
 	}
 	return nil
 }
 
+// accessLogColumns CopyFrom 写入的 access_logs 列
+var accessLogColumns = []string{
+	"link_id", "ip", "user_agent", "referer", "created_at", "browser", "browser_version", "os", "device", "is_bot",
+	"country_code", "region", "city", "asn", "as_org", "variant_id",
+}
+
+// VisitorDay 链接某一天（按天独立访客的键）
+type VisitorDay struct {
+	LinkID int64
+	Day    time.Time
+}
+
+// VisitorDayOf created_at 所在日期（与 created_at::date 口径一致）
+func VisitorDayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// VisitorCounts Redis HyperLogLog 给出的独立访客估计（累计 / 按天）
+type VisitorCounts struct {
+	Links map[int64]int64
+	Days  map[VisitorDay]int64
+}
+
+// WriteClickBatch 在同一事务内写入一批点击：CopyFrom 写访问日志、unnest 批量累加 links.click_count、累加小时/天预聚合、更新独立访客数、标记点击预算用尽的链接失效
+// 返回点击数有变化的链接写入后的计数与预算（用于判定点击预算是否用尽）
+// countBots 为 false 时爬虫访问只记录日志，不计入点击数、预聚合与独立访客
+// visitors 为 Redis 给出的估计（可为 nil），与 Postgres sketch 的估计取较大值写入 unique_clicks
+func (r *AccessLogRepo) WriteClickBatch(ctx context.Context, logs []*models.AccessLog, countBots bool, visitors *VisitorCounts) ([]ClickBudgetState, error) {
+	if len(logs) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin click batch tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	// 按 ID 顺序锁住涉及的链接：防止入队后被删除导致外键错误，并发批次之间也不会死锁
+	linkIDs := make([]int64, 0, len(logs))
+	seen := make(map[int64]bool, len(logs))
+	for _, log := range logs {
+		if !seen[log.LinkID] {
+			seen[log.LinkID] = true
+			linkIDs = append(linkIDs, log.LinkID)
+		}
+	}
+	rows, err := tx.Query(ctx, `SELECT id FROM links WHERE id = ANY($1) ORDER BY id FOR NO KEY UPDATE`, linkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("lock links failed: %w", err)
+	}
+	live := make(map[int64]bool, len(linkIDs))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan link id failed: %w", err)
+		}
+		live[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("lock links failed: %w", err)
+	}
+
+	// 链接可能在入队后被删除：跳过这类记录
+	copyRows := make([][]interface{}, 0, len(logs))
+	clicks := make(map[int64]int32)
+	var rollupIDs []int64
+	var rollupTimes []time.Time
+	var visits []*models.AccessLog
+	for _, log := range logs {
+		if !live[log.LinkID] {
+			continue
+		}
+		copyRows = append(copyRows, []interface{}{
+			log.LinkID, nullString(log.IP), nullString(log.UserAgent), log.Referer, log.CreatedAt,
+			nullString(log.Browser), nullString(log.BrowserVersion), nullString(log.OS), nullString(log.Device), log.IsBot,
+			nullString(log.CountryCode), nullString(log.Region), nullString(log.City), nullInt64(log.ASN), nullString(log.ASOrg),
+			nullInt64(log.VariantID),
+		})
+		if log.IsBot && !countBots {
+			continue
+		}
+		clicks[log.LinkID]++
+		rollupIDs = append(rollupIDs, log.LinkID)
+		rollupTimes = append(rollupTimes, log.CreatedAt)
+		if log.VisitorHash != 0 {
+			visits = append(visits, log)
+		}
+	}
+	if len(copyRows) == 0 {
+		return nil, tx.Commit(ctx)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"access_logs"}, accessLogColumns, pgx.CopyFromRows(copyRows)); err != nil {
+		return nil, fmt.Errorf("copy access logs failed: %w", err)
+	}
+
+	var states []ClickBudgetState
+	if len(clicks) > 0 {
+		ids := make([]int64, 0, len(clicks))
+		counts := make([]int32, 0, len(clicks))
+		for id, n := range clicks {
+			ids = append(ids, id)
+			counts = append(counts, n)
+		}
+		rows, err := tx.Query(ctx, `
+			UPDATE links l SET click_count = l.click_count + c.n, updated_at = NOW()
+			FROM unnest($1::bigint[], $2::int[]) AS c(id, n)
+			WHERE l.id = c.id
+			RETURNING l.id, l.domain_id, l.code, l.click_count, l.max_clicks
+		`, ids, counts)
+		if err != nil {
+			return nil, fmt.Errorf("increment click_count failed: %w", err)
+		}
+		for rows.Next() {
+			var st ClickBudgetState
+			if err := rows.Scan(&st.LinkID, &st.DomainID, &st.Code, &st.ClickCount, &st.MaxClicks); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan click_count failed: %w", err)
+			}
+			states = append(states, st)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("increment click_count failed: %w", err)
+		}
+
+		if err := upsertRollups(ctx, tx, rollupIDs, rollupTimes); err != nil {
+			return nil, err
+		}
+		if len(visits) > 0 {
+			if err := updateUniqueVisitors(ctx, tx, visits, visitors); err != nil {
+				return nil, err
+			}
+		}
+
+		// 本批用尽点击预算的链接标记失效（link.expired 事件）
+		var exhausted []int64
+		for i := range states {
+			if states[i].Exhausted() {
+				exhausted = append(exhausted, states[i].LinkID)
+			}
+		}
+		if len(exhausted) > 0 {
+			if _, err := expireLinks(ctx, tx, `SELECT unnest($1::BIGINT[])`, exhausted); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit click batch tx failed: %w", err)
+	}
+	return states, nil
+}
+
+// upsertRollups 按 (link_id, 时间桶) 累加小时/天预聚合（domain_id/user_id 取自 links）
+func upsertRollups(ctx context.Context, tx pgx.Tx, linkIDs []int64, createdAts []time.Time) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO click_rollups_hourly (bucket, link_id, domain_id, user_id, click_count)
+		SELECT date_trunc('hour', t.created_at), t.link_id, COALESCE(l.domain_id, 0), COALESCE(l.user_id, 0), COUNT(*)
+		FROM unnest($1::bigint[], $2::timestamp[]) AS t(link_id, created_at)
+		JOIN links l ON l.id = t.link_id
+		GROUP BY 1, 2, 3, 4
+		ORDER BY 2, 1
+		ON CONFLICT (link_id, bucket) DO UPDATE SET click_count = click_rollups_hourly.click_count + EXCLUDED.click_count
+	`, linkIDs, createdAts); err != nil {
+		return fmt.Errorf("upsert hourly rollups failed: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO click_rollups_daily (day, link_id, domain_id, user_id, click_count)
+		SELECT t.created_at::date, t.link_id, COALESCE(l.domain_id, 0), COALESCE(l.user_id, 0), COUNT(*)
+		FROM unnest($1::bigint[], $2::timestamp[]) AS t(link_id, created_at)
+		JOIN links l ON l.id = t.link_id
+		GROUP BY 1, 2, 3, 4
+		ORDER BY 2, 1
+		ON CONFLICT (link_id, day) DO UPDATE SET click_count = click_rollups_daily.click_count + EXCLUDED.click_count
+	`, linkIDs, createdAts); err != nil {
+		return fmt.Errorf("upsert daily rollups failed: %w", err)
+	}
+	return nil
+}
+
+// updateUniqueVisitors 更新 links / click_rollups_daily 的独立访客数（取较大值，sketch 丢失或模式切换时不回退）
+// 本批指纹总是合并进 visitors_hll sketch 后写回；counts 非 nil 时 unique_clicks 取 sketch 与 Redis 估计的较大值
+func updateUniqueVisitors(ctx context.Context, tx pgx.Tx, visits []*models.AccessLog, counts *VisitorCounts) error {
+	linkSketches := make(map[int64]*utils.HLL)
+	daySketches := make(map[VisitorDay]*utils.HLL)
+	var linkIDs []int64
+	var days []VisitorDay
+	for _, v := range visits {
+		if _, ok := linkSketches[v.LinkID]; !ok {
+			linkSketches[v.LinkID] = utils.NewHLL()
+			linkIDs = append(linkIDs, v.LinkID)
+		}
+		key := VisitorDay{LinkID: v.LinkID, Day: VisitorDayOf(v.CreatedAt)}
+		if _, ok := daySketches[key]; !ok {
+			daySketches[key] = utils.NewHLL()
+			days = append(days, key)
+		}
+	}
+	dayLinkIDs := make([]int64, len(days))
+	dayDates := make([]time.Time, len(days))
+	for i, d := range days {
+		dayLinkIDs[i] = d.LinkID
+		dayDates[i] = d.Day
+	}
+
+	linkCounts := make([]int64, len(linkIDs))
+	linkData := make([][]byte, len(linkIDs))
+	dayCounts := make([]int64, len(days))
+	dayData := make([][]byte, len(days))
+	if err := mergeSketches(ctx, tx, `SELECT id, NULL::date, visitors_hll FROM links WHERE id = ANY($1) AND visitors_hll IS NOT NULL`,
+		[]interface{}{linkIDs}, func(id int64, _ time.Time) *utils.HLL { return linkSketches[id] }); err != nil {
+		return fmt.Errorf("load link visitor sketches failed: %w", err)
+	}
+	if err := mergeSketches(ctx, tx, `
+		SELECT d.link_id, d.day, d.visitors_hll
+		FROM click_rollups_daily d
+		JOIN unnest($1::bigint[], $2::date[]) AS k(link_id, day) ON d.link_id = k.link_id AND d.day = k.day
+		WHERE d.visitors_hll IS NOT NULL
+	`, []interface{}{dayLinkIDs, dayDates}, func(id int64, day time.Time) *utils.HLL {
+		return daySketches[VisitorDay{LinkID: id, Day: VisitorDayOf(day)}]
+	}); err != nil {
+		return fmt.Errorf("load daily visitor sketches failed: %w", err)
+	}
+
+	for _, v := range visits {
+		linkSketches[v.LinkID].Add(v.VisitorHash)
+		daySketches[VisitorDay{LinkID: v.LinkID, Day: VisitorDayOf(v.CreatedAt)}].Add(v.VisitorHash)
+	}
+	for i, id := range linkIDs {
+		linkCounts[i] = int64(linkSketches[id].Count())
+		linkData[i], _ = linkSketches[id].MarshalBinary()
+		if counts != nil && counts.Links[id] > linkCounts[i] {
+			linkCounts[i] = counts.Links[id]
+		}
+	}
+	for i, d := range days {
+		dayCounts[i] = int64(daySketches[d].Count())
+		dayData[i], _ = daySketches[d].MarshalBinary()
+		if counts != nil && counts.Days[d] > dayCounts[i] {
+			dayCounts[i] = counts.Days[d]
+		}
+	}
 
+	if _, err := tx.Exec(ctx, `
+		UPDATE links l SET unique_clicks = GREATEST(l.unique_clicks, c.n), visitors_hll = COALESCE(c.hll, l.visitors_hll)
+		FROM unnest($1::bigint[], $2::bigint[], $3::bytea[]) AS c(id, n, hll)
+		WHERE l.id = c.id
+	`, linkIDs, linkCounts, linkData); err != nil {
+		return fmt.Errorf("update link unique clicks failed: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE click_rollups_daily d SET unique_clicks = GREATEST(d.unique_clicks, c.n), visitors_hll = COALESCE(c.hll, d.visitors_hll)
+		FROM unnest($1::bigint[], $2::date[], $3::bigint[], $4::bytea[]) AS c(link_id, day, n, hll)
+		WHERE d.link_id = c.link_id AND d.day = c.day
+	`, dayLinkIDs, dayDates, dayCounts, dayData); err != nil {
+		return fmt.Errorf("update daily unique clicks failed: %w", err)
+	}
+	return nil
+}
+
+// mergeSketches 查询 (link_id, day, visitors_hll) 并合并到 target 返回的 sketch（无法解析的 sketch 视为空）
+func mergeSketches(ctx context.Context, tx pgx.Tx, query string, args []interface{}, target func(linkID int64, day time.Time) *utils.HLL) error {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var day *time.Time
+		var data []byte
+		if err := rows.Scan(&id, &day, &data); err != nil {
+			return err
+		}
+		var d time.Time
+		if day != nil {
+			d = *day
+		}
+		h := target(id, d)
+		if parsed, err := utils.ParseHLL(data); err == nil && h != nil {
+			h.Merge(parsed)
+		}
+	}
+	return rows.Err()
+}
+
+// nullString 空串写入 NULL（与单条写入的 NULLIF 口径一致）
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullInt64 0 写入 NULL
+func nullInt64(n int64) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
@@ -0,0 +1,294 @@
+/**
+ * Alert Repo
+ * - alert_thresholds：告警阈值（用户默认 + 链接覆盖）
+ * - click_alerts：告警记录；InsertAlerts 按 (link_id, kind, window_start) 去重，并在同一事务内为订阅 link.alert 的接收地址写入投递
+ * - WindowStats：窗口点击取自 click_rollups_hourly，爬虫占比取自 access_logs
+ */
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"short-link/internal/db"
+	"short-link/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AlertRepo 告警仓储
+type AlertRepo struct {
+	pool *db.Pool
+}
+
+// NewAlertRepo 创建 AlertRepo
+func NewAlertRepo(pool *db.Pool) *AlertRepo {
+	return &AlertRepo{pool: pool}
+}
+
+// LinkWindowStat 单个链接在评估窗口内的点击统计
+type LinkWindowStat struct {
+	LinkID         int64
+	UserID         int64
+	Code           string
+	CreatedAt      time.Time
+	Clicks         int64 // 窗口内点击（click_rollups_hourly，与统计口径一致）
+	BaselineClicks int64 // 基线区间内的点击总数
+	BotClicks      int64 // 窗口内爬虫访问（access_logs）
+	TotalLogs      int64 // 窗口内全部访问（access_logs）
+}
+
+// WindowStats 获取窗口 [windowStart, windowEnd) 与基线区间 [baselineStart, windowStart) 内有点击的链接统计
+// 窗口为整点小时，与 click_rollups_hourly.bucket 对齐
+func (r *AlertRepo) WindowStats(ctx context.Context, windowStart, windowEnd, baselineStart time.Time) ([]LinkWindowStat, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH win AS (
+			SELECT link_id, click_count FROM click_rollups_hourly WHERE bucket = $1
+		), base AS (
+			SELECT link_id, SUM(click_count) AS clicks FROM click_rollups_hourly
+			WHERE bucket >= $3 AND bucket < $1
+			GROUP BY link_id
+		), logs AS (
+			SELECT link_id, COUNT(*) FILTER (WHERE is_bot) AS bots, COUNT(*) AS total FROM access_logs
+			WHERE created_at >= $1 AND created_at < $2
+			GROUP BY link_id
+		), ids AS (
+			SELECT link_id FROM win UNION SELECT link_id FROM base UNION SELECT link_id FROM logs
+		)
+		SELECT l.id, l.user_id, l.code, l.created_at, COALESCE(win.click_count, 0), COALESCE(base.clicks, 0),
+			COALESCE(logs.bots, 0), COALESCE(logs.total, 0)
+		FROM ids
+		JOIN links l ON l.id = ids.link_id
+		LEFT JOIN win ON win.link_id = ids.link_id
+		LEFT JOIN base ON base.link_id = ids.link_id
+		LEFT JOIN logs ON logs.link_id = ids.link_id
+		ORDER BY l.id
+	`, windowStart, windowEnd, baselineStart)
+	if err != nil {
+		return nil, fmt.Errorf("query alert window stats failed: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []LinkWindowStat
+	for rows.Next() {
+		var s LinkWindowStat
+		if err := rows.Scan(&s.LinkID, &s.UserID, &s.Code, &s.CreatedAt, &s.Clicks, &s.BaselineClicks, &s.BotClicks, &s.TotalLogs); err != nil {
+			return nil, fmt.Errorf("scan alert window stat failed: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// alertThresholdColumns 查询列（与 scanAlertThreshold 顺序一致）
+const alertThresholdColumns = `id, user_id, link_id, enabled, spike_factor, spike_min_clicks, drop_min_baseline, bot_ratio,
+		notify_email, created_at, updated_at`
+
+func scanAlertThreshold(row pgx.Row, t *models.AlertThreshold) error {
+	return row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.LinkID,
+		&t.Enabled,
+		&t.SpikeFactor,
+		&t.SpikeMinClicks,
+		&t.DropMinBaseline,
+		&t.BotRatio,
+		&t.NotifyEmail,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+}
+
+// ListThresholds 获取一批用户的全部阈值设置（用户默认与链接覆盖）
+func (r *AlertRepo) ListThresholds(ctx context.Context, userIDs []int64) ([]models.AlertThreshold, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+alertThresholdColumns+` FROM alert_thresholds WHERE user_id = ANY($1) ORDER BY id`, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list alert thresholds failed: %w", err)
+	}
+	defer rows.Close()
+
+	var thresholds []models.AlertThreshold
+	for rows.Next() {
+		var t models.AlertThreshold
+		if err := scanAlertThreshold(rows, &t); err != nil {
+			return nil, fmt.Errorf("scan alert threshold failed: %w", err)
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, rows.Err()
+}
+
+// GetThreshold 获取用户默认设置（linkID 为 0）或链接覆盖，不存在时返回 ErrNotFound
+func (r *AlertRepo) GetThreshold(ctx context.Context, userID int64, linkID int64) (*models.AlertThreshold, error) {
+	t := &models.AlertThreshold{}
+	var err error
+	if linkID == 0 {
+		err = scanAlertThreshold(r.pool.QueryRow(ctx,
+			`SELECT `+alertThresholdColumns+` FROM alert_thresholds WHERE user_id = $1 AND link_id IS NULL`, userID), t)
+	} else {
+		err = scanAlertThreshold(r.pool.QueryRow(ctx,
+			`SELECT `+alertThresholdColumns+` FROM alert_thresholds WHERE user_id = $1 AND link_id = $2`, userID, linkID), t)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get alert threshold failed: %w", err)
+	}
+	return t, nil
+}
+
+// UpsertThreshold 写入用户默认设置（t.LinkID 为 nil）或链接覆盖（整体替换）
+func (r *AlertRepo) UpsertThreshold(ctx context.Context, t *models.AlertThreshold) error {
+	conflict := `(user_id) WHERE link_id IS NULL`
+	if t.LinkID != nil {
+		conflict = `(link_id) WHERE link_id IS NOT NULL`
+	}
+	err := scanAlertThreshold(r.pool.QueryRow(ctx, `
+		INSERT INTO alert_thresholds (user_id, link_id, enabled, spike_factor, spike_min_clicks, drop_min_baseline, bot_ratio,
+			notify_email, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		ON CONFLICT `+conflict+` DO UPDATE SET
+			enabled = EXCLUDED.enabled, spike_factor = EXCLUDED.spike_factor, spike_min_clicks = EXCLUDED.spike_min_clicks,
+			drop_min_baseline = EXCLUDED.drop_min_baseline, bot_ratio = EXCLUDED.bot_ratio,
+			notify_email = EXCLUDED.notify_email, updated_at = EXCLUDED.updated_at
+		RETURNING `+alertThresholdColumns,
+		t.UserID, t.LinkID, t.Enabled, t.SpikeFactor, t.SpikeMinClicks, t.DropMinBaseline, t.BotRatio, t.NotifyEmail, t.UpdatedAt), t)
+	if err != nil {
+		return fmt.Errorf("upsert alert threshold failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteLinkThreshold 删除链接覆盖（恢复继承用户默认设置）
+func (r *AlertRepo) DeleteLinkThreshold(ctx context.Context, userID int64, linkID int64) error {
+	ct, err := r.pool.Exec(ctx, `DELETE FROM alert_thresholds WHERE user_id = $1 AND link_id = $2`, userID, linkID)
+	if err != nil {
+		return fmt.Errorf("delete alert threshold failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// clickAlertColumns 查询列（与 scanClickAlert 顺序一致）
+const clickAlertColumns = `id, user_id, link_id, code, kind, window_start, window_end, observed, baseline, threshold, message, created_at`
+
+func scanClickAlert(row pgx.Row, a *models.ClickAlert) error {
+	return row.Scan(
+		&a.ID,
+		&a.UserID,
+		&a.LinkID,
+		&a.Code,
+		&a.Kind,
+		&a.WindowStart,
+		&a.WindowEnd,
+		&a.Observed,
+		&a.Baseline,
+		&a.Threshold,
+		&a.Message,
+		&a.CreatedAt,
+	)
+}
+
+// InsertAlerts 写入告警并为订阅 link.alert 的接收地址写入 webhook 投递（同一事务）
+// 同一链接同一窗口同一类型已存在的告警被跳过；返回实际写入的告警（已回填 ID 与创建时间）
+func (r *AlertRepo) InsertAlerts(ctx context.Context, alerts []models.ClickAlert) ([]models.ClickAlert, error) {
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var inserted []models.ClickAlert
+	var ids []int64
+	for _, a := range alerts {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO click_alerts (user_id, link_id, code, kind, window_start, window_end, observed, baseline, threshold, message)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (link_id, kind, window_start) DO NOTHING
+			RETURNING id, created_at
+		`, a.UserID, a.LinkID, a.Code, a.Kind, a.WindowStart, a.WindowEnd, a.Observed, a.Baseline, a.Threshold, a.Message).Scan(&a.ID, &a.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("insert click alert failed: %w", err)
+		}
+		inserted = append(inserted, a)
+		ids = append(ids, a.ID)
+	}
+
+	if len(ids) > 0 {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO webhook_deliveries (endpoint_id, event_type, event_id, payload)
+			SELECT w.id, $2::text, 'alt_' || a.id,
+				jsonb_build_object('id', 'alt_' || a.id, 'type', $2::text, 'created_at', NOW(), 'data', to_jsonb(a))
+			FROM click_alerts a
+			JOIN webhook_endpoints w ON w.user_id = a.user_id AND w.active AND $2::text = ANY(w.events)
+			WHERE a.id = ANY($1)
+			ORDER BY a.id, w.id
+		`, ids, models.WebhookLinkAlert)
+		if err != nil {
+			return nil, fmt.Errorf("enqueue alert webhooks failed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit tx failed: %w", err)
+	}
+	return inserted, nil
+}
+
+// ListAlerts 分页获取用户的告警（新到旧；kind 为空表示全部，linkID 为 0 表示全部链接）
+func (r *AlertRepo) ListAlerts(ctx context.Context, userID int64, kind string, linkID int64, page, limit int) ([]models.ClickAlert, int64, error) {
+	where := `user_id = $1`
+	args := []interface{}{userID}
+	if kind != "" {
+		args = append(args, kind)
+		where += fmt.Sprintf(` AND kind = $%d`, len(args))
+	}
+	if linkID > 0 {
+		args = append(args, linkID)
+		where += fmt.Sprintf(` AND link_id = $%d`, len(args))
+	}
+
+	var total int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM click_alerts WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count click alerts failed: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM click_alerts WHERE %s ORDER BY id DESC LIMIT $%d OFFSET $%d`,
+		clickAlertColumns, where, len(args)+1, len(args)+2)
+	rows, err := r.pool.Query(ctx, query, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list click alerts failed: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []models.ClickAlert
+	for rows.Next() {
+		var a models.ClickAlert
+		if err := scanClickAlert(rows, &a); err != nil {
+			return nil, 0, fmt.Errorf("scan click alert failed: %w", err)
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, total, rows.Err()
+}
+
+// PurgeAlerts 清理创建超过 retention 的告警，返回删除行数
+func (r *AlertRepo) PurgeAlerts(ctx context.Context, retention time.Duration) (int64, error) {
+	ct, err := r.pool.Exec(ctx, `DELETE FROM click_alerts WHERE created_at < NOW() - make_interval(secs => $1)`, retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("purge click alerts failed: %w", err)
+	}
+	return ct.RowsAffected(), nil
+}
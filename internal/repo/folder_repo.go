@@ -0,0 +1,150 @@
+/**
+ * Folder Repo
+ * - 负责 folders 表读写（用户级一级文件夹）
+ * - 删除文件夹时链接的 folder_id 由外键置空（同事务写入 outbox 事件重建索引）
+ */
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"short-link/internal/db"
+	"short-link/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FolderRepo 文件夹仓储
+type FolderRepo struct {
+	pool *db.Pool
+}
+
+// NewFolderRepo 创建 FolderRepo
+func NewFolderRepo(pool *db.Pool) *FolderRepo {
+	return &FolderRepo{pool: pool}
+}
+
+// ListFolders 获取用户的全部文件夹（含链接数，按名称排序）
+func (r *FolderRepo) ListFolders(ctx context.Context, userID int64) ([]models.Folder, error) {
+	query := `
+		SELECT f.id, f.user_id, f.name, f.created_at, f.updated_at, COUNT(l.id)
+		FROM folders f
+		LEFT JOIN links l ON l.folder_id = f.id
+		WHERE f.user_id = $1
+		GROUP BY f.id
+		ORDER BY f.name ASC
+	`
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list folders failed: %w", err)
+	}
+	defer rows.Close()
+
+	var folders []models.Folder
+	for rows.Next() {
+		var f models.Folder
+		if err := rows.Scan(&f.ID, &f.UserID, &f.Name, &f.CreatedAt, &f.UpdatedAt, &f.LinkCount); err != nil {
+			return nil, fmt.Errorf("scan folder failed: %w", err)
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+// GetFolder 获取单个文件夹（限定 user_id）
+func (r *FolderRepo) GetFolder(ctx context.Context, userID int64, folderID int64) (*models.Folder, error) {
+	f := &models.Folder{}
+	err := r.pool.QueryRow(ctx, `SELECT id, user_id, name, created_at, updated_at FROM folders WHERE id = $1 AND user_id = $2`, folderID, userID).
+		Scan(&f.ID, &f.UserID, &f.Name, &f.CreatedAt, &f.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get folder failed: %w", err)
+	}
+	return f, nil
+}
+
+// CreateFolder 创建文件夹（同名冲突返回唯一约束错误）
+func (r *FolderRepo) CreateFolder(ctx context.Context, f *models.Folder) error {
+	query := `
+		INSERT INTO folders (user_id, name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+	if err := r.pool.QueryRow(ctx, query, f.UserID, f.Name, f.CreatedAt, f.UpdatedAt).Scan(&f.ID); err != nil {
+		return fmt.Errorf("create folder failed: %w", err)
+	}
+	return nil
+}
+
+// EnsureFolders 按名称获取用户文件夹，不存在的自动创建（导入时使用），返回 name -> id
+func (r *FolderRepo) EnsureFolders(ctx context.Context, userID int64, names []string) (map[string]int64, error) {
+	out := make(map[string]int64, len(names))
+	if len(names) == 0 {
+		return out, nil
+	}
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO folders (user_id, name)
+		SELECT DISTINCT $1::BIGINT, n FROM unnest($2::TEXT[]) AS n
+		ON CONFLICT (user_id, name) DO NOTHING
+	`, userID, names); err != nil {
+		return nil, fmt.Errorf("create folders failed: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, `SELECT id, name FROM folders WHERE user_id = $1 AND name = ANY($2)`, userID, names)
+	if err != nil {
+		return nil, fmt.Errorf("get folders failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   int64
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("scan folder failed: %w", err)
+		}
+		out[name] = id
+	}
+	return out, rows.Err()
+}
+
+// RenameFolder 重命名文件夹
+func (r *FolderRepo) RenameFolder(ctx context.Context, userID int64, folderID int64, name string) error {
+	ct, err := r.pool.Exec(ctx, `UPDATE folders SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND user_id = $3`, name, folderID, userID)
+	if err != nil {
+		return fmt.Errorf("rename folder failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteFolder 删除文件夹（其中的链接变为未归档）
+func (r *FolderRepo) DeleteFolder(ctx context.Context, userID int64, folderID int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin delete folder tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	// 文件夹内链接的 folder_id 将被置空，同一事务内写入 link.updated 事件
+	if err := insertLinkEvents(ctx, tx, models.OutboxLinkUpdated,
+		`SELECT id FROM links WHERE folder_id = $2 AND user_id = $3`, folderID, userID); err != nil {
+		return err
+	}
+	ct, err := tx.Exec(ctx, `DELETE FROM folders WHERE id = $1 AND user_id = $2`, folderID, userID)
+	if err != nil {
+		return fmt.Errorf("delete folder failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit delete folder tx failed: %w", err)
+	}
+	return nil
+}
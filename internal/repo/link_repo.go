@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"short-link/internal/db"
 	"short-link/models"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -27,6 +28,33 @@ func NewLinkRepo(pool *db.Pool) *LinkRepo {
 	return &LinkRepo{pool: pool}
 }
 
+// linkColumns links 表查询列（与 scanLink 顺序一致）
+const linkColumns = `id, user_id, domain_id, code, original_url, title, hash, qr_code, click_count, unique_clicks,
+		expires_at, max_clicks, COALESCE(expired_redirect_url, ''), COALESCE(password_hash, ''), folder_id, created_at, updated_at`
+
+// scanLink 按 linkColumns 顺序扫描一行链接
+func scanLink(row pgx.Row, l *models.Link) error {
+	return row.Scan(
+		&l.ID,
+		&l.UserID,
+		&l.DomainID,
+		&l.Code,
+		&l.OriginalURL,
+		&l.Title,
+		&l.Hash,
+		&l.QRCode,
+		&l.ClickCount,
+		&l.UniqueClicks,
+		&l.ExpiresAt,
+		&l.MaxClicks,
+		&l.ExpiredRedirectURL,
+		&l.PasswordHash,
+		&l.FolderID,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+	)
+}
+
 // IsUniqueViolation 判断是否唯一约束冲突（23505）
 func IsUniqueViolation(err error) bool {
 	var pgErr *pgconn.PgError
@@ -36,14 +64,21 @@ func IsUniqueViolation(err error) bool {
 	return false
 }
 
-// CreateLink 创建链接
+// CreateLink 创建链接（同一事务内写入 link.created outbox 事件）
 func (r *LinkRepo) CreateLink(ctx context.Context, link *models.Link) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin create link tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
 	query := `
-		INSERT INTO links (user_id, domain_id, code, original_url, title, hash, qr_code, click_count, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO links (user_id, domain_id, code, original_url, title, hash, qr_code, click_count,
+			expires_at, max_clicks, expired_redirect_url, password_hash, folder_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), NULLIF($12, ''), $13, $14, $15)
 		RETURNING id
 	`
-	err := r.pool.QueryRow(
+	err = tx.QueryRow(
 		ctx,
 		query,
 		link.UserID,
@@ -54,37 +89,273 @@ func (r *LinkRepo) CreateLink(ctx context.Context, link *models.Link) error {
 		link.Hash,
 		link.QRCode,
 		link.ClickCount,
+		link.ExpiresAt,
+		link.MaxClicks,
+		link.ExpiredRedirectURL,
+		link.PasswordHash,
+		link.FolderID,
 		link.CreatedAt,
 		link.UpdatedAt,
 	).Scan(&link.ID)
 	if err != nil {
 		return fmt.Errorf("create link failed: %w", err)
 	}
+	if err := insertLinkEvents(ctx, tx, models.OutboxLinkCreated, `SELECT $2::BIGINT`, link.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit create link tx failed: %w", err)
+	}
 	return nil
 }
 
+// linkInsertChunk 批量插入单条语句的行数（15 列 × 500 行，远低于 65535 个参数上限）
+const linkInsertChunk = 500
+
+// CreateLinks 多行 INSERT 批量创建链接
+// (domain_id, code) 冲突的行跳过（ID 保持 0），由调用方重新生成 code 或报告冲突
+// 每个分片一个事务，插入成功的行同事务写入标签（Link.Tags 非空时）与 link.created outbox 事件
+// 返回成功插入的行数
+func (r *LinkRepo) CreateLinks(ctx context.Context, links []*models.Link) (int, error) {
+	inserted := 0
+	for start := 0; start < len(links); start += linkInsertChunk {
+		end := start + linkInsertChunk
+		if end > len(links) {
+			end = len(links)
+		}
+		n, err := r.createLinksChunk(ctx, links[start:end])
+		inserted += n
+		if err != nil {
+			return inserted, err
+		}
+	}
+	return inserted, nil
+}
+
+// createLinksChunk 单事务插入一个分片
+func (r *LinkRepo) createLinksChunk(ctx context.Context, chunk []*models.Link) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin create links tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var sb strings.Builder
+	sb.WriteString(`
+		INSERT INTO links (user_id, domain_id, code, original_url, title, hash, qr_code, click_count,
+			expires_at, max_clicks, expired_redirect_url, password_hash, folder_id, created_at, updated_at)
+		VALUES `)
+	args := make([]interface{}, 0, len(chunk)*15)
+	byKey := make(map[string]*models.Link, len(chunk))
+	for i, l := range chunk {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		p := len(args)
+		fmt.Fprintf(&sb, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, NULLIF($%d, ''), NULLIF($%d, ''), $%d, $%d, $%d)",
+			p+1, p+2, p+3, p+4, p+5, p+6, p+7, p+8, p+9, p+10, p+11, p+12, p+13, p+14, p+15)
+		args = append(args,
+			l.UserID, l.DomainID, l.Code, l.OriginalURL, l.Title, l.Hash, l.QRCode, l.ClickCount,
+			l.ExpiresAt, l.MaxClicks, l.ExpiredRedirectURL, l.PasswordHash, l.FolderID, l.CreatedAt, l.UpdatedAt)
+		byKey[fmt.Sprintf("%d/%s", l.DomainID, l.Code)] = l
+	}
+	sb.WriteString(`
+		ON CONFLICT (domain_id, code) DO NOTHING
+		RETURNING id, domain_id, code`)
+
+	rows, err := tx.Query(ctx, sb.String(), args...)
+	if err != nil {
+		return 0, fmt.Errorf("create links failed: %w", err)
+	}
+	var (
+		ids     []int64
+		created []*models.Link
+	)
+	for rows.Next() {
+		var (
+			id       int64
+			domainID int64
+			code     string
+		)
+		if err := rows.Scan(&id, &domainID, &code); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan created link failed: %w", err)
+		}
+		if l, ok := byKey[fmt.Sprintf("%d/%s", domainID, code)]; ok {
+			ids = append(ids, id)
+			created = append(created, l)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("create links failed: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	// 标签按归属用户分组写入（导入时一个分片可能包含多个用户的链接）
+	userTags := map[int64]map[int64][]string{}
+	for i, l := range created {
+		if len(l.Tags) == 0 {
+			continue
+		}
+		if userTags[l.UserID] == nil {
+			userTags[l.UserID] = map[int64][]string{}
+		}
+		userTags[l.UserID][ids[i]] = l.Tags
+	}
+	for userID, linkTags := range userTags {
+		if _, err := replaceLinkTagsTx(ctx, tx, userID, linkTags); err != nil {
+			return 0, err
+		}
+	}
+	if err := insertLinkEvents(ctx, tx, models.OutboxLinkCreated, `SELECT unnest($2::BIGINT[])`, ids); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit create links tx failed: %w", err)
+	}
+	// 提交成功后才回写 ID，避免回滚后调用方误认为已插入
+	for i, l := range created {
+		l.ID = ids[i]
+	}
+	return len(ids), nil
+}
+
+// LinkKey 链接唯一键 (domain_id, code)
+type LinkKey struct {
+	DomainID int64
+	Code     string
+}
+
+// FindExistingCodes 批量检查 (domain_id, code) 是否已存在（导入演练）
+func (r *LinkRepo) FindExistingCodes(ctx context.Context, keys []LinkKey) (map[LinkKey]bool, error) {
+	out := make(map[LinkKey]bool)
+	if len(keys) == 0 {
+		return out, nil
+	}
+	domainIDs := make([]int64, len(keys))
+	codes := make([]string, len(keys))
+	for i, k := range keys {
+		domainIDs[i] = k.DomainID
+		codes[i] = k.Code
+	}
+	query := `
+		SELECT l.domain_id, l.code
+		FROM links l
+		JOIN unnest($1::bigint[], $2::text[]) AS k(domain_id, code)
+			ON l.domain_id = k.domain_id AND l.code = k.code
+	`
+	rows, err := r.pool.Query(ctx, query, domainIDs, codes)
+	if err != nil {
+		return nil, fmt.Errorf("find existing codes failed: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k LinkKey
+		if err := rows.Scan(&k.DomainID, &k.Code); err != nil {
+			return nil, fmt.Errorf("scan existing code failed: %w", err)
+		}
+		out[k] = true
+	}
+	return out, rows.Err()
+}
+
+// GetLinksByHashes 批量获取用户名下指定 hash 的链接（批量创建的幂等判断）
+func (r *LinkRepo) GetLinksByHashes(ctx context.Context, userID int64, hashes []string) ([]models.Link, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	query := `
+		SELECT ` + linkColumns + `
+		FROM links
+		WHERE user_id = $1 AND hash = ANY($2)
+		ORDER BY id ASC
+	`
+	rows, err := r.pool.Query(ctx, query, userID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("get links by hashes failed: %w", err)
+	}
+	defer rows.Close()
+
+	var links []models.Link
+	for rows.Next() {
+		var l models.Link
+		if err := scanLink(rows, &l); err != nil {
+			return nil, fmt.Errorf("scan link failed: %w", err)
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// linkExportPage 导出时按 id 游标分页的每页行数
+const linkExportPage = 1000
+
+// ForEachLinkRecord 按 id 游标分页遍历链接导出记录（userID 为 0 时遍历全实例）
+// 记录包含 password_hash，是否输出由导出编码器决定
+// 每页读完即释放连接再回调，慢速客户端不会长期占用连接池
+func (r *LinkRepo) ForEachLinkRecord(ctx context.Context, userID int64, fn func(rec *models.LinkRecord) error) error {
+	query := `
+		SELECT l.id, COALESCE(u.username, ''), COALESCE(d.domain, ''), l.code, l.original_url,
+			COALESCE(l.title, ''), COALESCE(l.click_count, 0), l.created_at,
+			l.expires_at, l.max_clicks, COALESCE(l.expired_redirect_url, ''), COALESCE(l.password_hash, ''),
+			COALESCE(f.name, ''),
+			ARRAY(SELECT t.name FROM link_tags lt JOIN tags t ON t.id = lt.tag_id WHERE lt.link_id = l.id ORDER BY t.name)
+		FROM links l
+		LEFT JOIN domains d ON d.id = l.domain_id
+		LEFT JOIN users u ON u.id = l.user_id
+		LEFT JOIN folders f ON f.id = l.folder_id
+		WHERE l.id > $1 AND ($2::bigint = 0 OR l.user_id = $2)
+		ORDER BY l.id ASC
+		LIMIT $3
+	`
+	var lastID int64
+	for {
+		rows, err := r.pool.Query(ctx, query, lastID, userID, linkExportPage)
+		if err != nil {
+			return fmt.Errorf("list link records failed: %w", err)
+		}
+		page := make([]models.LinkRecord, 0, linkExportPage)
+		for rows.Next() {
+			var rec models.LinkRecord
+			if err := rows.Scan(&rec.ID, &rec.Username, &rec.Domain, &rec.Code, &rec.OriginalURL,
+				&rec.Title, &rec.ClickCount, &rec.CreatedAt,
+				&rec.ExpiresAt, &rec.MaxClicks, &rec.ExpiredRedirectURL, &rec.PasswordHash,
+				&rec.Folder, &rec.Tags); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan link record failed: %w", err)
+			}
+			rec.PasswordProtected = rec.PasswordHash != ""
+			page = append(page, rec)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("list link records failed: %w", err)
+		}
+
+		for i := range page {
+			if err := fn(&page[i]); err != nil {
+				return err
+			}
+		}
+		if len(page) < linkExportPage {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
 // GetLinkByCode 根据 code + domain_id 获取链接
 func (r *LinkRepo) GetLinkByCode(ctx context.Context, code string, domainID int64) (*models.Link, error) {
 	l := &models.Link{}
 	query := `
-		SELECT id, user_id, domain_id, code, original_url, title, hash, qr_code, click_count, created_at, updated_at
+		SELECT ` + linkColumns + `
 		FROM links
 		WHERE code = $1 AND domain_id = $2
 		LIMIT 1
 	`
-	err := r.pool.QueryRow(ctx, query, code, domainID).Scan(
-		&l.ID,
-		&l.UserID,
-		&l.DomainID,
-		&l.Code,
-		&l.OriginalURL,
-		&l.Title,
-		&l.Hash,
-		&l.QRCode,
-		&l.ClickCount,
-		&l.CreatedAt,
-		&l.UpdatedAt,
-	)
+	err := scanLink(r.pool.QueryRow(ctx, query, code, domainID), l)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, ErrNotFound
 	}
@@ -94,13 +365,31 @@ func (r *LinkRepo) GetLinkByCode(ctx context.Context, code string, domainID int6
 	return l, nil
 }
 
+// GetLinkByID 根据ID获取链接
+func (r *LinkRepo) GetLinkByID(ctx context.Context, linkID int64) (*models.Link, error) {
+	l := &models.Link{}
+	query := `
+		SELECT ` + linkColumns + `
+		FROM links
+		WHERE id = $1
+	`
+	err := scanLink(r.pool.QueryRow(ctx, query, linkID), l)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get link by id failed: %w", err)
+	}
+	return l, nil
+}
+
 // GetLinkByCodeAnyDomain 兼容：按 code 查询任意域名（最多返回 limit 条，用于歧义判断）
 func (r *LinkRepo) GetLinkByCodeAnyDomain(ctx context.Context, code string, limit int) ([]models.Link, error) {
 	if limit <= 0 {
 		limit = 2
 	}
 	query := `
-		SELECT id, user_id, domain_id, code, original_url, title, hash, qr_code, click_count, created_at, updated_at
+		SELECT ` + linkColumns + `
 		FROM links
 		WHERE code = $1
 		LIMIT $2
@@ -114,19 +403,7 @@ func (r *LinkRepo) GetLinkByCodeAnyDomain(ctx context.Context, code string, limi
 	var out []models.Link
 	for rows.Next() {
 		var l models.Link
-		if err := rows.Scan(
-			&l.ID,
-			&l.UserID,
-			&l.DomainID,
-			&l.Code,
-			&l.OriginalURL,
-			&l.Title,
-			&l.Hash,
-			&l.QRCode,
-			&l.ClickCount,
-			&l.CreatedAt,
-			&l.UpdatedAt,
-		); err != nil {
+		if err := scanLink(rows, &l); err != nil {
 			return nil, fmt.Errorf("scan link failed: %w", err)
 		}
 		out = append(out, l)
@@ -138,24 +415,12 @@ func (r *LinkRepo) GetLinkByCodeAnyDomain(ctx context.Context, code string, limi
 func (r *LinkRepo) GetLinkByHashUserDomain(ctx context.Context, hash string, userID int64, domainID int64) (*models.Link, error) {
 	l := &models.Link{}
 	query := `
-		SELECT id, user_id, domain_id, code, original_url, title, hash, qr_code, click_count, created_at, updated_at
+		SELECT ` + linkColumns + `
 		FROM links
 		WHERE hash = $1 AND user_id = $2 AND domain_id = $3
 		LIMIT 1
 	`
-	err := r.pool.QueryRow(ctx, query, hash, userID, domainID).Scan(
-		&l.ID,
-		&l.UserID,
-		&l.DomainID,
-		&l.Code,
-		&l.OriginalURL,
-		&l.Title,
-		&l.Hash,
-		&l.QRCode,
-		&l.ClickCount,
-		&l.CreatedAt,
-		&l.UpdatedAt,
-	)
+	err := scanLink(r.pool.QueryRow(ctx, query, hash, userID, domainID), l)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
@@ -185,8 +450,37 @@ func (r *LinkRepo) GetCodeCountByLength(ctx context.Context, length int) (int64,
 	return count, nil
 }
 
+// LinkFilter 链接列表过滤条件（零值表示不过滤）
+type LinkFilter struct {
+	Tag      string // 标签名
+	FolderID *int64 // 文件夹 ID，0 表示未归档
+
+	// 以下用于全文检索降级（SearchLinks）
+	TSQuery     string // to_tsquery('simple', ...) 表达式，匹配 search_tsv
+	DomainID    int64
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MinClicks   *int64
+	MaxClicks   *int64
+}
+
 // GetUserLinks 获取用户链接分页列表
 func (r *LinkRepo) GetUserLinks(ctx context.Context, userID int64, page int, limit int) ([]models.Link, int64, error) {
+	return r.ListUserLinks(ctx, userID, LinkFilter{}, page, limit)
+}
+
+// ListUserLinks 按标签/文件夹过滤的用户链接分页列表
+func (r *LinkRepo) ListUserLinks(ctx context.Context, userID int64, filter LinkFilter, page int, limit int) ([]models.Link, int64, error) {
+	return r.listLinks(ctx, userID, filter, page, limit)
+}
+
+// SearchLinks 全文检索链接（Meilisearch 不可用时的降级路径），userID 为 0 表示全局（仅管理员）
+func (r *LinkRepo) SearchLinks(ctx context.Context, userID int64, filter LinkFilter, page int, limit int) ([]models.Link, int64, error) {
+	return r.listLinks(ctx, userID, filter, page, limit)
+}
+
+// listLinks 按过滤条件分页查询链接（按创建时间倒序），userID 为 0 表示不限用户
+func (r *LinkRepo) listLinks(ctx context.Context, userID int64, filter LinkFilter, page int, limit int) ([]models.Link, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -195,19 +489,65 @@ func (r *LinkRepo) GetUserLinks(ctx context.Context, userID int64, page int, lim
 	}
 	offset := (page - 1) * limit
 
+	where := []string{"TRUE"}
+	var args []interface{}
+	if userID > 0 {
+		args = append(args, userID)
+		where = append(where, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.TSQuery != "" {
+		args = append(args, filter.TSQuery)
+		where = append(where, fmt.Sprintf("search_tsv @@ to_tsquery('simple', $%d)", len(args)))
+	}
+	if filter.DomainID > 0 {
+		args = append(args, filter.DomainID)
+		where = append(where, fmt.Sprintf("domain_id = $%d", len(args)))
+	}
+	if filter.CreatedFrom != nil {
+		args = append(args, *filter.CreatedFrom)
+		where = append(where, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.CreatedTo != nil {
+		args = append(args, *filter.CreatedTo)
+		where = append(where, fmt.Sprintf("created_at <= $%d", len(args)))
+	}
+	if filter.MinClicks != nil {
+		args = append(args, *filter.MinClicks)
+		where = append(where, fmt.Sprintf("click_count >= $%d", len(args)))
+	}
+	if filter.MaxClicks != nil {
+		args = append(args, *filter.MaxClicks)
+		where = append(where, fmt.Sprintf("click_count <= $%d", len(args)))
+	}
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		where = append(where, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM link_tags lt JOIN tags t ON t.id = lt.tag_id
+			WHERE lt.link_id = links.id AND t.user_id = links.user_id AND t.name = $%d)`, len(args)))
+	}
+	if filter.FolderID != nil {
+		if *filter.FolderID == 0 {
+			where = append(where, "folder_id IS NULL")
+		} else {
+			args = append(args, *filter.FolderID)
+			where = append(where, fmt.Sprintf("folder_id = $%d", len(args)))
+		}
+	}
+	cond := strings.Join(where, " AND ")
+
 	var total int64
-	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM links WHERE user_id = $1`, userID).Scan(&total); err != nil {
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM links WHERE `+cond, args...).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("count links failed: %w", err)
 	}
 
-	query := `
-		SELECT id, user_id, domain_id, code, original_url, title, hash, qr_code, click_count, created_at, updated_at
+	query := fmt.Sprintf(`
+		SELECT `+linkColumns+`
 		FROM links
-		WHERE user_id = $1
+		WHERE %s
 		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.pool.Query(ctx, query, userID, limit, offset)
+		LIMIT $%d OFFSET $%d
+	`, cond, len(args)+1, len(args)+2)
+	rows, err := r.pool.Query(ctx, query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("list links failed: %w", err)
 	}
@@ -216,19 +556,7 @@ func (r *LinkRepo) GetUserLinks(ctx context.Context, userID int64, page int, lim
 	var links []models.Link
 	for rows.Next() {
 		var l models.Link
-		if err := rows.Scan(
-			&l.ID,
-			&l.UserID,
-			&l.DomainID,
-			&l.Code,
-			&l.OriginalURL,
-			&l.Title,
-			&l.Hash,
-			&l.QRCode,
-			&l.ClickCount,
-			&l.CreatedAt,
-			&l.UpdatedAt,
-		); err != nil {
+		if err := scanLink(rows, &l); err != nil {
 			return nil, 0, fmt.Errorf("scan link failed: %w", err)
 		}
 		links = append(links, l)
@@ -236,18 +564,175 @@ func (r *LinkRepo) GetUserLinks(ctx context.Context, userID int64, page int, lim
 	return links, total, nil
 }
 
-// DeleteUserLink 删除用户在指定 domain 下的链接
+// GetLinksByIDs 按 ID 批量获取链接（顺序不保证）
+func (r *LinkRepo) GetLinksByIDs(ctx context.Context, ids []int64) ([]models.Link, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := r.pool.Query(ctx, `SELECT `+linkColumns+` FROM links WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get links by ids failed: %w", err)
+	}
+	defer rows.Close()
+
+	var links []models.Link
+	for rows.Next() {
+		var l models.Link
+		if err := scanLink(rows, &l); err != nil {
+			return nil, fmt.Errorf("scan link failed: %w", err)
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// ListLinksAfter 按 ID 升序的键集分页（id > afterID），用于全量遍历
+func (r *LinkRepo) ListLinksAfter(ctx context.Context, afterID int64, limit int) ([]models.Link, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+linkColumns+` FROM links WHERE id > $1 ORDER BY id ASC LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list links after failed: %w", err)
+	}
+	defer rows.Close()
+
+	var links []models.Link
+	for rows.Next() {
+		var l models.Link
+		if err := scanLink(rows, &l); err != nil {
+			return nil, fmt.Errorf("scan link failed: %w", err)
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// UpdateLink 更新链接可编辑字段（code/original_url/title/hash/qr_code/folder_id）
+// code 冲突由 (domain_id, code) 唯一约束兜底；tags 非 nil 时同一事务内整体替换标签；同一事务内写入 link.updated outbox 事件
+func (r *LinkRepo) UpdateLink(ctx context.Context, link *models.Link, tags *[]string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin update link tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := `
+		UPDATE links
+		SET code = $1, original_url = $2, title = $3, hash = $4, qr_code = $5, folder_id = $6, updated_at = $7
+		WHERE id = $8
+	`
+	ct, err := tx.Exec(
+		ctx,
+		query,
+		link.Code,
+		link.OriginalURL,
+		link.Title,
+		link.Hash,
+		link.QRCode,
+		link.FolderID,
+		link.UpdatedAt,
+		link.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update link failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if tags != nil {
+		if _, err := replaceLinkTagsTx(ctx, tx, link.UserID, map[int64][]string{link.ID: *tags}); err != nil {
+			return err
+		}
+	}
+	if err := insertLinkEvents(ctx, tx, models.OutboxLinkUpdated, `SELECT $2::BIGINT`, link.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit update link tx failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserLink 删除用户在指定 domain 下的链接（删除前同一事务内写入 link.deleted outbox 事件）
 func (r *LinkRepo) DeleteUserLink(ctx context.Context, userID int64, domainID int64, code string) error {
-	ct, err := r.pool.Exec(ctx, `DELETE FROM links WHERE user_id = $1 AND domain_id = $2 AND code = $3`, userID, domainID, code)
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin delete link tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err := insertLinkEvents(ctx, tx, models.OutboxLinkDeleted,
+		`SELECT id FROM links WHERE user_id = $2 AND domain_id = $3 AND code = $4`, userID, domainID, code); err != nil {
+		return err
+	}
+	ct, err := tx.Exec(ctx, `DELETE FROM links WHERE user_id = $1 AND domain_id = $2 AND code = $3`, userID, domainID, code)
 	if err != nil {
 		return fmt.Errorf("delete link failed: %w", err)
 	}
 	if ct.RowsAffected() == 0 {
 		return ErrNotFound
 	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit delete link tx failed: %w", err)
+	}
 	return nil
 }
 
+// MarkExpiredLinks 标记已到期但尚未标记失效的链接（最多 limit 个）并写入 link.expired 事件，返回标记数
+// 多副本并发执行时 SKIP LOCKED 保证同一链接只标记一次
+func (r *LinkRepo) MarkExpiredLinks(ctx context.Context, limit int) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin expire links tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	n, err := expireLinks(ctx, tx, `
+		SELECT id FROM links
+		WHERE expired_at IS NULL AND expires_at <= NOW()
+		ORDER BY expires_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit expire links tx failed: %w", err)
+	}
+	return n, nil
+}
+
+// expireLinks 在事务内标记 idsQuery 选出的链接失效（已标记的跳过）并写入 link.expired 事件，返回标记数
+func expireLinks(ctx context.Context, tx pgx.Tx, idsQuery string, args ...interface{}) (int, error) {
+	rows, err := tx.Query(ctx, `
+		UPDATE links SET expired_at = NOW()
+		WHERE expired_at IS NULL AND id IN (`+idsQuery+`)
+		RETURNING id
+	`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("mark links expired failed: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan expired link id failed: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("mark links expired failed: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := insertLinkEvents(ctx, tx, models.OutboxLinkExpired, `SELECT unnest($2::BIGINT[])`, ids); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
 // CountLinksByUser 统计用户链接数量（用于 max_links 限制）
 func (r *LinkRepo) CountLinksByUser(ctx context.Context, userID int64) (int64, error) {
 	var count int64
@@ -258,45 +743,54 @@ func (r *LinkRepo) CountLinksByUser(ctx context.Context, userID int64) (int64, e
 	return count, nil
 }
 
-// IncrementClickCount 增加点击计数（支持批量计数）
-func (r *LinkRepo) IncrementClickCount(ctx context.Context, linkID int64, count int) error {
-	if count <= 0 {
-		count = 1
-	}
-	_, err := r.pool.Exec(ctx, `UPDATE links SET click_count = click_count + $1, updated_at = $2 WHERE id = $3`, count, time.Now(), linkID)
-	if err != nil {
-		return fmt.Errorf("increment click_count failed: %w", err)
-	}
-	return nil
+// ClickBudgetState 点击计数写入后的链接状态（用于点击预算判定）
+type ClickBudgetState struct {
+	LinkID     int64
+	DomainID   int64
+	Code       string
+	ClickCount int64
+	MaxClicks  *int64
 }
 
-// GetLinkStats 获取全局统计信息
-func (r *LinkRepo) GetLinkStats(ctx context.Context) (*models.LinkStats, error) {
+// Exhausted 点击预算是否已用尽
+func (s *ClickBudgetState) Exhausted() bool {
+	return s.MaxClicks != nil && s.ClickCount >= *s.MaxClicks
+}
+
+// GetLinkStats 获取统计信息（userID > 0 时仅统计该用户的链接，0 表示全局）
+func (r *LinkRepo) GetLinkStats(ctx context.Context, userID int64) (*models.LinkStats, error) {
 	stats := &models.LinkStats{}
 
+	linkCond := "TRUE"
+	var args []interface{}
+	if userID > 0 {
+		linkCond = "user_id = $1"
+		args = append(args, userID)
+	}
+
 	// 总链接数
-	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM links`).Scan(&stats.TotalLinks); err != nil {
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM links WHERE `+linkCond, args...).Scan(&stats.TotalLinks); err != nil {
 		return nil, fmt.Errorf("count links failed: %w", err)
 	}
 
 	// 总点击数
-	if err := r.pool.QueryRow(ctx, `SELECT COALESCE(SUM(click_count), 0) FROM links`).Scan(&stats.TotalClicks); err != nil {
+	if err := r.pool.QueryRow(ctx, `SELECT COALESCE(SUM(click_count), 0) FROM links WHERE `+linkCond, args...).Scan(&stats.TotalClicks); err != nil {
 		return nil, fmt.Errorf("sum click_count failed: %w", err)
 	}
 
-	// 今日点击数（通过访问日志）
-	today := time.Now().Format("2006-01-02")
-	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM access_logs WHERE DATE(created_at) = $1`, today).Scan(&stats.TodayClicks); err != nil {
+	// 今日点击数（通过按天预聚合表）
+	if err := r.pool.QueryRow(ctx, `SELECT COALESCE(SUM(click_count), 0) FROM click_rollups_daily WHERE day = CURRENT_DATE AND `+linkCond, args...).Scan(&stats.TodayClicks); err != nil {
 		return nil, fmt.Errorf("count today clicks failed: %w", err)
 	}
 
 	// 热门链接（前10）
 	rows, err := r.pool.Query(ctx, `
-		SELECT id, code, original_url, title, hash, click_count, created_at, updated_at
+		SELECT id, code, original_url, title, hash, click_count, unique_clicks, created_at, updated_at
 		FROM links
+		WHERE `+linkCond+`
 		ORDER BY click_count DESC
 		LIMIT 10
-	`)
+	`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query top links failed: %w", err)
 	}
@@ -311,6 +805,7 @@ func (r *LinkRepo) GetLinkStats(ctx context.Context) (*models.LinkStats, error)
 			&l.Title,
 			&l.Hash,
 			&l.ClickCount,
+			&l.UniqueClicks,
 			&l.CreatedAt,
 			&l.UpdatedAt,
 		); err != nil {
@@ -0,0 +1,158 @@
+/**
+ * LinkRule Repo
+ * - 负责 link_rules 表读写（定向跳转规则）
+ */
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"short-link/internal/db"
+	"short-link/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LinkRuleRepo 跳转规则仓储
+type LinkRuleRepo struct {
+	pool *db.Pool
+}
+
+// NewLinkRuleRepo 创建 LinkRuleRepo
+func NewLinkRuleRepo(pool *db.Pool) *LinkRuleRepo {
+	return &LinkRuleRepo{pool: pool}
+}
+
+const linkRuleColumns = `id, link_id, priority, countries, devices, os, languages, starts_at, ends_at, target_url, created_at, updated_at`
+
+func scanLinkRule(row pgx.Row, r *models.LinkRule) error {
+	return row.Scan(
+		&r.ID,
+		&r.LinkID,
+		&r.Priority,
+		&r.Countries,
+		&r.Devices,
+		&r.OS,
+		&r.Languages,
+		&r.StartsAt,
+		&r.EndsAt,
+		&r.TargetURL,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+}
+
+// nonNil pgx 将 nil 切片编码为 NULL，列为 NOT NULL，统一转为空数组
+func nonNil(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return v
+}
+
+// ListRules 获取链接的全部规则（按匹配顺序）
+func (r *LinkRuleRepo) ListRules(ctx context.Context, linkID int64) ([]models.LinkRule, error) {
+	query := `SELECT ` + linkRuleColumns + ` FROM link_rules WHERE link_id = $1 ORDER BY priority ASC, id ASC`
+	rows, err := r.pool.Query(ctx, query, linkID)
+	if err != nil {
+		return nil, fmt.Errorf("list link rules failed: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.LinkRule
+	for rows.Next() {
+		var rule models.LinkRule
+		if err := scanLinkRule(rows, &rule); err != nil {
+			return nil, fmt.Errorf("scan link rule failed: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// GetRule 获取单条规则（限定 link_id，防止越权访问其他链接的规则）
+func (r *LinkRuleRepo) GetRule(ctx context.Context, linkID int64, ruleID int64) (*models.LinkRule, error) {
+	rule := &models.LinkRule{}
+	query := `SELECT ` + linkRuleColumns + ` FROM link_rules WHERE id = $1 AND link_id = $2`
+	err := scanLinkRule(r.pool.QueryRow(ctx, query, ruleID, linkID), rule)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get link rule failed: %w", err)
+	}
+	return rule, nil
+}
+
+// CreateRule 创建规则
+func (r *LinkRuleRepo) CreateRule(ctx context.Context, rule *models.LinkRule) error {
+	query := `
+		INSERT INTO link_rules (link_id, priority, countries, devices, os, languages, starts_at, ends_at, target_url, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`
+	err := r.pool.QueryRow(
+		ctx,
+		query,
+		rule.LinkID,
+		rule.Priority,
+		nonNil(rule.Countries),
+		nonNil(rule.Devices),
+		nonNil(rule.OS),
+		nonNil(rule.Languages),
+		rule.StartsAt,
+		rule.EndsAt,
+		rule.TargetURL,
+		rule.CreatedAt,
+		rule.UpdatedAt,
+	).Scan(&rule.ID)
+	if err != nil {
+		return fmt.Errorf("create link rule failed: %w", err)
+	}
+	return nil
+}
+
+// UpdateRule 更新规则（整体替换条件与目标地址）
+func (r *LinkRuleRepo) UpdateRule(ctx context.Context, rule *models.LinkRule) error {
+	query := `
+		UPDATE link_rules
+		SET priority = $1, countries = $2, devices = $3, os = $4, languages = $5,
+			starts_at = $6, ends_at = $7, target_url = $8, updated_at = $9
+		WHERE id = $10 AND link_id = $11
+	`
+	ct, err := r.pool.Exec(
+		ctx,
+		query,
+		rule.Priority,
+		nonNil(rule.Countries),
+		nonNil(rule.Devices),
+		nonNil(rule.OS),
+		nonNil(rule.Languages),
+		rule.StartsAt,
+		rule.EndsAt,
+		rule.TargetURL,
+		rule.UpdatedAt,
+		rule.ID,
+		rule.LinkID,
+	)
+	if err != nil {
+		return fmt.Errorf("update link rule failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteRule 删除规则
+func (r *LinkRuleRepo) DeleteRule(ctx context.Context, linkID int64, ruleID int64) error {
+	ct, err := r.pool.Exec(ctx, `DELETE FROM link_rules WHERE id = $1 AND link_id = $2`, ruleID, linkID)
+	if err != nil {
+		return fmt.Errorf("delete link rule failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+/**
+ * LinkVariant Repo
+ * - 负责 link_variants 表读写（A/B 分流变体）
+ */
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"short-link/internal/db"
+	"short-link/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LinkVariantRepo 分流变体仓储
+type LinkVariantRepo struct {
+	pool *db.Pool
+}
+
+// NewLinkVariantRepo 创建 LinkVariantRepo
+func NewLinkVariantRepo(pool *db.Pool) *LinkVariantRepo {
+	return &LinkVariantRepo{pool: pool}
+}
+
+const linkVariantColumns = `id, link_id, label, target_url, weight, created_at, updated_at`
+
+func scanLinkVariant(row pgx.Row, v *models.LinkVariant) error {
+	return row.Scan(
+		&v.ID,
+		&v.LinkID,
+		&v.Label,
+		&v.TargetURL,
+		&v.Weight,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	)
+}
+
+// ListVariants 获取链接的全部变体（按 ID 升序，保证分配稳定）
+func (r *LinkVariantRepo) ListVariants(ctx context.Context, linkID int64) ([]models.LinkVariant, error) {
+	query := `SELECT ` + linkVariantColumns + ` FROM link_variants WHERE link_id = $1 ORDER BY id ASC`
+	rows, err := r.pool.Query(ctx, query, linkID)
+	if err != nil {
+		return nil, fmt.Errorf("list link variants failed: %w", err)
+	}
+	defer rows.Close()
+
+	var variants []models.LinkVariant
+	for rows.Next() {
+		var v models.LinkVariant
+		if err := scanLinkVariant(rows, &v); err != nil {
+			return nil, fmt.Errorf("scan link variant failed: %w", err)
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+// GetVariant 获取单个变体（限定 link_id）
+func (r *LinkVariantRepo) GetVariant(ctx context.Context, linkID int64, variantID int64) (*models.LinkVariant, error) {
+	v := &models.LinkVariant{}
+	query := `SELECT ` + linkVariantColumns + ` FROM link_variants WHERE id = $1 AND link_id = $2`
+	err := scanLinkVariant(r.pool.QueryRow(ctx, query, variantID, linkID), v)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get link variant failed: %w", err)
+	}
+	return v, nil
+}
+
+// CreateVariant 创建变体
+func (r *LinkVariantRepo) CreateVariant(ctx context.Context, v *models.LinkVariant) error {
+	query := `
+		INSERT INTO link_variants (link_id, label, target_url, weight, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	err := r.pool.QueryRow(ctx, query, v.LinkID, v.Label, v.TargetURL, v.Weight, v.CreatedAt, v.UpdatedAt).Scan(&v.ID)
+	if err != nil {
+		return fmt.Errorf("create link variant failed: %w", err)
+	}
+	return nil
+}
+
+// UpdateVariant 更新变体
+func (r *LinkVariantRepo) UpdateVariant(ctx context.Context, v *models.LinkVariant) error {
+	query := `
+		UPDATE link_variants
+		SET label = $1, target_url = $2, weight = $3, updated_at = $4
+		WHERE id = $5 AND link_id = $6
+	`
+	ct, err := r.pool.Exec(ctx, query, v.Label, v.TargetURL, v.Weight, v.UpdatedAt, v.ID, v.LinkID)
+	if err != nil {
+		return fmt.Errorf("update link variant failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteVariant 删除变体
+func (r *LinkVariantRepo) DeleteVariant(ctx context.Context, linkID int64, variantID int64) error {
+	ct, err := r.pool.Exec(ctx, `DELETE FROM link_variants WHERE id = $1 AND link_id = $2`, variantID, linkID)
+	if err != nil {
+		return fmt.Errorf("delete link variant failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+/**
+ * Outbox Repo
+ * - insertLinkEvents：在调用方事务内写入链接变更事件（与 links 写入同一事务提交/回滚）
+ *   同一语句内为订阅了该事件的 webhook 写入投递记录（不依赖 dispatcher，Meilisearch 不可用时 webhook 照常投递）
+ * - ClaimEvents：FOR UPDATE SKIP LOCKED 领取到期事件并顺延 available_at 作为租约，多副本互不重复领取
+ *   进程崩溃时租约到期后事件会被重新领取
+ * - 投递成功标记 done，失败按退避时间重试，超过最大次数标记 dead（死信）
+ */
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"short-link/internal/db"
+	"short-link/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// OutboxRepo outbox 事件仓储
+type OutboxRepo struct {
+	pool *db.Pool
+}
+
+// NewOutboxRepo 创建 OutboxRepo
+func NewOutboxRepo(pool *db.Pool) *OutboxRepo {
+	return &OutboxRepo{pool: pool}
+}
+
+// insertLinkEvents 在事务内为 idsQuery 选出的链接写入 outbox 事件，并为订阅了该事件的 webhook 写入投递记录
+// idsQuery 为返回链接 ID 的子查询，其参数从 $2 开始（$1 为事件类型）；链接须在事务内仍存在（删除前写入）
+func insertLinkEvents(ctx context.Context, tx pgx.Tx, eventType string, idsQuery string, args ...interface{}) error {
+	_, err := tx.Exec(ctx, `
+		WITH ev AS (
+			INSERT INTO outbox_events (event_type, aggregate_id, payload)
+			SELECT $1, l.id, jsonb_build_object('id', l.id, 'user_id', l.user_id, 'domain_id', l.domain_id, 'code', l.code)
+			FROM links l
+			WHERE l.id IN (`+idsQuery+`)
+			ORDER BY l.id
+			RETURNING id, event_type, aggregate_id
+		)
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, event_id, payload)
+		SELECT w.id, ev.event_type, 'evt_' || ev.id,
+			jsonb_build_object('id', 'evt_' || ev.id, 'type', ev.event_type, 'created_at', NOW(), 'data', `+webhookLinkData+`)
+		FROM ev
+		JOIN links l ON l.id = ev.aggregate_id
+		JOIN webhook_endpoints w ON w.user_id = l.user_id AND w.active AND ev.event_type = ANY(w.events)
+		ORDER BY ev.id, w.id
+	`, append([]interface{}{eventType}, args...)...)
+	if err != nil {
+		return fmt.Errorf("insert outbox events failed: %w", err)
+	}
+	return nil
+}
+
+// ClaimEvents 领取最多 limit 个到期的 pending 事件（attempts +1，available_at 顺延 lease）
+func (r *OutboxRepo) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	query := `
+		UPDATE outbox_events e
+		SET attempts = e.attempts + 1, available_at = NOW() + make_interval(secs => $2)
+		FROM (
+			SELECT id FROM outbox_events
+			WHERE status = 'pending' AND available_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		) c
+		WHERE e.id = c.id
+		RETURNING e.id, e.event_type, e.aggregate_id, e.payload, e.status, e.attempts, e.last_error,
+			e.available_at, e.created_at, e.processed_at
+	`
+	rows, err := r.pool.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events failed: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var e models.OutboxEvent
+		if err := rows.Scan(&e.ID, &e.EventType, &e.AggregateID, &e.Payload, &e.Status, &e.Attempts, &e.LastError,
+			&e.AvailableAt, &e.CreatedAt, &e.ProcessedAt); err != nil {
+			return nil, fmt.Errorf("scan outbox event failed: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// MarkDone 标记事件已投递
+func (r *OutboxRepo) MarkDone(ctx context.Context, ids []int64) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE outbox_events SET status = 'done', processed_at = NOW(), last_error = NULL
+		WHERE id = ANY($1)
+	`, ids)
+	if err != nil {
+		return fmt.Errorf("mark outbox events done failed: %w", err)
+	}
+	return nil
+}
+
+// MarkRetry 投递失败：retryAfter 后重新投递
+func (r *OutboxRepo) MarkRetry(ctx context.Context, id int64, retryAfter time.Duration, lastErr string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE outbox_events SET available_at = NOW() + make_interval(secs => $2), last_error = $3
+		WHERE id = $1
+	`, id, retryAfter.Seconds(), lastErr)
+	if err != nil {
+		return fmt.Errorf("mark outbox event retry failed: %w", err)
+	}
+	return nil
+}
+
+// MarkDead 投递失败且超过最大重试次数：标记死信
+func (r *OutboxRepo) MarkDead(ctx context.Context, id int64, lastErr string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE outbox_events SET status = 'dead', processed_at = NOW(), last_error = $2
+		WHERE id = $1
+	`, id, lastErr)
+	if err != nil {
+		return fmt.Errorf("mark outbox event dead failed: %w", err)
+	}
+	return nil
+}
+
+// RequeueDead 死信重新入队（重置重试次数），返回重新入队的事件数
+func (r *OutboxRepo) RequeueDead(ctx context.Context) (int64, error) {
+	ct, err := r.pool.Exec(ctx, `
+		UPDATE outbox_events SET status = 'pending', attempts = 0, available_at = NOW(), processed_at = NULL
+		WHERE status = 'dead'
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("requeue dead outbox events failed: %w", err)
+	}
+	return ct.RowsAffected(), nil
+}
+
+// PurgeDone 清理投递完成超过 retention 的事件，返回删除行数
+func (r *OutboxRepo) PurgeDone(ctx context.Context, retention time.Duration) (int64, error) {
+	ct, err := r.pool.Exec(ctx, `
+		DELETE FROM outbox_events WHERE status = 'done' AND processed_at < NOW() - make_interval(secs => $1)
+	`, retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("purge outbox events failed: %w", err)
+	}
+	return ct.RowsAffected(), nil
+}
+
+// CountByStatus 按状态统计事件数
+func (r *OutboxRepo) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	rows, err := r.pool.Query(ctx, `SELECT status, COUNT(*) FROM outbox_events GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("count outbox events failed: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var (
+			status string
+			n      int64
+		)
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("scan outbox count failed: %w", err)
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
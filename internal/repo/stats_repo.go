@@ -1,6 +1,6 @@
 /**
  * Stats Repo（重写版）
- * - 负责聚合统计查询（日/周/月、来源、UA 等维度）
+ * - 负责聚合统计查询（日/周/月、来源、UA、浏览器/系统/设备、国家/城市等维度）
  * 实现 redo.md 5.3：聚合统计扩展
  */
 package repo
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"short-link/internal/db"
 	"short-link/models"
+	"short-link/utils"
 	"time"
 )
 
@@ -23,22 +24,89 @@ func NewStatsRepo(pool *db.Pool) *StatsRepo {
 	return &StatsRepo{pool: pool}
 }
 
-// GetDailyStats 获取日统计（最近30天）
-func (r *StatsRepo) GetDailyStats(ctx context.Context, days int) ([]models.DailyStats, error) {
+// StatsScope 统计范围
+// - LinkID > 0：仅统计单个链接
+// - UserID > 0：仅统计该用户名下的链接
+// - 均为 0：全局统计（仅限管理员）
+type StatsScope struct {
+	LinkID int64
+	UserID int64
+}
+
+// where 生成 access_logs 的过滤条件（以 AND 开头），argPos 为占位符起始序号
+func (s StatsScope) where(argPos int) (string, []interface{}) {
+	switch {
+	case s.LinkID > 0:
+		return fmt.Sprintf(" AND link_id = $%d", argPos), []interface{}{s.LinkID}
+	case s.UserID > 0:
+		return fmt.Sprintf(" AND link_id IN (SELECT id FROM links WHERE user_id = $%d)", argPos), []interface{}{s.UserID}
+	}
+	return "", nil
+}
+
+// rollupWhere 生成预聚合表（click_rollups_*）的过滤条件，user_id 为冗余列无需回表
+func (s StatsScope) rollupWhere(argPos int) (string, []interface{}) {
+	switch {
+	case s.LinkID > 0:
+		return fmt.Sprintf(" AND link_id = $%d", argPos), []interface{}{s.LinkID}
+	case s.UserID > 0:
+		return fmt.Sprintf(" AND user_id = $%d", argPos), []interface{}{s.UserID}
+	}
+	return "", nil
+}
+
+// GetHourlyStats 获取小时统计（最近N小时，读取 click_rollups_hourly）
+func (r *StatsRepo) GetHourlyStats(ctx context.Context, scope StatsScope, hours int) ([]models.HourlyStats, error) {
+	if hours <= 0 {
+		hours = 24
+	}
+	query := `
+		SELECT 
+			TO_CHAR(bucket, 'YYYY-MM-DD HH24:00') as hour,
+			SUM(click_count) as click_count
+		FROM click_rollups_hourly
+		WHERE bucket >= date_trunc('hour', LOCALTIMESTAMP) - INTERVAL '%d hours'%s
+		GROUP BY bucket
+		ORDER BY bucket DESC
+		LIMIT $1
+	`
+	cond, args := scope.rollupWhere(2)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, hours-1, cond), append([]interface{}{hours}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("get hourly stats failed: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.HourlyStats
+	for rows.Next() {
+		var s models.HourlyStats
+		if err := rows.Scan(&s.Hour, &s.ClickCount); err != nil {
+			return nil, fmt.Errorf("scan hourly stats failed: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// GetDailyStats 获取日统计（最近N天）
+// 多个链接汇总时独立访客数由各链接当天的 visitors_hll sketch 合并估计（同一访客访问多个链接只计一次）
+func (r *StatsRepo) GetDailyStats(ctx context.Context, scope StatsScope, days int) ([]models.DailyStats, error) {
 	if days <= 0 {
 		days = 30
 	}
 	query := `
 		SELECT 
-			DATE(created_at) as date,
-			COUNT(*) as click_count
-		FROM access_logs
-		WHERE created_at >= NOW() - INTERVAL '%d days'
-		GROUP BY DATE(created_at)
-		ORDER BY date DESC
+			TO_CHAR(day, 'YYYY-MM-DD') as date,
+			SUM(click_count) as click_count,
+			MAX(unique_clicks) as unique_clicks
+		FROM click_rollups_daily
+		WHERE day >= CURRENT_DATE - %d%s
+		GROUP BY day
+		ORDER BY day DESC
 		LIMIT $1
 	`
-	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, days), days)
+	cond, args := scope.rollupWhere(2)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, days-1, cond), append([]interface{}{days}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("get daily stats failed: %w", err)
 	}
@@ -47,30 +115,89 @@ func (r *StatsRepo) GetDailyStats(ctx context.Context, days int) ([]models.Daily
 	var stats []models.DailyStats
 	for rows.Next() {
 		var s models.DailyStats
-		if err := rows.Scan(&s.Date, &s.ClickCount); err != nil {
+		if err := rows.Scan(&s.Date, &s.ClickCount, &s.UniqueClicks); err != nil {
 			return nil, fmt.Errorf("scan daily stats failed: %w", err)
 		}
 		stats = append(stats, s)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get daily stats failed: %w", err)
+	}
+	rows.Close()
+
+	if scope.LinkID == 0 && len(stats) > 0 {
+		if err := r.mergeDailyVisitors(ctx, scope, days, stats); err != nil {
+			return nil, err
+		}
+	}
 	return stats, nil
 }
 
+// mergeDailyVisitors 按天合并范围内各链接的 visitors_hll sketch，得到跨链接去重的独立访客数
+// 单个链接的 unique_clicks 可能高于 sketch 估计（Redis 估计值、sketch 之前的历史数据），取两者较大值
+func (r *StatsRepo) mergeDailyVisitors(ctx context.Context, scope StatsScope, days int, stats []models.DailyStats) error {
+	query := `
+		SELECT TO_CHAR(day, 'YYYY-MM-DD'), visitors_hll
+		FROM click_rollups_daily
+		WHERE day >= CURRENT_DATE - %d AND visitors_hll IS NOT NULL%s
+	`
+	cond, args := scope.rollupWhere(1)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, days-1, cond), args...)
+	if err != nil {
+		return fmt.Errorf("get daily visitor sketches failed: %w", err)
+	}
+	defer rows.Close()
+
+	sketches := make(map[string]*utils.HLL, len(stats))
+	for rows.Next() {
+		var (
+			date string
+			data []byte
+		)
+		if err := rows.Scan(&date, &data); err != nil {
+			return fmt.Errorf("scan daily visitor sketch failed: %w", err)
+		}
+		parsed, err := utils.ParseHLL(data)
+		if err != nil {
+			continue
+		}
+		if h, ok := sketches[date]; ok {
+			h.Merge(parsed)
+		} else {
+			sketches[date] = parsed
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("get daily visitor sketches failed: %w", err)
+	}
+
+	for i := range stats {
+		if h, ok := sketches[stats[i].Date]; ok {
+			if n := int64(h.Count()); n > stats[i].UniqueClicks {
+				stats[i].UniqueClicks = n
+			}
+		}
+	}
+	return nil
+}
+
 // GetWeeklyStats 获取周统计（最近12周）
-func (r *StatsRepo) GetWeeklyStats(ctx context.Context, weeks int) ([]models.WeeklyStats, error) {
+func (r *StatsRepo) GetWeeklyStats(ctx context.Context, scope StatsScope, weeks int) ([]models.WeeklyStats, error) {
 	if weeks <= 0 {
 		weeks = 12
 	}
 	query := `
 		SELECT 
-			TO_CHAR(created_at, 'IYYY-"W"IW') as week,
-			COUNT(*) as click_count
-		FROM access_logs
-		WHERE created_at >= NOW() - INTERVAL '%d weeks'
-		GROUP BY TO_CHAR(created_at, 'IYYY-"W"IW')
+			TO_CHAR(day, 'IYYY-"W"IW') as week,
+			SUM(click_count) as click_count
+		FROM click_rollups_daily
+		WHERE day >= CURRENT_DATE - INTERVAL '%d weeks'%s
+		GROUP BY TO_CHAR(day, 'IYYY-"W"IW')
 		ORDER BY week DESC
 		LIMIT $1
 	`
-	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, weeks), weeks)
+	cond, args := scope.rollupWhere(2)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, weeks, cond), append([]interface{}{weeks}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("get weekly stats failed: %w", err)
 	}
@@ -88,21 +215,22 @@ func (r *StatsRepo) GetWeeklyStats(ctx context.Context, weeks int) ([]models.Wee
 }
 
 // GetMonthlyStats 获取月统计（最近12个月）
-func (r *StatsRepo) GetMonthlyStats(ctx context.Context, months int) ([]models.MonthlyStats, error) {
+func (r *StatsRepo) GetMonthlyStats(ctx context.Context, scope StatsScope, months int) ([]models.MonthlyStats, error) {
 	if months <= 0 {
 		months = 12
 	}
 	query := `
 		SELECT 
-			TO_CHAR(created_at, 'YYYY-MM') as month,
-			COUNT(*) as click_count
-		FROM access_logs
-		WHERE created_at >= NOW() - INTERVAL '%d months'
-		GROUP BY TO_CHAR(created_at, 'YYYY-MM')
+			TO_CHAR(day, 'YYYY-MM') as month,
+			SUM(click_count) as click_count
+		FROM click_rollups_daily
+		WHERE day >= CURRENT_DATE - INTERVAL '%d months'%s
+		GROUP BY TO_CHAR(day, 'YYYY-MM')
 		ORDER BY month DESC
 		LIMIT $1
 	`
-	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, months), months)
+	cond, args := scope.rollupWhere(2)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, months, cond), append([]interface{}{months}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("get monthly stats failed: %w", err)
 	}
@@ -120,7 +248,7 @@ func (r *StatsRepo) GetMonthlyStats(ctx context.Context, months int) ([]models.M
 }
 
 // GetTopReferers 获取 Top 来源（Top N）
-func (r *StatsRepo) GetTopReferers(ctx context.Context, limit int) ([]models.RefererStats, error) {
+func (r *StatsRepo) GetTopReferers(ctx context.Context, scope StatsScope, limit int) ([]models.RefererStats, error) {
 	if limit <= 0 {
 		limit = 10
 	}
@@ -129,12 +257,13 @@ func (r *StatsRepo) GetTopReferers(ctx context.Context, limit int) ([]models.Ref
 			COALESCE(referer, 'direct') as referer,
 			COUNT(*) as click_count
 		FROM access_logs
-		WHERE referer IS NOT NULL OR referer = ''
+		WHERE (referer IS NOT NULL OR referer = '')%s
 		GROUP BY COALESCE(referer, 'direct')
 		ORDER BY click_count DESC
 		LIMIT $1
 	`
-	rows, err := r.pool.Query(ctx, query, limit)
+	cond, args := scope.where(2)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, cond), append([]interface{}{limit}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("get top referers failed: %w", err)
 	}
@@ -152,7 +281,7 @@ func (r *StatsRepo) GetTopReferers(ctx context.Context, limit int) ([]models.Ref
 }
 
 // GetTopUserAgents 获取 Top UA（Top N）
-func (r *StatsRepo) GetTopUserAgents(ctx context.Context, limit int) ([]models.UserAgentStats, error) {
+func (r *StatsRepo) GetTopUserAgents(ctx context.Context, scope StatsScope, limit int) ([]models.UserAgentStats, error) {
 	if limit <= 0 {
 		limit = 10
 	}
@@ -161,12 +290,13 @@ func (r *StatsRepo) GetTopUserAgents(ctx context.Context, limit int) ([]models.U
 			COALESCE(user_agent, 'unknown') as user_agent,
 			COUNT(*) as click_count
 		FROM access_logs
-		WHERE user_agent IS NOT NULL
+		WHERE user_agent IS NOT NULL%s
 		GROUP BY user_agent
 		ORDER BY click_count DESC
 		LIMIT $1
 	`
-	rows, err := r.pool.Query(ctx, query, limit)
+	cond, args := scope.where(2)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, cond), append([]interface{}{limit}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("get top user agents failed: %w", err)
 	}
@@ -184,7 +314,7 @@ func (r *StatsRepo) GetTopUserAgents(ctx context.Context, limit int) ([]models.U
 }
 
 // GetTopIPs 获取 Top IP（Top N）
-func (r *StatsRepo) GetTopIPs(ctx context.Context, limit int) ([]models.IPStats, error) {
+func (r *StatsRepo) GetTopIPs(ctx context.Context, scope StatsScope, limit int) ([]models.IPStats, error) {
 	if limit <= 0 {
 		limit = 10
 	}
@@ -193,12 +323,13 @@ func (r *StatsRepo) GetTopIPs(ctx context.Context, limit int) ([]models.IPStats,
 			COALESCE(ip, 'unknown') as ip,
 			COUNT(*) as click_count
 		FROM access_logs
-		WHERE ip IS NOT NULL
+		WHERE ip IS NOT NULL%s
 		GROUP BY ip
 		ORDER BY click_count DESC
 		LIMIT $1
 	`
-	rows, err := r.pool.Query(ctx, query, limit)
+	cond, args := scope.where(2)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, cond), append([]interface{}{limit}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("get top ips failed: %w", err)
 	}
@@ -215,11 +346,220 @@ func (r *StatsRepo) GetTopIPs(ctx context.Context, limit int) ([]models.IPStats,
 	return stats, nil
 }
 
+// topHumanDimension 按 UA 解析维度汇总（排除爬虫与未解析的历史记录）
+func (r *StatsRepo) topHumanDimension(ctx context.Context, scope StatsScope, column string, limit int) ([]string, []int64, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	query := `
+		SELECT 
+			%[1]s as dim,
+			COUNT(*) as click_count
+		FROM access_logs
+		WHERE NOT is_bot AND %[1]s IS NOT NULL%[2]s
+		GROUP BY %[1]s
+		ORDER BY click_count DESC
+		LIMIT $1
+	`
+	cond, args := scope.where(2)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, column, cond), append([]interface{}{limit}, args...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	var counts []int64
+	for rows.Next() {
+		var k string
+		var n int64
+		if err := rows.Scan(&k, &n); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, k)
+		counts = append(counts, n)
+	}
+	return keys, counts, rows.Err()
+}
+
+// GetTopBrowsers 获取 Top 浏览器（按家族汇总）
+func (r *StatsRepo) GetTopBrowsers(ctx context.Context, scope StatsScope, limit int) ([]models.BrowserStats, error) {
+	keys, counts, err := r.topHumanDimension(ctx, scope, "browser", limit)
+	if err != nil {
+		return nil, fmt.Errorf("get top browsers failed: %w", err)
+	}
+	stats := make([]models.BrowserStats, 0, len(keys))
+	for i := range keys {
+		stats = append(stats, models.BrowserStats{Browser: keys[i], ClickCount: counts[i]})
+	}
+	return stats, nil
+}
+
+// GetTopBrowserVersions 获取 Top 浏览器版本
+func (r *StatsRepo) GetTopBrowserVersions(ctx context.Context, scope StatsScope, limit int) ([]models.BrowserStats, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	query := `
+		SELECT 
+			browser,
+			COALESCE(browser_version, '') as version,
+			COUNT(*) as click_count
+		FROM access_logs
+		WHERE NOT is_bot AND browser IS NOT NULL%s
+		GROUP BY browser, browser_version
+		ORDER BY click_count DESC
+		LIMIT $1
+	`
+	cond, args := scope.where(2)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, cond), append([]interface{}{limit}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("get top browser versions failed: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.BrowserStats
+	for rows.Next() {
+		var s models.BrowserStats
+		if err := rows.Scan(&s.Browser, &s.Version, &s.ClickCount); err != nil {
+			return nil, fmt.Errorf("scan top browser versions failed: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// GetTopOS 获取 Top 操作系统
+func (r *StatsRepo) GetTopOS(ctx context.Context, scope StatsScope, limit int) ([]models.OSStats, error) {
+	keys, counts, err := r.topHumanDimension(ctx, scope, "os", limit)
+	if err != nil {
+		return nil, fmt.Errorf("get top os failed: %w", err)
+	}
+	stats := make([]models.OSStats, 0, len(keys))
+	for i := range keys {
+		stats = append(stats, models.OSStats{OS: keys[i], ClickCount: counts[i]})
+	}
+	return stats, nil
+}
+
+// GetDeviceStats 获取设备类型分布
+func (r *StatsRepo) GetDeviceStats(ctx context.Context, scope StatsScope) ([]models.DeviceStats, error) {
+	keys, counts, err := r.topHumanDimension(ctx, scope, "device", 10)
+	if err != nil {
+		return nil, fmt.Errorf("get device stats failed: %w", err)
+	}
+	stats := make([]models.DeviceStats, 0, len(keys))
+	for i := range keys {
+		stats = append(stats, models.DeviceStats{Device: keys[i], ClickCount: counts[i]})
+	}
+	return stats, nil
+}
+
+// GetTopCountries 获取 Top 国家/地区
+func (r *StatsRepo) GetTopCountries(ctx context.Context, scope StatsScope, limit int) ([]models.CountryStats, error) {
+	keys, counts, err := r.topHumanDimension(ctx, scope, "country_code", limit)
+	if err != nil {
+		return nil, fmt.Errorf("get top countries failed: %w", err)
+	}
+	stats := make([]models.CountryStats, 0, len(keys))
+	for i := range keys {
+		stats = append(stats, models.CountryStats{Country: keys[i], ClickCount: counts[i]})
+	}
+	return stats, nil
+}
+
+// GetTopCities 获取 Top 城市（同名城市按国家/省份区分）
+func (r *StatsRepo) GetTopCities(ctx context.Context, scope StatsScope, limit int) ([]models.CityStats, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	query := `
+		SELECT 
+			city,
+			COALESCE(region, '') as region,
+			COALESCE(country_code, '') as country,
+			COUNT(*) as click_count
+		FROM access_logs
+		WHERE NOT is_bot AND city IS NOT NULL%s
+		GROUP BY city, region, country_code
+		ORDER BY click_count DESC
+		LIMIT $1
+	`
+	cond, args := scope.where(2)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(query, cond), append([]interface{}{limit}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("get top cities failed: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.CityStats
+	for rows.Next() {
+		var s models.CityStats
+		if err := rows.Scan(&s.City, &s.Region, &s.Country, &s.ClickCount); err != nil {
+			return nil, fmt.Errorf("scan top cities failed: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// GetVariantStats 获取链接各 A/B 变体的点击数（含尚无点击的变体，不含爬虫）
+func (r *StatsRepo) GetVariantStats(ctx context.Context, linkID int64) ([]models.VariantStats, error) {
+	query := `
+		SELECT 
+			v.id,
+			v.label,
+			v.target_url,
+			v.weight,
+			COALESCE(a.click_count, 0) as click_count
+		FROM link_variants v
+		LEFT JOIN (
+			SELECT variant_id, COUNT(*) as click_count
+			FROM access_logs
+			WHERE link_id = $1 AND variant_id IS NOT NULL AND NOT is_bot
+			GROUP BY variant_id
+		) a ON a.variant_id = v.id
+		WHERE v.link_id = $1
+		ORDER BY v.id ASC
+	`
+	rows, err := r.pool.Query(ctx, query, linkID)
+	if err != nil {
+		return nil, fmt.Errorf("get variant stats failed: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.VariantStats
+	for rows.Next() {
+		var s models.VariantStats
+		if err := rows.Scan(&s.VariantID, &s.Label, &s.TargetURL, &s.Weight, &s.ClickCount); err != nil {
+			return nil, fmt.Errorf("scan variant stats failed: %w", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// GetBotStats 获取人类/爬虫访问数
+func (r *StatsRepo) GetBotStats(ctx context.Context, scope StatsScope) (*models.BotStats, error) {
+	cond, args := scope.where(1)
+	query := `
+		SELECT 
+			COUNT(*) FILTER (WHERE NOT is_bot),
+			COUNT(*) FILTER (WHERE is_bot)
+		FROM access_logs
+		WHERE TRUE` + cond
+	stats := &models.BotStats{}
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(&stats.HumanClicks, &stats.BotClicks); err != nil {
+		return nil, fmt.Errorf("get bot stats failed: %w", err)
+	}
+	return stats, nil
+}
+
 // GetTodayClicks 获取今日点击数
 func (r *StatsRepo) GetTodayClicks(ctx context.Context) (int64, error) {
 	today := time.Now().Format("2006-01-02")
 	var count int64
-	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM access_logs WHERE DATE(created_at) = $1`, today).Scan(&count)
+	err := r.pool.QueryRow(ctx, `SELECT COALESCE(SUM(click_count), 0) FROM click_rollups_daily WHERE day = $1::date`, today).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("get today clicks failed: %w", err)
 	}
@@ -236,3 +576,75 @@ func (r *StatsRepo) GetTotalClicks(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+// RebuildRollups 从 access_logs 重建预聚合表（回填/修复用），countBots 与 StatsWorker 口径一致
+// 说明：事务内先锁住预聚合表，StatsWorker 的并发写入会等待重建完成后再累加，避免重复计数
+// 只重建 access_logs 现存最早记录当天及之后的桶：按保留策略删除的分区对应的预聚合保留不动
+// 独立访客数（unique_clicks / visitors_hll）无法从访问日志还原（IP 可能已匿名化），重建时保留
+func (r *StatsRepo) RebuildRollups(ctx context.Context, countBots bool) (hourly int64, daily int64, err error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin rebuild rollups failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var legacy bool
+	if err := tx.QueryRow(ctx, `SELECT to_regclass('access_logs_legacy') IS NOT NULL`).Scan(&legacy); err != nil {
+		return 0, 0, fmt.Errorf("check legacy access logs failed: %w", err)
+	}
+	if legacy {
+		return 0, 0, fmt.Errorf("access_logs_legacy 尚未搬迁完成（由服务的分区维护任务或 nsl-admin -action=partitions 执行），完成后再重建")
+	}
+
+	if _, err := tx.Exec(ctx, `LOCK TABLE click_rollups_hourly, click_rollups_daily IN EXCLUSIVE MODE`); err != nil {
+		return 0, 0, fmt.Errorf("lock rollups failed: %w", err)
+	}
+	var since *time.Time
+	if err := tx.QueryRow(ctx, `SELECT date_trunc('day', MIN(created_at)) FROM access_logs`).Scan(&since); err != nil {
+		return 0, 0, fmt.Errorf("get earliest access log failed: %w", err)
+	}
+	if since == nil {
+		// 没有访问日志（可能已全部超出保留期）：保留现有预聚合
+		return 0, 0, tx.Commit(ctx)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM click_rollups_hourly WHERE bucket >= $1`, *since); err != nil {
+		return 0, 0, fmt.Errorf("clear hourly rollups failed: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE click_rollups_daily SET click_count = 0 WHERE day >= $1::date`, *since); err != nil {
+		return 0, 0, fmt.Errorf("clear daily rollups failed: %w", err)
+	}
+
+	ct, err := tx.Exec(ctx, `
+		INSERT INTO click_rollups_hourly (bucket, link_id, domain_id, user_id, click_count)
+		SELECT date_trunc('hour', a.created_at), a.link_id, COALESCE(l.domain_id, 0), COALESCE(l.user_id, 0), COUNT(*)
+		FROM access_logs a
+		JOIN links l ON l.id = a.link_id
+		WHERE a.created_at >= $2 AND ($1 OR NOT a.is_bot)
+		GROUP BY 1, 2, 3, 4
+	`, countBots, *since)
+	if err != nil {
+		return 0, 0, fmt.Errorf("rebuild hourly rollups failed: %w", err)
+	}
+	hourly = ct.RowsAffected()
+
+	ct, err = tx.Exec(ctx, `
+		INSERT INTO click_rollups_daily (day, link_id, domain_id, user_id, click_count)
+		SELECT a.created_at::date, a.link_id, COALESCE(l.domain_id, 0), COALESCE(l.user_id, 0), COUNT(*)
+		FROM access_logs a
+		JOIN links l ON l.id = a.link_id
+		WHERE a.created_at >= $2 AND ($1 OR NOT a.is_bot)
+		GROUP BY 1, 2, 3, 4
+		ON CONFLICT (link_id, day) DO UPDATE SET click_count = EXCLUDED.click_count
+	`, countBots, *since)
+	if err != nil {
+		return 0, 0, fmt.Errorf("rebuild daily rollups failed: %w", err)
+	}
+	daily = ct.RowsAffected()
+	if _, err := tx.Exec(ctx, `DELETE FROM click_rollups_daily WHERE day >= $1::date AND click_count = 0 AND unique_clicks = 0`, *since); err != nil {
+		return 0, 0, fmt.Errorf("clear empty daily rollups failed: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("commit rebuild rollups failed: %w", err)
+	}
+	return hourly, daily, nil
+}
@@ -0,0 +1,260 @@
+/**
+ * Tag Repo
+ * - 负责 tags / link_tags 表读写（用户级标签，与链接多对多）
+ * - 标签按 (user_id, name) 唯一，设置链接标签时不存在的标签自动创建
+ * - 变更链接标签的操作在同一事务内为受影响链接写入 link.updated outbox 事件（重建搜索索引）
+ */
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"short-link/internal/db"
+	"short-link/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TagRepo 标签仓储
+type TagRepo struct {
+	pool *db.Pool
+}
+
+// NewTagRepo 创建 TagRepo
+func NewTagRepo(pool *db.Pool) *TagRepo {
+	return &TagRepo{pool: pool}
+}
+
+// ListTags 获取用户的全部标签（含关联链接数，按名称排序）
+func (r *TagRepo) ListTags(ctx context.Context, userID int64) ([]models.Tag, error) {
+	query := `
+		SELECT t.id, t.user_id, t.name, t.created_at, COUNT(lt.link_id)
+		FROM tags t
+		LEFT JOIN link_tags lt ON lt.tag_id = t.id
+		WHERE t.user_id = $1
+		GROUP BY t.id
+		ORDER BY t.name ASC
+	`
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list tags failed: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt, &t.LinkCount); err != nil {
+			return nil, fmt.Errorf("scan tag failed: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// GetTag 获取单个标签（限定 user_id）
+func (r *TagRepo) GetTag(ctx context.Context, userID int64, tagID int64) (*models.Tag, error) {
+	t := &models.Tag{}
+	err := r.pool.QueryRow(ctx, `SELECT id, user_id, name, created_at FROM tags WHERE id = $1 AND user_id = $2`, tagID, userID).
+		Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get tag failed: %w", err)
+	}
+	return t, nil
+}
+
+// GetLinkTags 批量获取链接的标签名（link_id -> 按名称排序的标签）
+func (r *TagRepo) GetLinkTags(ctx context.Context, linkIDs []int64) (map[int64][]string, error) {
+	out := make(map[int64][]string, len(linkIDs))
+	if len(linkIDs) == 0 {
+		return out, nil
+	}
+	query := `
+		SELECT lt.link_id, t.name
+		FROM link_tags lt
+		JOIN tags t ON t.id = lt.tag_id
+		WHERE lt.link_id = ANY($1)
+		ORDER BY lt.link_id, t.name
+	`
+	rows, err := r.pool.Query(ctx, query, linkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get link tags failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			linkID int64
+			name   string
+		)
+		if err := rows.Scan(&linkID, &name); err != nil {
+			return nil, fmt.Errorf("scan link tag failed: %w", err)
+		}
+		out[linkID] = append(out[linkID], name)
+	}
+	return out, rows.Err()
+}
+
+// ReplaceLinkTags 整体替换链接的标签（link_id -> 标签名，空切片表示清空），单事务完成
+// 调用方需保证链接属于 userID
+func (r *TagRepo) ReplaceLinkTags(ctx context.Context, userID int64, linkTags map[int64][]string) error {
+	if len(linkTags) == 0 {
+		return nil
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin link tags tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	linkIDs, err := replaceLinkTagsTx(ctx, tx, userID, linkTags)
+	if err != nil {
+		return err
+	}
+	if err := insertLinkEvents(ctx, tx, models.OutboxLinkUpdated, `SELECT unnest($2::BIGINT[])`, linkIDs); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit link tags tx failed: %w", err)
+	}
+	return nil
+}
+
+// replaceLinkTagsTx 在调用方事务内替换链接标签（不写 outbox 事件），返回涉及的链接 ID
+func replaceLinkTagsTx(ctx context.Context, tx pgx.Tx, userID int64, linkTags map[int64][]string) ([]int64, error) {
+	linkIDs := make([]int64, 0, len(linkTags))
+	var pairLinks []int64
+	var pairNames []string
+	for linkID, names := range linkTags {
+		linkIDs = append(linkIDs, linkID)
+		for _, name := range names {
+			pairLinks = append(pairLinks, linkID)
+			pairNames = append(pairNames, name)
+		}
+	}
+
+	if len(pairNames) > 0 {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO tags (user_id, name)
+			SELECT DISTINCT $1::BIGINT, n FROM unnest($2::TEXT[]) AS n
+			ON CONFLICT (user_id, name) DO NOTHING
+		`, userID, pairNames); err != nil {
+			return nil, fmt.Errorf("create tags failed: %w", err)
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM link_tags WHERE link_id = ANY($1)`, linkIDs); err != nil {
+		return nil, fmt.Errorf("clear link tags failed: %w", err)
+	}
+	if len(pairNames) > 0 {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO link_tags (link_id, tag_id)
+			SELECT p.link_id, t.id
+			FROM unnest($2::BIGINT[], $3::TEXT[]) AS p(link_id, name)
+			JOIN tags t ON t.user_id = $1 AND t.name = p.name
+			ON CONFLICT DO NOTHING
+		`, userID, pairLinks, pairNames); err != nil {
+			return nil, fmt.Errorf("set link tags failed: %w", err)
+		}
+	}
+	return linkIDs, nil
+}
+
+// taggedLinksQuery 挂有任一指定标签（$2）的链接 ID 子查询，用于写入 outbox 事件
+const taggedLinksQuery = `SELECT link_id FROM link_tags WHERE tag_id = ANY($2::BIGINT[])`
+
+// RenameTag 重命名标签（新名称已存在时返回唯一约束错误，应改用合并）
+func (r *TagRepo) RenameTag(ctx context.Context, userID int64, tagID int64, name string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin rename tag tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	ct, err := tx.Exec(ctx, `UPDATE tags SET name = $1 WHERE id = $2 AND user_id = $3`, name, tagID, userID)
+	if err != nil {
+		return fmt.Errorf("rename tag failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if err := insertLinkEvents(ctx, tx, models.OutboxLinkUpdated, taggedLinksQuery, []int64{tagID}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit rename tag tx failed: %w", err)
+	}
+	return nil
+}
+
+// MergeTags 合并标签：sourceIDs 关联的链接改挂到 target（不存在时创建），随后删除 source 标签
+// 返回目标标签；不属于 userID 的 source 忽略
+func (r *TagRepo) MergeTags(ctx context.Context, userID int64, sourceIDs []int64, target string) (*models.Tag, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin merge tags tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	t := &models.Tag{}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO tags (user_id, name) VALUES ($1, $2)
+		ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id, user_id, name, created_at
+	`, userID, target).Scan(&t.ID, &t.UserID, &t.Name, &t.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("upsert merge target failed: %w", err)
+	}
+	// 事件须在 source 标签删除前写入（之后无法再按 source 查到链接）
+	if err := insertLinkEvents(ctx, tx, models.OutboxLinkUpdated,
+		`SELECT lt.link_id FROM link_tags lt JOIN tags s ON s.id = lt.tag_id AND s.user_id = $2 WHERE lt.tag_id = ANY($3) AND lt.tag_id <> $4`,
+		userID, sourceIDs, t.ID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO link_tags (link_id, tag_id)
+		SELECT lt.link_id, $3
+		FROM link_tags lt
+		JOIN tags s ON s.id = lt.tag_id AND s.user_id = $1
+		WHERE lt.tag_id = ANY($2) AND lt.tag_id <> $3
+		ON CONFLICT DO NOTHING
+	`, userID, sourceIDs, t.ID); err != nil {
+		return nil, fmt.Errorf("merge link tags failed: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM tags WHERE user_id = $1 AND id = ANY($2) AND id <> $3`, userID, sourceIDs, t.ID); err != nil {
+		return nil, fmt.Errorf("delete merged tags failed: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit merge tags tx failed: %w", err)
+	}
+	return t, nil
+}
+
+// DeleteTag 删除标签（关联随外键级联删除）
+func (r *TagRepo) DeleteTag(ctx context.Context, userID int64, tagID int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin delete tag tx failed: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err := insertLinkEvents(ctx, tx, models.OutboxLinkUpdated, taggedLinksQuery, []int64{tagID}); err != nil {
+		return err
+	}
+	ct, err := tx.Exec(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, tagID, userID)
+	if err != nil {
+		return fmt.Errorf("delete tag failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit delete tag tx failed: %w", err)
+	}
+	return nil
+}
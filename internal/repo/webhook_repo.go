@@ -0,0 +1,345 @@
+/**
+ * Webhook Repo
+ * - webhook_endpoints：用户注册的接收地址（按 user_id 隔离）
+ * - webhook_deliveries：投递记录；链接事件由 insertLinkEvents 在链接变更事务内写入，点击事件由 EnqueueClicks 按批写入
+ * - ClaimDeliveries：FOR UPDATE SKIP LOCKED 领取到期投递并顺延 next_attempt_at 作为租约，多副本互不重复领取
+ */
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"short-link/internal/db"
+	"short-link/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// webhookLinkData 链接事件 data 字段（别名 l 为 links 表，在链接变更事务内求值，为变更后的快照）
+const webhookLinkData = `jsonb_build_object('id', l.id, 'user_id', l.user_id, 'domain_id', l.domain_id, 'code', l.code,
+				'original_url', l.original_url, 'title', l.title, 'expires_at', l.expires_at, 'max_clicks', l.max_clicks,
+				'expired_at', l.expired_at, 'click_count', l.click_count, 'unique_clicks', l.unique_clicks,
+				'created_at', l.created_at, 'updated_at', l.updated_at)`
+
+// WebhookRepo webhook 仓储
+type WebhookRepo struct {
+	pool *db.Pool
+}
+
+// NewWebhookRepo 创建 WebhookRepo
+func NewWebhookRepo(pool *db.Pool) *WebhookRepo {
+	return &WebhookRepo{pool: pool}
+}
+
+// webhookEndpointColumns 查询列（不含 secret，与 scanWebhookEndpoint 顺序一致）
+const webhookEndpointColumns = `id, user_id, url, events, description, active, created_at, updated_at`
+
+func scanWebhookEndpoint(row pgx.Row, e *models.WebhookEndpoint) error {
+	return row.Scan(
+		&e.ID,
+		&e.UserID,
+		&e.URL,
+		&e.Events,
+		&e.Description,
+		&e.Active,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+	)
+}
+
+// webhookDeliveryColumns 投递记录列表查询列（不含 payload / response_body，与 scanWebhookDelivery 顺序一致）
+const webhookDeliveryColumns = `id, endpoint_id, event_type, event_id, status, attempts, response_status, last_error,
+		duration_ms, redelivery_of, next_attempt_at, created_at, delivered_at`
+
+func scanWebhookDelivery(row pgx.Row, d *models.WebhookDelivery, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&d.ID,
+		&d.EndpointID,
+		&d.EventType,
+		&d.EventID,
+		&d.Status,
+		&d.Attempts,
+		&d.ResponseStatus,
+		&d.LastError,
+		&d.DurationMs,
+		&d.RedeliveryOf,
+		&d.NextAttemptAt,
+		&d.CreatedAt,
+		&d.DeliveredAt,
+	}, extra...)...)
+}
+
+// CreateEndpoint 创建 webhook 接收地址
+func (r *WebhookRepo) CreateEndpoint(ctx context.Context, e *models.WebhookEndpoint) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO webhook_endpoints (user_id, url, secret, events, description, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, e.UserID, e.URL, e.Secret, e.Events, e.Description, e.Active, e.CreatedAt, e.UpdatedAt).Scan(&e.ID)
+	if err != nil {
+		return fmt.Errorf("create webhook endpoint failed: %w", err)
+	}
+	return nil
+}
+
+// ListEndpoints 获取用户的全部 webhook 接收地址
+func (r *WebhookRepo) ListEndpoints(ctx context.Context, userID int64) ([]models.WebhookEndpoint, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook endpoints failed: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []models.WebhookEndpoint
+	for rows.Next() {
+		var e models.WebhookEndpoint
+		if err := scanWebhookEndpoint(rows, &e); err != nil {
+			return nil, fmt.Errorf("scan webhook endpoint failed: %w", err)
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, rows.Err()
+}
+
+// CountEndpoints 统计用户的 webhook 接收地址数
+func (r *WebhookRepo) CountEndpoints(ctx context.Context, userID int64) (int, error) {
+	var n int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_endpoints WHERE user_id = $1`, userID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count webhook endpoints failed: %w", err)
+	}
+	return n, nil
+}
+
+// GetEndpoint 获取用户名下的 webhook 接收地址（不含 secret）
+func (r *WebhookRepo) GetEndpoint(ctx context.Context, userID int64, id int64) (*models.WebhookEndpoint, error) {
+	e := &models.WebhookEndpoint{}
+	err := scanWebhookEndpoint(r.pool.QueryRow(ctx, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE id = $1 AND user_id = $2`, id, userID), e)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get webhook endpoint failed: %w", err)
+	}
+	return e, nil
+}
+
+// UpdateEndpoint 更新 webhook 接收地址（url/events/description/active）
+func (r *WebhookRepo) UpdateEndpoint(ctx context.Context, e *models.WebhookEndpoint) error {
+	ct, err := r.pool.Exec(ctx, `
+		UPDATE webhook_endpoints SET url = $1, events = $2, description = $3, active = $4, updated_at = $5
+		WHERE id = $6 AND user_id = $7
+	`, e.URL, e.Events, e.Description, e.Active, e.UpdatedAt, e.ID, e.UserID)
+	if err != nil {
+		return fmt.Errorf("update webhook endpoint failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateSecret 轮换签名密钥
+func (r *WebhookRepo) UpdateSecret(ctx context.Context, userID int64, id int64, secret string, updatedAt time.Time) error {
+	ct, err := r.pool.Exec(ctx, `UPDATE webhook_endpoints SET secret = $1, updated_at = $2 WHERE id = $3 AND user_id = $4`,
+		secret, updatedAt, id, userID)
+	if err != nil {
+		return fmt.Errorf("update webhook secret failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteEndpoint 删除 webhook 接收地址（投递记录级联删除）
+func (r *WebhookRepo) DeleteEndpoint(ctx context.Context, userID int64, id int64) error {
+	ct, err := r.pool.Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete webhook endpoint failed: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// HasSubscribers 是否存在订阅了 eventType 的启用中的接收地址
+func (r *WebhookRepo) HasSubscribers(ctx context.Context, eventType string) (bool, error) {
+	var ok bool
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhook_endpoints WHERE active AND $1 = ANY(events))`, eventType).Scan(&ok)
+	if err != nil {
+		return false, fmt.Errorf("check webhook subscribers failed: %w", err)
+	}
+	return ok, nil
+}
+
+// EnqueueClicks 为一批点击写入 link.clicked 投递：每个订阅的接收地址一条，data.clicks 为其名下链接的点击（按输入顺序）
+// clicks 为 JSON 编码的 models.ClickEvent，user_id / code 取自 links；已删除链接的点击被忽略。返回写入的投递数
+func (r *WebhookRepo) EnqueueClicks(ctx context.Context, batchID string, linkIDs []int64, clicks []string) (int64, error) {
+	ct, err := r.pool.Exec(ctx, `
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, event_id, payload)
+		SELECT w.id, $4::text, 'clk_' || $1::text || '_' || w.id,
+			jsonb_build_object('id', 'clk_' || $1::text || '_' || w.id, 'type', $4::text, 'created_at', NOW(),
+				'data', jsonb_build_object('clicks', jsonb_agg(c.click::jsonb || jsonb_build_object('user_id', l.user_id, 'code', l.code) ORDER BY c.ord)))
+		FROM unnest($2::bigint[], $3::text[]) WITH ORDINALITY AS c(link_id, click, ord)
+		JOIN links l ON l.id = c.link_id
+		JOIN webhook_endpoints w ON w.user_id = l.user_id AND w.active AND $4::text = ANY(w.events)
+		GROUP BY w.id
+		ORDER BY w.id
+	`, batchID, linkIDs, clicks, models.WebhookLinkClicked)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue click webhooks failed: %w", err)
+	}
+	return ct.RowsAffected(), nil
+}
+
+// PendingDelivery 领取到的待投递记录（含接收地址与签名密钥）
+type PendingDelivery struct {
+	ID         int64
+	EndpointID int64
+	EventType  string
+	EventID    string
+	Payload    []byte
+	Attempts   int // 已尝试次数（含本次）
+	URL        string
+	Secret     string
+}
+
+// WebhookAttempt 一次投递尝试的结果
+type WebhookAttempt struct {
+	ResponseStatus int    // 0 表示未收到响应
+	ResponseBody   string // 已截断
+	Error          string
+	Duration       time.Duration
+}
+
+// ClaimDeliveries 领取最多 limit 个到期的 pending 投递（attempts +1，next_attempt_at 顺延 lease）
+// 停用的接收地址的投递保留为 pending，重新启用后继续投递
+func (r *WebhookRepo) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingDelivery, error) {
+	rows, err := r.pool.Query(ctx, `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_endpoints w ON w.id = d.endpoint_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.active
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		) c, webhook_endpoints w
+		WHERE d.id = c.id AND w.id = d.endpoint_id
+		RETURNING d.id, d.endpoint_id, d.event_type, d.event_id, d.payload, d.attempts, w.url, w.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries failed: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []PendingDelivery
+	for rows.Next() {
+		var d PendingDelivery
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.EventType, &d.EventID, &d.Payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, fmt.Errorf("scan webhook delivery failed: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordAttempt 记录投递结果：status 为 pending 时 retryAfter 后重试，succeeded 时记录送达时间
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, id int64, status string, retryAfter time.Duration, a *WebhookAttempt) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2::varchar, response_status = $3, response_body = $4, last_error = $5, duration_ms = $6,
+			next_attempt_at = CASE WHEN $2::varchar = 'pending' THEN NOW() + make_interval(secs => $7) ELSE next_attempt_at END,
+			delivered_at = CASE WHEN $2::varchar = 'succeeded' THEN NOW() ELSE delivered_at END
+		WHERE id = $1
+	`, id, status, nullInt64(int64(a.ResponseStatus)), nullString(a.ResponseBody), nullString(a.Error),
+		a.Duration.Milliseconds(), retryAfter.Seconds())
+	if err != nil {
+		return fmt.Errorf("record webhook attempt failed: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries 分页获取接收地址的投递记录（新到旧；status 为空表示全部）
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, endpointID int64, status string, page, limit int) ([]models.WebhookDelivery, int64, error) {
+	where := `endpoint_id = $1`
+	args := []interface{}{endpointID}
+	if status != "" {
+		where += ` AND status = $2`
+		args = append(args, status)
+	}
+
+	var total int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_deliveries WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count webhook deliveries failed: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM webhook_deliveries WHERE %s ORDER BY id DESC LIMIT $%d OFFSET $%d`,
+		webhookDeliveryColumns, where, len(args)+1, len(args)+2)
+	rows, err := r.pool.Query(ctx, query, append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list webhook deliveries failed: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, 0, fmt.Errorf("scan webhook delivery failed: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, total, rows.Err()
+}
+
+// GetDelivery 获取单条投递记录（含 payload 与响应体，限定 endpoint_id 防止越权）
+func (r *WebhookRepo) GetDelivery(ctx context.Context, endpointID int64, id int64) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{}
+	var payload []byte
+	err := scanWebhookDelivery(r.pool.QueryRow(ctx,
+		`SELECT `+webhookDeliveryColumns+`, payload, response_body FROM webhook_deliveries WHERE id = $1 AND endpoint_id = $2`, id, endpointID),
+		d, &payload, &d.ResponseBody)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get webhook delivery failed: %w", err)
+	}
+	d.Payload = payload
+	return d, nil
+}
+
+// Redeliver 复制投递记录为新的 pending 投递（沿用事件 ID 与 payload，立即投递）
+func (r *WebhookRepo) Redeliver(ctx context.Context, endpointID int64, id int64) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{}
+	err := scanWebhookDelivery(r.pool.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, event_id, payload, redelivery_of)
+		SELECT endpoint_id, event_type, event_id, payload, id
+		FROM webhook_deliveries
+		WHERE id = $1 AND endpoint_id = $2
+		RETURNING `+webhookDeliveryColumns, id, endpointID), d)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redeliver webhook failed: %w", err)
+	}
+	return d, nil
+}
+
+// PurgeDeliveries 清理创建超过 retention 的投递记录（含停用接收地址积压的 pending 投递），返回删除行数
+func (r *WebhookRepo) PurgeDeliveries(ctx context.Context, retention time.Duration) (int64, error) {
+	ct, err := r.pool.Exec(ctx, `
+		DELETE FROM webhook_deliveries WHERE created_at < NOW() - make_interval(secs => $1)
+	`, retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("purge webhook deliveries failed: %w", err)
+	}
+	return ct.RowsAffected(), nil
+}
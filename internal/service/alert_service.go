@@ -0,0 +1,179 @@
+/**
+ * Alert Service
+ * - 告警列表（GET /api/v2/alerts，仅本人）
+ * - 告警阈值：用户默认设置与单个链接覆盖（仅链接所有者，非所有者返回 repo.ErrNotFound）
+ */
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/models"
+)
+
+// AlertSettings 告警设置（全局默认、用户默认、链接覆盖）
+type AlertSettings struct {
+	Defaults     models.AlertRule        `json:"defaults"`
+	User         *models.AlertThreshold  `json:"user"`
+	Effective    models.AlertRule        `json:"effective"` // 用户默认设置合并后的生效值
+	Links        []models.AlertThreshold `json:"links"`
+	EmailEnabled bool                    `json:"email_enabled"` // 服务端是否配置了 SMTP
+}
+
+// LinkAlertSettings 单个链接的告警设置
+type LinkAlertSettings struct {
+	Override  *models.AlertThreshold `json:"override"`
+	Effective models.AlertRule       `json:"effective"`
+}
+
+// AlertService 告警服务
+type AlertService struct {
+	alertRepo    *repo.AlertRepo
+	linkRepo     *repo.LinkRepo
+	defaults     models.AlertRule
+	emailEnabled bool
+}
+
+// NewAlertService 创建 AlertService
+func NewAlertService(alertRepo *repo.AlertRepo, linkRepo *repo.LinkRepo, defaults models.AlertRule, emailEnabled bool) *AlertService {
+	return &AlertService{
+		alertRepo:    alertRepo,
+		linkRepo:     linkRepo,
+		defaults:     defaults,
+		emailEnabled: emailEnabled,
+	}
+}
+
+// ListAlerts 分页获取当前用户的告警
+func (s *AlertService) ListAlerts(ctx context.Context, userID int64, kind string, linkID int64, page, limit int) ([]models.ClickAlert, int64, error) {
+	if kind != "" && !validAlertKind(kind) {
+		return nil, 0, fmt.Errorf("无效的告警类型: %s", kind)
+	}
+	return s.alertRepo.ListAlerts(ctx, userID, kind, linkID, page, limit)
+}
+
+// GetSettings 获取当前用户的告警设置
+func (s *AlertService) GetSettings(ctx context.Context, userID int64) (*AlertSettings, error) {
+	thresholds, err := s.alertRepo.ListThresholds(ctx, []int64{userID})
+	if err != nil {
+		return nil, err
+	}
+	settings := &AlertSettings{Defaults: s.defaults, Links: []models.AlertThreshold{}, EmailEnabled: s.emailEnabled}
+	for i := range thresholds {
+		if thresholds[i].LinkID == nil {
+			settings.User = &thresholds[i]
+		} else {
+			settings.Links = append(settings.Links, thresholds[i])
+		}
+	}
+	settings.Effective = s.defaults.Apply(settings.User, nil)
+	return settings, nil
+}
+
+// UpdateUserSettings 设置当前用户的默认阈值
+func (s *AlertService) UpdateUserSettings(ctx context.Context, userID int64, req *models.AlertThresholdRequest) (*models.AlertThreshold, error) {
+	if err := validateAlertThreshold(req, false); err != nil {
+		return nil, err
+	}
+	t := buildAlertThreshold(userID, nil, req)
+	if err := s.alertRepo.UpsertThreshold(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetLinkSettings 获取链接的阈值覆盖与生效值
+func (s *AlertService) GetLinkSettings(ctx context.Context, userID int64, linkID int64) (*LinkAlertSettings, error) {
+	if _, err := ownedLink(ctx, s.linkRepo, userID, linkID); err != nil {
+		return nil, err
+	}
+	user, err := s.optionalThreshold(ctx, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	override, err := s.optionalThreshold(ctx, userID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	return &LinkAlertSettings{Override: override, Effective: s.defaults.Apply(user, override)}, nil
+}
+
+// UpdateLinkSettings 设置链接的阈值覆盖
+func (s *AlertService) UpdateLinkSettings(ctx context.Context, userID int64, linkID int64, req *models.AlertThresholdRequest) (*models.AlertThreshold, error) {
+	if _, err := ownedLink(ctx, s.linkRepo, userID, linkID); err != nil {
+		return nil, err
+	}
+	if err := validateAlertThreshold(req, true); err != nil {
+		return nil, err
+	}
+	t := buildAlertThreshold(userID, &linkID, req)
+	if err := s.alertRepo.UpsertThreshold(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// DeleteLinkSettings 删除链接的阈值覆盖（恢复继承用户默认设置）
+func (s *AlertService) DeleteLinkSettings(ctx context.Context, userID int64, linkID int64) error {
+	if _, err := ownedLink(ctx, s.linkRepo, userID, linkID); err != nil {
+		return err
+	}
+	return s.alertRepo.DeleteLinkThreshold(ctx, userID, linkID)
+}
+
+// optionalThreshold 获取阈值设置，不存在时返回 nil
+func (s *AlertService) optionalThreshold(ctx context.Context, userID int64, linkID int64) (*models.AlertThreshold, error) {
+	t, err := s.alertRepo.GetThreshold(ctx, userID, linkID)
+	if err == repo.ErrNotFound {
+		return nil, nil
+	}
+	return t, err
+}
+
+// buildAlertThreshold 由请求构造阈值设置
+func buildAlertThreshold(userID int64, linkID *int64, req *models.AlertThresholdRequest) *models.AlertThreshold {
+	return &models.AlertThreshold{
+		UserID:          userID,
+		LinkID:          linkID,
+		Enabled:         req.Enabled,
+		SpikeFactor:     req.SpikeFactor,
+		SpikeMinClicks:  req.SpikeMinClicks,
+		DropMinBaseline: req.DropMinBaseline,
+		BotRatio:        req.BotRatio,
+		NotifyEmail:     req.NotifyEmail,
+		UpdatedAt:       time.Now(),
+	}
+}
+
+// validateAlertThreshold 校验阈值范围（0 表示关闭对应检测）
+func validateAlertThreshold(req *models.AlertThresholdRequest, forLink bool) error {
+	if req.SpikeFactor != nil && *req.SpikeFactor != 0 && *req.SpikeFactor < 1 {
+		return fmt.Errorf("spike_factor 必须 >= 1（0 表示关闭激增告警）")
+	}
+	if req.SpikeMinClicks != nil && *req.SpikeMinClicks < 0 {
+		return fmt.Errorf("spike_min_clicks 不能为负数")
+	}
+	if req.DropMinBaseline != nil && *req.DropMinBaseline < 0 {
+		return fmt.Errorf("drop_min_baseline 不能为负数（0 表示关闭跌零告警）")
+	}
+	if req.BotRatio != nil && (*req.BotRatio < 0 || *req.BotRatio > 1) {
+		return fmt.Errorf("bot_ratio 必须在 0 到 1 之间（0 表示关闭爬虫流量告警）")
+	}
+	if forLink && req.NotifyEmail != nil {
+		return fmt.Errorf("notify_email 只能在用户默认设置中配置")
+	}
+	return nil
+}
+
+// validAlertKind 是否为已知告警类型
+func validAlertKind(kind string) bool {
+	for _, k := range models.AlertKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+
+	"short-link/models"
+)
+
+func TestValidateAlertThreshold(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	n := func(v int64) *int64 { return &v }
+	b := func(v bool) *bool { return &v }
+
+	valid := []models.AlertThresholdRequest{
+		{},
+		{SpikeFactor: f(0), DropMinBaseline: f(0), BotRatio: f(0)},
+		{SpikeFactor: f(3), SpikeMinClicks: n(10), DropMinBaseline: f(5), BotRatio: f(1)},
+	}
+	for i, req := range valid {
+		if err := validateAlertThreshold(&req, true); err != nil {
+			t.Errorf("valid[%d]: unexpected error: %v", i, err)
+		}
+	}
+
+	invalid := []models.AlertThresholdRequest{
+		{SpikeFactor: f(0.5)},
+		{SpikeMinClicks: n(-1)},
+		{DropMinBaseline: f(-1)},
+		{BotRatio: f(1.5)},
+	}
+	for i, req := range invalid {
+		if err := validateAlertThreshold(&req, false); err == nil {
+			t.Errorf("invalid[%d]: expected error", i)
+		}
+	}
+
+	notify := models.AlertThresholdRequest{NotifyEmail: b(true)}
+	if err := validateAlertThreshold(&notify, false); err != nil {
+		t.Errorf("notify_email should be allowed on user settings: %v", err)
+	}
+	if err := validateAlertThreshold(&notify, true); err == nil {
+		t.Error("notify_email should be rejected on link overrides")
+	}
+}
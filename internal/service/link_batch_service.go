@@ -0,0 +1,409 @@
+/**
+ * 批量创建短链（POST /api/v2/links/batch）
+ * - 并发校验 URL / 生命周期 / 访问密码，逐项返回结果（顺序与请求一致）
+ * - max_links、短码长度、默认域名只查询一次；幂等判断按 hash 一次查询
+ * - 多行 INSERT ... ON CONFLICT DO NOTHING 写入，随机 code 冲突的行重新生成后重试
+ * - 标签单事务写入；搜索索引由 outbox 事件异步写入，dispatcher 按批合并为一次 AddDocuments
+ */
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"short-link/models"
+	"short-link/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// batchConcurrency 校验 / 二维码生成的并发数
+	batchConcurrency = 8
+	// batchMaxRounds 随机 code 冲突重试轮数（每 3 轮递增一次长度）
+	batchMaxRounds = 9
+)
+
+// BatchCreateResult 批量创建单项结果
+type BatchCreateResult struct {
+	Link     *models.Link
+	ShortURL string
+	Created  bool // false 表示幂等命中已存在的链接
+	Err      error
+}
+
+// batchItem 批量创建的中间状态
+type batchItem struct {
+	req          *models.CreateLinkRequest
+	domain       *models.Domain
+	domainID     int64
+	hash         string
+	passwordHash string
+	customCode   string
+	tags         []string
+	folderID     *int64
+	dupOf        int // 同批次内幂等重复项（指向首个相同 URL 的下标，-1 表示无）
+	link         *models.Link
+}
+
+// forEachParallel 以固定并发数执行 fn(0..n-1)
+func forEachParallel(n, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// CreateLinksBatch 批量创建短链，返回与 reqs 等长、顺序一致的结果
+// 单项失败不影响其他项；仅在公共查询（幂等/短码长度）失败时返回 error
+func (s *LinkService) CreateLinksBatch(ctx context.Context, userID int64, reqs []models.CreateLinkRequest) ([]BatchCreateResult, error) {
+	results := make([]BatchCreateResult, len(reqs))
+	items := make([]batchItem, len(reqs))
+	now := time.Now()
+
+	// 1) 并发校验（URL 校验可能涉及 DNS 解析，bcrypt 计算耗时）
+	forEachParallel(len(reqs), batchConcurrency, func(i int) {
+		results[i].Err = s.prepareBatchItem(&items[i], &reqs[i], now)
+	})
+
+	// 2) 解析域名（同一 domain_id 只查询一次）
+	type domainResult struct {
+		domain *models.Domain
+		err    error
+	}
+	domains := map[int64]domainResult{}
+	resolveDomain := func(domainID int64) domainResult {
+		if r, ok := domains[domainID]; ok {
+			return r
+		}
+		var r domainResult
+		if domainID > 0 {
+			d, err := s.domainRepo.GetDomainByID(ctx, domainID)
+			switch {
+			case err != nil || (d.UserID != userID && d.UserID != 0):
+				r.err = fmt.Errorf("域名不存在或无权限")
+			case !d.IsActive:
+				r.err = fmt.Errorf("域名已停用")
+			default:
+				r.domain = d
+			}
+		} else if d, err := s.domainRepo.GetDefaultDomain(ctx, userID); err == nil && d != nil {
+			r.domain = d
+		}
+		domains[domainID] = r
+		return r
+	}
+	for i := range items {
+		if results[i].Err != nil {
+			continue
+		}
+		r := resolveDomain(items[i].req.DomainID)
+		if r.err != nil {
+			results[i].Err = r.err
+			continue
+		}
+		items[i].domain = r.domain
+		if r.domain != nil {
+			items[i].domainID = r.domain.ID
+		}
+	}
+
+	// 文件夹（同一 folder_id 只查询一次）
+	type folderResult struct {
+		id  *int64
+		err error
+	}
+	folders := map[int64]folderResult{}
+	for i := range items {
+		if results[i].Err != nil || items[i].req.FolderID <= 0 {
+			continue
+		}
+		folderID := items[i].req.FolderID
+		r, ok := folders[folderID]
+		if !ok {
+			r.id, r.err = s.resolveFolder(ctx, userID, folderID)
+			folders[folderID] = r
+		}
+		if r.err != nil {
+			results[i].Err = r.err
+			continue
+		}
+		items[i].folderID = r.id
+	}
+
+	// 3) 幂等：同一 user + domain + hash 返回已存在链接（指定生命周期或密码时总是新建）
+	var hashes []string
+	for i := range items {
+		if results[i].Err == nil && items[i].dedupable() {
+			hashes = append(hashes, items[i].hash)
+		}
+	}
+	existing, err := s.linkRepo.GetLinksByHashes(ctx, userID, hashes)
+	if err != nil {
+		return nil, err
+	}
+	pending, customCodes := s.planBatch(items, results, existing)
+
+	// 4) 用户链接数限制（max_links）：整批只检查一次，超出配额的项按顺序失败
+	if s.userRepo != nil && len(pending) > 0 {
+		u, err := s.userRepo.GetUserByID(ctx, userID)
+		if err == nil && u.MaxLinks != -1 {
+			cnt, err := s.linkRepo.CountLinksByUser(ctx, userID)
+			if err == nil {
+				remaining := int(int64(u.MaxLinks) - cnt)
+				if remaining < 0 {
+					remaining = 0
+				}
+				if len(pending) > remaining {
+					for _, i := range pending[remaining:] {
+						results[i].Err = fmt.Errorf("已达到最大链接数限制（%d条），请联系管理员", u.MaxLinks)
+					}
+					pending = pending[:remaining]
+				}
+			}
+		}
+	}
+
+	// 5) 写入：自定义 code 冲突直接失败，随机 code 冲突重新生成后重试
+	if err := s.insertBatch(ctx, userID, items, results, pending, customCodes, now); err != nil {
+		return nil, err
+	}
+
+	// 同批次幂等重复项复用首项结果
+	copyBatchDuplicates(items, results)
+
+	// 6) 标签（单事务）
+	var created []*models.Link
+	linkTags := map[int64][]string{}
+	for i := range results {
+		if results[i].Created {
+			created = append(created, results[i].Link)
+			if len(items[i].tags) > 0 {
+				linkTags[results[i].Link.ID] = items[i].tags
+			}
+		}
+	}
+	if len(linkTags) > 0 && s.tagRepo != nil {
+		if err := s.tagRepo.ReplaceLinkTags(ctx, userID, linkTags); err != nil {
+			utils.LogError("批量设置链接标签失败: user_id=%d, error=%v", userID, err)
+		} else {
+			for _, l := range created {
+				l.Tags = linkTags[l.ID]
+			}
+		}
+	}
+	return results, nil
+}
+
+// prepareBatchItem 校验单项请求并填充中间状态，返回该项的错误
+func (s *LinkService) prepareBatchItem(it *batchItem, req *models.CreateLinkRequest, now time.Time) error {
+	req.URL = strings.TrimSpace(req.URL)
+	it.req = req
+	it.dupOf = -1
+	if err := utils.ValidateExternalURL(req.URL); err != nil {
+		return fmt.Errorf("URL不合法: %s", err.Error())
+	}
+	if err := validateLifecycle(req, now); err != nil {
+		return err
+	}
+	if req.Password != "" {
+		if n := utf8.RuneCountInString(req.Password); n < 4 || n > 72 {
+			return fmt.Errorf("访问密码长度需为 4-72 个字符")
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("密码加密失败: %w", err)
+		}
+		it.passwordHash = string(hashed)
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return err
+	}
+	it.tags = tags
+	it.customCode = strings.TrimSpace(req.Code)
+	it.hash = s.GenerateHash(req.URL)
+	return nil
+}
+
+// dedupable 是否参与幂等判断（指定生命周期或密码时总是新建）
+func (it *batchItem) dedupable() bool {
+	return !it.req.HasLifecycle() && it.passwordHash == ""
+}
+
+// planBatch 幂等去重与自定义 code 去重，返回待写入项下标与已占用的 code（domain_id/code）
+// 命中已存在链接的项直接写入结果；同批次相同 URL 的项标记 dupOf；重复的自定义 code 逐项失败
+func (s *LinkService) planBatch(items []batchItem, results []BatchCreateResult, existing []models.Link) ([]int, map[string]bool) {
+	existingByKey := make(map[string]*models.Link, len(existing))
+	for i := range existing {
+		key := fmt.Sprintf("%d/%s", existing[i].DomainID, existing[i].Hash)
+		if _, ok := existingByKey[key]; !ok {
+			existingByKey[key] = &existing[i]
+		}
+	}
+	firstByKey := map[string]int{}
+	customCodes := map[string]bool{}
+	var pending []int
+	for i := range items {
+		it := &items[i]
+		if results[i].Err != nil {
+			continue
+		}
+		if it.dedupable() {
+			key := fmt.Sprintf("%d/%s", it.domainID, it.hash)
+			if l, ok := existingByKey[key]; ok {
+				results[i] = BatchCreateResult{Link: l, ShortURL: s.BuildShortURL(it.domain, l.Code)}
+				continue
+			}
+			if j, ok := firstByKey[key]; ok {
+				it.dupOf = j
+				continue
+			}
+			firstByKey[key] = i
+		}
+		if it.customCode != "" {
+			key := fmt.Sprintf("%d/%s", it.domainID, it.customCode)
+			if customCodes[key] {
+				results[i].Err = fmt.Errorf("代码 %s 已存在", it.customCode)
+				continue
+			}
+			customCodes[key] = true
+		}
+		pending = append(pending, i)
+	}
+	return pending, customCodes
+}
+
+// assignBatchCode 生成批次内未占用的随机 code 并登记到 usedCodes
+func (s *LinkService) assignBatchCode(domainID int64, length int, usedCodes map[string]bool) string {
+	for {
+		code := s.GenerateRandomCode(length)
+		key := fmt.Sprintf("%d/%s", domainID, code)
+		if !usedCodes[key] {
+			usedCodes[key] = true
+			return code
+		}
+	}
+}
+
+// copyBatchDuplicates 同批次幂等重复项复用首项结果
+func copyBatchDuplicates(items []batchItem, results []BatchCreateResult) {
+	for i := range items {
+		if j := items[i].dupOf; j >= 0 && results[i].Err == nil {
+			results[i] = BatchCreateResult{Link: results[j].Link, ShortURL: results[j].ShortURL, Err: results[j].Err}
+		}
+	}
+}
+
+// insertBatch 多轮多行插入 pending 项，结果写回 results
+func (s *LinkService) insertBatch(ctx context.Context, userID int64, items []batchItem, results []BatchCreateResult, pending []int, usedCodes map[string]bool, now time.Time) error {
+	if len(pending) == 0 {
+		return nil
+	}
+	length := 0
+	maxLen := 0
+	for _, i := range pending {
+		if items[i].customCode == "" {
+			var err error
+			if length, err = s.GetAvailableCodeLength(ctx); err != nil {
+				return fmt.Errorf("获取代码长度失败: %w", err)
+			}
+			_, maxLen, _ = s.getMinMaxCodeLength(ctx)
+			break
+		}
+	}
+
+	for round := 1; len(pending) > 0; round++ {
+		if round > batchMaxRounds {
+			for _, i := range pending {
+				results[i].Err = fmt.Errorf("生成短链冲突过多，请稍后重试")
+			}
+			return nil
+		}
+		if round > 1 && (round-1)%3 == 0 && length < maxLen {
+			length++
+		}
+
+		// 分配 code（随机 code 在批次内去重）
+		for _, i := range pending {
+			it := &items[i]
+			code := it.customCode
+			if code == "" {
+				code = s.assignBatchCode(it.domainID, length, usedCodes)
+			}
+			it.link = &models.Link{
+				UserID:             userID,
+				DomainID:           it.domainID,
+				Code:               code,
+				OriginalURL:        it.req.URL,
+				Title:              it.req.Title,
+				Hash:               it.hash,
+				ExpiresAt:          it.req.ExpiresAt,
+				MaxClicks:          it.req.MaxClicks,
+				ExpiredRedirectURL: strings.TrimSpace(it.req.ExpiredRedirectURL),
+				PasswordHash:       it.passwordHash,
+				FolderID:           it.folderID,
+				CreatedAt:          now,
+				UpdatedAt:          now,
+			}
+		}
+
+		// 二维码并发生成
+		forEachParallel(len(pending), batchConcurrency, func(k int) {
+			it := &items[pending[k]]
+			it.link.QRCode, _ = utils.GenerateQRCode(s.BuildShortURL(it.domain, it.link.Code), 256)
+		})
+
+		links := make([]*models.Link, len(pending))
+		for k, i := range pending {
+			links[k] = items[i].link
+		}
+		if _, err := s.linkRepo.CreateLinks(ctx, links); err != nil {
+			// 出错前的分块可能已写入，按 ID 区分
+			utils.LogError("批量创建链接失败: user_id=%d, error=%v", userID, err)
+			for _, i := range pending {
+				if l := items[i].link; l.ID > 0 {
+					results[i] = BatchCreateResult{Link: l, ShortURL: s.BuildShortURL(items[i].domain, l.Code), Created: true}
+				} else {
+					results[i].Err = errors.New("创建链接失败")
+				}
+			}
+			return nil
+		}
+
+		var retry []int
+		for _, i := range pending {
+			it := &items[i]
+			switch {
+			case it.link.ID > 0:
+				results[i] = BatchCreateResult{Link: it.link, ShortURL: s.BuildShortURL(it.domain, it.link.Code), Created: true}
+			case it.customCode != "":
+				results[i].Err = fmt.Errorf("代码 %s 已存在", it.customCode)
+			default:
+				retry = append(retry, i)
+			}
+		}
+		pending = retry
+	}
+	return nil
+}
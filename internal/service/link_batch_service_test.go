@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"short-link/models"
+)
+
+func TestPrepareBatchItem(t *testing.T) {
+	s := &LinkService{baseURL: "https://s.test"}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+
+	cases := []struct {
+		name    string
+		req     models.CreateLinkRequest
+		wantErr string // 为空表示成功
+	}{
+		{"valid", models.CreateLinkRequest{URL: " https://93.184.216.34/a ", Code: " mine ", Tags: []string{"x"}}, ""},
+		{"invalid url", models.CreateLinkRequest{URL: "javascript:alert(1)"}, "URL不合法"},
+		{"past expiry", models.CreateLinkRequest{URL: "https://93.184.216.34/a", ExpiresAt: &past}, "过期时间"},
+		{"short password", models.CreateLinkRequest{URL: "https://93.184.216.34/a", Password: "abc"}, "访问密码长度"},
+		{"long password", models.CreateLinkRequest{URL: "https://93.184.216.34/a", Password: strings.Repeat("p", 73)}, "访问密码长度"},
+	}
+	for _, tc := range cases {
+		var it batchItem
+		err := s.prepareBatchItem(&it, &tc.req, now)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.name, err)
+				continue
+			}
+			if it.req.URL != "https://93.184.216.34/a" || it.customCode != "mine" || it.hash != s.GenerateHash("https://93.184.216.34/a") || it.dupOf != -1 {
+				t.Errorf("%s: item not normalized: %+v", tc.name, it)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: err = %v, want containing %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestPlanBatch(t *testing.T) {
+	s := &LinkService{baseURL: "https://s.test"}
+	future := time.Now().Add(time.Hour)
+	item := func(url, code string, domainID int64) batchItem {
+		return batchItem{req: &models.CreateLinkRequest{URL: url}, hash: s.GenerateHash(url), customCode: code, domainID: domainID, dupOf: -1}
+	}
+	items := []batchItem{
+		0: item("https://a.test", "", 0),
+		1: item("https://a.test", "", 0),   // 同批次重复 -> 复用 0
+		2: item("https://a.test", "", 2),   // 不同域名 -> 独立创建
+		3: item("https://old.test", "", 0), // 已存在 -> 直接返回
+		4: item("https://b.test", "mine", 0),
+		5: item("https://c.test", "mine", 0), // 自定义 code 重复 -> 失败
+		6: item("https://d.test", "mine", 2), // 不同域名下相同 code -> 允许
+		7: item("https://e.test", "", 0),     // 已有校验错误 -> 跳过
+		8: item("https://a.test", "", 0),     // 带生命周期 -> 不去重
+	}
+	items[8].req.ExpiresAt = &future
+	results := make([]BatchCreateResult, len(items))
+	results[7].Err = fmt.Errorf("URL不合法")
+	existing := []models.Link{{ID: 42, Code: "old", DomainID: 0, Hash: s.GenerateHash("https://old.test")}}
+
+	pending, used := s.planBatch(items, results, existing)
+
+	if got := fmt.Sprint(pending); got != "[0 2 4 6 8]" {
+		t.Errorf("pending = %s, want [0 2 4 6 8]", got)
+	}
+	if items[1].dupOf != 0 {
+		t.Errorf("items[1].dupOf = %d, want 0", items[1].dupOf)
+	}
+	if items[2].dupOf != -1 || items[8].dupOf != -1 {
+		t.Errorf("items on other domains or with lifecycle must not be de-duplicated")
+	}
+	if results[3].Link == nil || results[3].Link.ID != 42 || results[3].Created || results[3].ShortURL != "https://s.test/old" {
+		t.Errorf("existing link result = %+v", results[3])
+	}
+	if results[5].Err == nil || !strings.Contains(results[5].Err.Error(), "mine") {
+		t.Errorf("duplicate custom code err = %v", results[5].Err)
+	}
+	for _, i := range []int{4, 6} {
+		if results[i].Err != nil {
+			t.Errorf("results[%d].Err = %v, want nil", i, results[i].Err)
+		}
+	}
+	if results[7].Err == nil || results[7].Err.Error() != "URL不合法" {
+		t.Errorf("prior validation error must be kept, got %v", results[7].Err)
+	}
+	if !used["0/mine"] || !used["2/mine"] || len(used) != 2 {
+		t.Errorf("used codes = %v", used)
+	}
+}
+
+func TestAssignBatchCodeAvoidsUsedCodes(t *testing.T) {
+	s := &LinkService{}
+	// 长度为 1 时只剩一个可用字符，生成结果必须是它
+	used := map[string]bool{}
+	for _, c := range v2Charset[1:] {
+		used["7/"+string(c)] = true
+	}
+	used["8/a"] = true // 其他域名占用不影响
+	if got := s.assignBatchCode(7, 1, used); got != "a" {
+		t.Fatalf("assignBatchCode = %q, want a", got)
+	}
+	if !used["7/a"] {
+		t.Fatal("assigned code should be marked as used")
+	}
+
+	used = map[string]bool{}
+	seen := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		code := s.assignBatchCode(1, 2, used)
+		if seen[code] {
+			t.Fatalf("code %q assigned twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestCopyBatchDuplicates(t *testing.T) {
+	link := &models.Link{ID: 9, Code: "xyz"}
+	items := []batchItem{{dupOf: -1}, {dupOf: 0}, {dupOf: 0}, {dupOf: -1}, {dupOf: 3}}
+	results := []BatchCreateResult{
+		{Link: link, ShortURL: "https://s.test/xyz", Created: true},
+		{},
+		{Err: fmt.Errorf("已有错误")},
+		{Err: fmt.Errorf("创建链接失败")},
+		{},
+	}
+	copyBatchDuplicates(items, results)
+
+	if results[1].Link != link || results[1].ShortURL != "https://s.test/xyz" || results[1].Created {
+		t.Errorf("duplicate should reuse first result without Created, got %+v", results[1])
+	}
+	if results[2].Err == nil || results[2].Err.Error() != "已有错误" {
+		t.Errorf("item with its own error must keep it, got %+v", results[2])
+	}
+	if results[4].Err == nil || results[4].Err.Error() != "创建链接失败" {
+		t.Errorf("duplicate of a failed item should fail too, got %+v", results[4])
+	}
+}
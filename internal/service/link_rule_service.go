@@ -0,0 +1,301 @@
+/**
+ * LinkRule Service
+ * - 定向跳转规则 CRUD（仅链接所有者），变更后清理跳转缓存
+ * - 规则匹配：国家（GeoIP）、设备/系统（UA 解析）、首选语言（Accept-Language）、时间窗口
+ */
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"short-link/cache"
+	"short-link/internal/geoip"
+	"short-link/internal/repo"
+	"short-link/models"
+	"short-link/utils"
+)
+
+// maxRulesPerLink 单个链接的规则数量上限（规则随跳转缓存整体存取）
+const maxRulesPerLink = 50
+
+// LinkRuleService 跳转规则服务
+type LinkRuleService struct {
+	linkRepo *repo.LinkRepo
+	ruleRepo *repo.LinkRuleRepo
+}
+
+// NewLinkRuleService 创建 LinkRuleService
+func NewLinkRuleService(linkRepo *repo.LinkRepo, ruleRepo *repo.LinkRuleRepo) *LinkRuleService {
+	return &LinkRuleService{
+		linkRepo: linkRepo,
+		ruleRepo: ruleRepo,
+	}
+}
+
+// ownedLink 获取当前用户名下的链接（非所有者返回 repo.ErrNotFound）
+func ownedLink(ctx context.Context, linkRepo *repo.LinkRepo, userID int64, linkID int64) (*models.Link, error) {
+	l, err := linkRepo.GetLinkByID(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if l.UserID != userID {
+		return nil, repo.ErrNotFound
+	}
+	return l, nil
+}
+
+// purgeRedirectCache 规则变更后清理跳转缓存（规则与 redir: 条目一起缓存）
+func purgeRedirectCache(l *models.Link) {
+	if err := cache.Delete(cache.RedirectKey(l.DomainID, l.Code)); err != nil {
+		utils.LogWarn("清理跳转缓存失败: link_id=%d, error=%v", l.ID, err)
+	}
+}
+
+// ListRules 获取链接的跳转规则
+func (s *LinkRuleService) ListRules(ctx context.Context, userID int64, linkID int64) ([]models.LinkRule, error) {
+	if _, err := ownedLink(ctx, s.linkRepo, userID, linkID); err != nil {
+		return nil, err
+	}
+	return s.ruleRepo.ListRules(ctx, linkID)
+}
+
+// CreateRule 新增跳转规则
+func (s *LinkRuleService) CreateRule(ctx context.Context, userID int64, linkID int64, req *models.LinkRuleRequest) (*models.LinkRule, error) {
+	l, err := ownedLink(ctx, s.linkRepo, userID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := buildLinkRule(req)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.ruleRepo.ListRules(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxRulesPerLink {
+		return nil, fmt.Errorf("每个链接最多 %d 条规则", maxRulesPerLink)
+	}
+
+	now := time.Now()
+	rule.LinkID = linkID
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	if err := s.ruleRepo.CreateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	purgeRedirectCache(l)
+	return rule, nil
+}
+
+// UpdateRule 更新跳转规则
+func (s *LinkRuleService) UpdateRule(ctx context.Context, userID int64, linkID int64, ruleID int64, req *models.LinkRuleRequest) (*models.LinkRule, error) {
+	l, err := ownedLink(ctx, s.linkRepo, userID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.ruleRepo.GetRule(ctx, linkID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := buildLinkRule(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.ID = current.ID
+	rule.LinkID = linkID
+	rule.CreatedAt = current.CreatedAt
+	rule.UpdatedAt = time.Now()
+	if err := s.ruleRepo.UpdateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	purgeRedirectCache(l)
+	return rule, nil
+}
+
+// DeleteRule 删除跳转规则
+func (s *LinkRuleService) DeleteRule(ctx context.Context, userID int64, linkID int64, ruleID int64) error {
+	l, err := ownedLink(ctx, s.linkRepo, userID, linkID)
+	if err != nil {
+		return err
+	}
+	if err := s.ruleRepo.DeleteRule(ctx, linkID, ruleID); err != nil {
+		return err
+	}
+	purgeRedirectCache(l)
+	return nil
+}
+
+// buildLinkRule 校验并规范化规则请求
+func buildLinkRule(req *models.LinkRuleRequest) (*models.LinkRule, error) {
+	target := strings.TrimSpace(req.TargetURL)
+	if err := utils.ValidateExternalURL(target); err != nil {
+		return nil, fmt.Errorf("目标地址不合法: %w", err)
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return nil, fmt.Errorf("ends_at 必须晚于 starts_at")
+	}
+
+	rule := &models.LinkRule{
+		Priority:  req.Priority,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		TargetURL: target,
+	}
+	for _, c := range req.Countries {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if len(c) != 2 {
+			return nil, fmt.Errorf("国家代码不合法: %q（应为 ISO 3166-1 两位代码）", c)
+		}
+		rule.Countries = append(rule.Countries, c)
+	}
+	for _, d := range req.Devices {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch d {
+		case utils.DeviceDesktop, utils.DeviceMobile, utils.DeviceTablet:
+		default:
+			return nil, fmt.Errorf("设备类型不合法: %q（可选 desktop/mobile/tablet）", d)
+		}
+		rule.Devices = append(rule.Devices, d)
+	}
+	for _, o := range req.OS {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			return nil, fmt.Errorf("系统名称不能为空")
+		}
+		rule.OS = append(rule.OS, o)
+	}
+	for _, lang := range req.Languages {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" || len(lang) > 35 {
+			return nil, fmt.Errorf("语言标签不合法: %q", lang)
+		}
+		rule.Languages = append(rule.Languages, lang)
+	}
+	if len(rule.Countries) == 0 && len(rule.Devices) == 0 && len(rule.OS) == 0 &&
+		len(rule.Languages) == 0 && rule.StartsAt == nil && rule.EndsAt == nil {
+		return nil, fmt.Errorf("规则至少需要一个匹配条件")
+	}
+	return rule, nil
+}
+
+// visitorProfile 规则匹配所需的访客属性（仅在链接存在规则时计算）
+type visitorProfile struct {
+	Country  string
+	Device   string
+	OS       string
+	Language string
+}
+
+// newVisitorProfile 由访客信息计算匹配属性（geo 为 nil 时国家为空）
+func newVisitorProfile(v *Visitor, geo *geoip.Enricher) *visitorProfile {
+	ua := utils.ParseUserAgent(v.UserAgent)
+	return &visitorProfile{
+		Country:  geo.Lookup(v.IP).CountryCode,
+		Device:   ua.Device,
+		OS:       ua.OS,
+		Language: preferredLanguage(v.AcceptLanguage),
+	}
+}
+
+// matchRule 判断规则是否命中（条件之间 AND，条件内 OR）
+func matchRule(r *models.LinkRule, p *visitorProfile, now time.Time) bool {
+	if r.StartsAt != nil && now.Before(*r.StartsAt) {
+		return false
+	}
+	if r.EndsAt != nil && !now.Before(*r.EndsAt) {
+		return false
+	}
+	if len(r.Countries) > 0 && !containsFold(r.Countries, p.Country) {
+		return false
+	}
+	if len(r.Devices) > 0 && !containsFold(r.Devices, p.Device) {
+		return false
+	}
+	if len(r.OS) > 0 && !containsFold(r.OS, p.OS) {
+		return false
+	}
+	if len(r.Languages) > 0 {
+		matched := false
+		for _, lang := range r.Languages {
+			if languageMatches(lang, p.Language) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// selectRuleTarget 按顺序匹配规则，返回首条命中的目标地址（未命中返回空）
+func selectRuleTarget(rules []models.LinkRule, p *visitorProfile, now time.Time) string {
+	for i := range rules {
+		if matchRule(&rules[i], p, now) {
+			return rules[i].TargetURL
+		}
+	}
+	return ""
+}
+
+func containsFold(values []string, v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, x := range values {
+		if strings.EqualFold(x, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// languageMatches 规则语言按前缀匹配：zh 命中 zh-cn / zh-tw，zh-cn 只命中 zh-cn
+func languageMatches(rule, lang string) bool {
+	if lang == "" {
+		return false
+	}
+	return lang == rule || strings.HasPrefix(lang, rule+"-")
+}
+
+// preferredLanguage 解析 Accept-Language，返回权重最高的语言标签（小写）
+func preferredLanguage(header string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.ToLower(strings.TrimSpace(fields[0]))
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{lang: lang, q: q})
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	return tags[0].lang
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"short-link/models"
+)
+
+func TestPreferredLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                            "",
+		"zh-CN,zh;q=0.9,en;q=0.8":     "zh-cn",
+		"en;q=0.5, fr-CA":             "fr-ca",
+		"*;q=0.1, de;q=0":             "",
+		"ja-JP;q=0.7, en-US;q=0.9, *": "en-us",
+	}
+	for header, want := range cases {
+		if got := preferredLanguage(header); got != want {
+			t.Errorf("preferredLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestSelectRuleTarget(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	rules := []models.LinkRule{
+		{OS: []string{"iOS"}, TargetURL: "https://apps.apple.com/app"},
+		{OS: []string{"Android"}, TargetURL: "https://play.google.com/store"},
+		{Countries: []string{"DE"}, Languages: []string{"de"}, TargetURL: "https://example.com/de"},
+		{EndsAt: &past, TargetURL: "https://example.com/expired-campaign"},
+	}
+
+	cases := []struct {
+		name    string
+		profile visitorProfile
+		want    string
+	}{
+		{"ios", visitorProfile{OS: "iOS", Device: "mobile"}, "https://apps.apple.com/app"},
+		{"android case-insensitive", visitorProfile{OS: "android"}, "https://play.google.com/store"},
+		{"german visitor", visitorProfile{Country: "DE", Language: "de-at", OS: "Windows"}, "https://example.com/de"},
+		{"german country, english browser", visitorProfile{Country: "DE", Language: "en-us"}, ""},
+		{"no geo", visitorProfile{Language: "de"}, ""},
+	}
+	for _, c := range cases {
+		if got := selectRuleTarget(rules, &c.profile, now); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -2,6 +2,8 @@
  * Link Service（重写版）
  * - 短链创建：幂等（user+domain+hash）、动态长度、并发安全重试
  * - URL 校验：复用 utils.ValidateExternalURL（基础 SSRF 防护）
+ * - 跳转：热点缓存（含生命周期、定向规则与 A/B 变体）
+ *   优先级：定向规则命中 > A/B 变体（粘性分配） > original_url
  */
 package service
 
@@ -10,29 +12,38 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
 	"short-link/cache"
+	"short-link/internal/geoip"
 	"short-link/internal/jobs"
 	"short-link/internal/repo"
 	"short-link/models"
 	"short-link/utils"
 	"strings"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 const v2Charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 // LinkService 链接服务（重写版）
 type LinkService struct {
-	linkRepo     *repo.LinkRepo
-	domainRepo   *repo.DomainRepo
-	settingsRepo *repo.SettingsRepo
-	userRepo     *repo.UserRepo
+	linkRepo      *repo.LinkRepo
+	domainRepo    *repo.DomainRepo
+	settingsRepo  *repo.SettingsRepo
+	userRepo      *repo.UserRepo
 	accessLogRepo *repo.AccessLogRepo
-	statsWorker  *jobs.StatsWorker // 异步统计 worker
-	meiliWorker  *jobs.MeiliWorker // Meilisearch 异步写入 worker
+	statsWorker   *jobs.StatsWorker     // 异步统计 worker
+	ruleRepo      *repo.LinkRuleRepo    // 定向跳转规则
+	variantRepo   *repo.LinkVariantRepo // A/B 分流变体
+	tagRepo       *repo.TagRepo         // 标签（可为 nil，不处理标签）
+	folderRepo    *repo.FolderRepo      // 文件夹（可为 nil，不处理文件夹）
+	geo           *geoip.Enricher       // 可为 nil（未配置 GeoIP，国家条件不命中）
 
 	// env 默认值（DB settings 可覆盖）
 	minCodeLen int
@@ -41,18 +52,22 @@ type LinkService struct {
 }
 
 // NewLinkService 创建 LinkService
-func NewLinkService(baseURL string, minCodeLen int, maxCodeLen int, linkRepo *repo.LinkRepo, domainRepo *repo.DomainRepo, settingsRepo *repo.SettingsRepo, userRepo *repo.UserRepo, accessLogRepo *repo.AccessLogRepo, statsWorker *jobs.StatsWorker, meiliWorker *jobs.MeiliWorker) *LinkService {
+func NewLinkService(baseURL string, minCodeLen int, maxCodeLen int, linkRepo *repo.LinkRepo, domainRepo *repo.DomainRepo, settingsRepo *repo.SettingsRepo, userRepo *repo.UserRepo, accessLogRepo *repo.AccessLogRepo, statsWorker *jobs.StatsWorker, ruleRepo *repo.LinkRuleRepo, variantRepo *repo.LinkVariantRepo, tagRepo *repo.TagRepo, folderRepo *repo.FolderRepo, geo *geoip.Enricher) *LinkService {
 	return &LinkService{
-		linkRepo:     linkRepo,
-		domainRepo:   domainRepo,
-		settingsRepo: settingsRepo,
-		userRepo:     userRepo,
+		linkRepo:      linkRepo,
+		domainRepo:    domainRepo,
+		settingsRepo:  settingsRepo,
+		userRepo:      userRepo,
 		accessLogRepo: accessLogRepo,
-		statsWorker:  statsWorker,
-		meiliWorker:  meiliWorker,
-		minCodeLen:   minCodeLen,
-		maxCodeLen:   maxCodeLen,
-		baseURL:      baseURL,
+		statsWorker:   statsWorker,
+		ruleRepo:      ruleRepo,
+		variantRepo:   variantRepo,
+		tagRepo:       tagRepo,
+		folderRepo:    folderRepo,
+		geo:           geo,
+		minCodeLen:    minCodeLen,
+		maxCodeLen:    maxCodeLen,
+		baseURL:       baseURL,
 	}
 }
 
@@ -212,11 +227,186 @@ func (s *LinkService) ResolveDomainForHost(ctx context.Context, hostport string)
 	return &found[0], nil
 }
 
-// RedirectLink v2 重定向解析（含热点缓存 + 点击/日志写入）
-func (s *LinkService) RedirectLink(ctx context.Context, hostport string, code string, ip string, userAgent string, referer string) (string, error) {
+var (
+	// ErrPasswordRequired 链接受密码保护，需要先解锁
+	ErrPasswordRequired = errors.New("password required")
+	// ErrInvalidLinkPassword 链接访问密码错误
+	ErrInvalidLinkPassword = errors.New("invalid link password")
+	// ErrTooManyUnlockAttempts 解锁密码错误次数过多
+	ErrTooManyUnlockAttempts = errors.New("too many unlock attempts")
+)
+
+// LinkGoneError 链接已过期或点击预算已用尽（对应 410 Gone）
+type LinkGoneError struct {
+	Reason      string // expired / exhausted
+	FallbackURL string // 可选的兜底跳转地址
+}
+
+func (e *LinkGoneError) Error() string {
+	return "链接已失效: " + e.Reason
+}
+
+// Visitor 跳转请求的访客信息（用于统计与定向规则匹配）
+type Visitor struct {
+	IP             string
+	UserAgent      string
+	Referer        string
+	AcceptLanguage string
+	VariantID      int64 // Cookie 中已分配的 A/B 变体（0 表示首次访问）
+	OptOut         bool  // 访客发送了 DNT: 1 或 Sec-GPC: 1
+}
+
+// RedirectResult 跳转结果
+type RedirectResult struct {
+	URL       string
+	VariantID int64 // 命中的 A/B 变体（0 表示未使用变体），handler 据此写入粘性 Cookie
+}
+
+// redirectCacheEntry 跳转缓存内容（携带生命周期，避免缓存越过过期时间）
+type redirectCacheEntry struct {
+	LinkID      int64             `json:"id"`
+	UserID      int64             `json:"uid"` // 链接所有者（实时点击流按所有者过滤）
+	Code        string            `json:"code"`
+	URL         string            `json:"url"`
+	ExpiresAt   int64             `json:"exp,omitempty"` // unix 秒，0 表示不过期
+	Exhausted   bool              `json:"exhausted,omitempty"`
+	FallbackURL string            `json:"fallback,omitempty"`
+	Protected   bool              `json:"protected,omitempty"` // 受密码保护（hash 不进缓存）
+	Rules       []models.LinkRule `json:"rules,omitempty"`     // 定向跳转规则（按匹配顺序）
+	Variants    []redirectVariant `json:"variants,omitempty"`  // A/B 分流变体
+}
+
+func newRedirectCacheEntry(l *models.Link) *redirectCacheEntry {
+	e := &redirectCacheEntry{
+		LinkID:      l.ID,
+		UserID:      l.UserID,
+		Code:        l.Code,
+		URL:         l.OriginalURL,
+		Exhausted:   l.IsExhausted(),
+		FallbackURL: l.ExpiredRedirectURL,
+		Protected:   l.IsProtected(),
+	}
+	if l.ExpiresAt != nil {
+		e.ExpiresAt = l.ExpiresAt.Unix()
+	}
+	return e
+}
+
+// gone 判断缓存的链接是否已失效
+func (e *redirectCacheEntry) gone(now time.Time) *LinkGoneError {
+	if e.ExpiresAt > 0 && now.Unix() >= e.ExpiresAt {
+		return &LinkGoneError{Reason: "expired", FallbackURL: e.FallbackURL}
+	}
+	if e.Exhausted {
+		return &LinkGoneError{Reason: "exhausted", FallbackURL: e.FallbackURL}
+	}
+	return nil
+}
+
+// ttl 缓存有效期：默认 1 小时，且不超过链接的过期时间
+func (e *redirectCacheEntry) ttl(now time.Time) time.Duration {
+	ttl := time.Hour
+	if e.ExpiresAt > 0 {
+		if until := time.Unix(e.ExpiresAt, 0).Sub(now); until > 0 && until < ttl {
+			ttl = until
+		}
+	}
+	return ttl
+}
+
+func getRedirectCache(key string) *redirectCacheEntry {
+	if cache.RedisClient == nil {
+		return nil
+	}
+	v, err := cache.Get(key)
+	if err != nil || v == "" {
+		return nil
+	}
+	var e redirectCacheEntry
+	// 缺少 code 的是旧版本写入的条目，按未命中处理（回源后覆盖）
+	if err := json.Unmarshal([]byte(v), &e); err != nil || e.LinkID == 0 || e.Code == "" {
+		return nil
+	}
+	return &e
+}
+
+func setRedirectCache(key string, e *redirectCacheEntry) {
+	if cache.RedisClient == nil {
+		return
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	_ = cache.Set(key, string(b), e.ttl(time.Now()))
+}
+
+// RedirectLink v2 重定向解析（含热点缓存 + 定向规则 + 点击/日志写入）
+// 已过期或点击预算已用尽时返回 *LinkGoneError；受密码保护时返回 ErrPasswordRequired
+func (s *LinkService) RedirectLink(ctx context.Context, hostport string, code string, v *Visitor) (*RedirectResult, error) {
+	e, err := s.resolveRedirect(ctx, hostport, code)
+	if err != nil {
+		return nil, err
+	}
+	if goneErr := e.gone(time.Now()); goneErr != nil {
+		return nil, goneErr
+	}
+	if e.Protected {
+		return nil, ErrPasswordRequired
+	}
+	return s.serveRedirect(e, v), nil
+}
+
+// UnlockLink 校验访问密码后返回跳转地址（密码错误返回 ErrInvalidLinkPassword）
+// limiter 非 nil 时按 链接域名 + code + 访客 IP 限制密码错误次数：校验前原子占用名额，密码正确时归还，
+// 名额用尽返回 ErrTooManyUnlockAttempts
+func (s *LinkService) UnlockLink(ctx context.Context, hostport string, code string, password string, v *Visitor, limiter *utils.SlidingWindowLimiter) (*RedirectResult, error) {
+	e, err := s.resolveRedirect(ctx, hostport, code)
+	if err != nil {
+		return nil, err
+	}
+	if goneErr := e.gone(time.Now()); goneErr != nil {
+		return nil, goneErr
+	}
+	if e.Protected {
+		// 缓存中不保存 hash，解锁时回源 DB 校验
+		l, err := s.linkRepo.GetLinkByID(ctx, e.LinkID)
+		if err != nil {
+			return nil, err
+		}
+		release := func() {}
+		if limiter != nil {
+			var allowed bool
+			if allowed, release = limiter.Reserve(unlockLimitKey(l, v.IP)); !allowed {
+				return nil, ErrTooManyUnlockAttempts
+			}
+		}
+		if err := verifyLinkPassword(l, password); err != nil {
+			return nil, err
+		}
+		release()
+	}
+	return s.serveRedirect(e, v), nil
+}
+
+// unlockLimitKey 解锁限流 key（按链接所在域名区分，同一 code 在不同域名下互不影响）
+func unlockLimitKey(l *models.Link, ip string) string {
+	return fmt.Sprintf("link_unlock:%d:%s:%s", l.DomainID, l.Code, ip)
+}
+
+// verifyLinkPassword 校验访问密码（未设置密码时直接通过，密码错误返回 ErrInvalidLinkPassword）
+func verifyLinkPassword(l *models.Link, password string) error {
+	if l.IsProtected() && bcrypt.CompareHashAndPassword([]byte(l.PasswordHash), []byte(password)) != nil {
+		return ErrInvalidLinkPassword
+	}
+	return nil
+}
+
+// resolveRedirect 按 Host + code 解析跳转目标（优先读热点缓存）
+func (s *LinkService) resolveRedirect(ctx context.Context, hostport string, code string) (*redirectCacheEntry, error) {
 	code = strings.TrimSpace(code)
 	if code == "" {
-		return "", repo.ErrNotFound
+		return nil, repo.ErrNotFound
 	}
 
 	// 解析域名（优先）
@@ -226,65 +416,96 @@ func (s *LinkService) RedirectLink(ctx context.Context, hostport string, code st
 		domainID = domain.ID
 	}
 
-	cacheKey := fmt.Sprintf("redir:%d:%s", domainID, code)
-	if cache.RedisClient != nil {
-		if v, err := cache.Get(cacheKey); err == nil && v != "" {
-			parts := strings.SplitN(v, "|", 2)
-			if len(parts) == 2 {
-				// 异步提交统计任务（非阻塞）
-				linkID := parseInt64(parts[0])
-				if s.statsWorker != nil {
-					s.statsWorker.Submit(linkID, ip, userAgent, referer)
-				}
-				return parts[1], nil
-			}
-		}
+	cacheKey := cache.RedirectKey(domainID, code)
+	if e := getRedirectCache(cacheKey); e != nil {
+		return e, nil
 	}
 
 	// 主查：domain + code
 	if domain != nil {
 		l, err := s.linkRepo.GetLinkByCode(ctx, code, domain.ID)
 		if err == nil {
-			// 异步提交统计任务（非阻塞）
-			if s.statsWorker != nil {
-				s.statsWorker.Submit(l.ID, ip, userAgent, referer)
-			}
-			if cache.RedisClient != nil {
-				_ = cache.Set(cacheKey, fmt.Sprintf("%d|%s", l.ID, l.OriginalURL), time.Hour)
-			}
-			return l.OriginalURL, nil
+			return s.buildRedirectEntry(ctx, l, cacheKey), nil
 		}
 	}
 
 	// 兼容回退：host 未识别时，若全库只有一个 code 命中则允许跳转，否则 404
 	ls, err := s.linkRepo.GetLinkByCodeAnyDomain(ctx, code, 2)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if len(ls) != 1 {
-		return "", repo.ErrNotFound
+		return nil, repo.ErrNotFound
 	}
 	l := ls[0]
-	// 异步提交统计任务（非阻塞）
-	if s.statsWorker != nil {
-		s.statsWorker.Submit(l.ID, ip, userAgent, referer)
+	return s.buildRedirectEntry(ctx, &l, cache.RedirectKey(l.DomainID, code)), nil
+}
+
+// buildRedirectEntry 回源构建缓存条目（附带定向规则与 A/B 变体）并写入缓存
+// 规则/变体读取失败时本次按原始地址跳转，且不写缓存，避免缓存残缺的条目
+func (s *LinkService) buildRedirectEntry(ctx context.Context, l *models.Link, cacheKey string) *redirectCacheEntry {
+	e := newRedirectCacheEntry(l)
+	if s.ruleRepo != nil {
+		rules, err := s.ruleRepo.ListRules(ctx, l.ID)
+		if err != nil {
+			utils.LogWarn("读取跳转规则失败: link_id=%d, error=%v", l.ID, err)
+			return e
+		}
+		e.Rules = rules
 	}
-	if cache.RedisClient != nil {
-		_ = cache.Set(fmt.Sprintf("redir:%d:%s", l.DomainID, code), fmt.Sprintf("%d|%s", l.ID, l.OriginalURL), time.Hour)
+	if s.variantRepo != nil {
+		variants, err := s.variantRepo.ListVariants(ctx, l.ID)
+		if err != nil {
+			utils.LogWarn("读取分流变体失败: link_id=%d, error=%v", l.ID, err)
+			e.Rules = nil
+			return e
+		}
+		for _, v := range variants {
+			e.Variants = append(e.Variants, redirectVariant{ID: v.ID, URL: v.TargetURL, Weight: v.Weight})
+		}
 	}
-	return l.OriginalURL, nil
+	setRedirectCache(cacheKey, e)
+	return e
 }
 
-func parseInt64(s string) int64 {
-	var n int64
-	for i := 0; i < len(s); i++ {
-		ch := s[i]
-		if ch < '0' || ch > '9' {
-			return 0
+// serveRedirect 匹配定向规则 / 分配 A/B 变体、异步提交统计任务并返回目标地址
+func (s *LinkService) serveRedirect(e *redirectCacheEntry, v *Visitor) *RedirectResult {
+	res := &RedirectResult{URL: e.URL}
+	matched := false
+	if len(e.Rules) > 0 {
+		if t := selectRuleTarget(e.Rules, newVisitorProfile(v, s.geo), time.Now()); t != "" {
+			res.URL = t
+			matched = true
 		}
-		n = n*10 + int64(ch-'0')
 	}
-	return n
+	if !matched {
+		if pv := pickVariant(e.LinkID, e.Variants, v.VariantID, v.IP); pv != nil {
+			res.URL = pv.URL
+			res.VariantID = pv.ID
+		}
+	}
+
+	// 异步提交统计任务（非阻塞）；点击预算由 StatsWorker 写入计数时判定
+	if s.statsWorker != nil {
+		s.statsWorker.Submit(e.LinkID, e.UserID, e.Code, v.IP, v.UserAgent, v.Referer, res.VariantID, v.OptOut)
+	}
+	return res
+}
+
+// validateLifecycle 校验生命周期参数（过期时间/点击预算/兜底地址）
+func validateLifecycle(req *models.CreateLinkRequest, now time.Time) error {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return fmt.Errorf("过期时间必须晚于当前时间")
+	}
+	if req.MaxClicks != nil && *req.MaxClicks <= 0 {
+		return fmt.Errorf("max_clicks 必须大于 0")
+	}
+	if fallback := strings.TrimSpace(req.ExpiredRedirectURL); fallback != "" {
+		if err := utils.ValidateExternalURL(fallback); err != nil {
+			return fmt.Errorf("expired_redirect_url不合法: %s", err.Error())
+		}
+	}
+	return nil
 }
 
 // CreateLink 创建短链（v2）
@@ -292,6 +513,27 @@ func (s *LinkService) CreateLink(ctx context.Context, userID int64, req *models.
 	if err := utils.ValidateExternalURL(req.URL); err != nil {
 		return nil, "", fmt.Errorf("URL不合法: %s", err.Error())
 	}
+	if err := validateLifecycle(req, time.Now()); err != nil {
+		return nil, "", err
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, "", err
+	}
+	folderID, err := s.resolveFolder(ctx, userID, req.FolderID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 访问密码：与用户密码一致使用 bcrypt 存储
+	passwordHash := ""
+	if req.Password != "" {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, "", fmt.Errorf("密码加密失败: %w", err)
+		}
+		passwordHash = string(hashed)
+	}
 
 	// 用户链接数限制（max_links）
 	if s.userRepo != nil && s.linkRepo != nil {
@@ -330,10 +572,12 @@ func (s *LinkService) CreateLink(ctx context.Context, userID int64, req *models.
 
 	hash := s.GenerateHash(req.URL)
 
-	// 幂等：同一 user + domain + hash 返回已存在链接
-	if existing, err := s.linkRepo.GetLinkByHashUserDomain(ctx, hash, userID, domainID); err == nil && existing != nil {
-		shortURL := s.BuildShortURL(domain, existing.Code)
-		return existing, shortURL, nil
+	// 幂等：同一 user + domain + hash 返回已存在链接（指定生命周期或密码时总是新建）
+	if !req.HasLifecycle() && passwordHash == "" {
+		if existing, err := s.linkRepo.GetLinkByHashUserDomain(ctx, hash, userID, domainID); err == nil && existing != nil {
+			shortURL := s.BuildShortURL(domain, existing.Code)
+			return existing, shortURL, nil
+		}
 	}
 
 	now := time.Now()
@@ -351,16 +595,21 @@ func (s *LinkService) CreateLink(ctx context.Context, userID int64, req *models.
 		shortURL := s.BuildShortURL(domain, code)
 		qr, _ := utils.GenerateQRCode(shortURL, 256)
 		link := &models.Link{
-			UserID:      userID,
-			DomainID:    domainID,
-			Code:        code,
-			OriginalURL: req.URL,
-			Title:       req.Title,
-			Hash:        hash,
-			QRCode:      qr,
-			ClickCount:  0,
-			CreatedAt:   now,
-			UpdatedAt:   now,
+			UserID:             userID,
+			DomainID:           domainID,
+			Code:               code,
+			OriginalURL:        req.URL,
+			Title:              req.Title,
+			Hash:               hash,
+			QRCode:             qr,
+			ClickCount:         0,
+			ExpiresAt:          req.ExpiresAt,
+			MaxClicks:          req.MaxClicks,
+			ExpiredRedirectURL: strings.TrimSpace(req.ExpiredRedirectURL),
+			PasswordHash:       passwordHash,
+			FolderID:           folderID,
+			CreatedAt:          now,
+			UpdatedAt:          now,
 		}
 		if err := s.linkRepo.CreateLink(ctx, link); err != nil {
 			if repo.IsUniqueViolation(err) {
@@ -368,10 +617,7 @@ func (s *LinkService) CreateLink(ctx context.Context, userID int64, req *models.
 			}
 			return nil, "", fmt.Errorf("创建链接失败: %w", err)
 		}
-		// 异步提交 Meilisearch 索引任务（非阻塞）
-		if s.meiliWorker != nil {
-			s.meiliWorker.Submit("index", link, link.ID)
-		}
+		s.afterCreate(ctx, link, tags)
 		return link, shortURL, nil
 	}
 
@@ -389,23 +635,25 @@ func (s *LinkService) CreateLink(ctx context.Context, userID int64, req *models.
 		shortURL := s.BuildShortURL(domain, code)
 		qr, _ := utils.GenerateQRCode(shortURL, 256)
 		link := &models.Link{
-			UserID:      userID,
-			DomainID:    domainID,
-			Code:        code,
-			OriginalURL: req.URL,
-			Title:       req.Title,
-			Hash:        hash,
-			QRCode:      qr,
-			ClickCount:  0,
-			CreatedAt:   now,
-			UpdatedAt:   now,
+			UserID:             userID,
+			DomainID:           domainID,
+			Code:               code,
+			OriginalURL:        req.URL,
+			Title:              req.Title,
+			Hash:               hash,
+			QRCode:             qr,
+			ClickCount:         0,
+			ExpiresAt:          req.ExpiresAt,
+			MaxClicks:          req.MaxClicks,
+			ExpiredRedirectURL: strings.TrimSpace(req.ExpiredRedirectURL),
+			PasswordHash:       passwordHash,
+			FolderID:           folderID,
+			CreatedAt:          now,
+			UpdatedAt:          now,
 		}
 		err := s.linkRepo.CreateLink(ctx, link)
 		if err == nil {
-			// 异步提交 Meilisearch 索引任务（非阻塞）
-			if s.meiliWorker != nil {
-				s.meiliWorker.Submit("index", link, link.ID)
-			}
+			s.afterCreate(ctx, link, tags)
 			return link, shortURL, nil
 		}
 		if repo.IsUniqueViolation(err) {
@@ -425,12 +673,134 @@ func (s *LinkService) CreateLink(ctx context.Context, userID int64, req *models.
 	}
 }
 
-// GetStats 获取全局统计信息
-func (s *LinkService) GetStats(ctx context.Context) (*models.LinkStats, error) {
+// afterCreate 新建链接后写入标签（标签写入失败只记录日志，不影响创建结果）
+// 搜索索引由 outbox 事件异步写入（见 jobs.OutboxDispatcher）
+func (s *LinkService) afterCreate(ctx context.Context, link *models.Link, tags []string) {
+	if len(tags) > 0 && s.tagRepo != nil {
+		if err := s.tagRepo.ReplaceLinkTags(ctx, link.UserID, map[int64][]string{link.ID: tags}); err != nil {
+			utils.LogError("设置链接标签失败: link_id=%d, error=%v", link.ID, err)
+		} else {
+			link.Tags = tags
+		}
+	}
+}
+
+// resolveFolder 校验文件夹归属，0 表示未归档（返回 nil）
+func (s *LinkService) resolveFolder(ctx context.Context, userID int64, folderID int64) (*int64, error) {
+	if folderID <= 0 || s.folderRepo == nil {
+		return nil, nil
+	}
+	f, err := s.folderRepo.GetFolder(ctx, userID, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("文件夹不存在或无权限")
+	}
+	return &f.ID, nil
+}
+
+// UpdateLink 更新短链（仅限所有者）
+// - 修改 original_url 时重新做 URL 校验并重算 hash
+// - 修改 code 时检查域名内冲突并重新生成二维码
+// - tags 非 nil 时整体替换标签，folder_id 为 0 表示移出文件夹
+// - 更新成功后清理跳转缓存（搜索索引由 outbox 事件异步重建）
+// 返回更新前/后的链接（用于审计）与新的短链接
+func (s *LinkService) UpdateLink(ctx context.Context, userID int64, linkID int64, req *models.UpdateLinkRequest) (*models.Link, *models.Link, string, error) {
+	before, err := s.linkRepo.GetLinkByID(ctx, linkID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if before.UserID != userID {
+		return nil, nil, "", repo.ErrNotFound
+	}
+
+	if s.tagRepo != nil {
+		tags, err := s.tagRepo.GetLinkTags(ctx, []int64{linkID})
+		if err != nil {
+			return nil, nil, "", err
+		}
+		before.Tags = tags[linkID]
+	}
+
+	after := *before
+	var newTags []string
+	if req.Tags != nil {
+		if newTags, err = normalizeTags(*req.Tags); err != nil {
+			return nil, nil, "", err
+		}
+		after.Tags = newTags
+	}
+	if req.FolderID != nil {
+		if after.FolderID, err = s.resolveFolder(ctx, userID, *req.FolderID); err != nil {
+			return nil, nil, "", err
+		}
+	}
+	if req.URL != nil {
+		newURL := strings.TrimSpace(*req.URL)
+		if err := utils.ValidateExternalURL(newURL); err != nil {
+			return nil, nil, "", fmt.Errorf("URL不合法: %s", err.Error())
+		}
+		after.OriginalURL = newURL
+		after.Hash = s.GenerateHash(newURL)
+	}
+	if req.Title != nil {
+		after.Title = *req.Title
+	}
+	if req.Code != nil {
+		code := strings.TrimSpace(*req.Code)
+		if code == "" {
+			return nil, nil, "", fmt.Errorf("代码不能为空")
+		}
+		if code != before.Code {
+			exists, err := s.linkRepo.CheckCodeExistsInDomain(ctx, code, before.DomainID)
+			if err != nil {
+				return nil, nil, "", fmt.Errorf("检查代码失败: %w", err)
+			}
+			if exists {
+				return nil, nil, "", fmt.Errorf("代码 %s 已存在", code)
+			}
+			after.Code = code
+		}
+	}
+
+	var domain *models.Domain
+	if before.DomainID > 0 {
+		if d, err := s.domainRepo.GetDomainByID(ctx, before.DomainID); err == nil {
+			domain = d
+		}
+	}
+	shortURL := s.BuildShortURL(domain, after.Code)
+	if after.Code != before.Code {
+		qr, _ := utils.GenerateQRCode(shortURL, 256)
+		after.QRCode = qr
+	}
+	after.UpdatedAt = time.Now()
+
+	// 标签与链接字段在同一事务内写入，不会出现链接已更新而标签失败的中间状态
+	var tags *[]string
+	if req.Tags != nil && s.tagRepo != nil {
+		tags = &newTags
+	}
+	if err := s.linkRepo.UpdateLink(ctx, &after, tags); err != nil {
+		if repo.IsUniqueViolation(err) {
+			return nil, nil, "", fmt.Errorf("代码 %s 已存在", after.Code)
+		}
+		return nil, nil, "", fmt.Errorf("更新链接失败: %w", err)
+	}
+
+	// 清理旧 code 的跳转缓存（新 code 不可能已有缓存）
+	if cache.RedisClient != nil {
+		if err := cache.Delete(cache.RedirectKey(before.DomainID, before.Code)); err != nil {
+			utils.LogWarn("清理跳转缓存失败: link_id=%d, error=%v", before.ID, err)
+		}
+	}
+	return before, &after, shortURL, nil
+}
+
+// GetStats 获取统计信息（userID > 0 时仅统计该用户的链接，0 表示全局）
+func (s *LinkService) GetStats(ctx context.Context, userID int64) (*models.LinkStats, error) {
 	if s.linkRepo == nil {
 		return nil, fmt.Errorf("link repo 未初始化")
 	}
-	return s.linkRepo.GetLinkStats(ctx)
+	return s.linkRepo.GetLinkStats(ctx, userID)
 }
 
 // GetAggregatedStats 获取聚合统计信息（日/周/月、来源、UA 等维度）
@@ -439,10 +809,3 @@ func (s *LinkService) GetAggregatedStats(ctx context.Context) (*models.Aggregate
 	// TODO: 在 router 中注入 statsRepo
 	return nil, fmt.Errorf("聚合统计功能待实现")
 }
-
-// GetMeiliWorker 获取 Meilisearch Worker（用于启动/停止）
-func (s *LinkService) GetMeiliWorker() *jobs.MeiliWorker {
-	return s.meiliWorker
-}
-
-
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"short-link/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidateLifecycle(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+	zero, five := int64(0), int64(5)
+
+	cases := []struct {
+		name    string
+		req     models.CreateLinkRequest
+		wantErr bool
+	}{
+		{"no lifecycle", models.CreateLinkRequest{}, false},
+		{"future expiry", models.CreateLinkRequest{ExpiresAt: &future}, false},
+		{"past expiry", models.CreateLinkRequest{ExpiresAt: &past}, true},
+		{"expiry equal to now", models.CreateLinkRequest{ExpiresAt: &now}, true},
+		{"positive budget", models.CreateLinkRequest{MaxClicks: &five}, false},
+		{"zero budget", models.CreateLinkRequest{MaxClicks: &zero}, true},
+		{"fallback url", models.CreateLinkRequest{ExpiresAt: &future, ExpiredRedirectURL: " https://93.184.216.34/gone "}, false},
+		{"invalid fallback url", models.CreateLinkRequest{ExpiredRedirectURL: "javascript:alert(1)"}, true},
+	}
+	for _, tc := range cases {
+		err := validateLifecycle(&tc.req, now)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestRedirectCacheEntryGone(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Second)
+	future := now.Add(10 * time.Minute)
+	budget := int64(3)
+
+	cases := []struct {
+		name         string
+		link         models.Link
+		wantReason   string // 为空表示未失效
+		wantFallback string
+	}{
+		{"active", models.Link{ID: 1, Code: "a"}, "", ""},
+		{"not yet expired", models.Link{ID: 1, Code: "a", ExpiresAt: &future}, "", ""},
+		{"expired", models.Link{ID: 1, Code: "a", ExpiresAt: &past}, "expired", ""},
+		{"expired with fallback", models.Link{ID: 1, Code: "a", ExpiresAt: &past, ExpiredRedirectURL: "https://example.com/gone"}, "expired", "https://example.com/gone"},
+		{"budget left", models.Link{ID: 1, Code: "a", MaxClicks: &budget, ClickCount: 2}, "", ""},
+		{"budget exhausted", models.Link{ID: 1, Code: "a", MaxClicks: &budget, ClickCount: 3, ExpiredRedirectURL: "https://example.com/gone"}, "exhausted", "https://example.com/gone"},
+	}
+	for _, tc := range cases {
+		goneErr := newRedirectCacheEntry(&tc.link).gone(now)
+		if tc.wantReason == "" {
+			if goneErr != nil {
+				t.Errorf("%s: unexpected gone error %v", tc.name, goneErr)
+			}
+			continue
+		}
+		if goneErr == nil || goneErr.Reason != tc.wantReason || goneErr.FallbackURL != tc.wantFallback {
+			t.Errorf("%s: got %+v, want reason %q fallback %q", tc.name, goneErr, tc.wantReason, tc.wantFallback)
+		}
+	}
+}
+
+func TestRedirectCacheEntryTTL(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	soon := now.Add(10 * time.Minute)
+	later := now.Add(3 * time.Hour)
+
+	if got := newRedirectCacheEntry(&models.Link{ID: 1, Code: "a"}).ttl(now); got != time.Hour {
+		t.Errorf("no expiry: ttl = %v, want 1h", got)
+	}
+	if got := newRedirectCacheEntry(&models.Link{ID: 1, Code: "a", ExpiresAt: &soon}).ttl(now); got != 10*time.Minute {
+		t.Errorf("expiring soon: ttl = %v, want 10m", got)
+	}
+	if got := newRedirectCacheEntry(&models.Link{ID: 1, Code: "a", ExpiresAt: &later}).ttl(now); got != time.Hour {
+		t.Errorf("expiring later: ttl = %v, want 1h", got)
+	}
+}
+
+func TestVerifyLinkPassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	protected := &models.Link{PasswordHash: string(hash)}
+
+	cases := []struct {
+		name     string
+		link     *models.Link
+		password string
+		want     error
+	}{
+		{"unprotected link ignores password", &models.Link{}, "anything", nil},
+		{"correct password", protected, "s3cret", nil},
+		{"wrong password", protected, "guess", ErrInvalidLinkPassword},
+		{"empty password", protected, "", ErrInvalidLinkPassword},
+		{"password is case sensitive", protected, "S3CRET", ErrInvalidLinkPassword},
+	}
+	for _, tc := range cases {
+		if got := verifyLinkPassword(tc.link, tc.password); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestUnlockLimitKey(t *testing.T) {
+	a := unlockLimitKey(&models.Link{DomainID: 1, Code: "abc"}, "1.2.3.4")
+	b := unlockLimitKey(&models.Link{DomainID: 2, Code: "abc"}, "1.2.3.4")
+	if a == b {
+		t.Fatalf("same code on different domains must not share a counter: %s", a)
+	}
+	if a != "link_unlock:1:abc:1.2.3.4" {
+		t.Fatalf("key = %s", a)
+	}
+}
@@ -0,0 +1,184 @@
+/**
+ * LinkTag Service
+ * - 标签：列表 / 重命名 / 合并 / 删除（用户级，创建/更新链接时自动创建）
+ * - 文件夹：列表 / 创建 / 重命名 / 删除（一级，删除后链接变为未归档）
+ * - 受影响链接的搜索索引由 repo 在同一事务内写入 outbox 事件后异步重建（tags / folder_id 为可过滤字段）
+ */
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"short-link/internal/repo"
+	"short-link/models"
+)
+
+const (
+	// maxTagsPerLink 单个链接的标签数量上限
+	maxTagsPerLink = 20
+	// maxTagLength 标签名最大长度（字符）
+	maxTagLength = 50
+	// maxFolderNameLength 文件夹名最大长度（字符）
+	maxFolderNameLength = 100
+)
+
+// normalizeTagName 规范化标签名：去除首尾空白、合并连续空白
+func normalizeTagName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", fmt.Errorf("标签名不能为空")
+	}
+	if utf8.RuneCountInString(name) > maxTagLength {
+		return "", fmt.Errorf("标签名不能超过 %d 个字符", maxTagLength)
+	}
+	return name, nil
+}
+
+// normalizeTags 规范化并去重标签列表（保持首次出现的顺序），空白项忽略
+func normalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		if strings.TrimSpace(t) == "" {
+			continue
+		}
+		name, err := normalizeTagName(t)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	if len(out) > maxTagsPerLink {
+		return nil, fmt.Errorf("每个链接最多 %d 个标签", maxTagsPerLink)
+	}
+	return out, nil
+}
+
+// normalizeFolderName 规范化文件夹名
+func normalizeFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("文件夹名不能为空")
+	}
+	if utf8.RuneCountInString(name) > maxFolderNameLength {
+		return "", fmt.Errorf("文件夹名不能超过 %d 个字符", maxFolderNameLength)
+	}
+	return name, nil
+}
+
+// LinkTagService 标签与文件夹服务
+type LinkTagService struct {
+	tagRepo    *repo.TagRepo
+	folderRepo *repo.FolderRepo
+}
+
+// NewLinkTagService 创建 LinkTagService
+func NewLinkTagService(tagRepo *repo.TagRepo, folderRepo *repo.FolderRepo) *LinkTagService {
+	return &LinkTagService{
+		tagRepo:    tagRepo,
+		folderRepo: folderRepo,
+	}
+}
+
+// ListTags 获取用户的标签（含链接数）
+func (s *LinkTagService) ListTags(ctx context.Context, userID int64) ([]models.Tag, error) {
+	return s.tagRepo.ListTags(ctx, userID)
+}
+
+// RenameTag 重命名标签（目标名称已存在时提示改用合并）
+func (s *LinkTagService) RenameTag(ctx context.Context, userID int64, tagID int64, name string) (*models.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := s.tagRepo.GetTag(ctx, userID, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.Name == name {
+		return tag, nil
+	}
+	if err := s.tagRepo.RenameTag(ctx, userID, tagID, name); err != nil {
+		if repo.IsUniqueViolation(err) {
+			return nil, fmt.Errorf("标签 %s 已存在，请使用合并", name)
+		}
+		return nil, err
+	}
+	tag.Name = name
+	return tag, nil
+}
+
+// MergeTags 合并标签：source 的链接改挂到 target 后删除 source
+func (s *LinkTagService) MergeTags(ctx context.Context, userID int64, req *models.MergeTagsRequest) (*models.Tag, error) {
+	target, err := normalizeTagName(req.Target)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range req.SourceIDs {
+		if _, err := s.tagRepo.GetTag(ctx, userID, id); err != nil {
+			return nil, err
+		}
+	}
+	return s.tagRepo.MergeTags(ctx, userID, req.SourceIDs, target)
+}
+
+// DeleteTag 删除标签（仅解除与链接的关联）
+func (s *LinkTagService) DeleteTag(ctx context.Context, userID int64, tagID int64) error {
+	if _, err := s.tagRepo.GetTag(ctx, userID, tagID); err != nil {
+		return err
+	}
+	return s.tagRepo.DeleteTag(ctx, userID, tagID)
+}
+
+// ListFolders 获取用户的文件夹（含链接数）
+func (s *LinkTagService) ListFolders(ctx context.Context, userID int64) ([]models.Folder, error) {
+	return s.folderRepo.ListFolders(ctx, userID)
+}
+
+// CreateFolder 创建文件夹
+func (s *LinkTagService) CreateFolder(ctx context.Context, userID int64, name string) (*models.Folder, error) {
+	name, err := normalizeFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	f := &models.Folder{UserID: userID, Name: name, CreatedAt: now, UpdatedAt: now}
+	if err := s.folderRepo.CreateFolder(ctx, f); err != nil {
+		if repo.IsUniqueViolation(err) {
+			return nil, fmt.Errorf("文件夹 %s 已存在", name)
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// RenameFolder 重命名文件夹（链接只关联 folder_id，无需重建索引）
+func (s *LinkTagService) RenameFolder(ctx context.Context, userID int64, folderID int64, name string) (*models.Folder, error) {
+	name, err := normalizeFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.folderRepo.RenameFolder(ctx, userID, folderID, name); err != nil {
+		if repo.IsUniqueViolation(err) {
+			return nil, fmt.Errorf("文件夹 %s 已存在", name)
+		}
+		return nil, err
+	}
+	return s.folderRepo.GetFolder(ctx, userID, folderID)
+}
+
+// DeleteFolder 删除文件夹（其中的链接变为未归档）
+func (s *LinkTagService) DeleteFolder(ctx context.Context, userID int64, folderID int64) error {
+	if _, err := s.folderRepo.GetFolder(ctx, userID, folderID); err != nil {
+		return err
+	}
+	return s.folderRepo.DeleteFolder(ctx, userID, folderID)
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := normalizeTags([]string{"  营销 ", "2024  春季", "", "营销", "  "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"营销", "2024 春季"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if _, err := normalizeTags([]string{strings.Repeat("标", maxTagLength+1)}); err == nil {
+		t.Fatalf("expected error for overlong tag")
+	}
+
+	many := make([]string, maxTagsPerLink+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("t%d", i)
+	}
+	if _, err := normalizeTags(many); err == nil {
+		t.Fatalf("expected error for too many tags")
+	}
+}
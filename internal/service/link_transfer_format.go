@@ -0,0 +1,368 @@
+/**
+ * 链接导入/导出格式（csv / json / ndjson）
+ * - 编码器逐条写出，json 以数组形式流式输出
+ * - 解码器逐条读取；单条记录字段错误返回 RecordError（跳过该条继续），其余错误终止导入
+ * - password_hash 只出现在全实例导出中；用户导出仅以 password_protected 标记受保护的链接
+ */
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"short-link/models"
+)
+
+// 支持的导入/导出格式
+const (
+	LinkFormatCSV    = "csv"
+	LinkFormatJSON   = "json"
+	LinkFormatNDJSON = "ndjson"
+)
+
+// ParseLinkFormat 校验并规范化格式名
+func ParseLinkFormat(format string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(format)); f {
+	case LinkFormatCSV, LinkFormatJSON, LinkFormatNDJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("不支持的格式: %q（可选 csv/json/ndjson）", format)
+	}
+}
+
+// RecordError 单条记录无效（导入时跳过该条，不影响后续记录）
+type RecordError struct {
+	Err error
+}
+
+func (e *RecordError) Error() string { return e.Err.Error() }
+
+// linkEncoder 导出编码器
+type linkEncoder interface {
+	Encode(rec *models.LinkRecord) error
+	Close() error
+}
+
+// newLinkEncoder 创建导出编码器；withOwner 为 true 时输出 username 与 password_hash 列（全实例导出）
+func newLinkEncoder(format string, w io.Writer, withOwner bool) (linkEncoder, error) {
+	switch format {
+	case LinkFormatCSV:
+		cw := csv.NewWriter(w)
+		header := []string{"domain", "code", "original_url", "title", "click_count", "created_at",
+			"expires_at", "max_clicks", "expired_redirect_url", "password_protected", "folder", "tags"}
+		if withOwner {
+			header = append([]string{"username"}, header...)
+			header = append(header, "password_hash")
+		}
+		if err := cw.Write(header); err != nil {
+			return nil, err
+		}
+		return &csvLinkEncoder{w: cw, withOwner: withOwner}, nil
+	case LinkFormatJSON:
+		return &jsonLinkEncoder{w: w, withOwner: withOwner}, nil
+	case LinkFormatNDJSON:
+		return &ndjsonLinkEncoder{enc: json.NewEncoder(w), withOwner: withOwner}, nil
+	default:
+		return nil, fmt.Errorf("不支持的格式: %q", format)
+	}
+}
+
+type csvLinkEncoder struct {
+	w         *csv.Writer
+	withOwner bool
+}
+
+func (e *csvLinkEncoder) Encode(rec *models.LinkRecord) error {
+	var expiresAt, maxClicks, protected string
+	if rec.ExpiresAt != nil {
+		expiresAt = rec.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if rec.MaxClicks != nil {
+		maxClicks = strconv.FormatInt(*rec.MaxClicks, 10)
+	}
+	if rec.PasswordProtected {
+		protected = "true"
+	}
+	tags, err := joinCSVList(rec.Tags)
+	if err != nil {
+		return err
+	}
+	row := []string{
+		rec.Domain,
+		rec.Code,
+		rec.OriginalURL,
+		rec.Title,
+		strconv.FormatInt(rec.ClickCount, 10),
+		rec.CreatedAt.UTC().Format(time.RFC3339),
+		expiresAt,
+		maxClicks,
+		rec.ExpiredRedirectURL,
+		protected,
+		rec.Folder,
+		tags,
+	}
+	if e.withOwner {
+		row = append([]string{rec.Username}, row...)
+		row = append(row, rec.PasswordHash)
+	}
+	return e.w.Write(row)
+}
+
+// joinCSVList 将标签编码为单个 CSV 单元格（标签内含逗号或引号时按 CSV 规则转义）
+func joinCSVList(items []string) (string, error) {
+	if len(items) == 0 {
+		return "", nil
+	}
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	if err := w.Write(items); err != nil {
+		return "", err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// splitCSVList 解析 joinCSVList 写出的单元格
+func splitCSVList(cell string) ([]string, error) {
+	if cell == "" {
+		return nil, nil
+	}
+	r := csv.NewReader(strings.NewReader(cell))
+	r.TrimLeadingSpace = true
+	return r.Read()
+}
+
+func (e *csvLinkEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonLinkEncoder struct {
+	w         io.Writer
+	withOwner bool
+	n         int
+}
+
+func (e *jsonLinkEncoder) Encode(rec *models.LinkRecord) error {
+	b, err := json.Marshal(exportRecord(rec, e.withOwner))
+	if err != nil {
+		return err
+	}
+	prefix := ",\n"
+	if e.n == 0 {
+		prefix = "[\n"
+	}
+	e.n++
+	if _, err := io.WriteString(e.w, prefix); err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *jsonLinkEncoder) Close() error {
+	tail := "\n]\n"
+	if e.n == 0 {
+		tail = "[]\n"
+	}
+	_, err := io.WriteString(e.w, tail)
+	return err
+}
+
+type ndjsonLinkEncoder struct {
+	enc       *json.Encoder
+	withOwner bool
+}
+
+func (e *ndjsonLinkEncoder) Encode(rec *models.LinkRecord) error {
+	return e.enc.Encode(exportRecord(rec, e.withOwner))
+}
+
+// exportRecord 用户导出时去掉 username 与 password_hash
+func exportRecord(rec *models.LinkRecord, withOwner bool) *models.LinkRecord {
+	if withOwner {
+		return rec
+	}
+	out := *rec
+	out.Username = ""
+	out.PasswordHash = ""
+	return &out
+}
+
+func (e *ndjsonLinkEncoder) Close() error { return nil }
+
+// LinkRecordReader 导入记录来源：读完返回 io.EOF，返回 *RecordError 时跳过该条继续
+type LinkRecordReader interface {
+	Decode() (*models.LinkRecord, error)
+}
+
+// newLinkDecoder 创建导入解码器
+func newLinkDecoder(format string, r io.Reader) (LinkRecordReader, error) {
+	switch format {
+	case LinkFormatCSV:
+		return newCSVLinkDecoder(r)
+	case LinkFormatJSON:
+		dec := json.NewDecoder(r)
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("读取 JSON 失败: %w", err)
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '[' {
+			return nil, fmt.Errorf("JSON 导入内容应为数组")
+		}
+		return &jsonLinkDecoder{dec: dec}, nil
+	case LinkFormatNDJSON:
+		return &ndjsonLinkDecoder{dec: json.NewDecoder(r)}, nil
+	default:
+		return nil, fmt.Errorf("不支持的格式: %q", format)
+	}
+}
+
+// decodeJSONRecord 解码单条 JSON 记录：语法错误终止，字段类型/时间格式错误仅跳过该条
+func decodeJSONRecord(dec *json.Decoder) (*models.LinkRecord, error) {
+	var rec models.LinkRecord
+	err := dec.Decode(&rec)
+	if err == nil {
+		return &rec, nil
+	}
+	if err == io.EOF {
+		return nil, err
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("JSON 格式错误: %w", err)
+	}
+	return nil, &RecordError{Err: err}
+}
+
+type jsonLinkDecoder struct {
+	dec *json.Decoder
+}
+
+func (d *jsonLinkDecoder) Decode() (*models.LinkRecord, error) {
+	if !d.dec.More() {
+		if _, err := d.dec.Token(); err != nil { // 结尾的 ]
+			return nil, fmt.Errorf("JSON 格式错误: %w", err)
+		}
+		return nil, io.EOF
+	}
+	return decodeJSONRecord(d.dec)
+}
+
+type ndjsonLinkDecoder struct {
+	dec *json.Decoder
+}
+
+func (d *ndjsonLinkDecoder) Decode() (*models.LinkRecord, error) {
+	return decodeJSONRecord(d.dec)
+}
+
+type csvLinkDecoder struct {
+	r   *csv.Reader
+	col map[string]int
+}
+
+// newCSVLinkDecoder 读取表头并建立列索引（url 可作为 original_url 的别名）
+func newCSVLinkDecoder(r io.Reader) (*csvLinkDecoder, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("CSV 内容为空")
+		}
+		return nil, fmt.Errorf("读取 CSV 表头失败: %w", err)
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "url" {
+			name = "original_url"
+		}
+		if _, ok := col[name]; !ok {
+			col[name] = i
+		}
+	}
+	if _, ok := col["original_url"]; !ok {
+		return nil, fmt.Errorf("CSV 缺少 original_url 列")
+	}
+	if _, ok := col["code"]; !ok {
+		return nil, fmt.Errorf("CSV 缺少 code 列")
+	}
+	return &csvLinkDecoder{r: cr, col: col}, nil
+}
+
+func (d *csvLinkDecoder) field(row []string, name string) string {
+	if i, ok := d.col[name]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+func (d *csvLinkDecoder) Decode() (*models.LinkRecord, error) {
+	row, err := d.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	rec := &models.LinkRecord{
+		Username:           d.field(row, "username"),
+		Domain:             d.field(row, "domain"),
+		Code:               d.field(row, "code"),
+		OriginalURL:        d.field(row, "original_url"),
+		Title:              d.field(row, "title"),
+		ExpiredRedirectURL: d.field(row, "expired_redirect_url"),
+		PasswordHash:       d.field(row, "password_hash"),
+		Folder:             d.field(row, "folder"),
+	}
+	if v := d.field(row, "click_count"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return rec, &RecordError{Err: fmt.Errorf("click_count 不合法: %q", v)}
+		}
+		rec.ClickCount = n
+	}
+	if v := d.field(row, "created_at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return rec, &RecordError{Err: fmt.Errorf("created_at 不合法（应为 RFC3339）: %q", v)}
+		}
+		rec.CreatedAt = t
+	}
+	if v := d.field(row, "expires_at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return rec, &RecordError{Err: fmt.Errorf("expires_at 不合法（应为 RFC3339）: %q", v)}
+		}
+		rec.ExpiresAt = &t
+	}
+	if v := d.field(row, "max_clicks"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return rec, &RecordError{Err: fmt.Errorf("max_clicks 不合法: %q", v)}
+		}
+		rec.MaxClicks = &n
+	}
+	if v := d.field(row, "password_protected"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return rec, &RecordError{Err: fmt.Errorf("password_protected 不合法: %q", v)}
+		}
+		rec.PasswordProtected = b
+	}
+	tags, err := splitCSVList(d.field(row, "tags"))
+	if err != nil {
+		return rec, &RecordError{Err: fmt.Errorf("tags 不合法: %w", err)}
+	}
+	rec.Tags = tags
+	return rec, nil
+}
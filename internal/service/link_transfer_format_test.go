@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"short-link/models"
+)
+
+func TestLinkFormatRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	expires := created.Add(24 * time.Hour)
+	maxClicks := int64(100)
+	records := []models.LinkRecord{
+		{Username: "alice", Domain: "s.example.com", Code: "abc123", OriginalURL: "https://example.com/a", Title: "A, \"quoted\"", ClickCount: 42, CreatedAt: created,
+			ExpiresAt: &expires, MaxClicks: &maxClicks, ExpiredRedirectURL: "https://example.com/gone",
+			PasswordProtected: true, PasswordHash: "$2a$10$abcdefghijklmnopqrstuv", Folder: "工作", Tags: []string{"a,b", "say \"hi\"", "c"}},
+		{Username: "bob", Code: "xyz789", OriginalURL: "https://example.com/b", CreatedAt: created},
+	}
+
+	for _, format := range []string{LinkFormatCSV, LinkFormatJSON, LinkFormatNDJSON} {
+		var buf bytes.Buffer
+		enc, err := newLinkEncoder(format, &buf, true)
+		if err != nil {
+			t.Fatalf("%s: new encoder: %v", format, err)
+		}
+		for i := range records {
+			if err := enc.Encode(&records[i]); err != nil {
+				t.Fatalf("%s: encode: %v", format, err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("%s: close: %v", format, err)
+		}
+
+		dec, err := newLinkDecoder(format, &buf)
+		if err != nil {
+			t.Fatalf("%s: new decoder: %v", format, err)
+		}
+		var got []models.LinkRecord
+		for {
+			rec, err := dec.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: decode: %v", format, err)
+			}
+			got = append(got, *rec)
+		}
+		if len(got) != len(records) {
+			t.Fatalf("%s: got %d records, want %d", format, len(got), len(records))
+		}
+		for i := range records {
+			want := records[i]
+			if got[i].Username != want.Username || got[i].Domain != want.Domain || got[i].Code != want.Code ||
+				got[i].OriginalURL != want.OriginalURL || got[i].Title != want.Title ||
+				got[i].ClickCount != want.ClickCount || !got[i].CreatedAt.Equal(want.CreatedAt) ||
+				(got[i].ExpiresAt == nil) != (want.ExpiresAt == nil) || (got[i].MaxClicks == nil) != (want.MaxClicks == nil) ||
+				got[i].ExpiredRedirectURL != want.ExpiredRedirectURL || got[i].PasswordProtected != want.PasswordProtected ||
+				got[i].PasswordHash != want.PasswordHash || got[i].Folder != want.Folder ||
+				strings.Join(got[i].Tags, "|") != strings.Join(want.Tags, "|") {
+				t.Fatalf("%s: record %d = %+v, want %+v", format, i, got[i], want)
+			}
+			if want.ExpiresAt != nil && (!got[i].ExpiresAt.Equal(*want.ExpiresAt) || *got[i].MaxClicks != *want.MaxClicks) {
+				t.Fatalf("%s: record %d lifecycle = %v/%d", format, i, got[i].ExpiresAt, *got[i].MaxClicks)
+			}
+		}
+	}
+}
+
+func TestUserExportOmitsPasswordHash(t *testing.T) {
+	rec := models.LinkRecord{Username: "alice", Code: "p1", OriginalURL: "https://example.com", PasswordProtected: true, PasswordHash: "$2a$10$secret"}
+	for _, format := range []string{LinkFormatCSV, LinkFormatJSON, LinkFormatNDJSON} {
+		var buf bytes.Buffer
+		enc, _ := newLinkEncoder(format, &buf, false)
+		if err := enc.Encode(&rec); err != nil {
+			t.Fatalf("%s: encode: %v", format, err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("%s: close: %v", format, err)
+		}
+		if out := buf.String(); strings.Contains(out, "secret") || strings.Contains(out, "alice") || !strings.Contains(out, "true") {
+			t.Fatalf("%s: user export = %q", format, out)
+		}
+	}
+	if rec.PasswordHash == "" {
+		t.Fatal("encoder must not modify the source record")
+	}
+}
+
+func TestCSVLinkDecoderRecordError(t *testing.T) {
+	input := "url,code,click_count\nhttps://example.com,a1,abc\nhttps://example.com,a2,3\n"
+	dec, err := newLinkDecoder(LinkFormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("new decoder: %v", err)
+	}
+
+	var recErr *RecordError
+	if _, err := dec.Decode(); !errors.As(err, &recErr) {
+		t.Fatalf("expected record error for bad click_count, got %v", err)
+	}
+	rec, err := dec.Decode()
+	if err != nil || rec.Code != "a2" || rec.ClickCount != 3 {
+		t.Fatalf("second record = %+v, %v", rec, err)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestEmptyJSONExport(t *testing.T) {
+	var buf bytes.Buffer
+	enc, _ := newLinkEncoder(LinkFormatJSON, &buf, false)
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Fatalf("empty export = %q", buf.String())
+	}
+}
@@ -0,0 +1,489 @@
+/**
+ * LinkTransfer Service（链接导入/导出）
+ * - 导出：按 id 游标分页读取，逐条编码写出，不在内存中保留全部链接
+ * - 导入：流式读取记录，每 500 条一批校验后多行 INSERT；(domain, code) 已存在的记录计为冲突
+ * - 过期时间、点击预算、兜底地址、文件夹与标签随记录恢复；访问密码哈希仅全实例导入可恢复，
+ *   用户导入遇到受密码保护的记录时拒绝该条，避免受保护链接被导入为公开链接
+ * - 记录来源：本系统导出格式（csv/json/ndjson）或 internal/importer 中的第三方解析器
+ */
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"short-link/internal/repo"
+	"short-link/models"
+	"short-link/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// importChunkSize 导入时每批写入的记录数
+	importChunkSize = 500
+	// maxImportErrors 导入报告中保留的错误条数上限
+	maxImportErrors = 1000
+)
+
+// LinkTransferService 链接导入/导出服务
+type LinkTransferService struct {
+	linkService *LinkService
+	linkRepo    *repo.LinkRepo
+	domainRepo  *repo.DomainRepo
+	userRepo    *repo.UserRepo
+	folderRepo  *repo.FolderRepo // 文件夹（可为 nil，导入时不恢复文件夹）
+}
+
+// NewLinkTransferService 创建 LinkTransferService
+func NewLinkTransferService(linkService *LinkService, linkRepo *repo.LinkRepo, domainRepo *repo.DomainRepo, userRepo *repo.UserRepo, folderRepo *repo.FolderRepo) *LinkTransferService {
+	return &LinkTransferService{
+		linkService: linkService,
+		linkRepo:    linkRepo,
+		domainRepo:  domainRepo,
+		userRepo:    userRepo,
+		folderRepo:  folderRepo,
+	}
+}
+
+// Export 导出链接（userID 为 0 时导出全实例并附带 username），返回导出条数
+func (s *LinkTransferService) Export(ctx context.Context, w io.Writer, format string, userID int64) (int, error) {
+	enc, err := newLinkEncoder(format, w, userID == 0)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = s.linkRepo.ForEachLinkRecord(ctx, userID, func(rec *models.LinkRecord) error {
+		n++
+		return enc.Encode(rec)
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, enc.Close()
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	UserID           int64 // 链接归属用户；0 表示按记录中的 username 归属（空则归 admin）
+	DomainID         int64 // >0 时全部写入该域名，忽略记录中的 domain
+	PreserveStats    bool  // 保留记录中的 click_count / created_at（管理员导入）
+	PreservePassword bool  // 恢复记录中的 password_hash（全实例导入）；否则受密码保护的记录被拒绝
+	EnforceQuota     bool  // 受归属用户 max_links 限制
+	DryRun           bool  // 只校验并报告冲突，不写入
+}
+
+// importRow 导入中的单条记录
+type importRow struct {
+	row    int
+	rec    *models.LinkRecord
+	domain *models.Domain
+	link   *models.Link
+	err    error
+}
+
+// importState 单次导入的共享状态
+type importState struct {
+	opts      ImportOptions
+	remaining int            // 剩余配额（-1 表示不限）
+	domain    *models.Domain // opts.DomainID 指定的域名
+	now       time.Time
+	owners    map[string]int64
+	domains   map[string]*models.Domain
+	domainErr map[string]error
+	folders   map[string]int64 // owner_id/文件夹名 -> folder_id
+	seen      map[string]bool  // 本次导入已出现的 domain_id/code
+	report    *models.LinkImportReport
+}
+
+// Import 导入本系统导出格式的链接；返回的报告在出错时也包含已处理部分
+func (s *LinkTransferService) Import(ctx context.Context, r io.Reader, format string, opts ImportOptions) (*models.LinkImportReport, error) {
+	dec, err := newLinkDecoder(format, r)
+	if err != nil {
+		return &models.LinkImportReport{Errors: []models.LinkImportError{}, DryRun: opts.DryRun}, err
+	}
+	return s.ImportRecords(ctx, dec, opts)
+}
+
+// ImportRecords 从任意记录来源导入链接
+func (s *LinkTransferService) ImportRecords(ctx context.Context, src LinkRecordReader, opts ImportOptions) (*models.LinkImportReport, error) {
+	report := &models.LinkImportReport{Errors: []models.LinkImportError{}, DryRun: opts.DryRun}
+	st := &importState{
+		opts:      opts,
+		remaining: -1,
+		now:       time.Now(),
+		owners:    map[string]int64{},
+		domains:   map[string]*models.Domain{},
+		domainErr: map[string]error{},
+		folders:   map[string]int64{},
+		seen:      map[string]bool{},
+		report:    report,
+	}
+	if opts.DomainID > 0 {
+		d, err := s.domainRepo.GetDomainByID(ctx, opts.DomainID)
+		if err != nil {
+			return report, fmt.Errorf("域名 %d 不存在", opts.DomainID)
+		}
+		if !d.IsActive {
+			return report, fmt.Errorf("域名 %s 已停用", d.Domain)
+		}
+		st.domain = d
+	}
+	if opts.EnforceQuota && opts.UserID > 0 && s.userRepo != nil {
+		if u, err := s.userRepo.GetUserByID(ctx, opts.UserID); err == nil && u.MaxLinks != -1 {
+			if cnt, err := s.linkRepo.CountLinksByUser(ctx, opts.UserID); err == nil {
+				st.remaining = int(int64(u.MaxLinks) - cnt)
+				if st.remaining < 0 {
+					st.remaining = 0
+				}
+			}
+		}
+	}
+
+	chunk := make([]*importRow, 0, importChunkSize)
+	for {
+		rec, err := src.Decode()
+		if err == io.EOF {
+			break
+		}
+		var recErr *RecordError
+		if err != nil && !errors.As(err, &recErr) {
+			return report, err
+		}
+		report.Total++
+		chunk = append(chunk, &importRow{row: report.Total, rec: rec, err: err})
+		if len(chunk) == importChunkSize {
+			if err := s.importChunk(ctx, st, chunk); err != nil {
+				return report, err
+			}
+			chunk = chunk[:0]
+		}
+	}
+	if err := s.importChunk(ctx, st, chunk); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// importChunk 校验并写入一批记录
+func (s *LinkTransferService) importChunk(ctx context.Context, st *importState, rows []*importRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// URL 校验可能涉及 DNS 解析，并发执行
+	forEachParallel(len(rows), batchConcurrency, func(i int) {
+		row := rows[i]
+		if row.err != nil {
+			return
+		}
+		row.err = prepareImportRecord(row.rec, st.opts)
+	})
+
+	var pending []*importRow
+	for _, row := range rows {
+		if row.err != nil {
+			s.reportError(st, row, false, row.err)
+			continue
+		}
+		ownerID, err := s.resolveOwner(ctx, st, row.rec.Username)
+		if err != nil {
+			s.reportError(st, row, false, err)
+			continue
+		}
+		domain := st.domain
+		if domain == nil {
+			if domain, err = s.resolveDomain(ctx, st, ownerID, row.rec.Domain); err != nil {
+				s.reportError(st, row, false, err)
+				continue
+			}
+		}
+		row.domain = domain
+		var domainID int64
+		if domain != nil {
+			domainID = domain.ID
+		}
+
+		key := fmt.Sprintf("%d/%s", domainID, row.rec.Code)
+		if st.seen[key] {
+			s.reportError(st, row, true, fmt.Errorf("代码 %s 在导入内容中重复", row.rec.Code))
+			continue
+		}
+		st.seen[key] = true
+
+		if st.remaining == 0 {
+			s.reportError(st, row, false, errors.New("已达到最大链接数限制，请联系管理员"))
+			continue
+		}
+
+		link := &models.Link{
+			UserID:             ownerID,
+			DomainID:           domainID,
+			Code:               row.rec.Code,
+			OriginalURL:        row.rec.OriginalURL,
+			Title:              row.rec.Title,
+			Hash:               s.linkService.GenerateHash(row.rec.OriginalURL),
+			ExpiresAt:          row.rec.ExpiresAt,
+			MaxClicks:          row.rec.MaxClicks,
+			ExpiredRedirectURL: row.rec.ExpiredRedirectURL,
+			PasswordHash:       row.rec.PasswordHash,
+			Tags:               row.rec.Tags,
+			CreatedAt:          st.now,
+			UpdatedAt:          st.now,
+		}
+		// 管理员导入保留原始点击数与创建时间；用户导入从零开始计数，
+		// 但设置了点击预算的链接保留已用点击（不超过预算），避免已用尽的链接被重新激活
+		if st.opts.PreserveStats {
+			link.ClickCount = row.rec.ClickCount
+			if !row.rec.CreatedAt.IsZero() {
+				link.CreatedAt = row.rec.CreatedAt
+			}
+		} else if link.MaxClicks != nil {
+			link.ClickCount = min(row.rec.ClickCount, *link.MaxClicks)
+		}
+		row.link = link
+		pending = append(pending, row)
+		if st.remaining > 0 {
+			st.remaining--
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if st.opts.DryRun {
+		return s.checkConflicts(ctx, st, pending)
+	}
+	if err := s.resolveFolders(ctx, st, pending); err != nil {
+		return err
+	}
+
+	forEachParallel(len(pending), batchConcurrency, func(i int) {
+		row := pending[i]
+		row.link.QRCode, _ = utils.GenerateQRCode(s.linkService.BuildShortURL(row.domain, row.link.Code), 256)
+	})
+
+	links := make([]*models.Link, len(pending))
+	for i, row := range pending {
+		links[i] = row.link
+	}
+	if _, err := s.linkRepo.CreateLinks(ctx, links); err != nil {
+		return err
+	}
+
+	for _, row := range pending {
+		if row.link.ID > 0 {
+			st.report.Imported++
+			continue
+		}
+		if st.remaining >= 0 {
+			st.remaining++ // 冲突未占用配额
+		}
+		s.reportError(st, row, true, fmt.Errorf("代码 %s 已存在", row.link.Code))
+	}
+	return nil
+}
+
+// prepareImportRecord 规范化并校验单条记录（URL 校验可能涉及 DNS 解析）
+func prepareImportRecord(rec *models.LinkRecord, opts ImportOptions) error {
+	rec.OriginalURL = strings.TrimSpace(rec.OriginalURL)
+	rec.Code = strings.TrimSpace(rec.Code)
+	rec.ExpiredRedirectURL = strings.TrimSpace(rec.ExpiredRedirectURL)
+	rec.PasswordHash = strings.TrimSpace(rec.PasswordHash)
+	switch {
+	case rec.Code == "":
+		return errors.New("code 不能为空")
+	case utf8.RuneCountInString(rec.Code) > 255:
+		return errors.New("code 过长")
+	case utf8.RuneCountInString(rec.Title) > 500:
+		return errors.New("title 过长（最多 500 字符）")
+	case rec.MaxClicks != nil && *rec.MaxClicks <= 0:
+		return errors.New("max_clicks 必须大于 0")
+	}
+
+	// 访问密码：只接受全实例导出中的 bcrypt 哈希；受保护但缺少哈希的记录拒绝导入，不降级为公开链接
+	if rec.PasswordHash != "" || rec.PasswordProtected {
+		if !opts.PreservePassword {
+			return errors.New("受密码保护的链接不支持通过此方式导入，请导入后重新设置访问密码")
+		}
+		if rec.PasswordHash == "" {
+			return errors.New("受密码保护的链接缺少 password_hash")
+		}
+		if _, err := bcrypt.Cost([]byte(rec.PasswordHash)); err != nil {
+			return errors.New("password_hash 不是有效的 bcrypt 哈希")
+		}
+	}
+
+	tags, err := normalizeTags(rec.Tags)
+	if err != nil {
+		return err
+	}
+	rec.Tags = tags
+	if rec.Folder != "" {
+		if rec.Folder, err = normalizeFolderName(rec.Folder); err != nil {
+			return err
+		}
+	}
+
+	if err := utils.ValidateExternalURL(rec.OriginalURL); err != nil {
+		return fmt.Errorf("URL不合法: %s", err.Error())
+	}
+	if rec.ExpiredRedirectURL != "" {
+		if err := utils.ValidateExternalURL(rec.ExpiredRedirectURL); err != nil {
+			return fmt.Errorf("expired_redirect_url不合法: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// resolveFolders 按名称解析待写入记录的文件夹（每个用户不存在的文件夹自动创建）
+func (s *LinkTransferService) resolveFolders(ctx context.Context, st *importState, pending []*importRow) error {
+	if s.folderRepo == nil {
+		return nil
+	}
+	missing := map[int64][]string{}
+	for _, row := range pending {
+		if row.rec.Folder == "" {
+			continue
+		}
+		key := fmt.Sprintf("%d/%s", row.link.UserID, row.rec.Folder)
+		if _, ok := st.folders[key]; !ok {
+			missing[row.link.UserID] = append(missing[row.link.UserID], row.rec.Folder)
+		}
+	}
+	for userID, names := range missing {
+		ids, err := s.folderRepo.EnsureFolders(ctx, userID, names)
+		if err != nil {
+			return err
+		}
+		for name, id := range ids {
+			st.folders[fmt.Sprintf("%d/%s", userID, name)] = id
+		}
+	}
+	for _, row := range pending {
+		if row.rec.Folder == "" {
+			continue
+		}
+		if id, ok := st.folders[fmt.Sprintf("%d/%s", row.link.UserID, row.rec.Folder)]; ok {
+			row.link.FolderID = &id
+		}
+	}
+	return nil
+}
+
+// checkConflicts 演练模式：只查询 (domain_id, code) 是否已存在
+func (s *LinkTransferService) checkConflicts(ctx context.Context, st *importState, pending []*importRow) error {
+	keys := make([]repo.LinkKey, len(pending))
+	for i, row := range pending {
+		keys[i] = repo.LinkKey{DomainID: row.link.DomainID, Code: row.link.Code}
+	}
+	existing, err := s.linkRepo.FindExistingCodes(ctx, keys)
+	if err != nil {
+		return err
+	}
+	for _, row := range pending {
+		if existing[repo.LinkKey{DomainID: row.link.DomainID, Code: row.link.Code}] {
+			if st.remaining >= 0 {
+				st.remaining++
+			}
+			s.reportError(st, row, true, fmt.Errorf("代码 %s 已存在", row.link.Code))
+			continue
+		}
+		st.report.Imported++
+	}
+	return nil
+}
+
+// reportError 记录失败/冲突（超出上限后只计数）
+func (s *LinkTransferService) reportError(st *importState, row *importRow, conflict bool, err error) {
+	if conflict {
+		st.report.Conflicts++
+	} else {
+		st.report.Failed++
+	}
+	if len(st.report.Errors) >= maxImportErrors {
+		st.report.Truncated = true
+		return
+	}
+	e := models.LinkImportError{Row: row.row, Conflict: conflict, Error: err.Error()}
+	if row.rec != nil {
+		e.Domain = row.rec.Domain
+		e.Code = row.rec.Code
+	}
+	st.report.Errors = append(st.report.Errors, e)
+}
+
+// resolveOwner 确定记录归属用户：指定了 UserID 时归该用户；否则按 username，空则归 admin
+func (s *LinkTransferService) resolveOwner(ctx context.Context, st *importState, username string) (int64, error) {
+	if st.opts.UserID > 0 {
+		return st.opts.UserID, nil
+	}
+	username = strings.TrimSpace(username)
+	if id, ok := st.owners[username]; ok {
+		if id == 0 {
+			return 0, fmt.Errorf("用户 %s 不存在", username)
+		}
+		return id, nil
+	}
+	var (
+		u   *models.User
+		err error
+	)
+	if username == "" {
+		u, err = s.userRepo.GetAdminUser(ctx)
+	} else {
+		u, err = s.userRepo.GetUserByUsername(ctx, username)
+	}
+	if err != nil {
+		st.owners[username] = 0
+		return 0, fmt.Errorf("用户 %s 不存在", username)
+	}
+	st.owners[username] = u.ID
+	return u.ID, nil
+}
+
+// resolveDomain 解析记录的域名：空表示归属用户的默认域名；指定时须为该用户或系统域名且已启用
+func (s *LinkTransferService) resolveDomain(ctx context.Context, st *importState, ownerID int64, name string) (*models.Domain, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	key := fmt.Sprintf("%d/%s", ownerID, name)
+	if d, ok := st.domains[key]; ok {
+		return d, nil
+	}
+	if err, ok := st.domainErr[key]; ok {
+		return nil, err
+	}
+
+	var (
+		domain *models.Domain
+		err    error
+	)
+	if name == "" {
+		if d, e := s.domainRepo.GetDefaultDomain(ctx, ownerID); e == nil {
+			domain = d
+		}
+	} else {
+		ds, e := s.domainRepo.FindActiveDomainsByName(ctx, name)
+		if e != nil {
+			return nil, e
+		}
+		for i := range ds {
+			if ds[i].UserID == ownerID || ds[i].UserID == 0 {
+				domain = &ds[i]
+				break
+			}
+		}
+		if domain == nil {
+			err = fmt.Errorf("域名 %s 不存在或无权限", name)
+		}
+	}
+	if err != nil {
+		st.domainErr[key] = err
+		return nil, err
+	}
+	st.domains[key] = domain
+	return domain, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"short-link/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPrepareImportRecord(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	zero := int64(0)
+	user := ImportOptions{UserID: 1}
+	admin := ImportOptions{PreserveStats: true, PreservePassword: true}
+
+	cases := []struct {
+		name    string
+		rec     models.LinkRecord
+		opts    ImportOptions
+		wantErr string // 为空表示成功
+	}{
+		{"expired link kept", models.LinkRecord{Code: "a", OriginalURL: "https://93.184.216.34/a", ExpiresAt: &past}, user, ""},
+		{"bad max_clicks", models.LinkRecord{Code: "a", OriginalURL: "https://93.184.216.34/a", MaxClicks: &zero}, user, "max_clicks"},
+		{"bad fallback", models.LinkRecord{Code: "a", OriginalURL: "https://93.184.216.34/a", ExpiredRedirectURL: "javascript:alert(1)"}, user, "expired_redirect_url"},
+		{"user hash refused", models.LinkRecord{Code: "a", OriginalURL: "https://93.184.216.34/a", PasswordHash: string(hash)}, user, "密码保护"},
+		{"user protected refused", models.LinkRecord{Code: "a", OriginalURL: "https://93.184.216.34/a", PasswordProtected: true}, user, "密码保护"},
+		{"admin protected without hash", models.LinkRecord{Code: "a", OriginalURL: "https://93.184.216.34/a", PasswordProtected: true}, admin, "缺少 password_hash"},
+		{"admin invalid hash", models.LinkRecord{Code: "a", OriginalURL: "https://93.184.216.34/a", PasswordHash: "plain"}, admin, "bcrypt"},
+		{"admin hash", models.LinkRecord{Code: "a", OriginalURL: "https://93.184.216.34/a", PasswordHash: string(hash), PasswordProtected: true}, admin, ""},
+		{"long folder", models.LinkRecord{Code: "a", OriginalURL: "https://93.184.216.34/a", Folder: strings.Repeat("f", 101)}, user, "文件夹名"},
+	}
+	for _, tc := range cases {
+		err := prepareImportRecord(&tc.rec, tc.opts)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: err = %v, want containing %q", tc.name, err, tc.wantErr)
+		}
+	}
+
+	rec := models.LinkRecord{Code: " a ", OriginalURL: " https://93.184.216.34/a ", Folder: " 工作 ", Tags: []string{" x ", "x", "", "y  z"}}
+	if err := prepareImportRecord(&rec, user); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != "a" || rec.Folder != "工作" || strings.Join(rec.Tags, "|") != "x|y z" {
+		t.Fatalf("record not normalized: %+v", rec)
+	}
+}
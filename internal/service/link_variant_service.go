@@ -0,0 +1,166 @@
+/**
+ * LinkVariant Service
+ * - A/B 分流变体 CRUD（仅链接所有者），变更后清理跳转缓存
+ * - 分配：优先沿用 Cookie 中已分配的变体，否则按 (link_id, IP) 哈希落入权重区间，保证粘性
+ */
+package service
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/models"
+	"short-link/utils"
+)
+
+// maxVariantsPerLink 单个链接的变体数量上限
+const maxVariantsPerLink = 10
+
+// LinkVariantService 分流变体服务
+type LinkVariantService struct {
+	linkRepo    *repo.LinkRepo
+	variantRepo *repo.LinkVariantRepo
+}
+
+// NewLinkVariantService 创建 LinkVariantService
+func NewLinkVariantService(linkRepo *repo.LinkRepo, variantRepo *repo.LinkVariantRepo) *LinkVariantService {
+	return &LinkVariantService{
+		linkRepo:    linkRepo,
+		variantRepo: variantRepo,
+	}
+}
+
+// ListVariants 获取链接的分流变体
+func (s *LinkVariantService) ListVariants(ctx context.Context, userID int64, linkID int64) ([]models.LinkVariant, error) {
+	if _, err := ownedLink(ctx, s.linkRepo, userID, linkID); err != nil {
+		return nil, err
+	}
+	return s.variantRepo.ListVariants(ctx, linkID)
+}
+
+// CreateVariant 新增分流变体
+func (s *LinkVariantService) CreateVariant(ctx context.Context, userID int64, linkID int64, req *models.LinkVariantRequest) (*models.LinkVariant, error) {
+	l, err := ownedLink(ctx, s.linkRepo, userID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	v, err := buildLinkVariant(req)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.variantRepo.ListVariants(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxVariantsPerLink {
+		return nil, fmt.Errorf("每个链接最多 %d 个变体", maxVariantsPerLink)
+	}
+
+	now := time.Now()
+	v.LinkID = linkID
+	v.CreatedAt = now
+	v.UpdatedAt = now
+	if err := s.variantRepo.CreateVariant(ctx, v); err != nil {
+		return nil, err
+	}
+	purgeRedirectCache(l)
+	return v, nil
+}
+
+// UpdateVariant 更新分流变体（调整权重会改变未持有 Cookie 的访客分配）
+func (s *LinkVariantService) UpdateVariant(ctx context.Context, userID int64, linkID int64, variantID int64, req *models.LinkVariantRequest) (*models.LinkVariant, error) {
+	l, err := ownedLink(ctx, s.linkRepo, userID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.variantRepo.GetVariant(ctx, linkID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	v, err := buildLinkVariant(req)
+	if err != nil {
+		return nil, err
+	}
+
+	v.ID = current.ID
+	v.LinkID = linkID
+	v.CreatedAt = current.CreatedAt
+	v.UpdatedAt = time.Now()
+	if err := s.variantRepo.UpdateVariant(ctx, v); err != nil {
+		return nil, err
+	}
+	purgeRedirectCache(l)
+	return v, nil
+}
+
+// DeleteVariant 删除分流变体（历史访问日志保留 variant_id）
+func (s *LinkVariantService) DeleteVariant(ctx context.Context, userID int64, linkID int64, variantID int64) error {
+	l, err := ownedLink(ctx, s.linkRepo, userID, linkID)
+	if err != nil {
+		return err
+	}
+	if err := s.variantRepo.DeleteVariant(ctx, linkID, variantID); err != nil {
+		return err
+	}
+	purgeRedirectCache(l)
+	return nil
+}
+
+// buildLinkVariant 校验并规范化变体请求
+func buildLinkVariant(req *models.LinkVariantRequest) (*models.LinkVariant, error) {
+	target := strings.TrimSpace(req.TargetURL)
+	if err := utils.ValidateExternalURL(target); err != nil {
+		return nil, fmt.Errorf("目标地址不合法: %w", err)
+	}
+	if req.Weight <= 0 {
+		return nil, fmt.Errorf("weight 必须大于 0")
+	}
+	return &models.LinkVariant{
+		Label:     strings.TrimSpace(req.Label),
+		TargetURL: target,
+		Weight:    req.Weight,
+	}, nil
+}
+
+// redirectVariant 跳转缓存中的变体（仅保留分配所需字段）
+type redirectVariant struct {
+	ID     int64  `json:"id"`
+	URL    string `json:"url"`
+	Weight int    `json:"w"`
+}
+
+// pickVariant 为访客分配变体
+// - stickyID 为 Cookie 中记录的变体，仍存在时直接沿用
+// - 否则按 FNV(link_id|ip) 对总权重取模，落入对应权重区间（同一 IP 结果稳定）
+func pickVariant(linkID int64, variants []redirectVariant, stickyID int64, ip string) *redirectVariant {
+	if len(variants) == 0 {
+		return nil
+	}
+	total := 0
+	for i := range variants {
+		if stickyID > 0 && variants[i].ID == stickyID {
+			return &variants[i]
+		}
+		total += variants[i].Weight
+	}
+	if total <= 0 {
+		return &variants[0]
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strconv.FormatInt(linkID, 10) + "|" + ip))
+	bucket := int(h.Sum64() % uint64(total))
+	for i := range variants {
+		if bucket < variants[i].Weight {
+			return &variants[i]
+		}
+		bucket -= variants[i].Weight
+	}
+	return &variants[len(variants)-1]
+}
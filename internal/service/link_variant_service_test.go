@@ -0,0 +1,44 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestPickVariant(t *testing.T) {
+	variants := []redirectVariant{
+		{ID: 1, URL: "https://a.example.com", Weight: 70},
+		{ID: 2, URL: "https://b.example.com", Weight: 30},
+	}
+
+	if pickVariant(1, nil, 0, "1.2.3.4") != nil {
+		t.Fatalf("no variants should yield nil")
+	}
+
+	// Cookie 中的变体优先
+	if v := pickVariant(1, variants, 2, "1.2.3.4"); v.ID != 2 {
+		t.Fatalf("sticky variant ignored: got %d", v.ID)
+	}
+
+	// 同一 IP 结果稳定
+	first := pickVariant(1, variants, 0, "1.2.3.4")
+	for i := 0; i < 10; i++ {
+		if v := pickVariant(1, variants, 0, "1.2.3.4"); v.ID != first.ID {
+			t.Fatalf("assignment not stable: %d vs %d", v.ID, first.ID)
+		}
+	}
+
+	// 失效的 Cookie 回退到哈希分配
+	if v := pickVariant(1, variants, 99, "1.2.3.4"); v.ID != first.ID {
+		t.Fatalf("stale sticky id should fall back to hash: got %d", v.ID)
+	}
+
+	// 分配比例接近权重
+	counts := map[int64]int{}
+	for i := 0; i < 10000; i++ {
+		counts[pickVariant(1, variants, 0, "10.0."+strconv.Itoa(i/256)+"."+strconv.Itoa(i%256)).ID]++
+	}
+	if counts[1] < 6500 || counts[1] > 7500 {
+		t.Fatalf("weight distribution off: %v", counts)
+	}
+}
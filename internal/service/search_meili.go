@@ -0,0 +1,178 @@
+/**
+ * Meilisearch 搜索后端
+ * - links 索引为全用户共享，按 user_id 等可过滤字段限定范围
+ * - 创建时配置可过滤/可排序字段；连接失败由 SearchService 在后台重试
+ */
+package service
+
+import (
+	"context"
+	"fmt"
+	"short-link/internal/config"
+	"short-link/internal/repo"
+	"short-link/models"
+	"strconv"
+	"time"
+
+	"github.com/meilisearch/meilisearch-go"
+)
+
+var (
+	// searchFilterableAttributes links 索引的可过滤字段
+	searchFilterableAttributes = []string{"user_id", "domain_id", "folder_id", "tags", "created_at", "click_count"}
+	// searchSortableAttributes links 索引的可排序字段
+	searchSortableAttributes = []string{"created_at", "click_count"}
+)
+
+// meiliSearchTimeout 单次 Meilisearch 请求超时（超时后降级到 Postgres）
+const meiliSearchTimeout = 3 * time.Second
+
+// meiliSearchBackend Meilisearch 搜索后端
+type meiliSearchBackend struct {
+	cfg         *config.Config
+	index       *meilisearch.Index
+	linkService *LinkService
+	domainRepo  *repo.DomainRepo
+}
+
+// newMeiliSearchBackend 连接 Meilisearch 并确保索引的可过滤/可排序字段已配置
+func newMeiliSearchBackend(cfg *config.Config, linkService *LinkService, domainRepo *repo.DomainRepo) (*meiliSearchBackend, error) {
+	client := meilisearch.NewClient(meilisearch.ClientConfig{
+		Host:    cfg.MeiliHost,
+		APIKey:  cfg.MeiliKey,
+		Timeout: meiliSearchTimeout,
+	})
+
+	if _, err := client.Health(); err != nil {
+		return nil, fmt.Errorf("Meilisearch连接失败: %w", err)
+	}
+
+	index := client.Index("links")
+	if _, err := index.UpdateFilterableAttributes(&searchFilterableAttributes); err != nil {
+		return nil, fmt.Errorf("设置 Meilisearch 可过滤字段失败: %w", err)
+	}
+	if _, err := index.UpdateSortableAttributes(&searchSortableAttributes); err != nil {
+		return nil, fmt.Errorf("设置 Meilisearch 可排序字段失败: %w", err)
+	}
+
+	return &meiliSearchBackend{
+		cfg:         cfg,
+		index:       index,
+		linkService: linkService,
+		domainRepo:  domainRepo,
+	}, nil
+}
+
+// Name 后端名称
+func (b *meiliSearchBackend) Name() string {
+	return SearchBackendMeilisearch
+}
+
+// buildSearchFilter 构建 Meilisearch 过滤表达式（数组元素之间为 AND）
+func buildSearchFilter(o *LinkSearchOptions) []string {
+	var filter []string
+	if o.UserID > 0 {
+		filter = append(filter, fmt.Sprintf("user_id = %d", o.UserID))
+	}
+	if o.DomainID > 0 {
+		filter = append(filter, fmt.Sprintf("domain_id = %d", o.DomainID))
+	}
+	if o.CreatedFrom != nil {
+		filter = append(filter, fmt.Sprintf("created_at >= %d", o.CreatedFrom.Unix()))
+	}
+	if o.CreatedTo != nil {
+		filter = append(filter, fmt.Sprintf("created_at <= %d", o.CreatedTo.Unix()))
+	}
+	if o.MinClicks != nil {
+		filter = append(filter, fmt.Sprintf("click_count >= %d", *o.MinClicks))
+	}
+	if o.MaxClicks != nil {
+		filter = append(filter, fmt.Sprintf("click_count <= %d", *o.MaxClicks))
+	}
+	if o.Tag != "" {
+		filter = append(filter, "tags = "+strconv.Quote(o.Tag))
+	}
+	if o.FolderID > 0 {
+		filter = append(filter, fmt.Sprintf("folder_id = %d", o.FolderID))
+	}
+	return filter
+}
+
+// SearchLinks 搜索链接（按 code/title/original_url，结果按创建时间倒序）
+func (b *meiliSearchBackend) SearchLinks(ctx context.Context, opts *LinkSearchOptions) (*models.PaginatedLinksResponse, error) {
+	page, limit := opts.pageLimit()
+
+	req := &meilisearch.SearchRequest{
+		Query:  opts.Query,
+		Limit:  int64(limit),
+		Offset: int64((page - 1) * limit),
+		Sort:   []string{"created_at:desc"},
+	}
+	if filter := buildSearchFilter(opts); len(filter) > 0 {
+		req.Filter = filter
+	}
+
+	result, err := b.index.Search(opts.Query, req)
+	if err != nil {
+		return nil, fmt.Errorf("搜索失败: %w", err)
+	}
+
+	domainOf := newDomainCache(ctx, b.domainRepo)
+	links := make([]models.LinkResponse, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		doc, ok := hit.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		link := models.LinkResponse{
+			ID:           int64(getFloat(doc, "id")),
+			Code:         getString(doc, "code"),
+			OriginalURL:  getString(doc, "original_url"),
+			Title:        getString(doc, "title"),
+			ClickCount:   int64(getFloat(doc, "click_count")),
+			UniqueClicks: int64(getFloat(doc, "unique_clicks")),
+		}
+		if tags, ok := doc["tags"].([]interface{}); ok {
+			for _, t := range tags {
+				if name, ok := t.(string); ok {
+					link.Tags = append(link.Tags, name)
+				}
+			}
+		}
+		if folderID := int64(getFloat(doc, "folder_id")); folderID > 0 {
+			link.FolderID = &folderID
+		}
+
+		d := domainOf(int64(getFloat(doc, "domain_id")))
+		if b.linkService != nil {
+			link.ShortURL = b.linkService.BuildShortURL(d, link.Code)
+		} else {
+			link.ShortURL = fmt.Sprintf("%s/%s", b.cfg.BaseURL, link.Code)
+		}
+
+		if createdAt, ok := doc["created_at"].(float64); ok {
+			link.CreatedAt = time.Unix(int64(createdAt), 0).Format(time.RFC3339)
+		}
+
+		links = append(links, link)
+	}
+
+	return paginatedLinks(links, result.EstimatedTotalHits, page, limit), nil
+}
+
+func getString(m map[string]interface{}, key string) string {
+	if v, ok := m[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func getFloat(m map[string]interface{}, key string) float64 {
+	if v, ok := m[key].(float64); ok {
+		return v
+	}
+	return 0
+}
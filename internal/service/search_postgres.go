@@ -0,0 +1,106 @@
+/**
+ * PostgreSQL 全文检索后端（Meilisearch 不可用时的降级路径）
+ * - 匹配 links.search_tsv（code / title / original_url，simple 配置），每个关键词按词首前缀匹配
+ * - 过滤条件与 Meilisearch 后端一致；点击数为实时值
+ */
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"short-link/internal/repo"
+	"short-link/models"
+)
+
+// maxTSQueryTerms 关键词最多取前若干个（避免超长查询）
+const maxTSQueryTerms = 8
+
+// buildTSQuery 将搜索关键词转换为 to_tsquery 表达式：按非字母数字切分，每个词前缀匹配，词之间为 AND
+// 没有可用关键词时返回空串
+func buildTSQuery(q string) string {
+	terms := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxTSQueryTerms {
+		terms = terms[:maxTSQueryTerms]
+	}
+	for i, t := range terms {
+		terms[i] = "'" + t + "':*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// postgresSearchBackend PostgreSQL 全文检索后端
+type postgresSearchBackend struct {
+	linkService *LinkService
+	linkRepo    *repo.LinkRepo
+	tagRepo     *repo.TagRepo // 可为 nil（结果不含标签）
+	domainRepo  *repo.DomainRepo
+}
+
+// Name 后端名称
+func (b *postgresSearchBackend) Name() string {
+	return SearchBackendPostgres
+}
+
+// SearchLinks 搜索链接（结果按创建时间倒序）
+func (b *postgresSearchBackend) SearchLinks(ctx context.Context, opts *LinkSearchOptions) (*models.PaginatedLinksResponse, error) {
+	page, limit := opts.pageLimit()
+
+	filter := repo.LinkFilter{
+		Tag:         opts.Tag,
+		DomainID:    opts.DomainID,
+		CreatedFrom: opts.CreatedFrom,
+		CreatedTo:   opts.CreatedTo,
+		MinClicks:   opts.MinClicks,
+		MaxClicks:   opts.MaxClicks,
+	}
+	if opts.FolderID > 0 {
+		filter.FolderID = &opts.FolderID
+	}
+	if opts.Query != "" {
+		filter.TSQuery = buildTSQuery(opts.Query)
+		if filter.TSQuery == "" {
+			// 关键词只有标点等无法检索的字符
+			return paginatedLinks([]models.LinkResponse{}, 0, page, limit), nil
+		}
+	}
+
+	rows, total, err := b.linkRepo.SearchLinks(ctx, opts.UserID, filter, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags map[int64][]string
+	if b.tagRepo != nil && len(rows) > 0 {
+		ids := make([]int64, len(rows))
+		for i := range rows {
+			ids[i] = rows[i].ID
+		}
+		if tags, err = b.tagRepo.GetLinkTags(ctx, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	domainOf := newDomainCache(ctx, b.domainRepo)
+	links := make([]models.LinkResponse, 0, len(rows))
+	for i := range rows {
+		l := &rows[i]
+		links = append(links, models.LinkResponse{
+			ID:           l.ID,
+			Code:         l.Code,
+			ShortURL:     b.linkService.BuildShortURL(domainOf(l.DomainID), l.Code),
+			OriginalURL:  l.OriginalURL,
+			Title:        l.Title,
+			ClickCount:   l.ClickCount,
+			UniqueClicks: l.UniqueClicks,
+			Tags:         tags[l.ID],
+			FolderID:     l.FolderID,
+			CreatedAt:    l.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return paginatedLinks(links, total, page, limit), nil
+}
@@ -1,6 +1,10 @@
 /**
- * 搜索服务（重写版，Meilisearch）
- * - 提供基于 Meilisearch 的链接搜索能力
+ * 搜索服务（重写版）
+ * - 优先使用 Meilisearch；启动时不可用或运行中请求失败时自动降级到 PostgreSQL 全文检索
+ * - Meilisearch 不可用期间在后台按指数退避重连，恢复后自动切回
+ * - 默认按 user_id 过滤，管理员可显式全局搜索
+ * - 支持按域名、创建时间区间、点击数区间、标签、文件夹过滤
+ * - 短链接按链接所属域名通过 LinkService.BuildShortURL 生成
  */
 package service
 
@@ -8,104 +12,241 @@ import (
 	"context"
 	"fmt"
 	"short-link/internal/config"
+	"short-link/internal/metrics"
+	"short-link/internal/repo"
 	"short-link/models"
+	"short-link/utils"
+	"strings"
+	"sync"
 	"time"
+)
+
+// 搜索后端名称（响应头 X-Search-Backend 与 Prometheus backend 标签）
+const (
+	SearchBackendMeilisearch = "meilisearch"
+	SearchBackendPostgres    = "postgres"
+)
 
-	"github.com/meilisearch/meilisearch-go"
+const (
+	// meiliReconnectMin / meiliReconnectMax Meilisearch 后台重连的退避区间
+	meiliReconnectMin = 5 * time.Second
+	meiliReconnectMax = time.Minute
 )
 
+// SearchBackend 搜索后端
+type SearchBackend interface {
+	Name() string
+	SearchLinks(ctx context.Context, opts *LinkSearchOptions) (*models.PaginatedLinksResponse, error)
+}
+
 // SearchService 搜索服务
 type SearchService struct {
-	cfg    *config.Config
-	client *meilisearch.Client
-	index  *meilisearch.Index
+	cfg         *config.Config
+	linkService *LinkService
+	domainRepo  *repo.DomainRepo
+	fallback    SearchBackend // PostgreSQL，始终可用
+
+	mu           sync.RWMutex
+	primary      SearchBackend // Meilisearch，不可用时为 nil
+	reconnecting bool
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
 }
 
 // NewSearchService 创建搜索服务实例（v2）
-func NewSearchService(cfg *config.Config) (*SearchService, error) {
-	client := meilisearch.NewClient(meilisearch.ClientConfig{
-		Host:   cfg.MeiliHost,
-		APIKey: cfg.MeiliKey,
-	})
-
-	if _, err := client.Health(); err != nil {
-		return nil, fmt.Errorf("Meilisearch连接失败: %w", err)
-	}
-
-	index := client.Index("links")
-	return &SearchService{
-		cfg:    cfg,
-		client: client,
-		index:  index,
-	}, nil
+// Meilisearch 连接失败时仅记录日志，搜索降级到 PostgreSQL，并在后台重连
+func NewSearchService(cfg *config.Config, linkService *LinkService, linkRepo *repo.LinkRepo, tagRepo *repo.TagRepo, domainRepo *repo.DomainRepo) *SearchService {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &SearchService{
+		cfg:         cfg,
+		linkService: linkService,
+		domainRepo:  domainRepo,
+		fallback: &postgresSearchBackend{
+			linkService: linkService,
+			linkRepo:    linkRepo,
+			tagRepo:     tagRepo,
+			domainRepo:  domainRepo,
+		},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	if b, err := newMeiliSearchBackend(cfg, linkService, domainRepo); err != nil {
+		utils.LogWarn("Meilisearch(v2) 初始化失败，搜索降级到 PostgreSQL 并在后台重连: %v", err)
+		s.startReconnect()
+	} else {
+		s.primary = b
+	}
+	return s
 }
 
-// SearchLinks 搜索链接（仅按 code/title/original_url）
-func (s *SearchService) SearchLinks(ctx context.Context, query string, page, limit int) (*models.PaginatedLinksResponse, error) {
-	if page < 1 {
-		page = 1
+// SearchLinks 搜索链接，返回结果与实际使用的后端名称
+func (s *SearchService) SearchLinks(ctx context.Context, opts *LinkSearchOptions) (*models.PaginatedLinksResponse, string, error) {
+	s.mu.RLock()
+	primary := s.primary
+	s.mu.RUnlock()
+
+	if primary != nil {
+		result, err := primary.SearchLinks(ctx, opts)
+		if err == nil {
+			metrics.SearchRequestsTotal.WithLabelValues(primary.Name()).Inc()
+			return result, primary.Name(), nil
+		}
+		utils.LogWarn("Meilisearch 搜索失败，降级到 PostgreSQL 并在后台重连: %v", err)
+		s.markPrimaryDown(primary)
 	}
-	if limit < 1 {
-		limit = 20
+
+	result, err := s.fallback.SearchLinks(ctx, opts)
+	if err != nil {
+		return nil, s.fallback.Name(), err
 	}
+	metrics.SearchRequestsTotal.WithLabelValues(s.fallback.Name()).Inc()
+	return result, s.fallback.Name(), nil
+}
 
-	req := &meilisearch.SearchRequest{
-		Query:  query,
-		Limit:  int64(limit),
-		Offset: int64((page - 1) * limit),
-		Sort:   []string{"created_at:desc"},
+// markPrimaryDown 摘除不可用的 Meilisearch 后端并启动后台重连
+func (s *SearchService) markPrimaryDown(failed SearchBackend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.primary != failed {
+		return // 已被其他请求摘除
 	}
+	s.primary = nil
+	s.startReconnectLocked()
+}
 
-	result, err := s.index.Search(query, req)
-	if err != nil {
-		return nil, fmt.Errorf("搜索失败: %w", err)
+// startReconnect 启动后台重连
+func (s *SearchService) startReconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startReconnectLocked()
+}
+
+// startReconnectLocked 启动后台重连（调用方持有 s.mu；已在重连或已停止时为空操作）
+func (s *SearchService) startReconnectLocked() {
+	if s.reconnecting || s.ctx.Err() != nil {
+		return
 	}
+	s.reconnecting = true
+	s.wg.Add(1)
+	go s.reconnect()
+}
 
-	var links []models.LinkResponse
-	for _, hit := range result.Hits {
-		doc, ok := hit.(map[string]interface{})
-		if !ok {
-			continue
+// reconnect 指数退避重连 Meilisearch，成功后切回
+func (s *SearchService) reconnect() {
+	defer s.wg.Done()
+
+	delay := meiliReconnectMin
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(delay):
 		}
 
-		link := models.LinkResponse{
-			ID:          int64(doc["id"].(float64)),
-			Code:        doc["code"].(string),
-			OriginalURL: doc["original_url"].(string),
-			Title:       getString(doc, "title"),
+		// 失败原因已在降级时记录，重连期间不重复输出
+		if b, err := newMeiliSearchBackend(s.cfg, s.linkService, s.domainRepo); err == nil {
+			s.mu.Lock()
+			s.primary = b
+			s.reconnecting = false
+			s.mu.Unlock()
+			utils.LogInfo("Meilisearch(v2) 已重新连接，搜索切回 Meilisearch")
+			return
+		}
+		if delay *= 2; delay > meiliReconnectMax {
+			delay = meiliReconnectMax
 		}
+	}
+}
+
+// Stop 停止后台重连
+func (s *SearchService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
 
-		// 短链URL：使用 BaseURL + code（与旧版保持一致）
-		link.ShortURL = fmt.Sprintf("%s/%s", s.cfg.BaseURL, link.Code)
+// LinkSearchOptions 搜索条件（零值字段表示不过滤）
+type LinkSearchOptions struct {
+	Query       string
+	UserID      int64 // 限定用户；0 表示全局搜索（仅管理员）
+	DomainID    int64
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MinClicks   *int64 // Meilisearch 中为索引写入时的快照，PostgreSQL 中为实时值
+	MaxClicks   *int64
+	Tag         string
+	FolderID    int64
+	Page        int
+	Limit       int
+}
 
-		if createdAt, ok := doc["created_at"].(float64); ok {
-			link.CreatedAt = time.Unix(int64(createdAt), 0).Format(time.RFC3339)
-		}
+// HasFilter 是否设置了除用户以外的过滤条件
+func (o *LinkSearchOptions) HasFilter() bool {
+	return o.DomainID > 0 || o.CreatedFrom != nil || o.CreatedTo != nil ||
+		o.MinClicks != nil || o.MaxClicks != nil || o.Tag != "" || o.FolderID > 0
+}
 
-		links = append(links, link)
+// pageLimit 规范化分页参数
+func (o *LinkSearchOptions) pageLimit() (int, int) {
+	page, limit := o.Page, o.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
 	}
+	return page, limit
+}
 
-	totalPages := int(result.EstimatedTotalHits) / limit
-	if int(result.EstimatedTotalHits)%limit > 0 {
+// paginatedLinks 构造分页响应
+func paginatedLinks(links []models.LinkResponse, total int64, page int, limit int) *models.PaginatedLinksResponse {
+	totalPages := int(total) / limit
+	if int(total)%limit > 0 {
 		totalPages++
 	}
-
 	return &models.PaginatedLinksResponse{
 		Links:      links,
-		Total:      result.EstimatedTotalHits,
+		Total:      total,
 		Page:       page,
 		Limit:      limit,
 		TotalPages: totalPages,
-	}, nil
+	}
 }
 
-func getString(m map[string]interface{}, key string) string {
-	if v, ok := m[key]; ok {
-		if s, ok := v.(string); ok {
-			return s
+// newDomainCache 按 ID 查询域名的缓存函数（同一页内每个域名只查询一次，查询失败返回 nil）
+func newDomainCache(ctx context.Context, domainRepo *repo.DomainRepo) func(domainID int64) *models.Domain {
+	domains := map[int64]*models.Domain{}
+	return func(domainID int64) *models.Domain {
+		if d, ok := domains[domainID]; ok {
+			return d
 		}
+		var d *models.Domain
+		if domainID > 0 && domainRepo != nil {
+			if found, err := domainRepo.GetDomainByID(ctx, domainID); err == nil {
+				d = found
+			}
+		}
+		domains[domainID] = d
+		return d
 	}
-	return ""
 }
 
-
+// ParseSearchTime 解析搜索时间参数（RFC3339 或 2006-01-02；endOfDay 为 true 时日期取当天结束）
+func ParseSearchTime(raw string, endOfDay bool) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("时间格式无效: %s（支持 RFC3339 或 YYYY-MM-DD）", raw)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return &t, nil
+}
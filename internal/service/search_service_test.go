@@ -0,0 +1,73 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildSearchFilter(t *testing.T) {
+	if f := buildSearchFilter(&LinkSearchOptions{}); len(f) != 0 {
+		t.Fatalf("empty options should yield no filter, got %q", f)
+	}
+
+	from := time.Unix(1700000000, 0)
+	to := time.Unix(1700086400, 0)
+	min, max := int64(0), int64(100)
+	got := buildSearchFilter(&LinkSearchOptions{
+		UserID:      7,
+		DomainID:    2,
+		CreatedFrom: &from,
+		CreatedTo:   &to,
+		MinClicks:   &min,
+		MaxClicks:   &max,
+		Tag:         `say "hi"`,
+		FolderID:    3,
+	})
+	want := []string{
+		"user_id = 7",
+		"domain_id = 2",
+		"created_at >= 1700000000",
+		"created_at <= 1700086400",
+		"click_count >= 0",
+		"click_count <= 100",
+		`tags = "say \"hi\""`,
+		"folder_id = 3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseSearchTime(t *testing.T) {
+	if v, err := ParseSearchTime("", false); err != nil || v != nil {
+		t.Fatalf("empty input: got %v, %v", v, err)
+	}
+	end, err := ParseSearchTime("2025-01-02", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if end.Hour() != 23 || end.Minute() != 59 || end.Day() != 2 {
+		t.Fatalf("end of day not applied: %v", end)
+	}
+	if _, err := ParseSearchTime("yesterday", false); err == nil {
+		t.Fatalf("expected error for invalid time")
+	}
+}
+
+func TestBuildTSQuery(t *testing.T) {
+	cases := map[string]string{
+		"":                     "",
+		"  !!! ":               "",
+		"Hello":                "'hello':*",
+		"example.com/Path?q=1": "'example':* & 'com':* & 'path':* & 'q':* & '1':*",
+		"营销 活动":                "'营销':* & '活动':*",
+		"it's":                 "'it':* & 's':*",
+		"a b c d e f g h i j":  "'a':* & 'b':* & 'c':* & 'd':* & 'e':* & 'f':* & 'g':* & 'h':*",
+	}
+	for in, want := range cases {
+		if got := buildTSQuery(in); got != want {
+			t.Errorf("buildTSQuery(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+/**
+ * Webhook Service
+ * - 接收地址 CRUD（仅所有者，非所有者返回 repo.ErrNotFound），签名密钥由服务端生成，仅在创建与轮换时返回
+ * - 投递记录查询与手动重新投递（新建一条 pending 投递，沿用原事件 ID 与 payload）
+ */
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"short-link/internal/repo"
+	"short-link/models"
+	"short-link/utils"
+)
+
+const (
+	// maxWebhooksPerUser 单个用户的接收地址数量上限
+	maxWebhooksPerUser = 10
+	// maxWebhookDescriptionLen 描述最大长度（与列定义一致）
+	maxWebhookDescriptionLen = 255
+)
+
+// WebhookService webhook 服务
+type WebhookService struct {
+	webhookRepo *repo.WebhookRepo
+}
+
+// NewWebhookService 创建 WebhookService
+func NewWebhookService(webhookRepo *repo.WebhookRepo) *WebhookService {
+	return &WebhookService{webhookRepo: webhookRepo}
+}
+
+// ListEndpoints 获取当前用户的接收地址
+func (s *WebhookService) ListEndpoints(ctx context.Context, userID int64) ([]models.WebhookEndpoint, error) {
+	return s.webhookRepo.ListEndpoints(ctx, userID)
+}
+
+// GetEndpoint 获取当前用户的接收地址
+func (s *WebhookService) GetEndpoint(ctx context.Context, userID int64, id int64) (*models.WebhookEndpoint, error) {
+	return s.webhookRepo.GetEndpoint(ctx, userID, id)
+}
+
+// CreateEndpoint 注册接收地址（返回值含签名密钥）
+func (s *WebhookService) CreateEndpoint(ctx context.Context, userID int64, req *models.WebhookEndpointRequest) (*models.WebhookEndpoint, error) {
+	target, err := validateWebhookURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+	description, err := validateWebhookDescription(req.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := s.webhookRepo.CountEndpoints(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if n >= maxWebhooksPerUser {
+		return nil, fmt.Errorf("每个用户最多 %d 个 webhook", maxWebhooksPerUser)
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	e := &models.WebhookEndpoint{
+		UserID:      userID,
+		URL:         target,
+		Secret:      secret,
+		Events:      events,
+		Description: description,
+		Active:      req.Active == nil || *req.Active,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.webhookRepo.CreateEndpoint(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// UpdateEndpoint 更新接收地址（未提供的字段保持不变）
+func (s *WebhookService) UpdateEndpoint(ctx context.Context, userID int64, id int64, req *models.WebhookEndpointUpdateRequest) (*models.WebhookEndpoint, error) {
+	e, err := s.webhookRepo.GetEndpoint(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL != nil {
+		if e.URL, err = validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+	}
+	if req.Events != nil {
+		if e.Events, err = normalizeWebhookEvents(req.Events); err != nil {
+			return nil, err
+		}
+	}
+	if req.Description != nil {
+		if e.Description, err = validateWebhookDescription(*req.Description); err != nil {
+			return nil, err
+		}
+	}
+	if req.Active != nil {
+		e.Active = *req.Active
+	}
+	e.UpdatedAt = time.Now()
+	if err := s.webhookRepo.UpdateEndpoint(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// RotateSecret 轮换签名密钥（返回值含新密钥；尚未投递的请求使用新密钥签名）
+func (s *WebhookService) RotateSecret(ctx context.Context, userID int64, id int64) (*models.WebhookEndpoint, error) {
+	e, err := s.webhookRepo.GetEndpoint(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Secret, err = generateWebhookSecret(); err != nil {
+		return nil, err
+	}
+	e.UpdatedAt = time.Now()
+	if err := s.webhookRepo.UpdateSecret(ctx, userID, id, e.Secret, e.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// DeleteEndpoint 删除接收地址（投递记录一并删除）
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, userID int64, id int64) error {
+	return s.webhookRepo.DeleteEndpoint(ctx, userID, id)
+}
+
+// ListDeliveries 分页获取接收地址的投递记录
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID int64, endpointID int64, status string, page, limit int) ([]models.WebhookDelivery, int64, error) {
+	switch status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+	default:
+		return nil, 0, fmt.Errorf("无效的投递状态: %s", status)
+	}
+	if _, err := s.webhookRepo.GetEndpoint(ctx, userID, endpointID); err != nil {
+		return nil, 0, err
+	}
+	return s.webhookRepo.ListDeliveries(ctx, endpointID, status, page, limit)
+}
+
+// GetDelivery 获取投递记录详情（含 payload 与响应体）
+func (s *WebhookService) GetDelivery(ctx context.Context, userID int64, endpointID int64, deliveryID int64) (*models.WebhookDelivery, error) {
+	if _, err := s.webhookRepo.GetEndpoint(ctx, userID, endpointID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.GetDelivery(ctx, endpointID, deliveryID)
+}
+
+// Redeliver 手动重新投递
+func (s *WebhookService) Redeliver(ctx context.Context, userID int64, endpointID int64, deliveryID int64) (*models.WebhookDelivery, error) {
+	if _, err := s.webhookRepo.GetEndpoint(ctx, userID, endpointID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.Redeliver(ctx, endpointID, deliveryID)
+}
+
+// validateWebhookURL 校验接收地址（与跳转目标相同的 SSRF 校验，投递时再按解析后的 IP 校验一次）
+func validateWebhookURL(raw string) (string, error) {
+	target := strings.TrimSpace(raw)
+	if err := utils.ValidateExternalURL(target); err != nil {
+		return "", fmt.Errorf("webhook 地址不合法: %w", err)
+	}
+	return target, nil
+}
+
+// validateWebhookDescription 校验描述长度
+func validateWebhookDescription(raw string) (string, error) {
+	description := strings.TrimSpace(raw)
+	if len([]rune(description)) > maxWebhookDescriptionLen {
+		return "", fmt.Errorf("描述最多 %d 个字符", maxWebhookDescriptionLen)
+	}
+	return description, nil
+}
+
+// normalizeWebhookEvents 校验订阅的事件类型（去重、排序，至少一个）
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	valid := make(map[string]bool, len(models.WebhookEventTypes))
+	for _, t := range models.WebhookEventTypes {
+		valid[t] = true
+	}
+
+	seen := make(map[string]bool, len(events))
+	out := make([]string, 0, len(events))
+	for _, raw := range events {
+		t := strings.ToLower(strings.TrimSpace(raw))
+		if !valid[t] {
+			return nil, fmt.Errorf("不支持的事件类型: %s（可选 %s）", raw, strings.Join(models.WebhookEventTypes, ", "))
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("至少订阅一个事件类型")
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// generateWebhookSecret 生成签名密钥（whsec_ + 32 字节随机数 hex）
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成 webhook 密钥失败: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
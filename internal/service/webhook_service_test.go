@@ -0,0 +1,38 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeWebhookEvents(t *testing.T) {
+	got, err := normalizeWebhookEvents([]string{" Link.Clicked", "link.created", "link.clicked"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"link.clicked", "link.created"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := normalizeWebhookEvents(nil); err == nil {
+		t.Error("empty event list should be rejected")
+	}
+	if _, err := normalizeWebhookEvents([]string{"link.created", "link.viewed"}); err == nil {
+		t.Error("unknown event type should be rejected")
+	}
+}
+
+func TestGenerateWebhookSecret(t *testing.T) {
+	a, err := generateWebhookSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := generateWebhookSecret()
+	if !strings.HasPrefix(a, "whsec_") || len(a) != len("whsec_")+64 {
+		t.Errorf("unexpected secret format: %q", a)
+	}
+	if a == b {
+		t.Error("secrets should be random")
+	}
+}
@@ -16,6 +16,26 @@ type AccessLog struct {
 	UserAgent string    `json:"user_agent" db:"user_agent"`
 	Referer   string    `json:"referer" db:"referer"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// UA 解析结果（入库时由 StatsWorker 填充）
+	Browser        string `json:"browser" db:"browser"`
+	BrowserVersion string `json:"browser_version" db:"browser_version"`
+	OS             string `json:"os" db:"os"`
+	Device         string `json:"device" db:"device"` // desktop/mobile/tablet/unknown
+	IsBot          bool   `json:"is_bot" db:"is_bot"`
+
+	// GeoIP 归属地（配置了 mmdb 时由 StatsWorker 填充）
+	CountryCode string `json:"country_code" db:"country_code"`
+	Region      string `json:"region" db:"region"`
+	City        string `json:"city" db:"city"`
+	ASN         int64  `json:"asn" db:"asn"`
+	ASOrg       string `json:"as_org" db:"as_org"`
+
+	// A/B 分流命中的变体（0 表示未使用变体）
+	VariantID int64 `json:"variant_id,omitempty" db:"variant_id"`
+
+	// 独立访客指纹（StatsWorker 在匿名化前计算，仅用于 HyperLogLog 计数，不落库；0 表示不计入）
+	VisitorHash uint64 `json:"-" db:"-"`
 }
 
 // AccessStats 访问统计
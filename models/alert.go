@@ -0,0 +1,98 @@
+/**
+ * 点击异常告警模型
+ * - AlertThreshold 为用户默认（LinkID 为空）或单个链接的阈值覆盖，字段为 nil 表示继承上一级
+ * - AlertRule 为按 链接 -> 用户 -> 全局配置 合并后的生效阈值
+ * - ClickAlert 为一条告警记录，同时经 webhook（link.alert）与邮件发送
+ */
+package models
+
+import "time"
+
+// 告警类型
+const (
+	AlertKindSpike      = "spike"       // 点击激增（如链接走红）
+	AlertKindDrop       = "drop"        // 常态繁忙的链接点击跌零
+	AlertKindBotTraffic = "bot_traffic" // 爬虫流量占比过高（疑似刷量）
+)
+
+// AlertKinds 全部告警类型
+var AlertKinds = []string{AlertKindSpike, AlertKindDrop, AlertKindBotTraffic}
+
+// AlertRule 生效的告警阈值（数值为 0 表示关闭对应检测）
+type AlertRule struct {
+	Enabled         bool    `json:"enabled"`
+	SpikeFactor     float64 `json:"spike_factor"`
+	SpikeMinClicks  int64   `json:"spike_min_clicks"`
+	DropMinBaseline float64 `json:"drop_min_baseline"`
+	BotRatio        float64 `json:"bot_ratio"`
+	NotifyEmail     bool    `json:"notify_email"`
+}
+
+// Apply 依次应用用户默认设置与链接覆盖（nil 字段继承；notify_email 只取用户默认设置）
+func (r AlertRule) Apply(user, link *AlertThreshold) AlertRule {
+	for _, t := range []*AlertThreshold{user, link} {
+		if t == nil {
+			continue
+		}
+		if t.Enabled != nil {
+			r.Enabled = *t.Enabled
+		}
+		if t.SpikeFactor != nil {
+			r.SpikeFactor = *t.SpikeFactor
+		}
+		if t.SpikeMinClicks != nil {
+			r.SpikeMinClicks = *t.SpikeMinClicks
+		}
+		if t.DropMinBaseline != nil {
+			r.DropMinBaseline = *t.DropMinBaseline
+		}
+		if t.BotRatio != nil {
+			r.BotRatio = *t.BotRatio
+		}
+	}
+	if user != nil && user.NotifyEmail != nil {
+		r.NotifyEmail = *user.NotifyEmail
+	}
+	return r
+}
+
+// AlertThreshold 告警阈值设置（nil 字段继承上一级）
+type AlertThreshold struct {
+	ID              int64     `json:"id" db:"id"`
+	UserID          int64     `json:"user_id" db:"user_id"`
+	LinkID          *int64    `json:"link_id,omitempty" db:"link_id"`
+	Enabled         *bool     `json:"enabled" db:"enabled"`
+	SpikeFactor     *float64  `json:"spike_factor" db:"spike_factor"`
+	SpikeMinClicks  *int64    `json:"spike_min_clicks" db:"spike_min_clicks"`
+	DropMinBaseline *float64  `json:"drop_min_baseline" db:"drop_min_baseline"`
+	BotRatio        *float64  `json:"bot_ratio" db:"bot_ratio"`
+	NotifyEmail     *bool     `json:"notify_email,omitempty" db:"notify_email"` // 仅用户默认设置
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AlertThresholdRequest 设置告警阈值请求（整体替换，未提供的字段继承上一级）
+type AlertThresholdRequest struct {
+	Enabled         *bool    `json:"enabled"`
+	SpikeFactor     *float64 `json:"spike_factor"`
+	SpikeMinClicks  *int64   `json:"spike_min_clicks"`
+	DropMinBaseline *float64 `json:"drop_min_baseline"`
+	BotRatio        *float64 `json:"bot_ratio"`
+	NotifyEmail     *bool    `json:"notify_email"` // 仅用户默认设置可用
+}
+
+// ClickAlert 点击异常告警
+type ClickAlert struct {
+	ID          int64     `json:"id" db:"id"`
+	UserID      int64     `json:"user_id" db:"user_id"`
+	LinkID      int64     `json:"link_id" db:"link_id"`
+	Code        string    `json:"code" db:"code"`
+	Kind        string    `json:"kind" db:"kind"`
+	WindowStart time.Time `json:"window_start" db:"window_start"`
+	WindowEnd   time.Time `json:"window_end" db:"window_end"`
+	Observed    int64     `json:"observed" db:"observed"`   // 窗口内点击数（bot_traffic 为爬虫点击数）
+	Baseline    float64   `json:"baseline" db:"baseline"`   // 平均每小时点击
+	Threshold   float64   `json:"threshold" db:"threshold"` // 触发阈值
+	Message     string    `json:"message" db:"message"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
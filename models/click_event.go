@@ -0,0 +1,31 @@
+/**
+ * 实时点击事件模型
+ * 由实时点击流（SSE）推送，不含 IP 与原始 User-Agent
+ */
+package models
+
+import (
+	"time"
+)
+
+// ClickEvent 实时点击事件
+type ClickEvent struct {
+	LinkID      int64     `json:"link_id"`
+	UserID      int64     `json:"user_id"` // 链接所有者（订阅按所有者过滤）
+	Code        string    `json:"code"`
+	Timestamp   time.Time `json:"ts"`
+	RefererHost string    `json:"referer_host,omitempty"`
+	VariantID   int64     `json:"variant_id,omitempty"`
+
+	// UA 解析结果
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	Device         string `json:"device,omitempty"`
+	IsBot          bool   `json:"is_bot,omitempty"`
+
+	// GeoIP 归属地（访客发送 DNT / Sec-GPC 时只保留国家）
+	CountryCode string `json:"country_code,omitempty"`
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city,omitempty"`
+}
@@ -19,10 +19,32 @@ type Link struct {
 	Hash        string    `json:"hash" db:"hash"` // URL内容的哈希值，用于一致性检查
 	QRCode      string    `json:"qr_code" db:"qr_code"` // 二维码Base64
 	ClickCount  int64     `json:"click_count" db:"click_count"`
+	UniqueClicks int64    `json:"unique_clicks" db:"unique_clicks"` // 独立访客（HyperLogLog 估计）
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`   // 过期时间，nil 表示永不过期
+	MaxClicks   *int64     `json:"max_clicks,omitempty" db:"max_clicks"`   // 点击预算，nil 表示不限
+	ExpiredRedirectURL string `json:"expired_redirect_url,omitempty" db:"expired_redirect_url"` // 过期/用尽后的兜底跳转地址
+	PasswordHash string   `json:"-" db:"password_hash"` // 访问密码（bcrypt），空表示不受保护
+	FolderID    *int64    `json:"folder_id,omitempty" db:"folder_id"` // 所属文件夹，nil 表示未归档
+	Tags        []string  `json:"tags,omitempty" db:"-"`              // 标签名（link_tags 关联，按需加载）
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// IsProtected 是否设置了访问密码
+func (l *Link) IsProtected() bool {
+	return l.PasswordHash != ""
+}
+
+// IsExpired 是否已过期（按时间）
+func (l *Link) IsExpired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// IsExhausted 点击预算是否已用尽
+func (l *Link) IsExhausted() bool {
+	return l.MaxClicks != nil && l.ClickCount >= *l.MaxClicks
+}
+
 // LinkStats 链接统计信息
 type LinkStats struct {
 	TotalLinks    int64 `json:"total_links"`
@@ -37,6 +59,38 @@ type CreateLinkRequest struct {
 	Title    string `json:"title"`
 	Code     string `json:"code"`      // 可选的自定义代码
 	DomainID int64  `json:"domain_id"` // 可选，使用指定域名
+
+	// 生命周期（可选）
+	ExpiresAt          *time.Time `json:"expires_at"`           // 过期时间（RFC3339）
+	MaxClicks          *int64     `json:"max_clicks"`           // 最大点击数
+	ExpiredRedirectURL string     `json:"expired_redirect_url"` // 过期/用尽后的兜底跳转地址
+
+	// 访问密码（可选），设置后跳转前需要输入密码
+	Password string `json:"password" binding:"omitempty,min=4,max=72"`
+
+	// 整理（可选）
+	Tags     []string `json:"tags"`      // 标签名，不存在时自动创建
+	FolderID int64    `json:"folder_id"` // 文件夹 ID，0 表示未归档
+}
+
+// HasLifecycle 是否设置了生命周期参数
+func (r *CreateLinkRequest) HasLifecycle() bool {
+	return r.ExpiresAt != nil || r.MaxClicks != nil || r.ExpiredRedirectURL != ""
+}
+
+// UpdateLinkRequest 更新链接请求（字段为空表示不修改）
+type UpdateLinkRequest struct {
+	URL   *string `json:"url"`
+	Title *string `json:"title"`
+	Code  *string `json:"code"`
+
+	Tags     *[]string `json:"tags"`      // 整体替换标签，空数组表示清空
+	FolderID *int64    `json:"folder_id"` // 移动到文件夹，0 表示移出文件夹
+}
+
+// IsEmpty 是否没有任何需要更新的字段
+func (r *UpdateLinkRequest) IsEmpty() bool {
+	return r.URL == nil && r.Title == nil && r.Code == nil && r.Tags == nil && r.FolderID == nil
 }
 
 // LinkResponse 链接响应
@@ -48,6 +102,12 @@ type LinkResponse struct {
 	Title       string `json:"title"`
 	QRCode      string `json:"qr_code"` // 二维码Base64
 	ClickCount  int64  `json:"click_count"`
+	UniqueClicks int64 `json:"unique_clicks"` // 独立访客（HyperLogLog 估计）
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	MaxClicks   *int64 `json:"max_clicks,omitempty"`
+	PasswordProtected bool `json:"password_protected,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	FolderID    *int64   `json:"folder_id,omitempty"`
 	CreatedAt   string `json:"created_at"`
 }
 
@@ -60,3 +120,23 @@ type PaginatedLinksResponse struct {
 	TotalPages int            `json:"total_pages"`
 }
 
+
+// BatchCreateLinksRequest 批量创建链接请求（各项字段与 CreateLinkRequest 一致，逐项校验）
+type BatchCreateLinksRequest struct {
+	Links []CreateLinkRequest `json:"links" binding:"required,min=1"`
+}
+
+// BatchLinkResult 批量创建单项结果（与请求顺序一致）
+type BatchLinkResult struct {
+	Index   int           `json:"index"`
+	Success bool          `json:"success"`
+	Link    *LinkResponse `json:"link,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// BatchCreateLinksResponse 批量创建响应
+type BatchCreateLinksResponse struct {
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Results []BatchLinkResult `json:"results"`
+}
@@ -0,0 +1,37 @@
+/**
+ * 定向跳转规则模型
+ * 按访客国家/设备/系统/语言/时间窗口将同一短链分流到不同目标地址
+ */
+package models
+
+import (
+	"time"
+)
+
+// LinkRule 跳转规则（各条件之间为 AND，条件内多个取值为 OR，空表示不限）
+type LinkRule struct {
+	ID        int64      `json:"id" db:"id"`
+	LinkID    int64      `json:"link_id" db:"link_id"`
+	Priority  int        `json:"priority" db:"priority"`             // 升序匹配，首条命中生效
+	Countries []string   `json:"countries,omitempty" db:"countries"` // ISO 国家代码，如 CN、US
+	Devices   []string   `json:"devices,omitempty" db:"devices"`     // desktop/mobile/tablet
+	OS        []string   `json:"os,omitempty" db:"os"`               // iOS、Android、Windows 等（不区分大小写）
+	Languages []string   `json:"languages,omitempty" db:"languages"` // Accept-Language 首选语言，如 zh、en-US
+	StartsAt  *time.Time `json:"starts_at,omitempty" db:"starts_at"` // 生效时间窗口（可选）
+	EndsAt    *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	TargetURL string     `json:"target_url" db:"target_url"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// LinkRuleRequest 创建/更新跳转规则请求
+type LinkRuleRequest struct {
+	Priority  int        `json:"priority"`
+	Countries []string   `json:"countries"`
+	Devices   []string   `json:"devices"`
+	OS        []string   `json:"os"`
+	Languages []string   `json:"languages"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	TargetURL string     `json:"target_url" binding:"required"`
+}
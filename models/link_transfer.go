@@ -0,0 +1,49 @@
+/**
+ * 链接导入/导出数据模型
+ * CSV 列名与 JSON 字段名一致：username（仅全实例）、domain、code、original_url、title、click_count、created_at、
+ * expires_at、max_clicks、expired_redirect_url、password_protected、password_hash（仅全实例）、folder、tags
+ * CSV 中 tags 为一个单元格，内部按 CSV 规则以逗号分隔多个标签
+ */
+package models
+
+import "time"
+
+// LinkRecord 导入/导出的单条链接记录
+type LinkRecord struct {
+	ID          int64     `json:"-"`
+	Username    string    `json:"username,omitempty"` // 所属用户（仅全实例导出/导入）
+	Domain      string    `json:"domain"`             // 域名，空表示系统默认域名
+	Code        string    `json:"code"`
+	OriginalURL string    `json:"original_url"`
+	Title       string    `json:"title"`
+	ClickCount  int64     `json:"click_count"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	MaxClicks          *int64     `json:"max_clicks,omitempty"`
+	ExpiredRedirectURL string     `json:"expired_redirect_url,omitempty"`
+	PasswordProtected  bool       `json:"password_protected,omitempty"` // 受密码保护（用户导出不含哈希，导入时据此拒绝该条）
+	PasswordHash       string     `json:"password_hash,omitempty"`      // bcrypt 哈希（仅全实例导出/导入）
+	Folder             string     `json:"folder,omitempty"`             // 文件夹名，导入时不存在则创建
+	Tags               []string   `json:"tags,omitempty"`
+}
+
+// LinkImportError 导入失败/冲突的记录
+type LinkImportError struct {
+	Row      int    `json:"row"` // 记录序号（从 1 开始，不含 CSV 表头）
+	Domain   string `json:"domain,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Conflict bool   `json:"conflict"` // true 表示 (domain, code) 已存在
+	Error    string `json:"error"`
+}
+
+// LinkImportReport 导入结果汇总
+type LinkImportReport struct {
+	DryRun    bool              `json:"dry_run"` // 演练模式：imported 表示可导入条数，未实际写入
+	Total     int               `json:"total"`
+	Imported  int               `json:"imported"`
+	Conflicts int               `json:"conflicts"`
+	Failed    int               `json:"failed"`
+	Errors    []LinkImportError `json:"errors"`           // 最多保留前 1000 条
+	Truncated bool              `json:"errors_truncated"` // errors 是否被截断
+}
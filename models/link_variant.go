@@ -0,0 +1,27 @@
+/**
+ * A/B 分流变体模型
+ * 同一短链按权重分配到多个目标地址，访客按 Cookie / IP 哈希粘性分配
+ */
+package models
+
+import (
+	"time"
+)
+
+// LinkVariant 分流变体
+type LinkVariant struct {
+	ID        int64     `json:"id" db:"id"`
+	LinkID    int64     `json:"link_id" db:"link_id"`
+	Label     string    `json:"label" db:"label"` // 变体名称，如 A / B / 新版落地页
+	TargetURL string    `json:"target_url" db:"target_url"`
+	Weight    int       `json:"weight" db:"weight"` // 相对权重，如 70 / 30
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// LinkVariantRequest 创建/更新变体请求
+type LinkVariantRequest struct {
+	Label     string `json:"label" binding:"max=100"`
+	TargetURL string `json:"target_url" binding:"required"`
+	Weight    int    `json:"weight" binding:"required,min=1,max=10000"`
+}
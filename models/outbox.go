@@ -0,0 +1,41 @@
+/**
+ * Outbox 事件模型
+ * - 与链接变更在同一事务内写入 outbox_events，由后台 dispatcher 投递
+ * - payload 为变更时的链接摘要（id/user_id/domain_id/code）；消费方按 aggregate_id 读取最新状态
+ * - 同一语句内按订阅关系写入 webhook 投递记录（见 repo.insertLinkEvents）
+ */
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outbox 事件类型
+const (
+	OutboxLinkCreated = "link.created"
+	OutboxLinkUpdated = "link.updated" // 含标签、文件夹变更
+	OutboxLinkDeleted = "link.deleted"
+	OutboxLinkExpired = "link.expired" // 到期或点击预算用尽
+)
+
+// Outbox 事件状态
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusDone    = "done"
+	OutboxStatusDead    = "dead"
+)
+
+// OutboxEvent outbox 事件
+type OutboxEvent struct {
+	ID          int64           `json:"id" db:"id"`
+	EventType   string          `json:"event_type" db:"event_type"`
+	AggregateID int64           `json:"aggregate_id" db:"aggregate_id"` // 链接 ID
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Status      string          `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"` // 已领取次数（含本次）
+	LastError   *string         `json:"last_error,omitempty" db:"last_error"`
+	AvailableAt time.Time       `json:"available_at" db:"available_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	ProcessedAt *time.Time      `json:"processed_at,omitempty" db:"processed_at"`
+}
@@ -4,10 +4,17 @@
  */
 package models
 
+// HourlyStats 小时统计
+type HourlyStats struct {
+	Hour       string `json:"hour"` // 格式：2025-01-15 13:00
+	ClickCount int64  `json:"click_count"`
+}
+
 // DailyStats 日统计
 type DailyStats struct {
-	Date       string `json:"date"`
-	ClickCount int64  `json:"click_count"`
+	Date         string `json:"date"`
+	ClickCount   int64  `json:"click_count"`
+	UniqueClicks int64  `json:"unique_clicks"` // 当天独立访客（HyperLogLog 估计；多个链接汇总时合并 sketch 去重）
 }
 
 // WeeklyStats 周统计
@@ -40,6 +47,54 @@ type IPStats struct {
 	ClickCount int64  `json:"click_count"`
 }
 
+// BrowserStats 浏览器统计（Version 为空表示按浏览器家族汇总）
+type BrowserStats struct {
+	Browser    string `json:"browser"`
+	Version    string `json:"version,omitempty"`
+	ClickCount int64  `json:"click_count"`
+}
+
+// OSStats 操作系统统计
+type OSStats struct {
+	OS         string `json:"os"`
+	ClickCount int64  `json:"click_count"`
+}
+
+// DeviceStats 设备类型统计（desktop/mobile/tablet/unknown）
+type DeviceStats struct {
+	Device     string `json:"device"`
+	ClickCount int64  `json:"click_count"`
+}
+
+// BotStats 人类/爬虫访问占比
+type BotStats struct {
+	HumanClicks int64 `json:"human_clicks"`
+	BotClicks   int64 `json:"bot_clicks"`
+}
+
+// CountryStats 国家/地区统计（ISO 3166-1 两位代码）
+type CountryStats struct {
+	Country    string `json:"country"`
+	ClickCount int64  `json:"click_count"`
+}
+
+// CityStats 城市统计
+type CityStats struct {
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"`
+	Country    string `json:"country"`
+	ClickCount int64  `json:"click_count"`
+}
+
+// VariantStats A/B 变体统计
+type VariantStats struct {
+	VariantID  int64  `json:"variant_id"`
+	Label      string `json:"label"`
+	TargetURL  string `json:"target_url"`
+	Weight     int    `json:"weight"`
+	ClickCount int64  `json:"click_count"`
+}
+
 // AggregatedStats 聚合统计响应
 type AggregatedStats struct {
 	// 基础统计
@@ -48,6 +103,7 @@ type AggregatedStats struct {
 	TodayClicks   int64 `json:"today_clicks"`
 	
 	// 时间维度统计
+	HourlyStats   []HourlyStats   `json:"hourly_stats,omitempty"`   // 最近24小时
 	DailyStats    []DailyStats    `json:"daily_stats,omitempty"`    // 最近30天
 	WeeklyStats   []WeeklyStats   `json:"weekly_stats,omitempty"`   // 最近12周
 	MonthlyStats  []MonthlyStats   `json:"monthly_stats,omitempty"`  // 最近12个月
@@ -56,8 +112,52 @@ type AggregatedStats struct {
 	TopReferers   []RefererStats  `json:"top_referers,omitempty"`   // Top 10
 	TopUserAgents []UserAgentStats `json:"top_user_agents,omitempty"` // Top 10
 	TopIPs        []IPStats       `json:"top_ips,omitempty"`        // Top 10
+
+	// 设备维度统计（UA 解析结果，不含爬虫）
+	TopBrowsers        []BrowserStats `json:"top_browsers,omitempty"`
+	TopBrowserVersions []BrowserStats `json:"top_browser_versions,omitempty"`
+	TopOS              []OSStats      `json:"top_os,omitempty"`
+	Devices            []DeviceStats  `json:"devices,omitempty"`
+	Bots               *BotStats      `json:"bots,omitempty"`
+
+	// 地理维度统计（需配置 GeoIP，不含爬虫）
+	TopCountries []CountryStats `json:"top_countries,omitempty"`
+	TopCities    []CityStats    `json:"top_cities,omitempty"`
 	
 	// 链接维度统计
 	TopLinks      []Link          `json:"top_links,omitempty"`      // Top 10
 }
 
+
+// LinkAnalytics 单个链接的统计（GET /api/v2/links/:id/stats）
+type LinkAnalytics struct {
+	LinkID       int64  `json:"link_id"`
+	Code         string `json:"code"`
+	ClickCount   int64  `json:"click_count"`
+	UniqueClicks int64  `json:"unique_clicks"` // 累计独立访客（HyperLogLog 估计）
+
+	// 时间维度统计
+	HourlyStats  []HourlyStats  `json:"hourly_stats"`
+	DailyStats   []DailyStats   `json:"daily_stats"`
+	WeeklyStats  []WeeklyStats  `json:"weekly_stats"`
+	MonthlyStats []MonthlyStats `json:"monthly_stats"`
+
+	// 来源维度统计
+	TopReferers   []RefererStats   `json:"top_referers"`
+	TopUserAgents []UserAgentStats `json:"top_user_agents"`
+	TopIPs        []IPStats        `json:"top_ips,omitempty"` // STATS_IP_MODE=hash/drop 时不返回
+
+	// 设备维度统计（UA 解析结果，不含爬虫）
+	TopBrowsers        []BrowserStats `json:"top_browsers"`
+	TopBrowserVersions []BrowserStats `json:"top_browser_versions"`
+	TopOS              []OSStats      `json:"top_os"`
+	Devices            []DeviceStats  `json:"devices"`
+	Bots               *BotStats      `json:"bots"`
+
+	// 地理维度统计（需配置 GeoIP，不含爬虫）
+	TopCountries []CountryStats `json:"top_countries"`
+	TopCities    []CityStats    `json:"top_cities"`
+
+	// A/B 分流统计（按变体，不含爬虫）
+	Variants []VariantStats `json:"variants"`
+}
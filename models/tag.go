@@ -0,0 +1,45 @@
+/**
+ * 标签与文件夹模型
+ * - 标签：用户级，与链接多对多
+ * - 文件夹：用户级一级目录（不可嵌套），每个链接最多属于一个文件夹
+ */
+package models
+
+import (
+	"time"
+)
+
+// Tag 标签
+type Tag struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	LinkCount int64     `json:"link_count" db:"-"` // 关联的链接数（列表时统计）
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Folder 文件夹
+type Folder struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	LinkCount int64     `json:"link_count" db:"-"` // 文件夹内的链接数（列表时统计）
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// FolderRequest 创建/重命名文件夹请求
+type FolderRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// RenameTagRequest 重命名标签请求
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// MergeTagsRequest 合并标签请求：source_ids 的链接全部改挂到 target（不存在时创建），随后删除 source 标签
+type MergeTagsRequest struct {
+	SourceIDs []int64 `json:"source_ids" binding:"required,min=1"`
+	Target    string  `json:"target" binding:"required,max=50"`
+}
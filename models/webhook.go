@@ -0,0 +1,95 @@
+/**
+ * Webhook 模型
+ * - 用户注册接收地址（WebhookEndpoint）并订阅事件类型，事件以 JSON POST 投递
+ * - 请求体为 WebhookEnvelope；链接事件的 data 为链接快照，link.clicked 的 data.clicks 为一批 ClickEvent，link.alert 的 data 为 ClickAlert
+ * - 每次投递记录在 WebhookDelivery（状态、响应码、响应体摘要、耗时），可手动重新投递
+ */
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook 事件类型（链接事件与 outbox 事件类型一致）
+const (
+	WebhookLinkCreated = OutboxLinkCreated
+	WebhookLinkUpdated = OutboxLinkUpdated
+	WebhookLinkDeleted = OutboxLinkDeleted
+	WebhookLinkExpired = OutboxLinkExpired
+	WebhookLinkClicked = "link.clicked" // 按批合并，data.clicks 为点击列表
+	WebhookLinkAlert   = "link.alert"   // 点击异常告警，data 为 ClickAlert
+)
+
+// WebhookEventTypes 可订阅的全部事件类型
+var WebhookEventTypes = []string{
+	WebhookLinkCreated,
+	WebhookLinkUpdated,
+	WebhookLinkDeleted,
+	WebhookLinkExpired,
+	WebhookLinkClicked,
+	WebhookLinkAlert,
+}
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryPending   = "pending" // 待投递（含退避重试中）
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed" // 超过最大尝试次数
+)
+
+// WebhookEndpoint webhook 接收地址
+type WebhookEndpoint struct {
+	ID          int64     `json:"id" db:"id"`
+	UserID      int64     `json:"user_id" db:"user_id"`
+	URL         string    `json:"url" db:"url"`
+	Secret      string    `json:"secret,omitempty" db:"secret"` // 仅在创建与轮换密钥时返回
+	Events      []string  `json:"events" db:"events"`
+	Description string    `json:"description" db:"description"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookEndpointRequest 创建 webhook 请求
+type WebhookEndpointRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"` // 默认 true
+}
+
+// WebhookEndpointUpdateRequest 更新 webhook 请求（字段为空表示不修改）
+type WebhookEndpointUpdateRequest struct {
+	URL         *string  `json:"url"`
+	Events      []string `json:"events"`
+	Description *string  `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// WebhookDelivery webhook 投递记录
+type WebhookDelivery struct {
+	ID             int64           `json:"id" db:"id"`
+	EndpointID     int64           `json:"endpoint_id" db:"endpoint_id"`
+	EventType      string          `json:"event_type" db:"event_type"`
+	EventID        string          `json:"event_id" db:"event_id"`
+	Payload        json.RawMessage `json:"payload,omitempty" db:"payload"` // 列表接口不返回
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	ResponseStatus *int            `json:"response_status,omitempty" db:"response_status"`
+	ResponseBody   *string         `json:"response_body,omitempty" db:"response_body"` // 截断保存，列表接口不返回
+	LastError      *string         `json:"last_error,omitempty" db:"last_error"`
+	DurationMs     *int            `json:"duration_ms,omitempty" db:"duration_ms"`
+	RedeliveryOf   *int64          `json:"redelivery_of,omitempty" db:"redelivery_of"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// WebhookEnvelope webhook 请求体
+type WebhookEnvelope struct {
+	ID        string          `json:"id"` // 事件 ID（接收方去重用）
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
@@ -0,0 +1,139 @@
+/**
+ * HyperLogLog 基数估计（独立访客数）
+ * - 精度 p=12（4096 个寄存器，标准误差约 1.6%），输入为 64 位哈希
+ * - 序列化：寄存器较少时为稀疏格式（索引 + 值），否则为稠密格式（每寄存器 1 字节），取较小者
+ * - 由 StatsWorker 在写入事务内合并后存入 Postgres（links / click_rollups_daily 的 visitors_hll），与是否配置 Redis 无关
+ */
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+const (
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+
+	hllFormatSparse byte = 1
+	hllFormatDense  byte = 2
+)
+
+// ErrInvalidHLL 无法解析的 HLL 序列化数据
+var ErrInvalidHLL = errors.New("invalid hyperloglog sketch")
+
+// HLL HyperLogLog sketch
+type HLL struct {
+	registers [hllRegisters]uint8
+}
+
+// NewHLL 创建空 sketch
+func NewHLL() *HLL {
+	return &HLL{}
+}
+
+// ParseHLL 解析 MarshalBinary 的输出；空数据返回空 sketch
+func ParseHLL(data []byte) (*HLL, error) {
+	h := NewHLL()
+	if len(data) == 0 {
+		return h, nil
+	}
+	body := data[1:]
+	switch data[0] {
+	case hllFormatSparse:
+		if len(body)%3 != 0 {
+			return nil, ErrInvalidHLL
+		}
+		for i := 0; i < len(body); i += 3 {
+			idx := binary.BigEndian.Uint16(body[i:])
+			if int(idx) >= hllRegisters {
+				return nil, ErrInvalidHLL
+			}
+			h.registers[idx] = body[i+2]
+		}
+	case hllFormatDense:
+		if len(body) != hllRegisters {
+			return nil, ErrInvalidHLL
+		}
+		copy(h.registers[:], body)
+	default:
+		return nil, ErrInvalidHLL
+	}
+	return h, nil
+}
+
+// Add 加入一个 64 位哈希
+func (h *HLL) Add(hash uint64) {
+	idx := hash >> (64 - hllPrecision)
+	// 剩余位左移补 1，保证前导零计数不超过 64-p
+	rest := hash<<hllPrecision | 1<<(hllPrecision-1)
+	rank := uint8(bits.LeadingZeros64(rest) + 1)
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// Merge 合并另一个 sketch（并集）
+func (h *HLL) Merge(other *HLL) {
+	for i, v := range other.registers {
+		if v > h.registers[i] {
+			h.registers[i] = v
+		}
+	}
+}
+
+// Count 估计基数
+func (h *HLL) Count() uint64 {
+	m := float64(hllRegisters)
+	var sum float64
+	zeros := 0
+	for _, v := range h.registers {
+		sum += 1 / float64(uint64(1)<<v)
+		if v == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	// 小基数时改用线性计数
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// MarshalBinary 序列化（稀疏/稠密取较小者）
+func (h *HLL) MarshalBinary() ([]byte, error) {
+	nonZero := 0
+	for _, v := range h.registers {
+		if v != 0 {
+			nonZero++
+		}
+	}
+	if nonZero*3 < hllRegisters {
+		out := make([]byte, 1, 1+nonZero*3)
+		out[0] = hllFormatSparse
+		for i, v := range h.registers {
+			if v != 0 {
+				out = append(out, byte(i>>8), byte(i), v)
+			}
+		}
+		return out, nil
+	}
+	out := make([]byte, 1+hllRegisters)
+	out[0] = hllFormatDense
+	copy(out[1:], h.registers[:])
+	return out, nil
+}
+
+// VisitorFingerprint 独立访客指纹：HMAC-SHA256(key, IP | UA) 的前 8 字节
+// 只用于 HyperLogLog 计数，不落库（sketch 与 Redis HLL 中只保存寄存器）；key 可为空
+func VisitorFingerprint(key []byte, ip, userAgent string) uint64 {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ip + "|" + userAgent))
+	return binary.BigEndian.Uint64(mac.Sum(nil)[:8])
+}
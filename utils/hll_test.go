@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestHLLCount(t *testing.T) {
+	key := []byte("k")
+	for _, n := range []int{0, 1, 10, 1000, 50000} {
+		h := NewHLL()
+		for i := 0; i < n; i++ {
+			fp := VisitorFingerprint(key, fmt.Sprintf("10.0.%d.%d", i/256, i%256), "ua")
+			h.Add(fp)
+			h.Add(fp) // 重复访问不增加计数
+		}
+		got := float64(h.Count())
+		if n == 0 {
+			if got != 0 {
+				t.Errorf("empty sketch: got %v", got)
+			}
+			continue
+		}
+		if diff := math.Abs(got-float64(n)) / float64(n); diff > 0.05 {
+			t.Errorf("n=%d: got %v (error %.3f)", n, got, diff)
+		}
+	}
+}
+
+func TestHLLMergeAndMarshal(t *testing.T) {
+	key := []byte("k")
+	a, b := NewHLL(), NewHLL()
+	for i := 0; i < 3000; i++ {
+		a.Add(VisitorFingerprint(key, fmt.Sprint(i), ""))
+		b.Add(VisitorFingerprint(key, fmt.Sprint(i+1500), ""))
+	}
+	a.Merge(b)
+	if got := float64(a.Count()); math.Abs(got-4500)/4500 > 0.05 {
+		t.Errorf("merged count: got %v, want ~4500", got)
+	}
+
+	small := NewHLL()
+	small.Add(VisitorFingerprint(key, "1.2.3.4", "ua"))
+	for _, h := range []*HLL{small, a} {
+		data, _ := h.MarshalBinary()
+		parsed, err := ParseHLL(data)
+		if err != nil {
+			t.Fatalf("ParseHLL: %v", err)
+		}
+		if parsed.Count() != h.Count() {
+			t.Errorf("round trip: got %d, want %d", parsed.Count(), h.Count())
+		}
+	}
+	if data, _ := small.MarshalBinary(); len(data) != 4 || data[0] != hllFormatSparse {
+		t.Errorf("small sketch should be sparse, got %d bytes", len(data))
+	}
+
+	if _, err := ParseHLL([]byte{hllFormatDense, 1, 2}); err == nil {
+		t.Error("expected error for truncated dense sketch")
+	}
+}
+
+func TestVisitorFingerprint(t *testing.T) {
+	key := []byte("k")
+	fp := VisitorFingerprint(key, "1.2.3.4", "ua")
+	if VisitorFingerprint(key, "1.2.3.4", "ua") != fp {
+		t.Error("fingerprint should be stable")
+	}
+	if VisitorFingerprint(key, "1.2.3.4", "other") == fp {
+		t.Error("fingerprint should depend on UA")
+	}
+	if VisitorFingerprint([]byte("other"), "1.2.3.4", "ua") == fp {
+		t.Error("fingerprint should depend on the key")
+	}
+}
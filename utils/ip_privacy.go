@@ -0,0 +1,57 @@
+/**
+ * IP 匿名化工具
+ * - truncate：IPv4 保留 /24，IPv6 保留 /48
+ * - hash：按天轮换的加盐哈希（同一天内同一 IP 的哈希相同，可统计独立访客；跨天不可关联）
+ */
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"time"
+)
+
+// IP 隐私模式（STATS_IP_MODE）
+const (
+	IPModeFull     = "full"     // 保存完整 IP
+	IPModeTruncate = "truncate" // 截断到 /24（IPv4）或 /48（IPv6）
+	IPModeHash     = "hash"     // 按天轮换的加盐哈希
+	IPModeDrop     = "drop"     // 不保存
+)
+
+// IsValidIPMode 是否为支持的 IP 隐私模式
+func IsValidIPMode(mode string) bool {
+	switch mode {
+	case IPModeFull, IPModeTruncate, IPModeHash, IPModeDrop:
+		return true
+	}
+	return false
+}
+
+// TruncateIP 截断 IP：IPv4 保留 /24，IPv6 保留 /48；无法解析时返回空串
+func TruncateIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// HashIP 按天轮换的加盐哈希：当天密钥 = HMAC(secret, 日期)，结果 = HMAC(当天密钥, ip) 前 8 字节（"h:" 前缀 + 16 位十六进制）
+// 空 IP 返回空串
+func HashIP(secret []byte, day time.Time, ip string) string {
+	if ip == "" {
+		return ""
+	}
+	dayKey := hmac.New(sha256.New, secret)
+	dayKey.Write([]byte(day.Format("2006-01-02")))
+
+	mac := hmac.New(sha256.New, dayKey.Sum(nil))
+	mac.Write([]byte(ip))
+	return "h:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTruncateIP(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.57":              "203.0.113.0",
+		"::ffff:203.0.113.57":       "203.0.113.0",
+		"2001:db8:abcd:12:34::1":    "2001:db8:abcd::",
+		"2001:0db8:0001:ffff::dead": "2001:db8:1::",
+		"not-an-ip":                 "",
+		"":                          "",
+	}
+	for in, want := range cases {
+		if got := TruncateIP(in); got != want {
+			t.Errorf("TruncateIP(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHashIP(t *testing.T) {
+	secret := []byte("secret")
+	day := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	sameDay := time.Date(2026, 5, 1, 23, 59, 0, 0, time.UTC)
+	nextDay := day.AddDate(0, 0, 1)
+
+	h := HashIP(secret, day, "203.0.113.57")
+	if len(h) != 18 || h[:2] != "h:" {
+		t.Fatalf("unexpected hash format: %q", h)
+	}
+	if HashIP(secret, sameDay, "203.0.113.57") != h {
+		t.Error("hash should be stable within a day")
+	}
+	if HashIP(secret, nextDay, "203.0.113.57") == h {
+		t.Error("hash should rotate daily")
+	}
+	if HashIP([]byte("other"), day, "203.0.113.57") == h {
+		t.Error("hash should depend on the secret")
+	}
+	if HashIP(secret, day, "203.0.113.58") == h {
+		t.Error("different IPs should hash differently")
+	}
+	if HashIP(secret, day, "") != "" {
+		t.Error("empty IP should stay empty")
+	}
+}
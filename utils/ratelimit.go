@@ -2,12 +2,16 @@
  * 限流工具
  * 实现滑动窗口限流算法（基于 Redis）
  * 实现 redo.md 2.5：限流策略优化（滑动窗口/令牌桶）
+ * - Reserve/release：原子地检查并占用名额，成功的尝试归还名额，实现“只统计失败”（如访问密码错误）；
+ *   未启用 Redis 或 Redis 出错时降级为进程内计数
  */
 package utils
 
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -19,8 +23,35 @@ type SlidingWindowLimiter struct {
 	ctx     context.Context
 	window  time.Duration // 时间窗口
 	limit   int           // 窗口内允许的最大请求数
+	mu      sync.Mutex
+	local   map[string][]localReservation // Reserve 的进程内降级计数
+	now     func() time.Time
 }
 
+// localReservation 进程内占用的一次名额
+type localReservation struct {
+	at time.Time
+	id uint64
+}
+
+// reserveLocalSweepSize 进程内计数的 key 数超过该值时清理过期 key
+const reserveLocalSweepSize = 10000
+
+// reserveSeq 占用记录序号（保证同一纳秒内的并发占用互不覆盖）
+var reserveSeq uint64
+
+// reserveScript 清理窗口外记录、检查并占用名额（单个脚本内完成，并发请求无法同时通过检查）
+var reserveScript = redis.NewScript(`
+local key = KEYS[1]
+redis.call('ZREMRANGEBYSCORE', key, '-inf', ARGV[1])
+if redis.call('ZCARD', key) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', key, ARGV[3], ARGV[4])
+redis.call('PEXPIRE', key, ARGV[5])
+return 1
+`)
+
 // NewSlidingWindowLimiter 创建滑动窗口限流器
 func NewSlidingWindowLimiter(client *redis.Client, window time.Duration, limit int) *SlidingWindowLimiter {
 	return &SlidingWindowLimiter{
@@ -28,7 +59,77 @@ func NewSlidingWindowLimiter(client *redis.Client, window time.Duration, limit i
 		ctx:    context.Background(),
 		window: window,
 		limit:  limit,
+		local:  make(map[string][]localReservation),
+		now:    time.Now,
+	}
+}
+
+// Reserve 原子地清理窗口外记录、检查并占用一次名额（窗口内已占满时返回 false）
+// 返回的 release 用于归还名额：调用方在尝试成功时调用，只有失败的尝试保留计数
+// 与 Allow 不同，未启用 Redis 或 Redis 出错时降级为进程内计数（仅对本副本生效），而不是放行
+func (l *SlidingWindowLimiter) Reserve(key string) (bool, func()) {
+	now := l.now()
+	id := atomic.AddUint64(&reserveSeq, 1)
+	if l.client != nil {
+		ctx, cancel := context.WithTimeout(l.ctx, time.Second)
+		defer cancel()
+		member := fmt.Sprintf("%d-%d", now.UnixNano(), id)
+		ok, err := reserveScript.Run(ctx, l.client, []string{key},
+			now.Add(-l.window).UnixNano(), l.limit, now.UnixNano(), member, (l.window + time.Second).Milliseconds()).Int()
+		if err == nil {
+			if ok == 0 {
+				return false, func() {}
+			}
+			return true, func() {
+				ctx, cancel := context.WithTimeout(l.ctx, time.Second)
+				defer cancel()
+				_ = l.client.ZRem(ctx, key, member).Err() //nolint:errcheck
+			}
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.local) >= reserveLocalSweepSize {
+		for k := range l.local {
+			l.pruneLocked(k, now)
+		}
+	}
+	if len(l.pruneLocked(key, now)) >= l.limit {
+		return false, func() {}
+	}
+	l.local[key] = append(l.local[key], localReservation{at: now, id: id})
+	return true, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		hits := l.local[key]
+		for i := range hits {
+			if hits[i].id == id {
+				l.local[key] = append(hits[:i:i], hits[i+1:]...)
+				break
+			}
+		}
+		if len(l.local[key]) == 0 {
+			delete(l.local, key)
+		}
+	}
+}
+
+// pruneLocked 移除窗口外的进程内记录并返回剩余记录（调用方持有 mu）
+func (l *SlidingWindowLimiter) pruneLocked(key string, now time.Time) []localReservation {
+	hits := l.local[key]
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(hits) && !hits[i].at.After(cutoff) {
+		i++
+	}
+	hits = hits[i:]
+	if len(hits) == 0 {
+		delete(l.local, key)
+		return nil
 	}
+	l.local[key] = hits
+	return hits
 }
 
 // Allow 检查是否允许请求（滑动窗口算法）
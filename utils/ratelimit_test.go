@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowReserveLocal(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	l := NewSlidingWindowLimiter(nil, 15*time.Minute, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Reserve("link_unlock:1:abc:1.2.3.4"); !ok {
+			t.Fatalf("reserve %d should be allowed", i)
+		}
+		now = now.Add(time.Minute)
+	}
+	if ok, _ := l.Reserve("link_unlock:1:abc:1.2.3.4"); ok {
+		t.Fatal("should be blocked after reaching the limit")
+	}
+	for _, key := range []string{"link_unlock:1:abc:5.6.7.8", "link_unlock:2:abc:1.2.3.4"} {
+		if ok, _ := l.Reserve(key); !ok {
+			t.Fatalf("limits must be per key, %s was blocked", key)
+		}
+	}
+
+	// 第一次占用移出窗口后恢复
+	now = now.Add(13 * time.Minute)
+	if ok, _ := l.Reserve("link_unlock:1:abc:1.2.3.4"); !ok {
+		t.Fatal("oldest reservation should have left the window")
+	}
+}
+
+func TestSlidingWindowReserveRelease(t *testing.T) {
+	l := NewSlidingWindowLimiter(nil, 15*time.Minute, 2)
+	// 成功的尝试归还名额，不计数
+	for i := 0; i < 10; i++ {
+		ok, release := l.Reserve("k")
+		if !ok {
+			t.Fatalf("released reservations should never block (iteration %d)", i)
+		}
+		release()
+	}
+	l.Reserve("k")
+	_, release := l.Reserve("k")
+	if ok, _ := l.Reserve("k"); ok {
+		t.Fatal("should be blocked after two kept reservations")
+	}
+	release()
+	if ok, _ := l.Reserve("k"); !ok {
+		t.Fatal("releasing one reservation should free a slot")
+	}
+}
+
+func TestSlidingWindowReserveConcurrent(t *testing.T) {
+	l := NewSlidingWindowLimiter(nil, time.Minute, 5)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := l.Reserve("k"); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 5 {
+		t.Fatalf("concurrent reservations allowed = %d, want 5", allowed)
+	}
+}
+
+func TestSlidingWindowReserveSweep(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	l := NewSlidingWindowLimiter(nil, time.Minute, 5)
+	l.now = func() time.Time { return now }
+	for i := 0; i < reserveLocalSweepSize; i++ {
+		l.local[fmt.Sprint(i)] = []localReservation{{at: now}}
+	}
+	now = now.Add(2 * time.Minute)
+	l.Reserve("fresh")
+	if len(l.local) != 1 {
+		t.Fatalf("expired keys should be swept, %d left", len(l.local))
+	}
+}
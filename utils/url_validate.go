@@ -4,6 +4,7 @@
  * - 仅允许 http/https
  * - 禁止 localhost / 127.0.0.1 / ::1 / 私有网段 / link-local / multicast
  * - 对域名进行DNS解析并校验解析结果（可通过环境变量关闭）
+ * - 服务端主动发起的请求（webhook 投递）在建立连接时再次校验目标 IP（DialExternal），防止 DNS rebinding
  *
  * 可通过环境变量调整：
  * - ALLOW_PRIVATE_URLS=true 允许内网地址（默认 false）
@@ -18,6 +19,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -84,6 +86,25 @@ func ValidateExternalURL(raw string) error {
 	return nil
 }
 
+// DialExternal 返回在 DNS 解析后、建立连接前校验目标 IP 的 DialContext（ALLOW_PRIVATE_URLS=true 时不校验）
+func DialExternal(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := *dialer
+	d.Control = func(network, address string, _ syscall.RawConn) error {
+		if allowPrivateURLs() {
+			return nil
+		}
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if isPrivateIP(net.ParseIP(host)) {
+			return errors.New("不允许连接内网/本机地址")
+		}
+		return nil
+	}
+	return d.DialContext
+}
+
 func allowPrivateURLs() bool {
 	return strings.EqualFold(os.Getenv("ALLOW_PRIVATE_URLS"), "true")
 }
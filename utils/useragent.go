@@ -0,0 +1,80 @@
+/**
+ * User-Agent 解析工具
+ * 将原始 UA 解析为浏览器/版本、操作系统、设备类型与爬虫标记，供统计入库使用
+ */
+package utils
+
+import (
+	"strings"
+
+	"github.com/mileusna/useragent"
+)
+
+// 设备类型
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceUnknown = "unknown"
+)
+
+// 解析库未识别、但明显是脚本/爬虫的 UA 关键字（小写）
+var botKeywords = []string{
+	"bot", "spider", "crawl", "slurp", "curl", "wget", "python-requests", "python-urllib",
+	"go-http-client", "java/", "libwww", "headlesschrome", "scrapy", "httpie", "node-fetch", "axios",
+}
+
+// UserAgentInfo UA 解析结果
+type UserAgentInfo struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	Device         string
+	IsBot          bool
+}
+
+// ParseUserAgent 解析 UA（空 UA 视为爬虫）
+func ParseUserAgent(raw string) UserAgentInfo {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return UserAgentInfo{Device: DeviceUnknown, IsBot: true}
+	}
+
+	ua := useragent.Parse(raw)
+	info := UserAgentInfo{
+		Browser:        truncateUTF8(ua.Name, 64),
+		BrowserVersion: truncateUTF8(ua.VersionNoShort(), 32),
+		OS:             truncateUTF8(ua.OS, 64),
+		IsBot:          ua.Bot,
+	}
+
+	switch {
+	case ua.Tablet:
+		info.Device = DeviceTablet
+	case ua.Mobile:
+		info.Device = DeviceMobile
+	case ua.Desktop:
+		info.Device = DeviceDesktop
+	default:
+		info.Device = DeviceUnknown
+	}
+
+	if !info.IsBot {
+		lower := strings.ToLower(raw)
+		for _, kw := range botKeywords {
+			if strings.Contains(lower, kw) {
+				info.IsBot = true
+				break
+			}
+		}
+	}
+	return info
+}
+
+// truncateUTF8 按字节截断到列宽上限（截断产生的残缺字符会被丢弃）
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return strings.ToValidUTF8(s[:max], "")
+}
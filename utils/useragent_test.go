@@ -0,0 +1,33 @@
+package utils
+
+import "testing"
+
+func TestParseUserAgent(t *testing.T) {
+	cases := []struct {
+		name   string
+		ua     string
+		device string
+		bot    bool
+	}{
+		{"chrome desktop", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", DeviceDesktop, false},
+		{"safari iphone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", DeviceMobile, false},
+		{"safari ipad", "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1", DeviceTablet, false},
+		{"googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", "", true},
+		{"curl", "curl/8.4.0", "", true},
+		{"empty", "", DeviceUnknown, true},
+	}
+	for _, c := range cases {
+		info := ParseUserAgent(c.ua)
+		if info.IsBot != c.bot {
+			t.Errorf("%s: expected is_bot=%v, got %v", c.name, c.bot, info.IsBot)
+		}
+		if c.device != "" && info.Device != c.device {
+			t.Errorf("%s: expected device=%s, got %s", c.name, c.device, info.Device)
+		}
+	}
+
+	info := ParseUserAgent(cases[0].ua)
+	if info.Browser != "Chrome" || info.OS != "Windows" {
+		t.Errorf("expected Chrome/Windows, got %s/%s", info.Browser, info.OS)
+	}
+}
@@ -0,0 +1,46 @@
+/**
+ * Webhook 签名
+ * - 签名串为 "<timestamp>.<body>"，timestamp 为 Unix 秒（X-Webhook-Timestamp）
+ * - X-Webhook-Signature: sha256=<hex(HMAC-SHA256(secret, 签名串))>
+ * - 接收方校验签名并拒绝时间戳偏差过大的请求（防重放）
+ */
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webhookSignaturePrefix 签名头前缀（预留算法升级）
+const webhookSignaturePrefix = "sha256="
+
+// SignWebhook 计算 webhook 签名头的值
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature 校验 webhook 签名（常量时间比较）；tolerance > 0 时拒绝时间戳偏差超过 tolerance 的请求
+func VerifyWebhookSignature(secret string, timestamp string, body []byte, signature string, tolerance time.Duration, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if tolerance > 0 {
+		skew := now.Sub(time.Unix(ts, 0))
+		if skew > tolerance || skew < -tolerance {
+			return false
+		}
+	}
+	if !strings.HasPrefix(signature, webhookSignaturePrefix) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(SignWebhook(secret, ts, body)))
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	want := "sha256=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925"
+	if got := SignWebhook("whsec_test", 1700000000, body); got != want {
+		t.Fatalf("SignWebhook = %q, want %q", got, want)
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1700000000, 0)
+	sig := SignWebhook("whsec_test", now.Unix(), body)
+
+	if !VerifyWebhookSignature("whsec_test", "1700000000", body, sig, 5*time.Minute, now.Add(time.Minute)) {
+		t.Error("valid signature rejected")
+	}
+	if VerifyWebhookSignature("whsec_other", "1700000000", body, sig, 5*time.Minute, now) {
+		t.Error("signature with wrong secret accepted")
+	}
+	if VerifyWebhookSignature("whsec_test", "1700000000", []byte(`{"id":"evt_2"}`), sig, 5*time.Minute, now) {
+		t.Error("signature over different body accepted")
+	}
+	if VerifyWebhookSignature("whsec_test", "1700000001", body, sig, 5*time.Minute, now) {
+		t.Error("signature with different timestamp accepted")
+	}
+	if VerifyWebhookSignature("whsec_test", "1700000000", body, sig, 5*time.Minute, now.Add(10*time.Minute)) {
+		t.Error("stale timestamp accepted")
+	}
+	if !VerifyWebhookSignature("whsec_test", "1700000000", body, sig, 0, now.Add(10*time.Minute)) {
+		t.Error("tolerance 0 should skip the timestamp check")
+	}
+}